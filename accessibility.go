@@ -0,0 +1,137 @@
+package jplaw2epub
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// rubyReadingLang is the language of ruby readings, which are written in kana
+const rubyReadingLang = "ja-Hrkt"
+
+// htmlRootPattern matches the root element of a content document
+var htmlRootPattern = regexp.MustCompile(`<html\b[^>]*>`)
+
+// accessibilitySummaries are the schema:accessibilitySummary sentences by book language.
+// Sentences about figures, tables and ruby are only added when the book has them.
+var accessibilitySummaries = map[string]struct {
+	base, figures, tables, ruby string
+}{
+	"ja": {
+		base:    "本書は章、節及び条の見出しによる構造及び目次によるナビゲーションを備えています。",
+		figures: "図には表題又は備考から作成した代替テキストを付しています。",
+		tables:  "表には見出しセルの範囲及びキャプションを付しています。",
+		ruby:    "ルビには読みの言語を指定しています。",
+	},
+	"en": {
+		base:    "This publication has structured headings for chapters, sections and articles and a table of contents for navigation.",
+		figures: "Figures have alternative text taken from their titles or remarks.",
+		tables:  "Tables have captions and scoped header cells.",
+		ruby:    "Ruby readings are tagged with their language.",
+	},
+}
+
+// addAccessibility tags the language of content documents and ruby readings and adds
+// accessibility metadata to the package document. Books have no print pages, so there is
+// no page list.
+func addAccessibility(archive *epubArchive, lang string) error {
+	pkg := archive.file(epubPackagePath)
+	if pkg == nil {
+		return fmt.Errorf("package document %s not found", epubPackagePath)
+	}
+	var doc packageDocument
+	if err := xml.Unmarshal(pkg.data, &doc); err != nil {
+		return fmt.Errorf("parsing %s: %w", epubPackagePath, err)
+	}
+	if lang == "" {
+		lang = "ja"
+	}
+
+	var hasImages, hasTables, hasRuby bool
+	for _, item := range doc.Manifest.Items {
+		f := archive.file(resolveArchivePath(epubPackagePath, item.Href))
+		switch {
+		case f == nil:
+			continue
+		case strings.HasPrefix(item.MediaType, "image/") && !strings.Contains(item.Properties, "cover-image"):
+			hasImages = true
+		case item.MediaType == "application/xhtml+xml":
+			hasTables = hasTables || bytes.Contains(f.data, []byte("<table"))
+			hasRuby = hasRuby || bytes.Contains(f.data, []byte("<rt>"))
+			tagContentLanguage(f, lang)
+		}
+	}
+
+	metadata := accessibilityMetadata(lang, hasImages, hasTables, hasRuby)
+	opf, err := insertPackageMetadata(string(pkg.data), metadata)
+	if err != nil {
+		return err
+	}
+	pkg.data = []byte(opf)
+	return nil
+}
+
+// tagContentLanguage sets the language of a content document and tags ruby readings as kana
+func tagContentLanguage(f *epubArchiveFile, lang string) {
+	content := string(f.data)
+	if root := htmlRootPattern.FindString(content); root != "" && !strings.Contains(root, " lang=") {
+		tagged := strings.TrimSuffix(root, ">") + fmt.Sprintf(` lang="%s" xml:lang="%s">`, lang, lang)
+		content = strings.Replace(content, root, tagged, 1)
+	}
+	content = strings.ReplaceAll(content, "<rt>", fmt.Sprintf(`<rt lang="%s" xml:lang="%s">`, rubyReadingLang, rubyReadingLang))
+	f.data = []byte(content)
+}
+
+// accessibilityMetadata returns the schema.org accessibility metadata of a book
+func accessibilityMetadata(lang string, hasImages, hasTables, hasRuby bool) []string {
+	var elements []string
+	addMeta := func(property, value string) {
+		elements = append(elements, fmt.Sprintf(`<meta property="%s">%s</meta>`, property, escapeXMLText(value)))
+	}
+
+	addMeta("schema:accessMode", "textual")
+	if hasImages {
+		addMeta("schema:accessMode", "visual")
+		addMeta("schema:accessModeSufficient", "textual,visual")
+	} else {
+		addMeta("schema:accessModeSufficient", "textual")
+	}
+
+	features := []string{"structuralNavigation", "tableOfContents", "readingOrder"}
+	if hasImages {
+		features = append(features, "alternativeText")
+	}
+	if hasRuby {
+		features = append(features, "rubyAnnotations")
+	}
+	for _, feature := range features {
+		addMeta("schema:accessibilityFeature", feature)
+	}
+	addMeta("schema:accessibilityHazard", "none")
+
+	base := strings.SplitN(lang, "-", 2)[0]
+	summary, ok := accessibilitySummaries[base]
+	if !ok {
+		base, summary = "ja", accessibilitySummaries["ja"]
+	}
+	sentences := []string{summary.base}
+	if hasImages {
+		sentences = append(sentences, summary.figures)
+	}
+	if hasTables {
+		sentences = append(sentences, summary.tables)
+	}
+	if hasRuby {
+		sentences = append(sentences, summary.ruby)
+	}
+	// Japanese sentences are not separated by spaces
+	separator := " "
+	if base == "ja" {
+		separator = ""
+	}
+	text := strings.Join(sentences, separator)
+	addMeta("schema:accessibilitySummary", text)
+	return elements
+}
@@ -0,0 +1,103 @@
+package jplaw2epub
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteEPUBAccessibility(t *testing.T) {
+	files := writeTestEPUB(t, &EPUBOptions{NoCover: true})
+
+	opf := files[epubPackagePath]
+	for _, want := range []string{
+		`<meta property="schema:accessMode">textual</meta>`,
+		`<meta property="schema:accessModeSufficient">textual</meta>`,
+		`<meta property="schema:accessibilityFeature">structuralNavigation</meta>`,
+		`<meta property="schema:accessibilityFeature">tableOfContents</meta>`,
+		`<meta property="schema:accessibilityHazard">none</meta>`,
+		`<meta property="schema:accessibilitySummary">本書は`,
+		"表には見出しセルの範囲及びキャプションを付しています。",
+	} {
+		if !strings.Contains(opf, want) {
+			t.Errorf("package document missing %q:\n%s", want, opf)
+		}
+	}
+
+	// Books have no print pages, so they claim no page navigation
+	if strings.Contains(opf, "pageNavigation") || strings.Contains(files["EPUB/nav.xhtml"], "page-list") {
+		t.Errorf("book should have no page list or pageNavigation feature")
+	}
+
+	if title := files["EPUB/xhtml/title.xhtml"]; !strings.Contains(title, `lang="ja" xml:lang="ja">`) {
+		t.Errorf("title page missing the language:\n%s", title)
+	}
+
+	if issues := validateTestArchive(t, testEPUBArchive(t)); len(issues) > 0 {
+		t.Errorf("accessible EPUB should validate: %v", issues)
+	}
+}
+
+func TestTagContentLanguage(t *testing.T) {
+	f := &epubArchiveFile{
+		name: "EPUB/xhtml/a.xhtml",
+		data: []byte(`<html xmlns="http://www.w3.org/1999/xhtml"><body><ruby>瑕疵<rt>かし</rt></ruby></body></html>`),
+	}
+	tagContentLanguage(f, "en")
+
+	want := `<html xmlns="http://www.w3.org/1999/xhtml" lang="en" xml:lang="en"><body><ruby>瑕疵<rt lang="ja-Hrkt" xml:lang="ja-Hrkt">かし</rt></ruby></body></html>`
+	if got := string(f.data); got != want {
+		t.Errorf("tagContentLanguage() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestAccessibilityMetadata(t *testing.T) {
+	tests := []struct {
+		name      string
+		lang      string
+		hasImages bool
+		hasRuby   bool
+		want      []string
+		notWant   []string
+	}{
+		{
+			name:    "text only",
+			lang:    "ja",
+			want:    []string{`<meta property="schema:accessModeSufficient">textual</meta>`},
+			notWant: []string{"visual", "alternativeText", "rubyAnnotations"},
+		},
+		{
+			name:      "figures and ruby",
+			lang:      "ja",
+			hasImages: true,
+			hasRuby:   true,
+			want: []string{
+				`<meta property="schema:accessMode">visual</meta>`,
+				`<meta property="schema:accessModeSufficient">textual,visual</meta>`,
+				`<meta property="schema:accessibilityFeature">alternativeText</meta>`,
+				`<meta property="schema:accessibilityFeature">rubyAnnotations</meta>`,
+				"代替テキスト",
+			},
+		},
+		{
+			name: "English translation",
+			lang: "en",
+			want: []string{`<meta property="schema:accessibilitySummary">This publication`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := strings.Join(accessibilityMetadata(tt.lang, tt.hasImages, false, tt.hasRuby), "\n")
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("accessibilityMetadata() missing %q:\n%s", want, got)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("accessibilityMetadata() should not contain %q:\n%s", notWant, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package jplaw2epub
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	jplaw "go.ngs.io/jplaw-xml"
+)
+
+// amendProvision is an amendment provision (改正規定) of an amending law: the amendment
+// instruction (改め文) and the provision text it adds or substitutes. The jplaw types have no
+// AmendProvision, so these are decoded from the XML separately.
+type amendProvision struct {
+	Sentence     []jplaw.Sentence `xml:"AmendProvisionSentence>Sentence"`
+	NewProvision []newProvision   `xml:"NewProvision"`
+}
+
+// newProvision is the provision text of an amendment provision
+type newProvision struct {
+	Chapter     []jplaw.Chapter     `xml:"Chapter"`
+	Section     []jplaw.Section     `xml:"Section"`
+	Article     []jplaw.Article     `xml:"Article"`
+	Paragraph   []jplaw.Paragraph   `xml:"Paragraph"`
+	Item        []jplaw.Item        `xml:"Item"`
+	Sentence    []jplaw.Sentence    `xml:"Sentence"`
+	TableStruct []jplaw.TableStruct `xml:"TableStruct"`
+	FigStruct   []jplaw.FigStruct   `xml:"FigStruct"`
+	StyleStruct []jplaw.StyleStruct `xml:"StyleStruct"`
+	AppdxTable  []jplaw.AppdxTable  `xml:"AppdxTable"`
+}
+
+// amendLaw mirrors the parts of a law that can hold amendment provisions
+type amendLaw struct {
+	LawBody struct {
+		MainProvision  amendProvisionParent   `xml:"MainProvision"`
+		SupplProvision []amendProvisionParent `xml:"SupplProvision"`
+	} `xml:"LawBody"`
+}
+
+// amendProvisionParent mirrors a main or supplementary provision
+type amendProvisionParent struct {
+	Chapter   []amendChapter   `xml:"Chapter"`
+	Article   []amendArticle   `xml:"Article"`
+	Paragraph []amendParagraph `xml:"Paragraph"`
+}
+
+// amendChapter mirrors a chapter
+type amendChapter struct {
+	Section []amendSection `xml:"Section"`
+	Article []amendArticle `xml:"Article"`
+}
+
+// amendSection mirrors a section
+type amendSection struct {
+	Article []amendArticle `xml:"Article"`
+}
+
+// amendArticle mirrors an article
+type amendArticle struct {
+	Paragraph []amendParagraph `xml:"Paragraph"`
+}
+
+// amendParagraph mirrors a paragraph with its amendment provisions
+type amendParagraph struct {
+	AmendProvision []amendProvision `xml:"AmendProvision"`
+}
+
+// amendProvisionIndex maps the paragraphs of a parsed law to their amendment provisions
+type amendProvisionIndex map[*jplaw.Paragraph][]amendProvision
+
+// decodeAmendProvisions decodes the amendment provisions of the XML data of law
+func decodeAmendProvisions(data []byte, law *jplaw.Law) (amendProvisionIndex, error) {
+	if !bytes.Contains(data, []byte("<AmendProvision")) {
+		return nil, nil
+	}
+	var mirror amendLaw
+	if err := xml.Unmarshal(data, &mirror); err != nil {
+		return nil, fmt.Errorf("unmarshalling amendment provisions: %w", err)
+	}
+
+	index := make(amendProvisionIndex)
+	mainProv := &law.LawBody.MainProvision
+	index.addProvision(mainProv.Chapter, mainProv.Article, mainProv.Paragraph, &mirror.LawBody.MainProvision)
+	for i := range law.LawBody.SupplProvision {
+		if i >= len(mirror.LawBody.SupplProvision) {
+			break
+		}
+		provision := &law.LawBody.SupplProvision[i]
+		index.addProvision(provision.Chapter, provision.Article, provision.Paragraph, &mirror.LawBody.SupplProvision[i])
+	}
+	return index, nil
+}
+
+// addProvision indexes the paragraphs of a main or supplementary provision
+func (index amendProvisionIndex) addProvision(
+	chapters []jplaw.Chapter,
+	articles []jplaw.Article,
+	paragraphs []jplaw.Paragraph,
+	mirror *amendProvisionParent,
+) {
+	for i := range chapters {
+		if i >= len(mirror.Chapter) {
+			break
+		}
+		chapter := &chapters[i]
+		for j := range chapter.Section {
+			if j >= len(mirror.Chapter[i].Section) {
+				break
+			}
+			index.addArticles(chapter.Section[j].Article, mirror.Chapter[i].Section[j].Article)
+		}
+		index.addArticles(chapter.Article, mirror.Chapter[i].Article)
+	}
+	index.addArticles(articles, mirror.Article)
+	index.addParagraphs(paragraphs, mirror.Paragraph)
+}
+
+// addArticles indexes the paragraphs of articles
+func (index amendProvisionIndex) addArticles(articles []jplaw.Article, mirror []amendArticle) {
+	for i := range articles {
+		if i >= len(mirror) {
+			break
+		}
+		index.addParagraphs(articles[i].Paragraph, mirror[i].Paragraph)
+	}
+}
+
+// addParagraphs indexes paragraphs that have amendment provisions
+func (index amendProvisionIndex) addParagraphs(paragraphs []jplaw.Paragraph, mirror []amendParagraph) {
+	for i := range paragraphs {
+		if i >= len(mirror) {
+			break
+		}
+		if len(mirror[i].AmendProvision) > 0 {
+			index[&paragraphs[i]] = mirror[i].AmendProvision
+		}
+	}
+}
+
+// amendingImageProcessor hands the amendment provisions of the law being converted to the
+// paragraph renderer along with the image processor it wraps, which is nil without images
+type amendingImageProcessor struct {
+	images     ImageProcessorInterface
+	amendments amendProvisionIndex
+}
+
+// withAmendProvisions returns imgProc carrying amendments, or imgProc itself when there are none
+func withAmendProvisions(imgProc ImageProcessorInterface, amendments amendProvisionIndex) ImageProcessorInterface {
+	if len(amendments) == 0 {
+		return imgProc
+	}
+	return &amendingImageProcessor{images: imgProc, amendments: amendments}
+}
+
+// ProcessFigStruct processes the figure with the wrapped image processor
+func (p *amendingImageProcessor) ProcessFigStruct(fig *jplaw.FigStruct) (string, error) {
+	if p.images == nil {
+		return "", fmt.Errorf("images are not processed")
+	}
+	return p.images.ProcessFigStruct(fig)
+}
+
+// SetMaxImageHeight sets the maximum height of the wrapped image processor
+func (p *amendingImageProcessor) SetMaxImageHeight(height string) {
+	if p.images != nil {
+		p.images.SetMaxImageHeight(height)
+	}
+}
+
+// hasImages reports whether imgProc processes images, looking through amendment provisions
+func hasImages(imgProc ImageProcessorInterface) bool {
+	if p, ok := imgProc.(*amendingImageProcessor); ok {
+		return p.images != nil
+	}
+	return imgProc != nil
+}
+
+// amendProvisionsOf returns the amendment provisions of a paragraph carried by imgProc
+func amendProvisionsOf(imgProc ImageProcessorInterface, para *jplaw.Paragraph) []amendProvision {
+	if p, ok := imgProc.(*amendingImageProcessor); ok {
+		return p.amendments[para]
+	}
+	return nil
+}
+
+// processAmendProvisions renders amendment provisions, setting the provision text apart
+// from the amendment instruction
+func processAmendProvisions(provisions []amendProvision, imgProc ImageProcessorInterface) string {
+	var body strings.Builder
+	for i := range provisions {
+		provision := &provisions[i]
+		body.WriteString(`<div class="amend-provision">`)
+		if len(provision.Sentence) > 0 {
+			body.WriteString(`<p class="amend-provision-sentence">`)
+			for j := range provision.Sentence {
+				body.WriteString(provision.Sentence[j].HTML())
+			}
+			body.WriteString("</p>")
+		}
+		for j := range provision.NewProvision {
+			body.WriteString(`<blockquote class="new-provision">`)
+			body.WriteString(processNewProvision(&provision.NewProvision[j], imgProc))
+			body.WriteString("</blockquote>")
+		}
+		body.WriteString(htmlDivEnd)
+	}
+	return body.String()
+}
+
+// processNewProvision renders the provision text of an amendment provision. Its articles may
+// share numbers with the articles of the amending law, so they get no ids.
+func processNewProvision(provision *newProvision, imgProc ImageProcessorInterface) string {
+	var body strings.Builder
+
+	for i := range provision.Chapter {
+		chapter := &provision.Chapter[i]
+		body.WriteString(provisionTitleHTML(processTextWithRuby(chapter.ChapterTitle.Content, chapter.ChapterTitle.Ruby)))
+		body.WriteString(processNewProvisionSections(chapter.Section, imgProc))
+		for j := range chapter.Article {
+			body.WriteString(processNestedArticle(&chapter.Article[j], imgProc))
+		}
+	}
+	body.WriteString(processNewProvisionSections(provision.Section, imgProc))
+	for i := range provision.Article {
+		body.WriteString(processNestedArticle(&provision.Article[i], imgProc))
+	}
+	body.WriteString(processParagraphsWithImages(provision.Paragraph, imgProc))
+	body.WriteString(processItemsWithImages(provision.Item, imgProc))
+
+	if len(provision.Sentence) > 0 {
+		body.WriteString("<p>")
+		for i := range provision.Sentence {
+			body.WriteString(provision.Sentence[i].HTML())
+		}
+		body.WriteString("</p>")
+	}
+
+	body.WriteString(processTableStructs(provision.TableStruct, imgProc))
+	if hasImages(imgProc) {
+		for i := range provision.FigStruct {
+			if figHTML, err := imgProc.ProcessFigStruct(&provision.FigStruct[i]); err == nil {
+				body.WriteString(figHTML)
+			}
+		}
+	}
+	body.WriteString(ProcessStyleStructs(provision.StyleStruct, imgProc))
+
+	for i := range provision.AppdxTable {
+		table := &provision.AppdxTable[i]
+		if table.AppdxTableTitle != nil {
+			body.WriteString(provisionTitleHTML(processTextWithRuby(table.AppdxTableTitle.Content, table.AppdxTableTitle.Ruby)))
+		}
+		body.WriteString(processTableStructs(table.TableStruct, imgProc))
+		body.WriteString(processItemsWithImages(table.Item, imgProc))
+		if table.Remarks != nil {
+			body.WriteString(processRemarks(table.Remarks))
+		}
+	}
+
+	return body.String()
+}
+
+// processNewProvisionSections renders the sections of the provision text of an amendment provision
+func processNewProvisionSections(sections []jplaw.Section, imgProc ImageProcessorInterface) string {
+	var body strings.Builder
+	for i := range sections {
+		section := &sections[i]
+		body.WriteString(provisionTitleHTML(processTextWithRuby(section.SectionTitle.Content, section.SectionTitle.Ruby)))
+		for j := range section.Article {
+			body.WriteString(processNestedArticle(&section.Article[j], imgProc))
+		}
+	}
+	return body.String()
+}
+
+// provisionTitleHTML returns the title of a chapter, section or appendix within provision text
+func provisionTitleHTML(title string) string {
+	if title == "" {
+		return ""
+	}
+	return fmt.Sprintf(`<p class="provision-title">%s</p>`, title)
+}
@@ -0,0 +1,166 @@
+package jplaw2epub
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	jplaw "go.ngs.io/jplaw-xml"
+)
+
+func TestDecodeAmendProvisions(t *testing.T) {
+	data, amendments, err := loadLawFromReader(bytes.NewReader(readTestdata(t, "amendment.xml")))
+	if err != nil {
+		t.Fatalf("loadLawFromReader() error = %v", err)
+	}
+
+	if len(amendments) != 1 {
+		t.Fatalf("amendment index has %d paragraphs, want 1", len(amendments))
+	}
+	provisions := amendments[&data.LawBody.MainProvision.Paragraph[0]]
+	if len(provisions) != 3 {
+		t.Fatalf("main provision paragraph has %d amendment provisions, want 3", len(provisions))
+	}
+	if got := provisions[0].Sentence[0].HTML(); got != "第一条中「別表」を「別表第一」に改める。" {
+		t.Errorf("first amendment sentence = %q", got)
+	}
+	if len(provisions[0].NewProvision) != 0 {
+		t.Errorf("first amendment has %d new provisions, want 0", len(provisions[0].NewProvision))
+	}
+	if len(provisions[1].NewProvision) != 1 || len(provisions[1].NewProvision[0].Article) != 1 {
+		t.Fatalf("second amendment new provisions = %+v, want one article", provisions[1].NewProvision)
+	}
+	if got := provisions[1].NewProvision[0].Article[0].ArticleTitle.Content; got != "第一条の三" {
+		t.Errorf("new article title = %q, want 第一条の三", got)
+	}
+}
+
+func TestDecodeAmendProvisionsWithoutAmendments(t *testing.T) {
+	_, amendments, err := loadLawFromReader(bytes.NewReader(readTestdata(t, "json/articles.xml")))
+	if err != nil {
+		t.Fatalf("loadLawFromReader() error = %v", err)
+	}
+	if amendments != nil {
+		t.Errorf("amendment index = %v, want nil", amendments)
+	}
+}
+
+func TestProcessAmendProvisions(t *testing.T) {
+	provisions := []amendProvision{{
+		Sentence: []jplaw.Sentence{createTestSentence("第二章の次に次の一章を加える。")},
+		NewProvision: []newProvision{{
+			Chapter: []jplaw.Chapter{{
+				ChapterTitle: jplaw.ChapterTitle{Content: "第三章　雑則"},
+				Section: []jplaw.Section{{
+					SectionTitle: jplaw.SectionTitle{Content: "第一節　通則"},
+					Article: []jplaw.Article{{
+						ArticleTitle: &jplaw.ArticleTitle{Content: "第十条"},
+						Paragraph: []jplaw.Paragraph{{
+							ParagraphSentence: jplaw.ParagraphSentence{Sentence: []jplaw.Sentence{createTestSentence("新しい規定")}},
+						}},
+					}},
+				}},
+			}},
+		}},
+	}}
+
+	result := processAmendProvisions(provisions, nil)
+
+	for _, want := range []string{
+		`<p class="amend-provision-sentence">第二章の次に次の一章を加える。</p>`,
+		`<blockquote class="new-provision">`,
+		`<p class="provision-title">第三章　雑則</p>`,
+		`<p class="provision-title">第一節　通則</p>`,
+		`<p class="article-title">第十条</p>`,
+		"新しい規定",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("processAmendProvisions() missing %q in %s", want, result)
+		}
+	}
+	if strings.Index(result, "第二章の次に") > strings.Index(result, "<blockquote") {
+		t.Errorf("amendment instruction should precede the new provision: %s", result)
+	}
+	if strings.Contains(result, "id=") {
+		t.Errorf("new provisions should have no ids: %s", result)
+	}
+}
+
+func TestAmendProvisionsInEPUB(t *testing.T) {
+	tests := []struct {
+		name string
+		opts *EPUBOptions
+	}{
+		{name: "whole law", opts: &EPUBOptions{NoCover: true}},
+		{name: "excerpt", opts: &EPUBOptions{NoCover: true, NoSupplProvisions: true}},
+		{name: "with images", opts: &EPUBOptions{NoCover: true, APIClient: &MockAPIClient{}, RevisionID: "rev"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			book, err := CreateEPUBFromXMLFileWithOptions(bytes.NewReader(readTestdata(t, "amendment.xml")), tt.opts)
+			if err != nil {
+				t.Fatalf("CreateEPUBFromXMLFileWithOptions() error = %v", err)
+			}
+			var buf bytes.Buffer
+			if err := WriteEPUBTo(book, &buf); err != nil {
+				t.Fatalf("WriteEPUBTo() error = %v", err)
+			}
+			archive, err := readEPUBArchive(buf.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var content string
+			for _, f := range archive.files {
+				if strings.Contains(string(f.data), "amend-provision") {
+					content = string(f.data)
+				}
+			}
+			for _, want := range []string{
+				"規程令（平成二十年政令第三号）の一部を次のように改正する。",
+				"第一条の二の次に次の一条を加える。",
+				"手数料は、収入印紙で納付しなければならない。",
+				"第一条の二中「ただし、免除することができる。」を削る。",
+			} {
+				if !strings.Contains(content, want) {
+					t.Errorf("EPUB content missing %q", want)
+				}
+			}
+			if issues := validateTestArchive(t, archive); len(issues) > 0 {
+				t.Errorf("validation issues: %v", issues)
+			}
+		})
+	}
+}
+
+func TestAmendingImageProcessor(t *testing.T) {
+	para := &jplaw.Paragraph{}
+	provisions := []amendProvision{{}}
+	amendments := amendProvisionIndex{para: provisions}
+
+	if got := withAmendProvisions(nil, nil); got != nil {
+		t.Errorf("withAmendProvisions(nil, nil) = %v, want nil", got)
+	}
+	imgProc := withAmendProvisions(nil, amendments)
+	if hasImages(imgProc) {
+		t.Error("hasImages() = true for amendment provisions without an image processor")
+	}
+	if got := amendProvisionsOf(imgProc, para); len(got) != 1 {
+		t.Errorf("amendProvisionsOf() = %v, want the indexed provisions", got)
+	}
+	if got := amendProvisionsOf(imgProc, &jplaw.Paragraph{}); got != nil {
+		t.Errorf("amendProvisionsOf() of another paragraph = %v, want nil", got)
+	}
+	if _, err := imgProc.ProcessFigStruct(&jplaw.FigStruct{}); err == nil {
+		t.Error("ProcessFigStruct() without an image processor should fail")
+	}
+
+	images := NewImageProcessor(&MockAPIClient{}, "rev", nil)
+	if !hasImages(withAmendProvisions(images, amendments)) {
+		t.Error("hasImages() = false for a wrapped image processor")
+	}
+	if got := amendProvisionsOf(images, para); got != nil {
+		t.Errorf("amendProvisionsOf() of a plain image processor = %v, want nil", got)
+	}
+}
@@ -0,0 +1,282 @@
+package jplaw2epub
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+
+	jplaw "go.ngs.io/jplaw-xml"
+	"gopkg.in/yaml.v3"
+)
+
+// Annotations are notes on the articles of the main provision, keyed by article number
+// in any form ParseProvisionNumber accepts (10_2, 10の2 or 第十条の二)
+type Annotations map[string]ArticleAnnotation
+
+// ArticleAnnotation is the commentary, highlighted phrases and related articles of an article
+type ArticleAnnotation struct {
+	// Notes are shown in a commentary box at the end of the article, one paragraph each
+	Notes StringList `json:"notes,omitempty" yaml:"notes,omitempty"`
+	// Highlights are phrases marked wherever they occur in the article text
+	Highlights StringList `json:"highlights,omitempty" yaml:"highlights,omitempty"`
+	// SeeAlso are the numbers of related articles, linked from the commentary box
+	SeeAlso StringList `json:"see_also,omitempty" yaml:"see_also,omitempty"`
+}
+
+// StringList is a list of strings that may also be written as a single string, and
+// whose numbers are read as strings, so that see_also: [2, 3_2] works in YAML and JSON
+type StringList []string
+
+// UnmarshalJSON reads a string, a number or a list of them
+func (l *StringList) UnmarshalJSON(data []byte) error {
+	var values []json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		values = []json.RawMessage{data}
+	}
+	list := make(StringList, 0, len(values))
+	for _, value := range values {
+		var s string
+		if err := json.Unmarshal(value, &s); err == nil {
+			list = append(list, s)
+			continue
+		}
+		var n json.Number
+		if err := json.Unmarshal(value, &n); err != nil {
+			return fmt.Errorf("expected a string or number, got %s", value)
+		}
+		list = append(list, n.String())
+	}
+	*l = list
+	return nil
+}
+
+// UnmarshalYAML reads a scalar or a list of scalars, keeping numbers as written
+func (l *StringList) UnmarshalYAML(value *yaml.Node) error {
+	values := []*yaml.Node{value}
+	if value.Kind == yaml.SequenceNode {
+		values = value.Content
+	}
+	list := make(StringList, 0, len(values))
+	for _, v := range values {
+		if tag := v.ShortTag(); tag != "!!str" && tag != "!!int" && tag != "!!float" {
+			return fmt.Errorf("line %d: expected a string or number", v.Line)
+		}
+		list = append(list, v.Value)
+	}
+	*l = list
+	return nil
+}
+
+// AnnotationWarning is an annotation that could not be anchored in the law, usually
+// because the article or phrase was removed or renumbered by an amendment
+type AnnotationWarning struct {
+	// Article is the article number the annotation is keyed by
+	Article string
+	Message string
+}
+
+// String formats the warning as article: message
+func (w AnnotationWarning) String() string {
+	return fmt.Sprintf("%s: %s", w.Article, w.Message)
+}
+
+// ParseAnnotations parses annotations written in JSON or YAML
+func ParseAnnotations(data []byte) (Annotations, error) {
+	var annotations Annotations
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	var err error
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		err = json.Unmarshal(trimmed, &annotations)
+	} else {
+		err = yaml.Unmarshal(trimmed, &annotations)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing annotations: %w", err)
+	}
+	return annotations, nil
+}
+
+// articleSectionPattern matches the start of an article section and its anchor
+var articleSectionPattern = regexp.MustCompile(`<section class="article" id="(art-[^"]+)">`)
+
+// annotationTarget is an article of the main provision annotations can be anchored to
+type annotationTarget struct {
+	anchor string
+	href   string
+	title  string
+}
+
+// annotatedArticle is an annotation resolved to its article
+type annotatedArticle struct {
+	key        string
+	target     annotationTarget
+	annotation ArticleAnnotation
+	seeAlso    []annotationTarget
+}
+
+// annotationWriter is a BookWriter that adds annotations to the article bodies
+type annotationWriter struct {
+	BookWriter
+	articles map[string]*annotatedArticle
+	warn     func(AnnotationWarning)
+}
+
+// newAnnotationWriter wraps book with the annotations of the articles of the main provision,
+// reporting annotations that cannot be anchored to warn
+func newAnnotationWriter(
+	book BookWriter,
+	data *jplaw.Law,
+	law *JSONLaw,
+	annotations Annotations,
+	warn func(AnnotationWarning),
+) *annotationWriter {
+	if warn == nil {
+		warn = func(AnnotationWarning) {}
+	}
+	w := &annotationWriter{BookWriter: book, articles: make(map[string]*annotatedArticle), warn: warn}
+
+	targets := make(map[string]annotationTarget)
+	for _, articles := range mainProvisionArticleLists(&data.LawBody.MainProvision) {
+		for i := range *articles {
+			article := &(*articles)[i]
+			number, ok := articleNumber(article)
+			if !ok {
+				continue
+			}
+			found := FindArticle(law, number)
+			if _, exists := targets[number.String()]; exists || found == nil {
+				continue
+			}
+			targets[number.String()] = annotationTarget{
+				anchor: articleAnchor(article),
+				href:   found.Href,
+				title:  getArticleTitlePlain(article),
+			}
+		}
+	}
+	lookup := func(key string) (annotationTarget, bool) {
+		number, err := ParseProvisionNumber(key)
+		if err != nil {
+			return annotationTarget{}, false
+		}
+		target, ok := targets[number.String()]
+		return target, ok
+	}
+
+	keys := make([]string, 0, len(annotations))
+	for key := range annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		target, ok := lookup(key)
+		if !ok {
+			w.warn(AnnotationWarning{Article: key, Message: "article not found"})
+			continue
+		}
+		article := &annotatedArticle{key: key, target: target, annotation: annotations[key]}
+		for _, ref := range article.annotation.SeeAlso {
+			if related, ok := lookup(ref); ok {
+				article.seeAlso = append(article.seeAlso, related)
+			} else {
+				w.warn(AnnotationWarning{Article: key, Message: fmt.Sprintf("see-also article %s not found", ref)})
+			}
+		}
+		w.articles[target.anchor] = article
+	}
+	return w
+}
+
+// AddSection adds annotations to body before adding the section
+func (w *annotationWriter) AddSection(body, sectionTitle, internalFilename, internalCSSPath string) (string, error) {
+	return w.BookWriter.AddSection(w.annotate(body, internalFilename), sectionTitle, internalFilename, internalCSSPath)
+}
+
+// AddSubSection adds annotations to body before adding the subsection
+func (w *annotationWriter) AddSubSection(parentFilename, body, sectionTitle, internalFilename, internalCSSPath string) (string, error) {
+	return w.BookWriter.AddSubSection(parentFilename, w.annotate(body, internalFilename), sectionTitle, internalFilename, internalCSSPath)
+}
+
+// annotate highlights the phrases of the annotated article sections in body and adds their
+// commentary boxes at the end of the sections
+func (w *annotationWriter) annotate(body, filename string) string {
+	var out strings.Builder
+	last := 0
+	for _, loc := range articleSectionPattern.FindAllStringSubmatchIndex(body, -1) {
+		article := w.articles[body[loc[2]:loc[3]]]
+		end := strings.Index(body[loc[1]:], htmlSectionEnd)
+		if article == nil || article.target.href != filename || end < 0 {
+			continue
+		}
+		end += loc[1]
+		out.WriteString(body[last:loc[1]])
+		out.WriteString(w.highlight(article, body[loc[1]:end]))
+		out.WriteString(article.commentary(filename))
+		last = end
+	}
+	if last == 0 {
+		return body
+	}
+	out.WriteString(body[last:])
+	return out.String()
+}
+
+// highlight marks the highlighted phrases of article in its section body
+func (w *annotationWriter) highlight(article *annotatedArticle, section string) string {
+	for _, phrase := range article.annotation.Highlights {
+		escaped := html.EscapeString(phrase)
+		if escaped == "" {
+			continue
+		}
+		found := false
+		section = mapTextSegments(section, func(text string) string {
+			if !strings.Contains(text, escaped) {
+				return text
+			}
+			found = true
+			return strings.ReplaceAll(text, escaped, `<mark class="annotation-highlight">`+escaped+"</mark>")
+		})
+		if !found {
+			w.warn(AnnotationWarning{Article: article.key, Message: fmt.Sprintf("「%s」 not found", phrase)})
+		}
+	}
+	return section
+}
+
+// commentary renders the commentary box of article, linking the related articles from filename
+func (a *annotatedArticle) commentary(filename string) string {
+	if len(a.annotation.Notes) == 0 && len(a.seeAlso) == 0 {
+		return ""
+	}
+
+	var body strings.Builder
+	body.WriteString(`<aside class="annotation" role="note">`)
+	for _, note := range a.annotation.Notes {
+		for _, para := range strings.Split(strings.TrimSpace(note), "\n\n") {
+			if para = strings.TrimSpace(para); para != "" {
+				lines := strings.Split(html.EscapeString(para), "\n")
+				body.WriteString("<p>" + strings.Join(lines, "<br/>") + "</p>")
+			}
+		}
+	}
+	if len(a.seeAlso) > 0 {
+		body.WriteString(`<p class="annotation-see-also">参照：`)
+		for i, related := range a.seeAlso {
+			if i > 0 {
+				body.WriteString("、")
+			}
+			href := "#" + related.anchor
+			if related.href != filename {
+				href = related.href + href
+			}
+			body.WriteString(fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(href), html.EscapeString(related.title)))
+		}
+		body.WriteString("</p>")
+	}
+	body.WriteString("</aside>")
+	return body.String()
+}
@@ -0,0 +1,151 @@
+package jplaw2epub
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseAnnotations(t *testing.T) {
+	want := Annotations{
+		"1_2": {
+			Notes:      StringList{"免除の運用に注意。\n通達あり。\n"},
+			Highlights: StringList{"手数料の額"},
+			SeeAlso:    StringList{"1", "第一条の二"},
+		},
+		"第一条": {Notes: StringList{"単独の注記"}},
+		"3":   {SeeAlso: StringList{"10"}},
+	}
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{
+			name: "YAML",
+			data: "# 規程令の注釈\n\"1_2\":\n  notes:\n    - |\n      免除の運用に注意。\n      通達あり。\n" +
+				"  highlights: [手数料の額]\n  see_also: [1, 第一条の二]\n第一条:\n  notes: 単独の注記\n3:\n  see_also: 10\n",
+		},
+		{
+			name: "JSON",
+			data: `{"1_2": {"notes": ["免除の運用に注意。\n通達あり。\n"], "highlights": ["手数料の額"], "see_also": [1, "第一条の二"]},` +
+				` "第一条": {"notes": "単独の注記"}, "3": {"see_also": 10}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAnnotations([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("ParseAnnotations() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("ParseAnnotations() = %#v, want %#v", got, want)
+			}
+		})
+	}
+
+	if _, err := ParseAnnotations([]byte(`{"1": {"see_also": [true]}}`)); err == nil {
+		t.Error("ParseAnnotations() with a boolean see_also should fail")
+	}
+	if _, err := ParseAnnotations([]byte("1:\n  see_also: [true]\n")); err == nil {
+		t.Error("ParseAnnotations() with a boolean see_also in YAML should fail")
+	}
+}
+
+func TestAnnotationsInEPUB(t *testing.T) {
+	var warnings []string
+	opts := &EPUBOptions{
+		NoCover: true,
+		Annotations: Annotations{
+			"1_2": {
+				Notes:      StringList{"免除の運用に注意。\n\n通達 <第5号> あり。"},
+				Highlights: StringList{"手数料の額", "存在しない語句"},
+				SeeAlso:    StringList{"1", "9"},
+			},
+			"第一条": {Highlights: StringList{"別表"}},
+			"9":   {Notes: StringList{"削除された条"}},
+		},
+		AnnotationWarning: func(w AnnotationWarning) { warnings = append(warnings, w.String()) },
+	}
+
+	book, err := CreateEPUBFromXMLFileWithOptions(bytes.NewReader(readTestdata(t, "json/articles.xml")), opts)
+	if err != nil {
+		t.Fatalf("CreateEPUBFromXMLFileWithOptions() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := WriteEPUBTo(book, &buf); err != nil {
+		t.Fatalf("WriteEPUBTo() error = %v", err)
+	}
+	archive, err := readEPUBArchive(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var first, second, suppl string
+	for _, f := range archive.files {
+		switch content := string(f.data); {
+		case strings.Contains(content, `id="art-1_2"`):
+			second = content
+		case strings.Contains(content, "施行する"):
+			suppl = content
+		case strings.Contains(content, `id="art-1"`):
+			first = content
+		}
+	}
+
+	for _, want := range []string{
+		`<mark class="annotation-highlight">手数料の額</mark>`,
+		`<aside class="annotation" role="note"><p>免除の運用に注意。</p><p>通達 &lt;第5号&gt; あり。</p>`,
+		`<p class="annotation-see-also">参照：<a href="article-0.xhtml#art-1">第一条</a></p></aside></section>`,
+	} {
+		if !strings.Contains(second, want) {
+			t.Errorf("第一条の二 missing %q in %s", want, second)
+		}
+	}
+	if !strings.Contains(first, `<mark class="annotation-highlight">別表</mark>`) || strings.Contains(first, "annotation\"") {
+		t.Errorf("第一条 should have a highlight and no commentary box: %s", first)
+	}
+	if strings.Contains(suppl, "annotation") {
+		t.Errorf("supplementary provision articles should not be annotated: %s", suppl)
+	}
+
+	wantWarnings := []string{
+		"1_2: see-also article 9 not found",
+		"9: article not found",
+		"1_2: 「存在しない語句」 not found",
+	}
+	if !reflect.DeepEqual(warnings, wantWarnings) {
+		t.Errorf("warnings = %q, want %q", warnings, wantWarnings)
+	}
+	if issues := validateTestArchive(t, archive); len(issues) > 0 {
+		t.Errorf("validation issues: %v", issues)
+	}
+}
+
+func TestAnnotationsAfterAmendment(t *testing.T) {
+	law, _, err := ApplyAmendments(mustLoadTestdataLaw(t, "json/articles.xml"), bytes.NewReader(readTestdata(t, "amendment.xml")))
+	if err != nil {
+		t.Fatalf("ApplyAmendments() error = %v", err)
+	}
+
+	var warnings []string
+	opts := &EPUBOptions{
+		NoCover: true,
+		Annotations: Annotations{
+			"1_2": {Highlights: StringList{"免除することができる"}},
+			"1_3": {Notes: StringList{"新設"}, SeeAlso: StringList{"1_2"}},
+		},
+		AnnotationWarning: func(w AnnotationWarning) { warnings = append(warnings, w.String()) },
+	}
+	if _, err := CreateEPUBFromLawWithContext(context.Background(), law, opts); err != nil {
+		t.Fatalf("CreateEPUBFromLawWithContext() error = %v", err)
+	}
+
+	want := []string{"1_2: 「免除することができる」 not found"}
+	if !reflect.DeepEqual(warnings, want) {
+		t.Errorf("warnings = %q, want %q", warnings, want)
+	}
+}
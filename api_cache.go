@@ -0,0 +1,68 @@
+package jplaw2epub
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	lawapi "go.ngs.io/jplaw-api-v2"
+)
+
+// cachingAPIClient is an APIClient that keeps downloaded attachments in a directory
+type cachingAPIClient struct {
+	client APIClient
+	dir    string
+}
+
+// NewCachingAPIClient returns an APIClient that keeps the attachments client downloads in dir,
+// one subdirectory per revision, and reads them from there on later conversions
+func NewCachingAPIClient(client APIClient, dir string) ContextAPIClient {
+	return &cachingAPIClient{client: client, dir: dir}
+}
+
+// GetAttachment returns the cached attachment, downloading it on a miss
+func (c *cachingAPIClient) GetAttachment(lawRevisionID string, params *lawapi.GetAttachmentParams) (*string, error) {
+	return c.GetAttachmentWithContext(context.Background(), lawRevisionID, params)
+}
+
+// GetAttachmentWithContext returns the cached attachment, downloading it on a miss until ctx is done
+func (c *cachingAPIClient) GetAttachmentWithContext(
+	ctx context.Context,
+	lawRevisionID string,
+	params *lawapi.GetAttachmentParams,
+) (*string, error) {
+	cachePath := c.path(lawRevisionID, params)
+	if cachePath != "" {
+		if data, err := os.ReadFile(cachePath); err == nil {
+			attachment := string(data)
+			return &attachment, nil
+		}
+	}
+
+	attachment, err := getAttachment(ctx, c.client, lawRevisionID, params)
+	if err != nil || attachment == nil || cachePath == "" {
+		return attachment, err
+	}
+
+	// A failed write only costs a download next time, so it is not reported
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0o755); err == nil {
+		tmp := cachePath + ".tmp"
+		if err := os.WriteFile(tmp, []byte(*attachment), 0o644); err == nil {
+			_ = os.Rename(tmp, cachePath)
+		}
+	}
+	return attachment, nil
+}
+
+// path returns the cache file of an attachment, or an empty string when it cannot be cached
+func (c *cachingAPIClient) path(lawRevisionID string, params *lawapi.GetAttachmentParams) string {
+	if params == nil || params.Src == nil || *params.Src == "" || lawRevisionID == "" {
+		return ""
+	}
+	name := strings.NewReplacer("/", "_", "\\", "_", ":", "_").Replace(*params.Src)
+	if strings.Trim(name, ".") == "" || strings.ContainsAny(lawRevisionID, `/\`) {
+		return ""
+	}
+	return filepath.Join(c.dir, lawRevisionID, name)
+}
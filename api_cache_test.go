@@ -0,0 +1,67 @@
+package jplaw2epub
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	lawapi "go.ngs.io/jplaw-api-v2"
+)
+
+func TestCachingAPIClient(t *testing.T) {
+	dir := t.TempDir()
+	mock := &MockAPIClient{GetAttachmentData: map[string]string{"./pict/fig1.jpg": "image-data"}}
+	client := NewCachingAPIClient(mock, dir)
+	src := "./pict/fig1.jpg"
+	params := &lawapi.GetAttachmentParams{Src: &src}
+
+	for i := 0; i < 2; i++ {
+		got, err := client.GetAttachment("rev_1", params)
+		if err != nil {
+			t.Fatalf("GetAttachment() error = %v", err)
+		}
+		if *got != "image-data" {
+			t.Errorf("GetAttachment() = %q, want image-data", *got)
+		}
+	}
+	if len(mock.GetAttachmentCalls) != 1 {
+		t.Errorf("downloads = %d, want 1", len(mock.GetAttachmentCalls))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "rev_1", "._pict_fig1.jpg")); err != nil {
+		t.Errorf("cache file not written: %v", err)
+	}
+
+	// A new client reads the cache of an earlier conversion
+	other := &MockAPIClient{}
+	if got, err := NewCachingAPIClient(other, dir).GetAttachment("rev_1", params); err != nil || *got != "image-data" {
+		t.Errorf("GetAttachment() from cache = %v, %v", got, err)
+	}
+	if len(other.GetAttachmentCalls) != 0 {
+		t.Errorf("downloads = %d, want 0", len(other.GetAttachmentCalls))
+	}
+}
+
+func TestCachingAPIClientErrors(t *testing.T) {
+	dir := t.TempDir()
+	mock := &MockAPIClient{GetAttachmentErr: errors.New("not found")}
+	src := "missing.jpg"
+	if _, err := NewCachingAPIClient(mock, dir).GetAttachment("rev_1", &lawapi.GetAttachmentParams{Src: &src}); err == nil {
+		t.Error("GetAttachment() error = nil, want error")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("failed downloads should not be cached, found %d entries", len(entries))
+	}
+
+	// Revision IDs that would leave the cache directory are not cached
+	mock = &MockAPIClient{}
+	client := NewCachingAPIClient(mock, dir)
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetAttachment("../rev", &lawapi.GetAttachmentParams{Src: &src}); err != nil {
+			t.Fatalf("GetAttachment() error = %v", err)
+		}
+	}
+	if len(mock.GetAttachmentCalls) != 2 {
+		t.Errorf("downloads = %d, want 2", len(mock.GetAttachmentCalls))
+	}
+}
@@ -0,0 +1,72 @@
+package jplaw2epub
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	lawapi "go.ngs.io/jplaw-api-v2"
+)
+
+// DefaultAPIBaseURL is the base URL of version 2 of the 法令API
+const DefaultAPIBaseURL = "https://laws.e-gov.go.jp/api/2"
+
+// HTTPAPIClient downloads attachments from the 法令API. Unlike *lawapi.Client, it sends
+// each request with its context, so that canceling the context interrupts the download.
+type HTTPAPIClient struct {
+	// BaseURL is the base URL of the API, DefaultAPIBaseURL when empty
+	BaseURL string
+	// HTTPClient sends the requests, http.DefaultClient when nil
+	HTTPClient *http.Client
+}
+
+// Ensure HTTPAPIClient implements ContextAPIClient
+var _ ContextAPIClient = (*HTTPAPIClient)(nil)
+
+// GetAttachment downloads an attachment of a law revision
+func (c *HTTPAPIClient) GetAttachment(lawRevisionID string, params *lawapi.GetAttachmentParams) (*string, error) {
+	return c.GetAttachmentWithContext(context.Background(), lawRevisionID, params)
+}
+
+// GetAttachmentWithContext downloads an attachment of a law revision until ctx is done
+func (c *HTTPAPIClient) GetAttachmentWithContext(
+	ctx context.Context,
+	lawRevisionID string,
+	params *lawapi.GetAttachmentParams,
+) (*string, error) {
+	baseURL := c.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultAPIBaseURL
+	}
+	attachmentURL := strings.TrimSuffix(baseURL, "/") + "/attachment/" + url.PathEscape(lawRevisionID)
+	if params != nil && params.Src != nil {
+		attachmentURL += "?" + url.Values{"src": {*params.Src}}.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, attachmentURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating attachment request: %w", err)
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("downloading attachment: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading attachment: %s", resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading attachment: %w", err)
+	}
+	attachment := string(data)
+	return &attachment, nil
+}
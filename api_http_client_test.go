@@ -0,0 +1,43 @@
+package jplaw2epub
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	lawapi "go.ngs.io/jplaw-api-v2"
+)
+
+func TestHTTPAPIClient(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path != "/attachment/rev1":
+			http.NotFound(w, r)
+		case r.URL.Query().Get("src") == "./pict/slow.jpg":
+			<-r.Context().Done()
+		default:
+			w.Write([]byte("image " + r.URL.Query().Get("src")))
+		}
+	}))
+	defer ts.Close()
+	client := &HTTPAPIClient{BaseURL: ts.URL + "/"}
+
+	attachment, err := client.GetAttachment("rev1", &lawapi.GetAttachmentParams{Src: lawapi.StringPtr("./pict/a b.jpg")})
+	if err != nil || attachment == nil || *attachment != "image ./pict/a b.jpg" {
+		t.Errorf("GetAttachment() = %v, %v", attachment, err)
+	}
+
+	if _, err := client.GetAttachment("rev2", &lawapi.GetAttachmentParams{Src: lawapi.StringPtr("./pict/a.jpg")}); err == nil {
+		t.Error("GetAttachment() should fail for a 404 response")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	params := &lawapi.GetAttachmentParams{Src: lawapi.StringPtr("./pict/slow.jpg")}
+	if _, err := getAttachment(ctx, client, "rev1", params); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("getAttachment() error = %v, want context.DeadlineExceeded", err)
+	}
+}
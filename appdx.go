@@ -3,20 +3,19 @@ package jplaw2epub
 import (
 	"fmt"
 
-	"github.com/go-shiori/go-epub"
 	"go.ngs.io/jplaw-xml"
 )
 
 const htmlDivEnd = "</div>"
 
 // processAppdxStyles processes AppdxStyle elements (appendix styles)
-func processAppdxStyles(book *epub.Epub, styles []jplaw.AppdxStyle, imgProc ImageProcessorInterface) error {
+func processAppdxStyles(book BookWriter, styles []jplaw.AppdxStyle, imgProc ImageProcessorInterface) error {
 	if len(styles) == 0 {
 		return nil
 	}
 
 	// Create a section for appendix styles
-	sectionBody := "<h2>様式</h2>"
+	sectionBody := openSection("appendix", "appendix", "") + headingHTML(headingLevelTop, "", "様式") + htmlSectionEnd
 	sectionFilename := "appdx-styles.xhtml"
 
 	// Add the section to the book
@@ -36,14 +35,14 @@ func processAppdxStyles(book *epub.Epub, styles []jplaw.AppdxStyle, imgProc Imag
 }
 
 // processAppdxStyle processes a single AppdxStyle
-func processAppdxStyle(book *epub.Epub, style *jplaw.AppdxStyle, parentFilename string, idx int, imgProc ImageProcessorInterface) error {
+func processAppdxStyle(book BookWriter, style *jplaw.AppdxStyle, parentFilename string, idx int, imgProc ImageProcessorInterface) error {
 	// Build the body content
-	body := ""
+	body := openSection("appendix", "appendix", "")
 
 	// Add title if present
 	if style.AppdxStyleTitle != nil && style.AppdxStyleTitle.Content != "" {
 		titleHTML := processTextWithRuby(style.AppdxStyleTitle.Content, style.AppdxStyleTitle.Ruby)
-		body += fmt.Sprintf("<h3>%s</h3>", titleHTML)
+		body += headingHTML(headingLevelChapter, "", titleHTML)
 	}
 
 	// Add related article reference if present
@@ -62,6 +61,8 @@ func processAppdxStyle(book *epub.Epub, style *jplaw.AppdxStyle, parentFilename
 		body += processAppdxRemark(style.Remarks, imgProc)
 	}
 
+	body += htmlSectionEnd
+
 	// Create a subsection for this style
 	subFilename := fmt.Sprintf("appdx-style-%d.xhtml", idx)
 	title := "様式"
@@ -104,13 +105,13 @@ func processAppdxRemark(remark *jplaw.Remarks, imgProc ImageProcessorInterface)
 }
 
 // processAppdxFig processes AppdxFig elements (appendix figures)
-func processAppdxFig(book *epub.Epub, figures []jplaw.AppdxFig, imgProc ImageProcessorInterface) error {
+func processAppdxFig(book BookWriter, figures []jplaw.AppdxFig, imgProc ImageProcessorInterface) error {
 	if len(figures) == 0 {
 		return nil
 	}
 
 	// Create a section for appendix figures
-	sectionBody := "<h2>附図</h2>"
+	sectionBody := openSection("appendix", "appendix", "") + headingHTML(headingLevelTop, "", "附図") + htmlSectionEnd
 	sectionFilename := "appdx-figures.xhtml"
 
 	// Add the section to the book
@@ -130,19 +131,19 @@ func processAppdxFig(book *epub.Epub, figures []jplaw.AppdxFig, imgProc ImagePro
 }
 
 // processAppdxFigItem processes a single AppdxFig
-func processAppdxFigItem(book *epub.Epub, fig *jplaw.AppdxFig, parentFilename string, idx int, imgProc ImageProcessorInterface) error {
+func processAppdxFigItem(book BookWriter, fig *jplaw.AppdxFig, parentFilename string, idx int, imgProc ImageProcessorInterface) error {
 	// Build the body content
-	body := ""
+	body := openSection("appendix", "appendix", "")
 
 	// Add title if present
 	if fig.AppdxFigTitle != nil && fig.AppdxFigTitle.Content != "" {
 		titleHTML := processTextWithRuby(fig.AppdxFigTitle.Content, fig.AppdxFigTitle.Ruby)
-		body += fmt.Sprintf("<h3>%s</h3>", titleHTML)
+		body += headingHTML(headingLevelChapter, "", titleHTML)
 	}
 
 	// Process FigStruct elements
 	for _, figStruct := range fig.FigStruct {
-		if imgProc != nil {
+		if hasImages(imgProc) {
 			if html, err := imgProc.ProcessFigStruct(&figStruct); err == nil {
 				body += html
 			}
@@ -154,6 +155,8 @@ func processAppdxFigItem(book *epub.Epub, fig *jplaw.AppdxFig, parentFilename st
 		body += processTableStructWithImages(&table, imgProc)
 	}
 
+	body += htmlSectionEnd
+
 	// Create a subsection for this figure
 	subFilename := fmt.Sprintf("appdx-fig-%d.xhtml", idx)
 	title := "附図"
@@ -4,14 +4,13 @@ import (
 	"encoding/xml"
 	"fmt"
 
-	"github.com/go-shiori/go-epub"
 	"go.ngs.io/jplaw-xml"
 )
 
 const defaultAppdxNoteTitle = "附則"
 
 // processAppdxNotes processes appendix notes
-func processAppdxNotes(book *epub.Epub, notes []jplaw.AppdxNote, imgProc ImageProcessorInterface) error {
+func processAppdxNotes(book BookWriter, notes []jplaw.AppdxNote, imgProc ImageProcessorInterface) error {
 	if len(notes) == 0 {
 		return nil
 	}
@@ -26,15 +25,15 @@ func processAppdxNotes(book *epub.Epub, notes []jplaw.AppdxNote, imgProc ImagePr
 }
 
 // processAppdxNote processes a single appendix note
-func processAppdxNote(book *epub.Epub, note *jplaw.AppdxNote, idx int, imgProc ImageProcessorInterface) error {
+func processAppdxNote(book BookWriter, note *jplaw.AppdxNote, idx int, imgProc ImageProcessorInterface) error {
 	filename := fmt.Sprintf("appdx-note-%d.xhtml", idx)
-	body := ""
+	body := openSection("appendix", "appendix", "")
 
 	// Add title if present
 	title := defaultAppdxNoteTitle
 	if note.AppdxNoteTitle != nil && note.AppdxNoteTitle.Content != "" {
 		title = note.AppdxNoteTitle.Content
-		body += fmt.Sprintf(`<div class="chapter-title">%s</div>`, processTextWithRuby(title, note.AppdxNoteTitle.Ruby))
+		body += headingHTML(headingLevelTop, "chapter-title", processTextWithRuby(title, note.AppdxNoteTitle.Ruby))
 	}
 
 	// Process related article number if present
@@ -50,7 +49,7 @@ func processAppdxNote(book *epub.Epub, note *jplaw.AppdxNote, idx int, imgProc I
 
 	// Process FigStructs
 	for _, figStruct := range note.FigStruct {
-		if imgProc != nil {
+		if hasImages(imgProc) {
 			html, err := imgProc.ProcessFigStruct(&figStruct)
 			if err != nil {
 				// Log error but continue
@@ -71,6 +70,8 @@ func processAppdxNote(book *epub.Epub, note *jplaw.AppdxNote, idx int, imgProc I
 		body += processRemarks(note.Remarks)
 	}
 
+	body += htmlSectionEnd
+
 	// Add the section to the book
 	_, err := book.AddSection(body, title, filename, "")
 	if err != nil {
@@ -158,7 +159,7 @@ func processRemarks(remarks *jplaw.Remarks) string {
 }
 
 // processAppdxTables processes appendix tables
-func processAppdxTables(book *epub.Epub, tables []jplaw.AppdxTable, imgProc ImageProcessorInterface) error {
+func processAppdxTables(book BookWriter, tables []jplaw.AppdxTable, imgProc ImageProcessorInterface) error {
 	if len(tables) == 0 {
 		return nil
 	}
@@ -173,15 +174,15 @@ func processAppdxTables(book *epub.Epub, tables []jplaw.AppdxTable, imgProc Imag
 }
 
 // processAppdxTable processes a single appendix table
-func processAppdxTable(book *epub.Epub, table *jplaw.AppdxTable, idx int, imgProc ImageProcessorInterface) error {
+func processAppdxTable(book BookWriter, table *jplaw.AppdxTable, idx int, imgProc ImageProcessorInterface) error {
 	filename := fmt.Sprintf("appdx-table-%d.xhtml", idx)
-	body := ""
+	body := openSection("appendix", "appendix", "")
 
 	// Add title if present
 	title := "附表"
 	if table.AppdxTableTitle != nil && table.AppdxTableTitle.Content != "" {
 		title = table.AppdxTableTitle.Content
-		body += fmt.Sprintf(`<div class="chapter-title">%s</div>`, processTextWithRuby(title, table.AppdxTableTitle.Ruby))
+		body += headingHTML(headingLevelTop, "chapter-title", processTextWithRuby(title, table.AppdxTableTitle.Ruby))
 	}
 
 	// Process related article number if present
@@ -200,6 +201,8 @@ func processAppdxTable(book *epub.Epub, table *jplaw.AppdxTable, idx int, imgPro
 		body += processRemarks(table.Remarks)
 	}
 
+	body += htmlSectionEnd
+
 	// Add the section to the book
 	_, err := book.AddSection(body, title, filename, "")
 	if err != nil {
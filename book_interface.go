@@ -0,0 +1,16 @@
+package jplaw2epub
+
+import "github.com/go-shiori/go-epub"
+
+// BookWriter defines the interface for the output book the law content is rendered into
+type BookWriter interface {
+	AddSection(body string, sectionTitle string, internalFilename string, internalCSSPath string) (string, error)
+	AddSubSection(parentFilename string, body string, sectionTitle string, internalFilename string, internalCSSPath string) (string, error)
+	AddImage(source string, imageFilename string) (string, error)
+}
+
+// Ensure epub.Epub and PDFDocument implement BookWriter
+var (
+	_ BookWriter = (*epub.Epub)(nil)
+	_ BookWriter = (*PDFDocument)(nil)
+)
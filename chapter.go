@@ -3,12 +3,11 @@ package jplaw2epub
 import (
 	"fmt"
 
-	"github.com/go-shiori/go-epub"
 	"go.ngs.io/jplaw-xml"
 )
 
 // processChapterWithImages processes a single chapter with image support
-func processChapterWithImages(book *epub.Epub, chapter *jplaw.Chapter, chapterIdx int, imgProc ImageProcessorInterface) error {
+func processChapterWithImages(book BookWriter, chapter *jplaw.Chapter, chapterIdx int, imgProc ImageProcessorInterface) error {
 	chapterFilename := fmt.Sprintf("chapter-%d.xhtml", chapterIdx)
 	body := buildChapterBody(chapter)
 
@@ -39,25 +38,37 @@ func processChapterWithImages(book *epub.Epub, chapter *jplaw.Chapter, chapterId
 
 // buildChapterBody builds the HTML body for a chapter
 func buildChapterBody(chapter *jplaw.Chapter) string {
+	id := ""
+	if number, ok := chapterNumber(chapter); ok {
+		id = "chap-" + number.String()
+	}
 	chapterTitleHTML := processTextWithRuby(chapter.ChapterTitle.Content, chapter.ChapterTitle.Ruby)
-	body := fmt.Sprintf(`<div class="chapter-title">%s</div>`, chapterTitleHTML)
+	body := openSection("chapter", "chapter", id)
+	body += headingHTML(headingLevelTop, "chapter-title", chapterTitleHTML)
 
 	// Process Sections if any
 	if len(chapter.Section) > 0 {
-		body += buildSectionsHTML(chapter.Section)
+		body += buildSectionsHTML(chapter.Section, id)
 	}
 
+	body += htmlSectionEnd
 	return body
 }
 
-// buildSectionsHTML builds HTML for sections
-func buildSectionsHTML(sections []jplaw.Section) string {
-	body := "<div class='sections'>"
+// buildSectionsHTML builds HTML for the sections of the chapter with the given id. Section
+// numbers restart in each chapter, so their ids extend the chapter id (e.g. chap-1-sec-2).
+func buildSectionsHTML(sections []jplaw.Section, chapterID string) string {
+	var body string
 
 	for sIdx := range sections {
 		section := &sections[sIdx]
+		id := ""
+		if number, ok := sectionNumber(section); ok && chapterID != "" {
+			id = chapterID + "-sec-" + number.String()
+		}
 		sectionTitleHTML := processTextWithRuby(section.SectionTitle.Content, section.SectionTitle.Ruby)
-		body += fmt.Sprintf("<h3>%s</h3>", sectionTitleHTML)
+		body += openSection("subchapter", "section", id)
+		body += headingHTML(headingLevelChapter, "", sectionTitleHTML)
 
 		// Add a note about articles in this section
 		if len(section.Article) > 0 {
@@ -65,8 +76,8 @@ func buildSectionsHTML(sections []jplaw.Section) string {
 				section.Article[0].ArticleTitle.Content,
 				section.Article[len(section.Article)-1].ArticleTitle.Content)
 		}
+		body += htmlSectionEnd
 	}
 
-	body += htmlDivEnd
 	return body
 }
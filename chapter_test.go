@@ -157,7 +157,7 @@ func TestBuildChapterBody(t *testing.T) {
 				},
 			},
 			want: []string{
-				`<div class="chapter-title">第一章 総則</div>`,
+				`<section epub:type="chapter" class="chapter" id="chap-1"><h2 class="chapter-title">第一章 総則</h2>`,
 			},
 		},
 		{
@@ -179,11 +179,10 @@ func TestBuildChapterBody(t *testing.T) {
 				},
 			},
 			want: []string{
-				`<div class="chapter-title">第一章</div>`,
-				`<div class='sections'>`,
-				`<h3>第一節</h3>`,
+				`<h2 class="chapter-title">第一章</h2>`,
+				`<section epub:type="subchapter" class="section" id="chap-1-sec-1"><h3>第一節</h3>`,
 				`<p>（第一条 から 第二条 まで）</p>`,
-				`</div>`,
+				`</section></section>`,
 			},
 		},
 	}
@@ -227,15 +226,14 @@ func TestBuildSectionsHTML(t *testing.T) {
 		},
 	}
 
-	got := buildSectionsHTML(sections)
+	got := buildSectionsHTML(sections, "chap-2")
 
 	expectedContent := []string{
-		`<div class='sections'>`,
-		`<h3>第一節 総則</h3>`,
-		`<p>（第一条 から 第五条 まで）</p>`,
+		`<section epub:type="subchapter" class="section" id="chap-2-sec-1"><h3>第一節 総則</h3>`,
+		`<section epub:type="subchapter" class="section" id="chap-2-sec-2"><h3>第二節 手続`,
+		`<p>（第一条 から 第五条 まで）</p></section>`,
 		`<h3>第二節 手続<ruby>手続<rt>てつづき</rt></ruby></h3>`,
-		`<p>（第六条 から 第六条 まで）</p>`,
-		`</div>`,
+		`<p>（第六条 から 第六条 まで）</p></section>`,
 	}
 
 	for _, expected := range expectedContent {
@@ -243,4 +241,9 @@ func TestBuildSectionsHTML(t *testing.T) {
 			t.Errorf("buildSectionsHTML() missing expected content: %s", expected)
 		}
 	}
+
+	// Sections of a chapter without a number get no id
+	if got := buildSectionsHTML(sections, ""); strings.Contains(got, " id=") {
+		t.Errorf("buildSectionsHTML() without a chapter id = %s, want no ids", got)
+	}
 }
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"os"
+
+	jplaw "go.ngs.io/jplaw-xml"
+	"go.ngs.io/jplaw2epub"
+)
+
+// runAmend applies the amendment provisions of an amending law to a base law and writes the
+// consolidated law as an EPUB. Instructions that could not be applied are printed as warnings.
+func runAmend(args []string) int {
+	fs := flag.NewFlagSet("amend", flag.ContinueOnError)
+	destPath := fs.String("d", "", "Destination file path (- for standard output)")
+	noValidate := fs.Bool("no-validate", false, "Skip checking the written EPUB for problems")
+	noCover := fs.Bool("no-cover", false, "Do not add a cover")
+	annotations := fs.String("annotations", "", "Add the article notes of this YAML or JSON file, warning about those the amendments orphaned")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: jplaw2epub amend -d consolidated.epub base.xml amending.xml")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 || *destPath == "" {
+		fs.Usage()
+		return 2
+	}
+
+	base, err := readLaw(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	amending, err := os.Open(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening amending law: %v\n", err)
+		return 1
+	}
+	defer amending.Close()
+
+	law, diagnostics, err := jplaw2epub.ApplyAmendments(base, amending)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error applying amendments: %v\n", err)
+		return 1
+	}
+	for _, diagnostic := range diagnostics {
+		fmt.Fprintf(os.Stderr, "Warning: not applied: %s\n", diagnostic)
+	}
+
+	epubOpts := &jplaw2epub.EPUBOptions{Validate: !*noValidate, NoCover: *noCover}
+	if err := loadAnnotations(*annotations, epubOpts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	book, err := jplaw2epub.NewBookFromLaw(context.Background(), law, epubOpts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating EPUB file: %v\n", explainCover(err, "-no-cover"))
+		return 1
+	}
+	if *destPath == stdioPath {
+		_, err = book.WriteTo(os.Stdout)
+	} else {
+		err = book.Write(*destPath)
+	}
+	if err = explainValidation(err, *destPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing EPUB file: %v\n", err)
+		return 1
+	}
+
+	reportSuccess("EPUB", *destPath)
+	return 0
+}
+
+// readLaw parses the law XML file at path
+func readLaw(path string) (*jplaw.Law, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading base law: %w", err)
+	}
+	return parseLaw(data)
+}
+
+// parseLaw parses law XML data
+func parseLaw(data []byte) (*jplaw.Law, error) {
+	var law jplaw.Law
+	if err := xml.Unmarshal(data, &law); err != nil {
+		return nil, fmt.Errorf("parsing law: %w", err)
+	}
+	return &law, nil
+}
+
+// loadAnnotations reads the annotations file at path into epubOpts, printing the annotations
+// that cannot be anchored as warnings. An empty path adds no annotations.
+func loadAnnotations(path string, epubOpts *jplaw2epub.EPUBOptions) error {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	if epubOpts.Annotations, err = jplaw2epub.ParseAnnotations(data); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	epubOpts.AnnotationWarning = warnAnnotation
+	return nil
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"go.ngs.io/jplaw2epub"
+)
+
+// configPathEnv names a configuration file used instead of the project configuration
+const configPathEnv = jplaw2epub.ConfigEnvPrefix + "CONFIG"
+
+// configFileNames are the names of project configuration files, in order of preference
+var configFileNames = []string{"jplaw2epub.yaml", "jplaw2epub.yml", "jplaw2epub.toml", "jplaw2epub.json"}
+
+// flagSettings maps the flags that have a configuration setting to its key
+var flagSettings = map[string]string{
+	"format":             "format",
+	"theme":              "theme",
+	"search":             "search",
+	"definitions":        "definitions",
+	"definition-links":   "definition_links",
+	"popup-notes":        "popup_notes",
+	"cover":              "cover.image",
+	"cover-template":     "cover.template",
+	"cover-font":         "cover.font",
+	"no-cover":           "cover.disabled",
+	"embed-font":         "embed_font",
+	"arabic-toc":         "arabic_toc",
+	"no-suppl":           "no_suppl",
+	"no-appendix":        "no_appendix",
+	"wide-tables":        "wide_tables",
+	"wide-table-columns": "wide_table_columns",
+	"dates":              "dates",
+	"annotations":        "annotations",
+	"max-image-height":   "images.max_height",
+	"cache-dir":          "images.cache_dir",
+}
+
+// settingFlags collects repeated -set key=value flags
+type settingFlags []string
+
+func (s *settingFlags) String() string { return strings.Join(*s, ",") }
+
+func (s *settingFlags) Set(value string) error {
+	if !strings.Contains(value, "=") {
+		return fmt.Errorf("want key=value, got %q", value)
+	}
+	*s = append(*s, value)
+	return nil
+}
+
+// configFiles returns the configuration files to merge, in order: the user configuration,
+// then the project configuration nearest to dir, or explicit instead of the project
+// configuration when it is set
+func configFiles(explicit, dir string) []string {
+	var files []string
+	if userDir, err := os.UserConfigDir(); err == nil {
+		if path := findConfigFile(filepath.Join(userDir, "jplaw2epub"), "config"); path != "" {
+			files = append(files, path)
+		}
+	}
+	if explicit != "" {
+		return append(files, explicit)
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return files
+	}
+	for {
+		if path := findConfigFile(dir, "jplaw2epub"); path != "" {
+			return append(files, path)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return files
+		}
+		dir = parent
+	}
+}
+
+// findConfigFile returns the first existing configuration file named base in dir
+func findConfigFile(dir, base string) string {
+	for _, name := range configFileNames {
+		path := filepath.Join(dir, base+filepath.Ext(name))
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// loadConfig merges the defaults, the configuration files and the environment variables,
+// returning the configuration and the files it was read from
+func loadConfig(explicit string) (*jplaw2epub.Config, []string, error) {
+	if explicit == "" {
+		explicit = os.Getenv(configPathEnv)
+	}
+	config := jplaw2epub.DefaultConfig()
+	files := configFiles(explicit, ".")
+	for _, path := range files {
+		if err := config.MergeFile(path); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := config.MergeEnv(os.Environ(), configPathEnv); err != nil {
+		return nil, nil, err
+	}
+	return config, files, nil
+}
+
+// applyFlags sets the settings of the flags given on the command line, which take
+// precedence over the environment and the configuration files
+func applyFlags(config *jplaw2epub.Config, fs *flag.FlagSet, settings settingFlags) error {
+	var err error
+	set := func(key, value string) {
+		if err == nil {
+			err = config.Set(key, value)
+		}
+	}
+	fs.Visit(func(f *flag.Flag) {
+		value := f.Value.String()
+		if key, ok := flagSettings[f.Name]; ok {
+			set(key, value)
+			return
+		}
+		enabled, _ := strconv.ParseBool(value)
+		switch f.Name {
+		case "vertical":
+			mode := jplaw2epub.WritingModeHorizontal
+			if enabled {
+				mode = jplaw2epub.WritingModeVertical
+			}
+			set("writing_mode", mode)
+		case "no-validate":
+			set("validate", strconv.FormatBool(!enabled))
+		case "no-images":
+			set("images.download", strconv.FormatBool(!enabled))
+		case "images":
+			// The deprecated -images flag can only turn downloads on
+			if enabled {
+				set("images.download", "true")
+			}
+		}
+	})
+	for _, setting := range settings {
+		key, value, _ := strings.Cut(setting, "=")
+		set(strings.TrimSpace(key), value)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid flag: %w", err)
+	}
+	return nil
+}
+
+// runConfig runs the config subcommand
+func runConfig(args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "Usage: jplaw2epub config print [-config file] [-json]")
+		return 2
+	}
+
+	fs := flag.NewFlagSet("config print", flag.ContinueOnError)
+	configPath := fs.String("config", "", "Configuration file to use instead of the project configuration")
+	jsonFlag := fs.Bool("json", false, "Print the configuration as JSON")
+	var settings settingFlags
+	fs.Var(&settings, "set", "Set a configuration setting, e.g. -set theme=dark (repeatable)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: jplaw2epub config print [-config file] [-json]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	config, files, err := loadConfig(*configPath)
+	if err == nil {
+		err = applyFlags(config, fs, settings)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := printConfig(os.Stdout, config, files, *jsonFlag); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// printConfig writes the effective configuration, as YAML preceded by the files and
+// environment variables it was read from, or as JSON
+func printConfig(w io.Writer, config *jplaw2epub.Config, files []string, asJSON bool) error {
+	if asJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(config)
+	}
+
+	var header strings.Builder
+	for _, path := range files {
+		fmt.Fprintf(&header, "# from %s\n", path)
+	}
+	for _, variable := range os.Environ() {
+		name, _, _ := strings.Cut(variable, "=")
+		if strings.HasPrefix(name, jplaw2epub.ConfigEnvPrefix) && name != configPathEnv {
+			fmt.Fprintf(&header, "# from $%s\n", name)
+		}
+	}
+	if _, err := io.WriteString(w, header.String()); err != nil {
+		return err
+	}
+	return config.WriteYAML(w)
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"go.ngs.io/jplaw2epub"
+)
+
+// runInfo prints the title, number, promulgation date, element counts and structural features of a law
+func runInfo(args []string) int {
+	return runInspect("info", args, func(w io.Writer, inspection *jplaw2epub.Inspection, asJSON bool) error {
+		if asJSON {
+			return writeJSON(w, inspection.Info)
+		}
+		return printInfo(w, &inspection.Info)
+	})
+}
+
+// runTree prints the outline of the divisions, articles and appendixes of a law
+func runTree(args []string) int {
+	return runInspect("tree", args, func(w io.Writer, inspection *jplaw2epub.Inspection, asJSON bool) error {
+		if asJSON {
+			return writeJSON(w, inspection.Outline)
+		}
+		return printTree(w, inspection.Outline, 0)
+	})
+}
+
+// runImages lists the Fig elements of a law with their locations
+func runImages(args []string) int {
+	return runInspect("images", args, func(w io.Writer, inspection *jplaw2epub.Inspection, asJSON bool) error {
+		if asJSON {
+			return writeJSON(w, inspection.Figures)
+		}
+		return printFigures(w, inspection.Figures)
+	})
+}
+
+// runInspect parses the flags of an inspection subcommand, inspects the law and prints the result
+func runInspect(name string, args []string, print func(io.Writer, *jplaw2epub.Inspection, bool) error) int {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	jsonFlag := fs.Bool("json", false, "Print JSON instead of text")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: jplaw2epub %s [-json] input.xml (or - for standard input)\n", name)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	source := io.Reader(os.Stdin)
+	if path := fs.Arg(0); path != stdioPath {
+		xmlFile, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening source file: %v\n", err)
+			return 1
+		}
+		defer xmlFile.Close()
+		source = xmlFile
+	}
+
+	inspection, err := jplaw2epub.InspectXML(source)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := print(os.Stdout, inspection, *jsonFlag); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// writeJSON writes v as indented JSON
+func writeJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// printInfo writes the summary of a law as aligned text
+func printInfo(w io.Writer, info *jplaw2epub.LawInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	promulgated := info.Promulgated
+	if info.PromulgatedISO != "" {
+		promulgated += " (" + info.PromulgatedISO + ")"
+	}
+	for _, row := range [][2]string{
+		{"Title", info.Title},
+		{"Law number", info.LawNum},
+		{"Law type", info.LawType},
+		{"Promulgated", promulgated},
+	} {
+		if row[1] != "" {
+			fmt.Fprintf(tw, "%s:\t%s\n", row[0], row[1])
+		}
+	}
+
+	counts := info.Counts
+	fmt.Fprintln(tw)
+	for _, row := range []struct {
+		label string
+		count int
+	}{
+		{"Parts", counts.Parts},
+		{"Chapters", counts.Chapters},
+		{"Sections", counts.Sections},
+		{"Articles", counts.Articles},
+		{"Paragraphs", counts.Paragraphs},
+		{"Items", counts.Items},
+		{"Supplementary provisions", counts.SupplProvisions},
+		{"Appendixes", counts.Appendixes},
+		{"Tables", counts.Tables},
+		{"Figures", counts.Figures},
+	} {
+		fmt.Fprintf(tw, "%s:\t%d\n", row.label, row.count)
+	}
+
+	if len(info.Features) > 0 {
+		// Counts come first, as tabwriter does not know the width of Japanese text
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "Features:")
+		for _, feature := range info.Features {
+			note := ""
+			if feature.Unsupported {
+				note = ", not rendered"
+			}
+			fmt.Fprintf(tw, "  %5d  %s%s\n", feature.Count, feature.Label, note)
+		}
+	}
+	return tw.Flush()
+}
+
+// printTree writes an outline indented by depth, with the path of each node
+func printTree(w io.Writer, nodes []*jplaw2epub.OutlineNode, depth int) error {
+	for _, node := range nodes {
+		title := node.Title + node.Caption
+		if node.Kind == "suppl" && node.Caption != "" {
+			title = fmt.Sprintf("%s（%s）", node.Title, node.Caption)
+		}
+		if title == "" {
+			title = node.Kind
+		}
+		if _, err := fmt.Fprintf(w, "%s%s  [%s]\n", strings.Repeat("  ", depth), title, node.Path); err != nil {
+			return err
+		}
+		if err := printTree(w, node.Children, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printFigures writes one line per figure with its source, element, path and location
+func printFigures(w io.Writer, figures []jplaw2epub.FigureRef) error {
+	if len(figures) == 0 {
+		_, err := fmt.Fprintln(w, "No figures")
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SRC\tELEMENT\tPATH\tLOCATION")
+	for _, figure := range figures {
+		location := figure.Location
+		if location == "" {
+			location = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", figure.Src, figure.Element, figure.Path, location)
+	}
+	return tw.Flush()
+}
@@ -1,13 +1,20 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
 
-	lawapi "go.ngs.io/jplaw-api-v2"
 	"go.ngs.io/jplaw2epub"
 )
 
@@ -15,74 +22,383 @@ func main() {
 	os.Exit(run())
 }
 
+// subcommands are the commands selected by the first argument. Without one, the arguments
+// are those of convert.
+var subcommands = map[string]func(args []string) int{
+	"convert":  runConvert,
+	"info":     runInfo,
+	"tree":     runTree,
+	"images":   runImages,
+	"serve":    runServe,
+	"validate": runValidate,
+	"amend":    runAmend,
+	"config":   runConfig,
+}
+
 func run() int {
-	opts, err := parseFlags()
+	if len(os.Args) > 1 {
+		if command, ok := subcommands[os.Args[1]]; ok {
+			return command(os.Args[2:])
+		}
+	}
+	return runConvert(os.Args[1:])
+}
+
+// runConvert converts a law to EPUB, PDF or JSON
+func runConvert(args []string) int {
+	opts, err := parseFlags(args)
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		return 1
 	}
 
-	xmlFile, openErr := os.Open(opts.sourcePath)
-	if openErr != nil {
-		fmt.Printf("Error opening source file: %v\n", openErr)
-		return 1
+	source := io.Reader(os.Stdin)
+	if opts.sourcePath != stdioPath {
+		xmlFile, openErr := os.Open(opts.sourcePath)
+		if openErr != nil {
+			fmt.Fprintf(os.Stderr, "Error opening source file: %v\n", openErr)
+			return 1
+		}
+		defer xmlFile.Close()
+		source = xmlFile
+	}
+
+	// Stop on Ctrl-C or when the timeout passes
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+	}
+
+	if opts.article != nil {
+		return printArticle(source, opts.article, opts.config.Format)
+	}
+
+	// Name the output after the law when the configuration has an output name
+	if opts.destPath == "" {
+		if source, err = nameOutput(opts, source); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
 	}
-	defer xmlFile.Close()
 
 	// Create EPUB options
-	epubOpts := createEPUBOptions(opts)
+	epubOpts, err := createEPUBOptions(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	// Report progress on standard error, which stays free of output data
+	var bar *progressBar
+	switch {
+	case opts.jsonProgress:
+		epubOpts.Progress = jsonProgress(os.Stderr)
+	case opts.progress:
+		bar = &progressBar{w: os.Stderr}
+		epubOpts.Progress = bar.update
+	}
+
+	switch opts.config.Format {
+	case formatPDF:
+		return convertToPDF(ctx, source, epubOpts, opts.destPath, bar)
+	case formatJSON:
+		return convertToJSON(source, opts.destPath)
+	}
+
+	book, createErr := jplaw2epub.NewBookFromXMLFile(ctx, source, epubOpts)
+	if createErr != nil {
+		bar.finish()
+		createErr = explainCover(createErr, "-cover-font, -cover or -no-cover")
+		fmt.Fprintf(os.Stderr, "Error creating EPUB file: %v\n", createErr)
+		return 1
+	}
+
+	var writeErr error
+	if opts.destPath == stdioPath {
+		_, writeErr = book.WriteTo(os.Stdout)
+	} else {
+		writeErr = book.Write(opts.destPath)
+	}
+	bar.finish()
+	if writeErr = explainValidation(writeErr, opts.destPath); writeErr != nil {
+		fmt.Fprintf(os.Stderr, "Error writing EPUB file: %v\n", writeErr)
+		return 1
+	}
+
+	reportSuccess("EPUB", opts.destPath)
+	return 0
+}
+
+func convertToPDF(ctx context.Context, source io.Reader, epubOpts *jplaw2epub.EPUBOptions, destPath string, bar *progressBar) int {
+	doc, createErr := jplaw2epub.CreatePDFFromXMLFileWithContext(ctx, source, epubOpts)
+	bar.finish()
+	if createErr != nil {
+		fmt.Fprintf(os.Stderr, "Error creating PDF file: %v\n", createErr)
+		return 1
+	}
+
+	var writeErr error
+	if destPath == stdioPath {
+		_, writeErr = doc.WriteTo(os.Stdout)
+	} else {
+		writeErr = jplaw2epub.WritePDF(doc, destPath)
+	}
+	if writeErr != nil {
+		fmt.Fprintf(os.Stderr, "Error writing PDF file: %v\n", writeErr)
+		return 1
+	}
+
+	reportSuccess("PDF", destPath)
+	return 0
+}
 
-	book, createErr := jplaw2epub.CreateEPUBFromXMLFileWithOptions(xmlFile, epubOpts)
+func convertToJSON(source io.Reader, destPath string) int {
+	doc, createErr := jplaw2epub.CreateJSONFromXMLFile(source)
 	if createErr != nil {
-		fmt.Printf("Error creating EPUB file: %v\n", createErr)
+		fmt.Fprintf(os.Stderr, "Error creating JSON file: %v\n", createErr)
 		return 1
 	}
 
-	if writeErr := jplaw2epub.WriteEPUB(book, opts.destPath); writeErr != nil {
-		fmt.Printf("Error writing EPUB file: %v\n", writeErr)
+	var writeErr error
+	if destPath == stdioPath {
+		writeErr = jplaw2epub.WriteJSONTo(doc, os.Stdout)
+	} else {
+		writeErr = jplaw2epub.WriteJSON(doc, destPath)
+	}
+	if writeErr != nil {
+		fmt.Fprintf(os.Stderr, "Error writing JSON file: %v\n", writeErr)
 		return 1
 	}
 
-	fmt.Printf("Successfully created EPUB: %s\n", opts.destPath)
+	reportSuccess("JSON", destPath)
 	return 0
 }
 
+// printArticle writes the main provision article with the given number to standard output,
+// as text or, with the json format, as its JSON export
+func printArticle(source io.Reader, number jplaw2epub.ProvisionNumber, format string) int {
+	doc, err := jplaw2epub.CreateJSONFromXMLFile(source)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading law: %v\n", err)
+		return 1
+	}
+	article := jplaw2epub.FindArticle(doc, number)
+	if article == nil {
+		fmt.Fprintf(os.Stderr, "Error: article %s not found\n", number)
+		return 1
+	}
+
+	if format == formatJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(article)
+	} else {
+		err = writeArticleText(os.Stdout, article)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing article: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// writeArticleText writes an article as plain text, one paragraph or item per line
+func writeArticleText(w io.Writer, article *jplaw2epub.JSONArticle) error {
+	var text strings.Builder
+	text.WriteString(article.Title.Text)
+	if article.Caption != nil {
+		text.WriteString(" " + article.Caption.Text)
+	}
+	text.WriteString("\n")
+
+	var writeItems func(items []jplaw2epub.JSONItem, indent string)
+	writeItems = func(items []jplaw2epub.JSONItem, indent string) {
+		for i := range items {
+			text.WriteString(indent + strings.TrimSpace(items[i].Label+"　"+joinSentences(items[i].Sentences)) + "\n")
+			writeItems(items[i].Subitems, indent+"　")
+		}
+	}
+	for i := range article.Paragraphs {
+		para := &article.Paragraphs[i]
+		text.WriteString(strings.TrimSpace(para.Label+"　"+joinSentences(para.Sentences)) + "\n")
+		writeItems(para.Items, "　")
+	}
+
+	_, err := io.WriteString(w, text.String())
+	return err
+}
+
+// joinSentences concatenates the text of sentences
+func joinSentences(sentences []jplaw2epub.JSONText) string {
+	var text strings.Builder
+	for _, s := range sentences {
+		text.WriteString(s.Text)
+	}
+	return text.String()
+}
+
+// reportSuccess prints where the output was written, staying quiet when it went to standard output
+func reportSuccess(kind, destPath string) {
+	if destPath == stdioPath {
+		return
+	}
+	fmt.Printf("Successfully created %s: %s\n", kind, destPath)
+}
+
+// explainCover replaces ErrNoCoverFont with a message naming the flags that avoid it.
+// Other errors are returned as they are.
+func explainCover(err error, flags string) error {
+	if !errors.Is(err, jplaw2epub.ErrNoCoverFont) {
+		return err
+	}
+	return fmt.Errorf("no CJK font was found for the generated cover (use %s)", flags)
+}
+
+// stdioPath as a source or destination selects standard input or output
+const stdioPath = "-"
+
+// Output formats
+const (
+	formatEPUB = "epub"
+	formatPDF  = "pdf"
+	formatJSON = "json"
+)
+
 type options struct {
-	destPath       string
-	sourcePath     string
-	downloadImages bool
-	maxImageHeight string
+	config       *jplaw2epub.Config
+	destPath     string
+	sourcePath   string
+	timeout      time.Duration
+	progress     bool
+	jsonProgress bool
+	article      jplaw2epub.ProvisionNumber
+	chapters     []jplaw2epub.ProvisionRange
+	articles     []jplaw2epub.ProvisionRange
 }
 
-func parseFlags() (*options, error) {
-	destPathFlag := flag.String("d", "", "Destination file path")
-	downloadImagesFlag := flag.Bool("no-images", false, "Skip downloading and embedding images")
-	maxImageHeightFlag := flag.String("max-image-height", "80vh", "Maximum image height (e.g., '300px', '80vh', '50%')")
+// parseFlags parses the command line. Flags given on the command line take precedence over
+// the environment variables and configuration files, whose settings the other flags keep.
+func parseFlags(args []string) (*options, error) {
+	destPathFlag := flag.String("d", "", "Destination file path (- for standard output)")
+	configFlag := flag.String("config", "", "Configuration file to use instead of the project configuration")
+	var settings settingFlags
+	flag.Var(&settings, "set", "Set a configuration setting, e.g. -set metadata.title=… (repeatable)")
+	flag.Bool("no-images", false, "Skip downloading and embedding images")
+	flag.String("max-image-height", "80vh", "Maximum image height (e.g., '300px', '80vh', '50%')")
+	flag.String("cache-dir", "", "Keep downloaded images in this directory for later conversions")
 	// For backward compatibility, also accept the old -images flag
-	oldImagesFlag := flag.Bool("images", false, "Download and embed images (deprecated, images are embedded by default)")
-	flag.Parse()
+	flag.Bool("images", false, "Download and embed images (deprecated, images are embedded by default)")
+	flag.String("format", formatEPUB, "Output format (epub, pdf, json)")
+	flag.String("theme", string(jplaw2epub.ThemeDefault), "Colour theme (default, sepia, dark)")
+	flag.Bool("vertical", false, "Use vertical writing (PDF output and generated cover)")
+	flag.Bool("search", false, "Embed a full-text search page (EPUB output only)")
+	flag.Bool("definitions", false, "Add an index of defined terms (定義語索引)")
+	flag.Bool("definition-links", false, "Link defined terms in later articles to their definitions")
+	flag.Bool("popup-notes", false, "Show referenced articles and defined terms as popup footnotes")
+	timeoutFlag := flag.Duration("timeout", 0, "Abort the conversion after this duration, e.g. 5m (default: no limit)")
+	progressFlag := flag.Bool("progress", isTerminal(os.Stderr), "Show a progress bar on standard error")
+	jsonProgressFlag := flag.Bool("json-progress", false, "Write progress events to standard error as JSON lines")
+	flag.String("cover", "", "Use this PNG or JPEG image as the cover")
+	flag.String("cover-template", "", "PNG or JPEG background for the generated cover")
+	flag.String("cover-font", "", "OpenType font for the generated cover (default: a system CJK font)")
+	flag.Bool("no-cover", false, "Do not add a cover")
+	flag.String("embed-font", "", "Embed this OpenType font, subset to the characters of the law (EPUB output only)")
+	flag.Bool("arabic-toc", false, "Show chapter and article numbers in Arabic numerals in the table of contents")
+	articleFlag := flag.String("article", "", "Print the article with this number (e.g. 123_2 for 第百二十三条の二) instead of converting")
+	chaptersFlag := flag.String("chapters", "", "Convert only these chapters, e.g. 3 or 1-2,5")
+	articlesFlag := flag.String("articles", "", "Convert only these articles, e.g. 1-20,35 (branch numbers as 10_2)")
+	flag.Bool("no-suppl", false, "Leave out the supplementary provisions (附則)")
+	flag.Bool("no-appendix", false, "Leave out the appendix tables, notes, styles and formats")
+	flag.Bool("no-validate", false, "Skip checking the written EPUB for problems (EPUB output only)")
+	flag.String("wide-tables", string(jplaw2epub.WideTableScroll), "Layout of wide tables (scroll, stack, image)")
+	flag.Int("wide-table-columns", 6, "Number of columns above which a table is wide")
+	flag.String("annotations", "", "Add the article notes, highlights and see-also links of this YAML or JSON file")
+	flag.String("dates", string(jplaw2epub.DateDisplayJapanese), "Calendar of displayed dates (japanese, gregorian, both)")
+	flag.Usage = usage
+	if err := flag.CommandLine.Parse(args); err != nil {
+		return nil, err
+	}
 
-	if *destPathFlag == "" {
-		return nil, fmt.Errorf("destination file path is required")
+	config, _, err := loadConfig(*configFlag)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyFlags(config, flag.CommandLine, settings); err != nil {
+		return nil, err
 	}
 
-	if len(flag.Args()) < 1 {
-		return nil, fmt.Errorf("source file path is required as the first argument")
+	var article jplaw2epub.ProvisionNumber
+	if *articleFlag != "" {
+		if article, err = jplaw2epub.ParseProvisionNumber(*articleFlag); err != nil {
+			return nil, fmt.Errorf("invalid -article: %w", err)
+		}
 	}
 
-	// Default to downloading images unless explicitly disabled
-	downloadImages := !*downloadImagesFlag || *oldImagesFlag
+	switch config.Format {
+	case formatEPUB, formatPDF, formatJSON:
+	default:
+		return nil, fmt.Errorf("unsupported output format: %s", config.Format)
+	}
+
+	var chapters, articles []jplaw2epub.ProvisionRange
+	if *chaptersFlag != "" {
+		if chapters, err = jplaw2epub.ParseProvisionRanges(*chaptersFlag); err != nil {
+			return nil, fmt.Errorf("invalid -chapters: %w", err)
+		}
+	}
+	if *articlesFlag != "" {
+		if articles, err = jplaw2epub.ParseProvisionRanges(*articlesFlag); err != nil {
+			return nil, fmt.Errorf("invalid -articles: %w", err)
+		}
+	}
+
+	if len(flag.Args()) < 1 {
+		return nil, fmt.Errorf("source file path (or - for standard input) is required as the first argument")
+	}
 
 	opts := &options{
-		destPath:       *destPathFlag,
-		sourcePath:     flag.Arg(0),
-		downloadImages: downloadImages,
-		maxImageHeight: *maxImageHeightFlag,
+		config:       config,
+		destPath:     *destPathFlag,
+		sourcePath:   flag.Arg(0),
+		timeout:      *timeoutFlag,
+		progress:     *progressFlag,
+		jsonProgress: *jsonProgressFlag,
+		article:      article,
+		chapters:     chapters,
+		articles:     articles,
 	}
 
 	return opts, nil
 }
 
+// usage prints the subcommands and the flags of convert
+func usage() {
+	w := flag.CommandLine.Output()
+	fmt.Fprint(w, `Usage: jplaw2epub [convert] [flags] input.xml (or - for standard input)
+       jplaw2epub info|tree|images [-json] input.xml
+       jplaw2epub amend -d consolidated.epub base.xml amending.xml
+       jplaw2epub validate book.epub...
+       jplaw2epub serve [flags]
+       jplaw2epub config print [-config file] [-json]
+
+Commands:
+  convert   Convert a law to EPUB, PDF or JSON (the default)
+  info      Print the title, number, date, counts and structural features of a law
+  tree      Print the outline of a law
+  images    List the figures of a law with their locations
+  amend     Apply an amending law and convert the consolidated law
+  validate  Check EPUB files for problems
+  serve     Run the HTTP conversion service
+  config    Print the effective configuration
+
+Flags of convert:
+`)
+	flag.PrintDefaults()
+}
+
 func extractRevisionIDFromPath(path string) string {
 	base := filepath.Base(path)
 	base = strings.TrimSuffix(base, filepath.Ext(base))
@@ -94,24 +410,57 @@ func extractRevisionIDFromPath(path string) string {
 	return ""
 }
 
-func createEPUBOptions(opts *options) *jplaw2epub.EPUBOptions {
-	if !opts.downloadImages {
-		return nil
+// createEPUBOptions maps the configuration onto EPUB options, adding the excerpt, the revision
+// ID of the source and the API client for images
+func createEPUBOptions(opts *options) (*jplaw2epub.EPUBOptions, error) {
+	epubOpts, err := opts.config.EPUBOptions()
+	if err != nil {
+		return nil, err
+	}
+	epubOpts.Chapters = opts.chapters
+	epubOpts.Articles = opts.articles
+	if epubOpts.Annotations != nil {
+		epubOpts.AnnotationWarning = warnAnnotation
 	}
-
 	// Extract revision ID from source path
-	revisionID := extractRevisionIDFromPath(opts.sourcePath)
-	if revisionID == "" {
-		fmt.Println("Warning: Could not extract revision ID from filename, images will not be downloaded")
-		return nil
+	epubOpts.RevisionID = extractRevisionIDFromPath(opts.sourcePath)
+
+	if !opts.config.Images.Download {
+		return epubOpts, nil
+	}
+	if epubOpts.RevisionID == "" {
+		fmt.Fprintln(os.Stderr, "Warning: Could not extract revision ID from filename, images will not be downloaded")
+		return epubOpts, nil
 	}
 
-	// Create API client
-	client := lawapi.NewClient()
+	// Create API client, keeping its downloads when there is a cache directory
+	epubOpts.APIClient = &jplaw2epub.HTTPAPIClient{}
+	if cacheDir := opts.config.Images.CacheDir; cacheDir != "" {
+		epubOpts.APIClient = jplaw2epub.NewCachingAPIClient(epubOpts.APIClient, cacheDir)
+	}
+
+	return epubOpts, nil
+}
 
-	return &jplaw2epub.EPUBOptions{
-		APIClient:      client,
-		RevisionID:     revisionID,
-		MaxImageHeight: opts.maxImageHeight,
+// nameOutput sets the destination of opts from the output name of the configuration, which
+// needs the law, and returns a reader of the source data for the conversion
+func nameOutput(opts *options, source io.Reader) (io.Reader, error) {
+	if opts.config.Output.Name == "" {
+		return nil, fmt.Errorf("destination file path is required (-d, or output.name in the configuration)")
 	}
+	data, err := io.ReadAll(source)
+	if err != nil {
+		return nil, fmt.Errorf("reading source: %w", err)
+	}
+	law, err := parseLaw(data)
+	if err != nil {
+		return nil, err
+	}
+	opts.destPath = opts.config.OutputPath(law, opts.sourcePath)
+	return bytes.NewReader(data), nil
+}
+
+// warnAnnotation prints an annotation that could not be anchored
+func warnAnnotation(warning jplaw2epub.AnnotationWarning) {
+	fmt.Fprintf(os.Stderr, "Warning: annotation not anchored: %s\n", warning)
 }
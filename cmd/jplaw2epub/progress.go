@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"go.ngs.io/jplaw2epub"
+)
+
+// progressBarWidth is the number of cells of the progress bar
+const progressBarWidth = 30
+
+// progressBar draws conversion progress on a single terminal line
+type progressBar struct {
+	w     io.Writer
+	drawn bool
+}
+
+// update redraws the bar for an event
+func (b *progressBar) update(event jplaw2epub.ProgressEvent) {
+	filled := int(event.Fraction() * progressBarWidth)
+	bar := strings.Repeat("#", filled) + strings.Repeat("-", progressBarWidth-filled)
+	fmt.Fprintf(b.w, "\r[%s] %3.0f%% %s\x1b[K", bar, event.Fraction()*100, progressLabel(event))
+	b.drawn = true
+}
+
+// finish ends the progress line so later output starts on a new line. It does nothing on a nil bar.
+func (b *progressBar) finish() {
+	if b != nil && b.drawn {
+		fmt.Fprintln(b.w)
+		b.drawn = false
+	}
+}
+
+// progressLabel describes the current step of an event
+func progressLabel(event jplaw2epub.ProgressEvent) string {
+	switch event.Type {
+	case jplaw2epub.ProgressImageDownloaded, jplaw2epub.ProgressImageConverted:
+		return fmt.Sprintf("images %d/%d %s", event.Images, event.ImagesTotal, event.Title)
+	case jplaw2epub.ProgressPackagingStarted:
+		return "packaging"
+	case jplaw2epub.ProgressDone:
+		return "done"
+	default:
+		return fmt.Sprintf("articles %d/%d %s", event.Articles, event.ArticlesTotal, event.Title)
+	}
+}
+
+// jsonProgress writes each event as a line of JSON
+func jsonProgress(w io.Writer) jplaw2epub.ProgressFunc {
+	encoder := json.NewEncoder(w)
+	return func(event jplaw2epub.ProgressEvent) {
+		_ = encoder.Encode(event)
+	}
+}
+
+// isTerminal reports whether f is a character device such as a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	lawapi "go.ngs.io/jplaw-api-v2"
+	"go.ngs.io/jplaw2epub"
+)
+
+// shutdownTimeout is how long in-flight requests may take after a shutdown signal
+const shutdownTimeout = 30 * time.Second
+
+// runServe runs the HTTP conversion service
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addrFlag := fs.String("addr", ":8080", "Address to listen on")
+	noImagesFlag := fs.Bool("no-images", false, "Skip downloading and embedding images")
+	maxImageHeightFlag := fs.String("max-image-height", "80vh", "Maximum image height (e.g., '300px', '80vh', '50%')")
+	maxRequestBytesFlag := fs.Int64("max-request-bytes", jplaw2epub.DefaultMaxRequestBytes, "Maximum size of posted XML in bytes")
+	maxConcurrentFlag := fs.Int("max-concurrent", 0, "Maximum number of simultaneous conversions (default: number of CPUs)")
+	cacheEntriesFlag := fs.Int("cache-entries", jplaw2epub.DefaultCacheEntries, "Number of converted EPUBs kept in memory (0 disables the cache)")
+	revisionTTLFlag := fs.Duration("revision-ttl", jplaw2epub.DefaultRevisionTTL, "How long the revision of a law looked up by GET /laws/{lawID}.epub is reused (0 looks it up every time)")
+	conversionTimeoutFlag := fs.Duration("conversion-timeout", 5*time.Minute, "Abort conversions, including image downloads, after this duration (0 for no limit)")
+	coverFontFlag := fs.String("cover-font", "", "OpenType font for generated covers (default: a system CJK font)")
+	coverTemplateFlag := fs.String("cover-template", "", "PNG or JPEG background for generated covers")
+	noCoverFlag := fs.Bool("no-cover", false, "Do not add covers")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	cacheEntries := *cacheEntriesFlag
+	if cacheEntries == 0 {
+		cacheEntries = -1
+	}
+	revisionTTL := *revisionTTLFlag
+	if revisionTTL == 0 {
+		revisionTTL = -1
+	}
+
+	serverOpts := jplaw2epub.ServerOptions{
+		Fetcher:           jplaw2epub.NewLawFetcher(lawapi.NewClient()),
+		MaxImageHeight:    *maxImageHeightFlag,
+		MaxRequestBytes:   *maxRequestBytesFlag,
+		MaxConcurrent:     *maxConcurrentFlag,
+		CacheEntries:      cacheEntries,
+		RevisionTTL:       revisionTTL,
+		ConversionTimeout: *conversionTimeoutFlag,
+		NoCover:           *noCoverFlag,
+	}
+	if !*noImagesFlag {
+		serverOpts.APIClient = &jplaw2epub.HTTPAPIClient{}
+	}
+	coverFiles := []struct {
+		path string
+		dest *[]byte
+	}{
+		{*coverFontFlag, &serverOpts.CoverFont},
+		{*coverTemplateFlag, &serverOpts.CoverTemplate},
+	}
+	for _, f := range coverFiles {
+		if f.path == "" {
+			continue
+		}
+		data, err := os.ReadFile(f.path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading cover file: %v\n", err)
+			return 1
+		}
+		*f.dest = data
+	}
+
+	srv := &http.Server{
+		Addr:              *addrFlag,
+		Handler:           jplaw2epub.NewServer(serverOpts),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errCh := make(chan error, 1)
+	go func() {
+		fmt.Printf("Listening on %s\n", *addrFlag)
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		fmt.Fprintf(os.Stderr, "Error serving HTTP: %v\n", err)
+		return 1
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, "Error shutting down: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"go.ngs.io/jplaw2epub"
+)
+
+// runValidate checks EPUB files, printing their problems. It fails when any file has problems.
+func runValidate(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: jplaw2epub validate book.epub...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	status := 0
+	for _, path := range fs.Args() {
+		issues, err := jplaw2epub.ValidateEPUBFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", path, err)
+			status = 1
+			continue
+		}
+		if len(issues) > 0 {
+			status = 1
+		}
+		printIssues(os.Stdout, path, issues)
+	}
+	return status
+}
+
+// printIssues writes the problems found in an EPUB, or that it has none
+func printIssues(w io.Writer, path string, issues []jplaw2epub.ValidationIssue) {
+	if len(issues) == 0 {
+		fmt.Fprintf(w, "%s: no problems found\n", path)
+		return
+	}
+	for _, issue := range issues {
+		fmt.Fprintf(w, "%s: %s\n", path, issue)
+	}
+	fmt.Fprintf(w, "%s: %d problems found\n", path, len(issues))
+}
+
+// explainValidation prints the problems of an EPUB that was not written because it failed
+// validation, returning a shorter error in its place. Other errors are returned as they are.
+func explainValidation(err error, destPath string) error {
+	var validationErr *jplaw2epub.ValidationError
+	if !errors.As(err, &validationErr) {
+		return err
+	}
+	if destPath == stdioPath {
+		destPath = "EPUB"
+	}
+	printIssues(os.Stderr, destPath, validationErr.Issues)
+	return errors.New("the EPUB did not pass validation and was not written (-no-validate writes it anyway)")
+}
@@ -0,0 +1,359 @@
+package jplaw2epub
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	jplaw "go.ngs.io/jplaw-xml"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigEnvPrefix is the prefix of the environment variables of the settings, e.g.
+// JPLAW2EPUB_THEME for theme and JPLAW2EPUB_IMAGES_CACHE_DIR for images.cache_dir
+const ConfigEnvPrefix = "JPLAW2EPUB_"
+
+// Writing modes of Config.WritingMode
+const (
+	WritingModeHorizontal = "horizontal"
+	WritingModeVertical   = "vertical"
+)
+
+// Config is the conversion settings of a jplaw2epub.yaml, jplaw2epub.toml or jplaw2epub.json
+// file. Files and environment variables are merged into a Config in order, later values
+// replacing earlier ones, and EPUBOptions maps the result onto EPUBOptions.
+type Config struct {
+	// Format is the output format: epub, pdf or json
+	Format string `json:"format" yaml:"format"`
+	// Theme is the colour theme: default, sepia or dark
+	Theme Theme `json:"theme" yaml:"theme"`
+	// WritingMode is horizontal or vertical (縦書き, for PDF output and the generated cover)
+	WritingMode string `json:"writing_mode" yaml:"writing_mode"`
+	// Dates is the calendar of displayed dates: japanese, gregorian or both
+	Dates           DateDisplay       `json:"dates" yaml:"dates"`
+	ArabicTOC       bool              `json:"arabic_toc" yaml:"arabic_toc"`
+	Search          bool              `json:"search" yaml:"search"`
+	Definitions     bool              `json:"definitions" yaml:"definitions"`
+	DefinitionLinks bool              `json:"definition_links" yaml:"definition_links"`
+	PopupNotes      bool              `json:"popup_notes" yaml:"popup_notes"`
+	WideTables      WideTableStrategy `json:"wide_tables" yaml:"wide_tables"`
+	WideTableCols   int               `json:"wide_table_columns" yaml:"wide_table_columns"`
+	NoSuppl         bool              `json:"no_suppl" yaml:"no_suppl"`
+	NoAppendix      bool              `json:"no_appendix" yaml:"no_appendix"`
+	Validate        bool              `json:"validate" yaml:"validate"`
+	// Annotations is the path of a YAML or JSON annotations file
+	Annotations string `json:"annotations" yaml:"annotations"`
+	// EmbedFont is the path of a font embedded in the EPUB
+	EmbedFont string            `json:"embed_font" yaml:"embed_font"`
+	Images    ImageConfig       `json:"images" yaml:"images"`
+	Cover     CoverConfig       `json:"cover" yaml:"cover"`
+	Metadata  MetadataOverrides `json:"metadata" yaml:"metadata"`
+	Output    OutputConfig      `json:"output" yaml:"output"`
+}
+
+// ImageConfig is the image policy of a Config
+type ImageConfig struct {
+	// Download downloads and embeds the figures of laws with a revision ID
+	Download bool `json:"download" yaml:"download"`
+	// MaxHeight is the maximum image height (e.g. 300px, 80vh, 50%)
+	MaxHeight string `json:"max_height" yaml:"max_height"`
+	// CacheDir keeps downloaded images for later conversions when set
+	CacheDir string `json:"cache_dir" yaml:"cache_dir"`
+}
+
+// CoverConfig is the cover settings of a Config
+type CoverConfig struct {
+	// Image, Template and Font are the paths of EPUBOptions.CoverImage, CoverTemplate and CoverFont
+	Image    string `json:"image" yaml:"image"`
+	Template string `json:"template" yaml:"template"`
+	Font     string `json:"font" yaml:"font"`
+	Disabled bool   `json:"disabled" yaml:"disabled"`
+}
+
+// OutputConfig names the output files when no destination is given
+type OutputConfig struct {
+	// Dir is the directory output files are written to
+	Dir string `json:"dir" yaml:"dir"`
+	// Name is the file name template. {title}, {law_num}, {law_id}, {source} (the source file
+	// name without extension) and {format} are replaced.
+	Name string `json:"name" yaml:"name"`
+}
+
+// DefaultConfig returns the settings used when no file or variable sets them
+func DefaultConfig() *Config {
+	return &Config{
+		Format:        "epub",
+		Theme:         ThemeDefault,
+		WritingMode:   WritingModeHorizontal,
+		Dates:         DateDisplayJapanese,
+		WideTables:    WideTableScroll,
+		WideTableCols: defaultWideTableColumns,
+		Validate:      true,
+		Images:        ImageConfig{Download: true, MaxHeight: "80vh"},
+	}
+}
+
+// Merge merges settings written in format (yaml, toml or json) into c
+func (c *Config) Merge(data []byte, format string) error {
+	var doc any
+	var err error
+	switch format {
+	case "yaml", "yml":
+		err = yaml.Unmarshal(data, &doc)
+	case "toml":
+		var table map[string]any
+		err = toml.Unmarshal(data, &table)
+		doc = table
+	case "json":
+		err = json.Unmarshal(data, &doc)
+	default:
+		return fmt.Errorf("unknown configuration format %q (want yaml, toml or json)", format)
+	}
+	if err != nil {
+		return fmt.Errorf("parsing configuration: %w", err)
+	}
+	if doc == nil {
+		return nil
+	}
+	return c.decode(doc)
+}
+
+// MergeFile merges the settings of a configuration file into c, choosing the format by the
+// file extension. Relative paths in the file are relative to its directory.
+func (c *Config) MergeFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading configuration: %w", err)
+	}
+	if err := c.Merge(data, strings.TrimPrefix(filepath.Ext(path), ".")); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	c.resolvePaths(filepath.Dir(path))
+	return nil
+}
+
+// Set sets the setting with the dotted key (e.g. images.max_height) to value, which is
+// read like a YAML scalar. Booleans may also be written 1 or 0.
+func (c *Config) Set(key, value string) error {
+	if !slices.Contains(configKeys(), key) {
+		return fmt.Errorf("unknown setting %q", key)
+	}
+	var typed any
+	if err := yaml.Unmarshal([]byte(value), &typed); err != nil {
+		typed = value
+	}
+	values := []any{typed, value}
+	if enabled, err := strconv.ParseBool(value); err == nil {
+		values = append(values, enabled)
+	}
+	for _, v := range values {
+		doc := v
+		parts := strings.Split(key, ".")
+		for i := len(parts) - 1; i >= 0; i-- {
+			doc = map[string]any{parts[i]: doc}
+		}
+		// Decode into a copy so that a failed attempt leaves c unchanged
+		updated := *c
+		if err := updated.decode(doc); err == nil {
+			*c = updated
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid value %q for %s", value, key)
+}
+
+// MergeEnv sets the settings named by the ConfigEnvPrefix variables of environ (as returned
+// by os.Environ). skip lists variables with the prefix that are not settings.
+func (c *Config) MergeEnv(environ []string, skip ...string) error {
+	keys := make(map[string]string)
+	for _, key := range configKeys() {
+		keys[ConfigEnvVar(key)] = key
+	}
+	for _, variable := range environ {
+		name, value, _ := strings.Cut(variable, "=")
+		if !strings.HasPrefix(name, ConfigEnvPrefix) || slices.Contains(skip, name) {
+			continue
+		}
+		key, ok := keys[name]
+		if !ok {
+			return fmt.Errorf("unknown setting in environment variable %s", name)
+		}
+		if err := c.Set(key, value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ConfigEnvVar returns the environment variable of a setting
+func ConfigEnvVar(key string) string {
+	return ConfigEnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// EPUBOptions maps the settings onto EPUBOptions, reading the cover, font and annotations
+// files. The API client and revision ID are left to the caller.
+func (c *Config) EPUBOptions() (*EPUBOptions, error) {
+	theme, err := ParseTheme(string(c.Theme))
+	if err != nil {
+		return nil, err
+	}
+	dates, err := ParseDateDisplay(string(c.Dates))
+	if err != nil {
+		return nil, err
+	}
+	wideTables, err := ParseWideTableStrategy(string(c.WideTables))
+	if err != nil {
+		return nil, err
+	}
+	switch c.WritingMode {
+	case "", WritingModeHorizontal, WritingModeVertical:
+	default:
+		return nil, fmt.Errorf("unknown writing mode %q (want horizontal or vertical)", c.WritingMode)
+	}
+
+	opts := &EPUBOptions{
+		MaxImageHeight:    c.Images.MaxHeight,
+		VerticalWriting:   c.WritingMode == WritingModeVertical,
+		SearchIndex:       c.Search,
+		DefinitionIndex:   c.Definitions,
+		DefinitionLinks:   c.DefinitionLinks,
+		PopupNotes:        c.PopupNotes,
+		NoCover:           c.Cover.Disabled,
+		Theme:             theme,
+		Metadata:          c.Metadata,
+		DateDisplay:       dates,
+		ArabicTOC:         c.ArabicTOC,
+		NoSupplProvisions: c.NoSuppl,
+		NoAppendixes:      c.NoAppendix,
+		WideTables:        wideTables,
+		WideTableColumns:  c.WideTableCols,
+		Validate:          c.Validate,
+	}
+
+	files := []struct {
+		path string
+		dest *[]byte
+	}{
+		{c.Cover.Image, &opts.CoverImage},
+		{c.Cover.Template, &opts.CoverTemplate},
+		{c.Cover.Font, &opts.CoverFont},
+		{c.EmbedFont, &opts.EmbedFont},
+	}
+	for _, f := range files {
+		if f.path == "" {
+			continue
+		}
+		if *f.dest, err = os.ReadFile(f.path); err != nil {
+			return nil, fmt.Errorf("reading %s: %w", f.path, err)
+		}
+	}
+	if c.Annotations != "" {
+		data, err := os.ReadFile(c.Annotations)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", c.Annotations, err)
+		}
+		if opts.Annotations, err = ParseAnnotations(data); err != nil {
+			return nil, fmt.Errorf("%s: %w", c.Annotations, err)
+		}
+	}
+	return opts, nil
+}
+
+// OutputPath returns the output file of a law converted from sourcePath, following
+// Output.Name and Output.Dir, or an empty string when Output.Name is not set
+func (c *Config) OutputPath(data *jplaw.Law, sourcePath string) string {
+	if c.Output.Name == "" {
+		return ""
+	}
+	var title string
+	if data.LawBody.LawTitle != nil {
+		title = data.LawBody.LawTitle.Content
+	}
+	source := filepath.Base(sourcePath)
+	source = strings.TrimSuffix(source, filepath.Ext(source))
+	lawID := deriveLawID(data)
+	if lawID == "" {
+		lawID = source
+	}
+
+	unsafe := strings.NewReplacer("/", "_", "\\", "_", ":", "_", "*", "_", "?", "_", `"`, "_", "<", "_", ">", "_", "|", "_")
+	name := strings.NewReplacer(
+		"{title}", unsafe.Replace(title),
+		"{law_num}", unsafe.Replace(data.LawNum),
+		"{law_id}", unsafe.Replace(lawID),
+		"{source}", unsafe.Replace(source),
+		"{format}", unsafe.Replace(c.Format),
+	).Replace(c.Output.Name)
+	return filepath.Join(c.Output.Dir, name)
+}
+
+// WriteYAML writes the settings as YAML
+func (c *Config) WriteYAML(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c); err != nil {
+		return fmt.Errorf("encoding configuration: %w", err)
+	}
+	return encoder.Close()
+}
+
+// decode merges a parsed configuration document into c, rejecting unknown settings
+func (c *Config) decode(doc any) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("encoding configuration: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	return nil
+}
+
+// resolvePaths makes the relative file and directory paths of the settings relative to dir,
+// expanding a leading ~ to the home directory
+func (c *Config) resolvePaths(dir string) {
+	home, _ := os.UserHomeDir()
+	for _, p := range []*string{
+		&c.Annotations, &c.EmbedFont, &c.Images.CacheDir,
+		&c.Cover.Image, &c.Cover.Template, &c.Cover.Font, &c.Output.Dir,
+	} {
+		switch {
+		case *p == "":
+		case (*p == "~" || strings.HasPrefix(*p, "~/")) && home != "":
+			*p = filepath.Join(home, strings.TrimPrefix(*p, "~"))
+		case !filepath.IsAbs(*p):
+			*p = filepath.Join(dir, *p)
+		}
+	}
+}
+
+// configKeys returns the dotted keys of all settings, sorted
+func configKeys() []string {
+	var keys []string
+	var walk func(t reflect.Type, prefix string)
+	walk = func(t reflect.Type, prefix string) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if field.Type.Kind() == reflect.Struct {
+				walk(field.Type, prefix+name+".")
+				continue
+			}
+			keys = append(keys, prefix+name)
+		}
+	}
+	walk(reflect.TypeOf(Config{}), "")
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,279 @@
+package jplaw2epub
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.ngs.io/jplaw-xml"
+)
+
+func TestConfigMerge(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		data   string
+		check  func(t *testing.T, c *Config)
+	}{
+		{
+			name:   "yaml",
+			format: "yaml",
+			data: `theme: sepia
+search: true
+images:
+  download: false
+metadata:
+  publisher: 出版社
+`,
+			check: func(t *testing.T, c *Config) {
+				if c.Theme != ThemeSepia || !c.Search || c.Images.Download || c.Metadata.Publisher != "出版社" {
+					t.Errorf("config = %+v", c)
+				}
+				if c.Images.MaxHeight != "80vh" {
+					t.Errorf("unset images.max_height = %q, want default kept", c.Images.MaxHeight)
+				}
+			},
+		},
+		{
+			name:   "toml",
+			format: "toml",
+			data: `format = "pdf"
+writing_mode = "vertical"
+wide_table_columns = 8
+
+[cover]
+disabled = true
+`,
+			check: func(t *testing.T, c *Config) {
+				if c.Format != "pdf" || c.WritingMode != WritingModeVertical || c.WideTableCols != 8 || !c.Cover.Disabled {
+					t.Errorf("config = %+v", c)
+				}
+			},
+		},
+		{
+			name:   "json",
+			format: "json",
+			data:   `{"dates": "both", "output": {"name": "{law_id}.epub"}}`,
+			check: func(t *testing.T, c *Config) {
+				if c.Dates != DateDisplayBoth || c.Output.Name != "{law_id}.epub" {
+					t.Errorf("config = %+v", c)
+				}
+			},
+		},
+		{
+			name:   "empty",
+			format: "yaml",
+			data:   "# nothing set\n",
+			check: func(t *testing.T, c *Config) {
+				if !reflect.DeepEqual(c, DefaultConfig()) {
+					t.Errorf("config = %+v, want defaults", c)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := DefaultConfig()
+			if err := c.Merge([]byte(tt.data), tt.format); err != nil {
+				t.Fatalf("Merge() error = %v", err)
+			}
+			tt.check(t, c)
+		})
+	}
+}
+
+func TestConfigMergeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		data   string
+	}{
+		{"unknown setting", "yaml", "themes: dark\n"},
+		{"unknown nested setting", "json", `{"images": {"size": 3}}`},
+		{"wrong type", "toml", "search = \"yes\"\n"},
+		{"unknown format", "ini", "theme=dark"},
+		{"syntax", "toml", "theme = \n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := DefaultConfig().Merge([]byte(tt.data), tt.format); err == nil {
+				t.Error("Merge() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestConfigMergeFile(t *testing.T) {
+	dir := t.TempDir()
+	userPath := filepath.Join(dir, "config.toml")
+	projectPath := filepath.Join(dir, "project", "jplaw2epub.yml")
+	if err := os.MkdirAll(filepath.Dir(projectPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(userPath, []byte("theme = \"dark\"\nsearch = true\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(projectPath, []byte("theme: sepia\nannotations: notes.yaml\noutput:\n  dir: out\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := DefaultConfig()
+	for _, path := range []string{userPath, projectPath} {
+		if err := c.MergeFile(path); err != nil {
+			t.Fatalf("MergeFile(%s) error = %v", path, err)
+		}
+	}
+	if c.Theme != ThemeSepia || !c.Search {
+		t.Errorf("theme = %q, search = %v; want the project theme and the user search", c.Theme, c.Search)
+	}
+	if want := filepath.Join(dir, "project", "notes.yaml"); c.Annotations != want {
+		t.Errorf("annotations = %q, want %q", c.Annotations, want)
+	}
+	if want := filepath.Join(dir, "project", "out"); c.Output.Dir != want {
+		t.Errorf("output.dir = %q, want %q", c.Output.Dir, want)
+	}
+
+	if err := c.MergeFile(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("MergeFile() of a missing file error = nil, want error")
+	}
+}
+
+func TestConfigSet(t *testing.T) {
+	c := DefaultConfig()
+	for _, setting := range [][2]string{
+		{"theme", "dark"},
+		{"search", "true"},
+		{"wide_table_columns", "10"},
+		{"metadata.title", "123"},
+		{"images.max_height", "300px"},
+	} {
+		if err := c.Set(setting[0], setting[1]); err != nil {
+			t.Fatalf("Set(%q, %q) error = %v", setting[0], setting[1], err)
+		}
+	}
+	if c.Theme != ThemeDark || !c.Search || c.WideTableCols != 10 || c.Metadata.Title != "123" || c.Images.MaxHeight != "300px" {
+		t.Errorf("config = %+v", c)
+	}
+
+	for _, setting := range [][2]string{
+		{"colour", "red"},
+		{"images", "true"},
+		{"wide_table_columns", "many"},
+	} {
+		if err := c.Set(setting[0], setting[1]); err == nil {
+			t.Errorf("Set(%q, %q) error = nil, want error", setting[0], setting[1])
+		}
+	}
+}
+
+func TestConfigMergeEnv(t *testing.T) {
+	c := DefaultConfig()
+	environ := []string{
+		"HOME=/home/user",
+		"JPLAW2EPUB_THEME=sepia",
+		"JPLAW2EPUB_IMAGES_CACHE_DIR=/tmp/cache",
+		"JPLAW2EPUB_NO_SUPPL=1",
+		"JPLAW2EPUB_CONFIG=other.yaml",
+	}
+	if err := c.MergeEnv(environ, "JPLAW2EPUB_CONFIG"); err != nil {
+		t.Fatalf("MergeEnv() error = %v", err)
+	}
+	if c.Theme != ThemeSepia || c.Images.CacheDir != "/tmp/cache" || !c.NoSuppl {
+		t.Errorf("config = %+v", c)
+	}
+
+	if err := DefaultConfig().MergeEnv([]string{"JPLAW2EPUB_COLOUR=red"}); err == nil {
+		t.Error("MergeEnv() of an unknown variable error = nil, want error")
+	}
+	if got := ConfigEnvVar("images.cache_dir"); got != "JPLAW2EPUB_IMAGES_CACHE_DIR" {
+		t.Errorf("ConfigEnvVar() = %q", got)
+	}
+}
+
+func TestConfigEPUBOptions(t *testing.T) {
+	c := DefaultConfig()
+	c.Theme = ThemeDark
+	c.WritingMode = WritingModeVertical
+	c.Cover.Disabled = true
+	c.Metadata.Author = "編者"
+	c.Validate = false
+
+	opts, err := c.EPUBOptions()
+	if err != nil {
+		t.Fatalf("EPUBOptions() error = %v", err)
+	}
+	if opts.Theme != ThemeDark || !opts.VerticalWriting || !opts.NoCover || opts.Metadata.Author != "編者" ||
+		opts.Validate || opts.MaxImageHeight != "80vh" || opts.WideTableColumns != defaultWideTableColumns {
+		t.Errorf("options = %+v", opts)
+	}
+
+	for _, modify := range []func(c *Config){
+		func(c *Config) { c.Theme = "neon" },
+		func(c *Config) { c.WritingMode = "diagonal" },
+		func(c *Config) { c.Dates = "mayan" },
+		func(c *Config) { c.Cover.Image = filepath.Join(t.TempDir(), "missing.png") },
+	} {
+		c := DefaultConfig()
+		modify(c)
+		if _, err := c.EPUBOptions(); err == nil {
+			t.Errorf("EPUBOptions() of %+v error = nil, want error", c)
+		}
+	}
+}
+
+func TestConfigOutputPath(t *testing.T) {
+	law := &jplaw.Law{
+		LawNum: "令和六年法律第一号",
+		LawBody: jplaw.LawBody{
+			LawTitle: &jplaw.LawTitle{Content: "届出/事業法"},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		output OutputConfig
+		want   string
+	}{
+		{"no name", OutputConfig{Dir: "out"}, ""},
+		{"title", OutputConfig{Name: "{title}.{format}"}, "届出_事業法.epub"},
+		{"law number in dir", OutputConfig{Dir: "out", Name: "{law_num}-{source}.epub"}, filepath.Join("out", "令和六年法律第一号-law.epub")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := DefaultConfig()
+			c.Output = tt.output
+			if got := c.OutputPath(law, "/data/law.xml"); got != tt.want {
+				t.Errorf("OutputPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigWriteYAML(t *testing.T) {
+	c := DefaultConfig()
+	c.Theme = ThemeSepia
+	c.Metadata.Title = "題名: 副題"
+	c.Output.Name = "{title}.epub"
+
+	var buf bytes.Buffer
+	if err := c.WriteYAML(&buf); err != nil {
+		t.Fatalf("WriteYAML() error = %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "format: epub\ntheme: sepia\n") {
+		t.Errorf("WriteYAML() should keep the field order, got:\n%s", buf.String())
+	}
+
+	got := DefaultConfig()
+	if err := got.Merge(buf.Bytes(), "yaml"); err != nil {
+		t.Fatalf("Merge() of written YAML error = %v\n%s", err, buf.String())
+	}
+	if !reflect.DeepEqual(got, c) {
+		t.Errorf("round trip = %+v, want %+v", got, c)
+	}
+}
@@ -0,0 +1,354 @@
+package jplaw2epub
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	jplaw "go.ngs.io/jplaw-xml"
+)
+
+// AmendmentDiagnostic is an amendment instruction ApplyAmendments could not apply
+type AmendmentDiagnostic struct {
+	// Instruction is the text of the amendment instruction (改め文)
+	Instruction string
+	Message     string
+}
+
+// String formats the diagnostic as instruction: message
+func (d AmendmentDiagnostic) String() string {
+	return fmt.Sprintf("%s: %s", d.Instruction, d.Message)
+}
+
+// amendNumber matches a kanji number in an amendment instruction
+const amendNumber = `[〇一二三四五六七八九十百千]+`
+
+// amendArticlePattern matches an article in an amendment instruction, as in 第三条の二
+const amendArticlePattern = `第(` + amendNumber + `条(?:の` + amendNumber + `)*)`
+
+var (
+	// amendTarget matches the provision an instruction edits, as in 第三条の二第二項第一号
+	amendTarget = amendArticlePattern + `(?:第(` + amendNumber + `)項)?(?:第(` + amendNumber + `)号)?`
+	// amendReplacePattern matches 「A」を「B」に改める, with any number of pairs
+	amendReplacePattern = regexp.MustCompile(`^` + amendTarget + `中((?:「[^「」]+」を「[^「」]*」に、?)+)改める。$`)
+	// amendDeleteTextPattern matches 「A」を削る, with any number of quoted phrases
+	amendDeleteTextPattern = regexp.MustCompile(`^` + amendTarget + `中((?:「[^「」]+」(?:、|及び)?)+)を削る。$`)
+	// amendDeleteArticlePattern matches 第X条を削る
+	amendDeleteArticlePattern = regexp.MustCompile(`^` + amendArticlePattern + `を削る。$`)
+	// amendInsertPattern matches 第X条の次に次のN条を加える
+	amendInsertPattern = regexp.MustCompile(`^` + amendArticlePattern + `の次に次の(` + amendNumber + `)条を加える。$`)
+	// amendPairPattern matches one 「A」を「B」に pair of a replacement
+	amendPairPattern = regexp.MustCompile(`「([^「」]+)」を「([^「」]*)」に`)
+	// amendQuotePattern matches a quoted phrase
+	amendQuotePattern = regexp.MustCompile(`「([^「」]+)」`)
+)
+
+// amendmentTarget is the provision an amendment instruction edits. Paragraph and item are
+// 1-based positions, or 0 for the whole article or paragraph.
+type amendmentTarget struct {
+	article         ProvisionNumber
+	paragraph, item int
+}
+
+// consolidation applies amendment instructions to a copy of a law
+type consolidation struct {
+	law         *jplaw.Law
+	diagnostics []AmendmentDiagnostic
+}
+
+// ApplyAmendments applies the amendment provisions (改正規定) of the amending law XML read
+// from amending to a copy of base, returning the consolidated law. base is not modified.
+//
+// The instructions 「A」を「B」に改める, 「A」を削る, 第X条を削る and 第X条の次に次のN条を加える are
+// applied. Instructions that cannot be parsed or applied, and the amendment provisions of
+// other laws, are returned as diagnostics.
+func ApplyAmendments(base *jplaw.Law, amending io.Reader) (*jplaw.Law, []AmendmentDiagnostic, error) {
+	amendingLaw, amendments, err := loadLawFromReader(amending)
+	if err != nil {
+		return nil, nil, fmt.Errorf("loading amending law: %w", err)
+	}
+
+	c := &consolidation{law: copyLawArticles(base)}
+	for _, para := range lawParagraphs(amendingLaw) {
+		provisions := amendments[para]
+		if len(provisions) == 0 {
+			continue
+		}
+		if sentence := sentencesText(para.ParagraphSentence.Sentence); !amendsLaw(sentence, base) {
+			c.report(sentence, "does not amend this law")
+			continue
+		}
+		for i := range provisions {
+			c.apply(&provisions[i])
+		}
+	}
+	return c.law, c.diagnostics, nil
+}
+
+// amendsLaw reports whether the sentence introducing amendment provisions names law by title or number
+func amendsLaw(sentence string, law *jplaw.Law) bool {
+	if law.LawNum != "" && strings.Contains(sentence, law.LawNum) {
+		return true
+	}
+	return law.LawBody.LawTitle != nil && law.LawBody.LawTitle.Content != "" &&
+		strings.Contains(sentence, law.LawBody.LawTitle.Content)
+}
+
+// report adds a diagnostic
+func (c *consolidation) report(instruction, format string, args ...any) {
+	c.diagnostics = append(c.diagnostics, AmendmentDiagnostic{Instruction: instruction, Message: fmt.Sprintf(format, args...)})
+}
+
+// apply applies one amendment provision
+func (c *consolidation) apply(provision *amendProvision) {
+	instruction := sentencesText(provision.Sentence)
+	if m := amendReplacePattern.FindStringSubmatch(instruction); m != nil {
+		target, ok := c.target(instruction, m[1:4])
+		if !ok {
+			return
+		}
+		for _, pair := range amendPairPattern.FindAllStringSubmatch(m[4], -1) {
+			c.editText(instruction, target, pair[1], pair[2])
+		}
+		return
+	}
+	if m := amendDeleteTextPattern.FindStringSubmatch(instruction); m != nil {
+		target, ok := c.target(instruction, m[1:4])
+		if !ok {
+			return
+		}
+		for _, quote := range amendQuotePattern.FindAllStringSubmatch(m[4], -1) {
+			c.editText(instruction, target, quote[1], "")
+		}
+		return
+	}
+	if m := amendDeleteArticlePattern.FindStringSubmatch(instruction); m != nil {
+		c.deleteArticle(instruction, m[1])
+		return
+	}
+	if m := amendInsertPattern.FindStringSubmatch(instruction); m != nil {
+		c.insertArticles(instruction, m[1], m[2], provision.NewProvision)
+		return
+	}
+	c.report(instruction, "unsupported amendment instruction")
+}
+
+// target parses the article, paragraph and item numbers of an instruction
+func (c *consolidation) target(instruction string, parts []string) (amendmentTarget, bool) {
+	article, err := ParseProvisionNumber(parts[0])
+	if err != nil {
+		c.report(instruction, "invalid article number: %v", err)
+		return amendmentTarget{}, false
+	}
+	target := amendmentTarget{article: article}
+	for i, dest := range []*int{&target.paragraph, &target.item} {
+		if parts[i+1] == "" {
+			continue
+		}
+		if *dest, err = ParseKanjiNumber(parts[i+1]); err != nil {
+			c.report(instruction, "invalid number: %v", err)
+			return amendmentTarget{}, false
+		}
+	}
+	return target, true
+}
+
+// editText replaces oldText with newText in the target provision, reporting when oldText is not found
+func (c *consolidation) editText(instruction string, target amendmentTarget, oldText, newText string) {
+	articles, idx := c.findArticle(target.article)
+	if articles == nil {
+		c.report(instruction, "article %s not found", target.article)
+		return
+	}
+	article := &(*articles)[idx]
+	if target.paragraph > len(article.Paragraph) {
+		c.report(instruction, "paragraph %d of article %s not found", target.paragraph, target.article)
+		return
+	}
+	if replaceArticleText(article, target, oldText, newText) == 0 {
+		c.report(instruction, "「%s」 not found", oldText)
+	}
+}
+
+// deleteArticle removes an article
+func (c *consolidation) deleteArticle(instruction, title string) {
+	number, err := ParseProvisionNumber(title)
+	if err != nil {
+		c.report(instruction, "invalid article number: %v", err)
+		return
+	}
+	articles, idx := c.findArticle(number)
+	if articles == nil {
+		c.report(instruction, "article %s not found", number)
+		return
+	}
+	*articles = slices.Delete(*articles, idx, idx+1)
+}
+
+// insertArticles adds the articles of the new provisions after an article
+func (c *consolidation) insertArticles(instruction, title, count string, provisions []newProvision) {
+	number, err := ParseProvisionNumber(title)
+	if err != nil {
+		c.report(instruction, "invalid article number: %v", err)
+		return
+	}
+	want, err := ParseKanjiNumber(count)
+	if err != nil {
+		c.report(instruction, "invalid number: %v", err)
+		return
+	}
+	var added []jplaw.Article
+	for i := range provisions {
+		added = append(added, provisions[i].Article...)
+	}
+	if len(added) != want {
+		c.report(instruction, "%d articles to add, but the new provision has %d", want, len(added))
+		return
+	}
+	articles, idx := c.findArticle(number)
+	if articles == nil {
+		c.report(instruction, "article %s not found", number)
+		return
+	}
+	*articles = slices.Insert(*articles, idx+1, added...)
+}
+
+// findArticle returns the article list holding the main provision article with number, and its index
+func (c *consolidation) findArticle(number ProvisionNumber) (*[]jplaw.Article, int) {
+	for _, articles := range mainProvisionArticleLists(&c.law.LawBody.MainProvision) {
+		for i := range *articles {
+			if n, ok := articleNumber(&(*articles)[i]); ok && n.Compare(number) == 0 {
+				return articles, i
+			}
+		}
+	}
+	return nil, -1
+}
+
+// mainProvisionArticleLists returns the article lists of a main provision: those of its
+// sections, chapters and the main provision itself
+func mainProvisionArticleLists(mainProv *jplaw.MainProvision) []*[]jplaw.Article {
+	var lists []*[]jplaw.Article
+	for i := range mainProv.Chapter {
+		chapter := &mainProv.Chapter[i]
+		for j := range chapter.Section {
+			lists = append(lists, &chapter.Section[j].Article)
+		}
+		lists = append(lists, &chapter.Article)
+	}
+	return append(lists, &mainProv.Article)
+}
+
+// copyLawArticles copies a law down to the article lists of its main provision, so that
+// articles can be added, removed and replaced without modifying the original
+func copyLawArticles(base *jplaw.Law) *jplaw.Law {
+	law := *base
+	mainProv := &law.LawBody.MainProvision
+	mainProv.Chapter = slices.Clone(mainProv.Chapter)
+	for i := range mainProv.Chapter {
+		chapter := &mainProv.Chapter[i]
+		chapter.Article = slices.Clone(chapter.Article)
+		chapter.Section = slices.Clone(chapter.Section)
+		for j := range chapter.Section {
+			chapter.Section[j].Article = slices.Clone(chapter.Section[j].Article)
+		}
+	}
+	mainProv.Article = slices.Clone(mainProv.Article)
+	return &law
+}
+
+// replaceArticleText replaces oldText with newText in the sentences of the target part of an
+// article, returning the number of replacements. The paragraphs and items are copied before editing.
+func replaceArticleText(article *jplaw.Article, target amendmentTarget, oldText, newText string) int {
+	count := 0
+	article.Paragraph = slices.Clone(article.Paragraph)
+	for i := range article.Paragraph {
+		if target.paragraph > 0 && i != target.paragraph-1 {
+			continue
+		}
+		para := &article.Paragraph[i]
+		if target.item == 0 {
+			var n int
+			para.ParagraphSentence.Sentence, n = replaceSentenceText(para.ParagraphSentence.Sentence, oldText, newText)
+			count += n
+		}
+		para.Item = slices.Clone(para.Item)
+		for j := range para.Item {
+			if target.item > 0 && j != target.item-1 {
+				continue
+			}
+			var n int
+			para.Item[j].ItemSentence.Sentence, n = replaceSentenceText(para.Item[j].ItemSentence.Sentence, oldText, newText)
+			count += n
+		}
+	}
+	return count
+}
+
+// replaceSentenceText replaces oldText with newText in copies of sentences, returning the copies
+// and the number of replacements. Text split by ruby or other markup is not matched.
+func replaceSentenceText(sentences []jplaw.Sentence, oldText, newText string) ([]jplaw.Sentence, int) {
+	sentences = slices.Clone(sentences)
+	count := 0
+	for i := range sentences {
+		sentence := &sentences[i]
+		if len(sentence.MixedContent.Nodes) == 0 {
+			count += strings.Count(sentence.Content, oldText)
+			sentence.Content = strings.ReplaceAll(sentence.Content, oldText, newText)
+			continue
+		}
+		sentence.MixedContent.Nodes = slices.Clone(sentence.MixedContent.Nodes)
+		for j, node := range sentence.MixedContent.Nodes {
+			if text, ok := node.(jplaw.TextNode); ok && strings.Contains(text.Text, oldText) {
+				count += strings.Count(text.Text, oldText)
+				sentence.MixedContent.Nodes[j] = jplaw.TextNode{Text: strings.ReplaceAll(text.Text, oldText, newText)}
+			}
+		}
+	}
+	return sentences, count
+}
+
+// sentencesText returns the plain text of sentences
+func sentencesText(sentences []jplaw.Sentence) string {
+	var text strings.Builder
+	for i := range sentences {
+		text.WriteString(plainText(sentences[i].HTML()))
+	}
+	return text.String()
+}
+
+// lawParagraphs returns the paragraphs of the main and supplementary provisions of a law
+// that can hold amendment provisions, in document order
+func lawParagraphs(law *jplaw.Law) []*jplaw.Paragraph {
+	var paragraphs []*jplaw.Paragraph
+	addParagraphs := func(paras []jplaw.Paragraph) {
+		for i := range paras {
+			paragraphs = append(paragraphs, &paras[i])
+		}
+	}
+	addArticles := func(articles []jplaw.Article) {
+		for i := range articles {
+			addParagraphs(articles[i].Paragraph)
+		}
+	}
+	addProvision := func(chapters []jplaw.Chapter, articles []jplaw.Article, paras []jplaw.Paragraph) {
+		for i := range chapters {
+			for j := range chapters[i].Section {
+				addArticles(chapters[i].Section[j].Article)
+			}
+			addArticles(chapters[i].Article)
+		}
+		addArticles(articles)
+		addParagraphs(paras)
+	}
+
+	mainProv := &law.LawBody.MainProvision
+	addProvision(mainProv.Chapter, mainProv.Article, mainProv.Paragraph)
+	for i := range law.LawBody.SupplProvision {
+		provision := &law.LawBody.SupplProvision[i]
+		addProvision(provision.Chapter, provision.Article, provision.Paragraph)
+	}
+	return paragraphs
+}
@@ -0,0 +1,182 @@
+package jplaw2epub
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	jplaw "go.ngs.io/jplaw-xml"
+)
+
+// amendingLawXML wraps amendment provisions in an amending law of the articles.xml law
+func amendingLawXML(provisions ...string) string {
+	var body strings.Builder
+	for _, provision := range provisions {
+		body.WriteString(provision)
+	}
+	return `<Law Era="Heisei" Year="22" Num="1" LawType="CabinetOrder" Lang="ja"><LawNum>平成二十二年政令第一号</LawNum>` +
+		`<LawBody><LawTitle>規程令の一部を改正する政令</LawTitle><MainProvision><Paragraph Num="1"><ParagraphNum/>` +
+		`<ParagraphSentence><Sentence>規程令（平成二十年政令第三号）の一部を次のように改正する。</Sentence></ParagraphSentence>` +
+		body.String() + `</Paragraph></MainProvision></LawBody></Law>`
+}
+
+// amendXML returns an amendment provision with the instruction and new provision content
+func amendXML(instruction, newProvision string) string {
+	provision := `<AmendProvision><AmendProvisionSentence><Sentence>` + instruction + `</Sentence></AmendProvisionSentence>`
+	if newProvision != "" {
+		provision += `<NewProvision>` + newProvision + `</NewProvision>`
+	}
+	return provision + `</AmendProvision>`
+}
+
+// articleTexts returns the titles and sentences of the main provision articles of a law
+func articleTexts(law *jplaw.Law) []string {
+	var texts []string
+	for i := range law.LawBody.MainProvision.Article {
+		article := &law.LawBody.MainProvision.Article[i]
+		text := article.ArticleTitle.Content
+		for j := range article.Paragraph {
+			text += " " + sentencesText(article.Paragraph[j].ParagraphSentence.Sentence)
+		}
+		texts = append(texts, text)
+	}
+	return texts
+}
+
+func TestApplyAmendments(t *testing.T) {
+	const newArticle = `<Article Num="1_3"><ArticleTitle>第一条の三</ArticleTitle><Paragraph Num="1"><ParagraphNum/>` +
+		`<ParagraphSentence><Sentence>新しい規定</Sentence></ParagraphSentence></Paragraph></Article>`
+
+	tests := []struct {
+		name            string
+		provisions      []string
+		want            []string
+		wantDiagnostics []string
+	}{
+		{
+			name:       "Replace text",
+			provisions: []string{amendXML("第一条中「別表」を「別表第一」に、「手数料」を「料金」に改める。", "")},
+			want: []string{
+				"第一条 この政令は、別表第一に掲げる料金について定める。",
+				"第一条の二 手数料の額は、別表のとおりとする。ただし、免除することができる。",
+			},
+		},
+		{
+			name:       "Delete text",
+			provisions: []string{amendXML("第一条の二第一項中「ただし、免除することができる。」を削る。", "")},
+			want: []string{
+				"第一条 この政令は、別表に掲げる手数料について定める。",
+				"第一条の二 手数料の額は、別表のとおりとする。",
+			},
+		},
+		{
+			name:       "Delete article",
+			provisions: []string{amendXML("第一条の二を削る。", "")},
+			want:       []string{"第一条 この政令は、別表に掲げる手数料について定める。"},
+		},
+		{
+			name:       "Add article",
+			provisions: []string{amendXML("第一条の次に次の一条を加える。", newArticle)},
+			want: []string{
+				"第一条 この政令は、別表に掲げる手数料について定める。",
+				"第一条の三 新しい規定",
+				"第一条の二 手数料の額は、別表のとおりとする。ただし、免除することができる。",
+			},
+		},
+		{
+			name: "Diagnostics",
+			provisions: []string{
+				amendXML("第一条を次のように改める。", ""),
+				amendXML("第九条中「別表」を「別表第一」に改める。", ""),
+				amendXML("第一条中「存在しない」を削る。", ""),
+				amendXML("第一条の二の次に次の二条を加える。", newArticle),
+			},
+			want: []string{
+				"第一条 この政令は、別表に掲げる手数料について定める。",
+				"第一条の二 手数料の額は、別表のとおりとする。ただし、免除することができる。",
+			},
+			wantDiagnostics: []string{
+				"第一条を次のように改める。: unsupported amendment instruction",
+				"第九条中「別表」を「別表第一」に改める。: article 9 not found",
+				"第一条中「存在しない」を削る。: 「存在しない」 not found",
+				"第一条の二の次に次の二条を加える。: 2 articles to add, but the new provision has 1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := mustLoadTestdataLaw(t, "json/articles.xml")
+			before := articleTexts(base)
+
+			law, diagnostics, err := ApplyAmendments(base, strings.NewReader(amendingLawXML(tt.provisions...)))
+			if err != nil {
+				t.Fatalf("ApplyAmendments() error = %v", err)
+			}
+
+			if got := articleTexts(law); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("articles = %q, want %q", got, tt.want)
+			}
+			var got []string
+			for _, d := range diagnostics {
+				got = append(got, d.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.wantDiagnostics, "\n") {
+				t.Errorf("diagnostics = %q, want %q", got, tt.wantDiagnostics)
+			}
+			if after := articleTexts(base); strings.Join(after, "\n") != strings.Join(before, "\n") {
+				t.Errorf("base law was modified: %q", after)
+			}
+		})
+	}
+}
+
+func TestApplyAmendmentsOtherLaw(t *testing.T) {
+	base := mustLoadTestdataLaw(t, "json/chapters.xml")
+
+	_, diagnostics, err := ApplyAmendments(base, bytes.NewReader(readTestdata(t, "amendment.xml")))
+	if err != nil {
+		t.Fatalf("ApplyAmendments() error = %v", err)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Message != "does not amend this law" {
+		t.Errorf("diagnostics = %v, want one for the other law", diagnostics)
+	}
+}
+
+func TestApplyAmendmentsToEPUB(t *testing.T) {
+	base := mustLoadTestdataLaw(t, "json/articles.xml")
+
+	law, diagnostics, err := ApplyAmendments(base, bytes.NewReader(readTestdata(t, "amendment.xml")))
+	if err != nil {
+		t.Fatalf("ApplyAmendments() error = %v", err)
+	}
+	if len(diagnostics) > 0 {
+		t.Errorf("diagnostics = %v, want none", diagnostics)
+	}
+
+	book, err := CreateEPUBFromLawWithContext(context.Background(), law, &EPUBOptions{NoCover: true})
+	if err != nil {
+		t.Fatalf("CreateEPUBFromLawWithContext() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := WriteEPUBTo(book, &buf); err != nil {
+		t.Fatalf("WriteEPUBTo() error = %v", err)
+	}
+	archive, err := readEPUBArchive(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var content strings.Builder
+	for _, f := range archive.files {
+		content.Write(f.data)
+	}
+	for _, want := range []string{"別表第一に掲げる手数料", "手数料は、収入印紙で納付しなければならない。"} {
+		if !strings.Contains(content.String(), want) {
+			t.Errorf("consolidated EPUB missing %q", want)
+		}
+	}
+	if strings.Contains(content.String(), "免除することができる") {
+		t.Error("consolidated EPUB still has the deleted sentence")
+	}
+}
@@ -0,0 +1,95 @@
+package jplaw2epub
+
+import (
+	"context"
+
+	lawapi "go.ngs.io/jplaw-api-v2"
+)
+
+// ContextAPIClient is an APIClient whose attachment downloads can be canceled, such as HTTPAPIClient.
+// Clients that only implement APIClient are abandoned, not interrupted, when the context is done.
+type ContextAPIClient interface {
+	APIClient
+	GetAttachmentWithContext(ctx context.Context, lawRevisionID string, params *lawapi.GetAttachmentParams) (*string, error)
+}
+
+// getAttachment downloads an attachment, returning as soon as ctx is done
+func getAttachment(ctx context.Context, client APIClient, lawRevisionID string, params *lawapi.GetAttachmentParams) (*string, error) {
+	if c, ok := client.(ContextAPIClient); ok {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return c.GetAttachmentWithContext(ctx, lawRevisionID, params)
+	}
+	return callWithContext(ctx, func() (*string, error) {
+		return client.GetAttachment(lawRevisionID, params)
+	})
+}
+
+// callWithContext calls fn, returning as soon as ctx is done.
+// Like downloads of clients without context support, an abandoned call finishes in the background.
+func callWithContext[T any](ctx context.Context, fn func() (T, error)) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+	if ctx.Done() == nil {
+		return fn()
+	}
+
+	type result struct {
+		value T
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		value, err := fn()
+		done <- result{value: value, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+// contextBookWriter is a BookWriter that fails once its context is done,
+// stopping the processing pipeline at the next section or image
+type contextBookWriter struct {
+	BookWriter
+	ctx context.Context
+}
+
+// withContext wraps book so that it honors ctx, returning book itself for contexts that are never done
+func withContext(ctx context.Context, book BookWriter) BookWriter {
+	if ctx.Done() == nil {
+		return book
+	}
+	return &contextBookWriter{BookWriter: book, ctx: ctx}
+}
+
+// AddSection adds the section unless the context is done
+func (w *contextBookWriter) AddSection(body, sectionTitle, internalFilename, internalCSSPath string) (string, error) {
+	if err := w.ctx.Err(); err != nil {
+		return "", err
+	}
+	return w.BookWriter.AddSection(body, sectionTitle, internalFilename, internalCSSPath)
+}
+
+// AddSubSection adds the subsection unless the context is done
+func (w *contextBookWriter) AddSubSection(parentFilename, body, sectionTitle, internalFilename, internalCSSPath string) (string, error) {
+	if err := w.ctx.Err(); err != nil {
+		return "", err
+	}
+	return w.BookWriter.AddSubSection(parentFilename, body, sectionTitle, internalFilename, internalCSSPath)
+}
+
+// AddImage adds the image unless the context is done
+func (w *contextBookWriter) AddImage(source, imageFilename string) (string, error) {
+	if err := w.ctx.Err(); err != nil {
+		return "", err
+	}
+	return w.BookWriter.AddImage(source, imageFilename)
+}
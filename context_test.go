@@ -0,0 +1,190 @@
+package jplaw2epub
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	lawapi "go.ngs.io/jplaw-api-v2"
+)
+
+// contextMockAPIClient implements ContextAPIClient and records the context it receives
+type contextMockAPIClient struct {
+	MockAPIClient
+	ctx context.Context
+}
+
+// GetAttachmentWithContext records ctx and waits for it to be done
+func (m *contextMockAPIClient) GetAttachmentWithContext(ctx context.Context, _ string, _ *lawapi.GetAttachmentParams) (*string, error) {
+	m.ctx = ctx
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// blockingAPIClient returns a client whose downloads never finish, and a func releasing them
+func blockingAPIClient() (*MockAPIClient, func()) {
+	release := make(chan struct{})
+	client := &MockAPIClient{
+		GetAttachmentFunc: func(string, *lawapi.GetAttachmentParams) (*string, error) {
+			<-release
+			return nil, errors.New("released")
+		},
+	}
+	return client, func() { close(release) }
+}
+
+// cancelingBookWriter cancels its context after a number of sections have been added
+type cancelingBookWriter struct {
+	BookWriter
+	cancel context.CancelFunc
+	after  int
+	added  int
+}
+
+// AddSection adds the section and cancels once enough sections exist
+func (w *cancelingBookWriter) AddSection(body, sectionTitle, internalFilename, internalCSSPath string) (string, error) {
+	w.added++
+	if w.added == w.after {
+		w.cancel()
+	}
+	return w.BookWriter.AddSection(body, sectionTitle, internalFilename, internalCSSPath)
+}
+
+func TestGetAttachmentContext(t *testing.T) {
+	params := &lawapi.GetAttachmentParams{Src: lawapi.StringPtr("./pict/a.jpg")}
+
+	t.Run("already canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		client := &MockAPIClient{}
+		if _, err := getAttachment(ctx, client, "rev", params); !errors.Is(err, context.Canceled) {
+			t.Errorf("getAttachment() error = %v, want context.Canceled", err)
+		}
+		if len(client.GetAttachmentCalls) != 0 {
+			t.Error("a canceled context should not reach the client")
+		}
+	})
+
+	t.Run("blocking client is abandoned at the deadline", func(t *testing.T) {
+		client, release := blockingAPIClient()
+		defer release()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if _, err := getAttachment(ctx, client, "rev", params); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("getAttachment() error = %v, want context.DeadlineExceeded", err)
+		}
+	})
+
+	t.Run("context-aware client receives the context", func(t *testing.T) {
+		client := &contextMockAPIClient{}
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if _, err := getAttachment(ctx, client, "rev", params); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("getAttachment() error = %v, want context.DeadlineExceeded", err)
+		}
+		if client.ctx != ctx {
+			t.Error("GetAttachmentWithContext did not receive the context")
+		}
+		if len(client.GetAttachmentCalls) != 0 {
+			t.Error("GetAttachment should not be used when GetAttachmentWithContext exists")
+		}
+	})
+}
+
+func TestCreateEPUBFromXMLFileWithContext(t *testing.T) {
+	xmlData := readTestdata(t, "json/chapters.xml")
+
+	t.Run("completes with a live context", func(t *testing.T) {
+		book, err := CreateEPUBFromXMLFileWithContext(context.Background(), bytes.NewReader(xmlData), nil)
+		if err != nil || book == nil {
+			t.Fatalf("CreateEPUBFromXMLFileWithContext() = %v, %v", book, err)
+		}
+	})
+
+	t.Run("canceled before starting", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := CreateEPUBFromXMLFileWithContext(ctx, bytes.NewReader(xmlData), nil); !errors.Is(err, context.Canceled) {
+			t.Errorf("error = %v, want context.Canceled", err)
+		}
+	})
+
+	t.Run("canceled during an image download", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		client := &MockAPIClient{
+			GetAttachmentFunc: func(string, *lawapi.GetAttachmentParams) (*string, error) {
+				cancel()
+				data := createPNGAttachment()
+				return &data, nil
+			},
+		}
+
+		opts := &EPUBOptions{APIClient: client, RevisionID: "rev", NoCover: true}
+		if _, err := CreateEPUBFromXMLFileWithContext(ctx, bytes.NewReader(xmlData), opts); !errors.Is(err, context.Canceled) {
+			t.Errorf("error = %v, want context.Canceled", err)
+		}
+		if len(client.GetAttachmentCalls) != 1 {
+			t.Errorf("GetAttachment calls = %d, want 1", len(client.GetAttachmentCalls))
+		}
+	})
+
+	t.Run("deadline passes while a download hangs", func(t *testing.T) {
+		client, release := blockingAPIClient()
+		defer release()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		opts := &EPUBOptions{APIClient: client, RevisionID: "rev", NoCover: true}
+		if _, err := CreateEPUBFromXMLFileWithContext(ctx, bytes.NewReader(xmlData), opts); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("error = %v, want context.DeadlineExceeded", err)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("conversion took %v after the deadline", elapsed)
+		}
+	})
+}
+
+func TestProcessChaptersWithContextStopsMidConversion(t *testing.T) {
+	data := mustLoadTestdataLaw(t, "definitions.xml")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	doc := NewPDFDocument("test")
+	book := &cancelingBookWriter{BookWriter: doc, cancel: cancel, after: 2}
+	err := processChaptersWithContext(ctx, book, data, nil, &EPUBOptions{DefinitionIndex: true})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("processChaptersWithContext() error = %v, want context.Canceled", err)
+	}
+	if len(doc.sections) != 2 {
+		t.Errorf("sections added = %d, want 2 before cancellation", len(doc.sections))
+	}
+	if _, ok := doc.files[definitionIndexFilename]; ok {
+		t.Error("definition index should not be added after cancellation")
+	}
+}
+
+func TestServerConversionTimeout(t *testing.T) {
+	client, release := blockingAPIClient()
+	defer release()
+
+	fetcher := &mockLawFetcher{xml: readTestdata(t, "json/chapters.xml"), revision: "rev1"}
+	s := NewServer(ServerOptions{Fetcher: fetcher, APIClient: client, ConversionTimeout: 50 * time.Millisecond, NoCover: true})
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/laws/X.epub", nil))
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusGatewayTimeout, rec.Body.String())
+	}
+	if got := metricValue(t, s, "jplaw2epub_cache_entries"); got != "0" {
+		t.Errorf("timed out conversions should not be cached, entries = %s", got)
+	}
+}
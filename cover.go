@@ -0,0 +1,350 @@
+package jplaw2epub
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/go-shiori/go-epub"
+	"go.ngs.io/jplaw-xml"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// Generated cover dimensions in pixels
+const (
+	coverWidth  = 1600
+	coverHeight = 2560
+	coverMargin = 160
+)
+
+// Generated cover colors
+var (
+	coverBackground = color.RGBA{0xf7, 0xf4, 0xec, 0xff}
+	coverBand       = color.RGBA{0x1f, 0x2f, 0x4f, 0xff}
+	coverText       = color.RGBA{0x1a, 0x1a, 0x1a, 0xff}
+)
+
+// systemCoverFontPaths lists common locations of CJK fonts tried when no cover font is supplied
+var systemCoverFontPaths = []string{
+	"/usr/share/fonts/opentype/noto/NotoSansCJK-Regular.ttc",
+	"/usr/share/fonts/noto-cjk/NotoSansCJK-Regular.ttc",
+	"/usr/share/fonts/google-noto-cjk/NotoSansCJK-Regular.ttc",
+	"/usr/share/fonts/opentype/ipafont-gothic/ipag.ttf",
+	"/usr/share/fonts/truetype/fonts-japanese-gothic.ttf",
+	"/System/Library/Fonts/ヒラギノ角ゴシック W3.ttc",
+	"/System/Library/Fonts/Hiragino Sans GB.ttc",
+	`C:\Windows\Fonts\meiryo.ttc`,
+	`C:\Windows\Fonts\msgothic.ttc`,
+}
+
+// ErrNoCoverFont is returned when a cover should be generated but neither a cover font
+// nor a system CJK font is available
+var ErrNoCoverFont = errors.New("no font for the generated cover: set CoverFont, CoverImage or NoCover, or install a CJK font")
+
+var (
+	systemCoverFontOnce sync.Once
+	systemCoverFont     *sfnt.Font
+)
+
+// addCover sets the book cover: the supplied cover image, or a generated one.
+// When neither a cover font nor a system CJK font is available, it returns ErrNoCoverFont;
+// books created without options have no cover then.
+func addCover(book *epub.Epub, data *jplaw.Law, opts *EPUBOptions) error {
+	if opts != nil && opts.NoCover {
+		return nil
+	}
+
+	if opts != nil && len(opts.CoverImage) > 0 {
+		_, format, err := image.DecodeConfig(bytes.NewReader(opts.CoverImage))
+		if err != nil {
+			return fmt.Errorf("decoding cover image: %w", err)
+		}
+		return setCoverImage(book, opts.CoverImage, format)
+	}
+
+	f, err := coverFont(opts)
+	if err != nil {
+		return err
+	}
+	if f == nil {
+		if opts == nil {
+			return nil
+		}
+		return ErrNoCoverFont
+	}
+
+	var template []byte
+	vertical := false
+	display := DateDisplayJapanese
+	if opts != nil {
+		template = opts.CoverTemplate
+		vertical = opts.VerticalWriting
+		display = opts.DateDisplay
+	}
+	img, err := renderCover(data, f, template, vertical, display)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return fmt.Errorf("encoding cover image: %w", err)
+	}
+	return setCoverImage(book, buf.Bytes(), "png")
+}
+
+// coverFont returns opts.CoverFont, or else the first system CJK font found, or nil
+func coverFont(opts *EPUBOptions) (*sfnt.Font, error) {
+	if opts != nil && len(opts.CoverFont) > 0 {
+		return parseFont(opts.CoverFont)
+	}
+	systemCoverFontOnce.Do(func() {
+		systemCoverFont = loadCoverFont(systemCoverFontPaths)
+	})
+	return systemCoverFont, nil
+}
+
+// setCoverImage adds an encoded image to the book and registers it as the cover
+func setCoverImage(book *epub.Epub, data []byte, format string) error {
+	dataURL := fmt.Sprintf("data:image/%s;base64,%s", format, base64.StdEncoding.EncodeToString(data))
+	imagePath, err := book.AddImage(dataURL, "cover."+format)
+	if err != nil {
+		return fmt.Errorf("adding cover image: %w", err)
+	}
+	if err := book.SetCover(imagePath, ""); err != nil {
+		return fmt.Errorf("setting cover: %w", err)
+	}
+	return nil
+}
+
+// parseFont parses an OpenType or TrueType font, using the first font of a collection
+func parseFont(data []byte) (*sfnt.Font, error) {
+	if f, err := opentype.Parse(data); err == nil {
+		return f, nil
+	}
+	collection, err := opentype.ParseCollection(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing font: %w", err)
+	}
+	f, err := collection.Font(0)
+	if err != nil {
+		return nil, fmt.Errorf("parsing font: %w", err)
+	}
+	return f, nil
+}
+
+// loadCoverFont returns the first font in paths that has kanji glyphs, or nil
+func loadCoverFont(paths []string) *sfnt.Font {
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		f, err := parseFont(data)
+		if err != nil {
+			continue
+		}
+		var buf sfnt.Buffer
+		if index, err := f.GlyphIndex(&buf, '法'); err == nil && index != 0 {
+			return f
+		}
+	}
+	return nil
+}
+
+// renderCover draws the law title, law number and promulgation date on the template,
+// or on a plain background when template is empty
+func renderCover(data *jplaw.Law, f *sfnt.Font, template []byte, vertical bool, display DateDisplay) (*image.RGBA, error) {
+	img := image.NewRGBA(image.Rect(0, 0, coverWidth, coverHeight))
+	if len(template) > 0 {
+		background, _, err := image.Decode(bytes.NewReader(template))
+		if err != nil {
+			return nil, fmt.Errorf("decoding cover template: %w", err)
+		}
+		xdraw.CatmullRom.Scale(img, img.Bounds(), background, background.Bounds(), xdraw.Src, nil)
+	} else {
+		draw.Draw(img, img.Bounds(), image.NewUniform(coverBackground), image.Point{}, draw.Src)
+		draw.Draw(img, image.Rect(0, 0, coverWidth, coverMargin/2), image.NewUniform(coverBand), image.Point{}, draw.Src)
+		draw.Draw(img, image.Rect(0, coverHeight-coverMargin/2, coverWidth, coverHeight), image.NewUniform(coverBand), image.Point{}, draw.Src)
+	}
+
+	title := ""
+	if data.LawBody.LawTitle != nil {
+		title = data.LawBody.LawTitle.Content
+	}
+	subtitles := []string{data.LawNum, coverDate(data, display)}
+
+	if vertical {
+		return img, drawVerticalCover(img, f, title, subtitles)
+	}
+	return img, drawHorizontalCover(img, f, title, subtitles)
+}
+
+// coverDate formats the promulgation date as on official documents (e.g. 令和五年四月一日公布),
+// in the Gregorian calendar (e.g. 2023年4月1日公布) or both, as display selects
+func coverDate(data *jplaw.Law, display DateDisplay) string {
+	era := getEraString(data.Era)
+	if era == "" || data.Year <= 0 || data.PromulgateMonth <= 0 || data.PromulgateDay <= 0 {
+		return ""
+	}
+	year := formatKanjiNumber(data.Year)
+	if data.Year == 1 {
+		year = "元"
+	}
+	japanese := fmt.Sprintf("%s%s年%s月%s日公布", era, year, formatKanjiNumber(data.PromulgateMonth), formatKanjiNumber(data.PromulgateDay))
+
+	gregorian := promulgationDate(data).FormatGregorian()
+	switch {
+	case gregorian == "" || display == "" || display == DateDisplayJapanese:
+		return japanese
+	case display == DateDisplayGregorian:
+		return gregorian + "公布"
+	default:
+		return fmt.Sprintf("%s（%s）", japanese, gregorian)
+	}
+}
+
+// newCoverFace creates a face of the given size in pixels
+func newCoverFace(f *sfnt.Font, size float64) (font.Face, error) {
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingNone})
+	if err != nil {
+		return nil, fmt.Errorf("creating cover font face: %w", err)
+	}
+	return face, nil
+}
+
+// wrapCoverLine breaks text into lines no wider than width. Lines break between any
+// characters since Japanese titles have no spaces.
+func wrapCoverLine(face font.Face, text string, width fixed.Int26_6) []string {
+	var lines []string
+	var line []rune
+	for _, r := range text {
+		if len(line) > 0 && font.MeasureString(face, string(append(line, r))) > width {
+			lines = append(lines, string(line))
+			line = nil
+		}
+		line = append(line, r)
+	}
+	if len(line) > 0 {
+		lines = append(lines, string(line))
+	}
+	return lines
+}
+
+// drawHorizontalCover draws the title in centered lines, shrinking it to fit the upper
+// two thirds of the cover, and the subtitles near the bottom
+func drawHorizontalCover(img *image.RGBA, f *sfnt.Font, title string, subtitles []string) error {
+	width := fixed.I(coverWidth - 2*coverMargin)
+	src := image.NewUniform(coverText)
+
+	size := 128.0
+	var face font.Face
+	var lines []string
+	for {
+		var err error
+		if face, err = newCoverFace(f, size); err != nil {
+			return err
+		}
+		lines = wrapCoverLine(face, title, width)
+		if float64(len(lines))*size*1.4 <= coverHeight*2/3-2*coverMargin || size <= 48 {
+			break
+		}
+		size -= 8
+	}
+
+	y := coverMargin*2 + int(size)
+	for _, line := range lines {
+		drawer := &font.Drawer{Dst: img, Src: src, Face: face}
+		drawer.Dot = fixed.P((coverWidth-drawer.MeasureString(line).Round())/2, y)
+		drawer.DrawString(line)
+		y += int(size * 1.4)
+	}
+
+	subtitleFace, err := newCoverFace(f, 56)
+	if err != nil {
+		return err
+	}
+	y = coverHeight - coverMargin*2 - 56*2*len(subtitles)
+	for _, subtitle := range subtitles {
+		y += 56 * 2
+		if subtitle == "" {
+			continue
+		}
+		drawer := &font.Drawer{Dst: img, Src: src, Face: subtitleFace}
+		drawer.Dot = fixed.P((coverWidth-drawer.MeasureString(subtitle).Round())/2, y)
+		drawer.DrawString(subtitle)
+	}
+	return nil
+}
+
+// verticalForms maps punctuation to its vertical presentation form
+var verticalForms = strings.NewReplacer(
+	"（", "︵", "）", "︶", "「", "﹁", "」", "﹂", "、", "︑", "。", "︒", "ー", "丨", "－", "丨",
+)
+
+// drawVerticalCover draws the title in columns from right to left and the subtitles in
+// smaller columns at the left edge, each character upright and centered in its column
+func drawVerticalCover(img *image.RGBA, f *sfnt.Font, title string, subtitles []string) error {
+	src := image.NewUniform(coverText)
+	height := coverHeight - 3*coverMargin
+
+	drawColumns := func(text string, size, right int) (int, error) {
+		face, err := newCoverFace(f, float64(size))
+		if err != nil {
+			return 0, err
+		}
+		perColumn := max(height/size, 1)
+		chars := []rune(verticalForms.Replace(text))
+		x := right
+		for start := 0; start < len(chars); start += perColumn {
+			x -= size
+			column := chars[start:min(start+perColumn, len(chars))]
+			for i, r := range column {
+				drawer := &font.Drawer{Dst: img, Src: src, Face: face}
+				offset := (size - drawer.MeasureString(string(r)).Round()) / 2
+				drawer.Dot = fixed.P(x+offset, coverMargin*3/2+(i+1)*size)
+				drawer.DrawString(string(r))
+			}
+			x -= size / 2
+		}
+		return x, nil
+	}
+
+	// Shrink the title until its columns fit the right two thirds of the cover
+	titleWidth := func(size int) int {
+		perColumn := height / size
+		columns := (len([]rune(title)) + perColumn - 1) / perColumn
+		return columns * size * 3 / 2
+	}
+	size := 160
+	for size > 64 && titleWidth(size) > coverWidth*2/3-coverMargin {
+		size -= 16
+	}
+	if _, err := drawColumns(title, size, coverWidth-coverMargin); err != nil {
+		return err
+	}
+
+	x := coverMargin + 2*72*len(subtitles)
+	for _, subtitle := range subtitles {
+		if subtitle == "" {
+			continue
+		}
+		var err error
+		if x, err = drawColumns(subtitle, 64, x); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package jplaw2epub
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.ngs.io/jplaw-xml"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// writeTestEPUB converts the chapters fixture with opts and returns the archive entries
+func writeTestEPUB(t *testing.T, opts *EPUBOptions) map[string]string {
+	t.Helper()
+	book, err := NewBookFromXMLFile(context.Background(), bytes.NewReader(readTestdata(t, "json/chapters.xml")), opts)
+	if err != nil {
+		t.Fatalf("NewBookFromXMLFile() error = %v", err)
+	}
+	destPath := filepath.Join(t.TempDir(), "law.epub")
+	if err := book.Write(destPath); err != nil {
+		t.Fatalf("Book.Write() error = %v", err)
+	}
+	return readZipFiles(t, destPath)
+}
+
+// solidPNG encodes a single-color PNG
+func solidPNG(t *testing.T, width, height int, c color.Color) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestGeneratedCover(t *testing.T) {
+	for _, vertical := range []bool{false, true} {
+		files := writeTestEPUB(t, &EPUBOptions{CoverFont: goregular.TTF, VerticalWriting: vertical})
+
+		cover, ok := files["EPUB/images/cover.png"]
+		if !ok {
+			t.Fatalf("vertical=%t: cover image not found", vertical)
+		}
+		config, err := png.DecodeConfig(strings.NewReader(cover))
+		if err != nil {
+			t.Fatalf("vertical=%t: decoding cover: %v", vertical, err)
+		}
+		if config.Width != coverWidth || config.Height != coverHeight {
+			t.Errorf("vertical=%t: cover size = %dx%d, want %dx%d", vertical, config.Width, config.Height, coverWidth, coverHeight)
+		}
+		if !strings.Contains(files[epubPackagePath], `properties="cover-image"`) {
+			t.Errorf("vertical=%t: package document has no cover-image item", vertical)
+		}
+	}
+}
+
+func TestSuppliedCover(t *testing.T) {
+	t.Run("cover image is used as is", func(t *testing.T) {
+		cover := solidPNG(t, 4, 6, color.White)
+		files := writeTestEPUB(t, &EPUBOptions{CoverImage: cover})
+		if files["EPUB/images/cover.png"] != string(cover) {
+			t.Error("supplied cover image should be embedded unchanged")
+		}
+	})
+
+	t.Run("no cover", func(t *testing.T) {
+		files := writeTestEPUB(t, &EPUBOptions{CoverFont: goregular.TTF, NoCover: true})
+		if strings.Contains(files[epubPackagePath], "cover-image") {
+			t.Error("NoCover should not add a cover")
+		}
+	})
+
+	t.Run("invalid cover image", func(t *testing.T) {
+		_, err := CreateEPUBFromXMLFileWithOptions(bytes.NewReader(readTestdata(t, "json/chapters.xml")), &EPUBOptions{CoverImage: []byte("not an image")})
+		if err == nil {
+			t.Error("expected an error for an invalid cover image")
+		}
+	})
+
+	t.Run("invalid cover font", func(t *testing.T) {
+		_, err := CreateEPUBFromXMLFileWithOptions(bytes.NewReader(readTestdata(t, "json/chapters.xml")), &EPUBOptions{CoverFont: []byte("not a font")})
+		if err == nil {
+			t.Error("expected an error for an invalid cover font")
+		}
+	})
+}
+
+func TestCoverWithoutFont(t *testing.T) {
+	// Pretend that no system CJK font is installed
+	systemCoverFontOnce.Do(func() {
+		systemCoverFont = loadCoverFont(systemCoverFontPaths)
+	})
+	saved := systemCoverFont
+	systemCoverFont = nil
+	t.Cleanup(func() { systemCoverFont = saved })
+
+	xmlData := readTestdata(t, "json/chapters.xml")
+	if _, err := CreateEPUBFromXMLFileWithOptions(bytes.NewReader(xmlData), &EPUBOptions{}); !errors.Is(err, ErrNoCoverFont) {
+		t.Errorf("CreateEPUBFromXMLFileWithOptions() error = %v, want ErrNoCoverFont", err)
+	}
+
+	// Books without a cover, with a cover image or created without options need no font
+	for _, opts := range []*EPUBOptions{{NoCover: true}, {CoverImage: solidPNG(t, 10, 16, color.White)}, nil} {
+		book, err := CreateEPUBFromXMLFileWithOptions(bytes.NewReader(xmlData), opts)
+		if err != nil {
+			t.Fatalf("CreateEPUBFromXMLFileWithOptions(%+v) error = %v", opts, err)
+		}
+		if opts != nil {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := WriteEPUBTo(book, &buf); err != nil {
+			t.Fatal(err)
+		}
+		archive, err := readEPUBArchive(buf.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(archive.file(epubPackagePath).data), "cover-image") {
+			t.Error("no cover should be generated without a font")
+		}
+	}
+}
+
+func TestRenderCoverTemplate(t *testing.T) {
+	f, err := parseFont(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	red := color.RGBA{0xff, 0, 0, 0xff}
+	data := &jplaw.Law{LawNum: "Act No. 10", LawBody: jplaw.LawBody{LawTitle: &jplaw.LawTitle{Content: "Test Act"}}}
+
+	img, err := renderCover(data, f, solidPNG(t, 10, 16, red), false, DateDisplayJapanese)
+	if err != nil {
+		t.Fatalf("renderCover() error = %v", err)
+	}
+	if got := img.RGBAAt(0, 0); got != red {
+		t.Errorf("template background = %v, want %v", got, red)
+	}
+
+	if _, err := renderCover(data, f, []byte("not an image"), false, DateDisplayJapanese); err == nil {
+		t.Error("expected an error for an invalid template")
+	}
+}
+
+func TestLoadCoverFont(t *testing.T) {
+	if f := loadCoverFont([]string{filepath.Join(t.TempDir(), "missing.ttf")}); f != nil {
+		t.Error("missing fonts should be skipped")
+	}
+}
+
+func TestCoverDate(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    *jplaw.Law
+		display DateDisplay
+		want    string
+	}{
+		{name: "reiwa", data: &jplaw.Law{Era: "Reiwa", Year: 5, PromulgateMonth: 4, PromulgateDay: 1}, display: DateDisplayJapanese, want: "令和五年四月一日公布"},
+		{name: "first year", data: &jplaw.Law{Era: "Heisei", Year: 1, PromulgateMonth: 12, PromulgateDay: 22}, display: DateDisplayJapanese, want: "平成元年十二月二十二日公布"},
+		{name: "gregorian", data: &jplaw.Law{Era: "Reiwa", Year: 5, PromulgateMonth: 4, PromulgateDay: 1}, display: DateDisplayGregorian, want: "2023年4月1日公布"},
+		{name: "both", data: &jplaw.Law{Era: "Reiwa", Year: 5, PromulgateMonth: 4, PromulgateDay: 1}, display: DateDisplayBoth, want: "令和五年四月一日公布（2023年4月1日）"},
+		{name: "unknown date", data: &jplaw.Law{Era: "Showa", Year: 22}, display: DateDisplayBoth, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := coverDate(tt.data, tt.display); got != tt.want {
+				t.Errorf("coverDate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package jplaw2epub
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.ngs.io/jplaw-xml"
+)
+
+// DateDisplay selects the calendar dates are shown in
+type DateDisplay string
+
+// Date display modes
+const (
+	// DateDisplayJapanese shows dates in the Japanese calendar (和暦), e.g. 令和5年4月1日. It is the default.
+	DateDisplayJapanese DateDisplay = "japanese"
+	// DateDisplayGregorian shows dates in the Gregorian calendar (西暦), e.g. 2023年4月1日
+	DateDisplayGregorian DateDisplay = "gregorian"
+	// DateDisplayBoth shows the Japanese date followed by the Gregorian one, e.g. 令和5年4月1日（2023年4月1日）
+	DateDisplayBoth DateDisplay = "both"
+)
+
+// ParseDateDisplay parses a date display mode, returning DateDisplayJapanese for an empty string
+func ParseDateDisplay(s string) (DateDisplay, error) {
+	switch display := DateDisplay(s); display {
+	case "":
+		return DateDisplayJapanese, nil
+	case DateDisplayJapanese, DateDisplayGregorian, DateDisplayBoth:
+		return display, nil
+	default:
+		return "", fmt.Errorf("unknown date display %q (want japanese, gregorian or both)", s)
+	}
+}
+
+// ErrLunisolarDate is returned for dates before 明治6年 (1873), when Japan adopted the
+// Gregorian calendar. Their months and days follow the lunisolar calendar, so only the year converts.
+var ErrLunisolarDate = errors.New("date precedes the adoption of the Gregorian calendar")
+
+// gregorianAdoptionYear is the year 明治6年, which began on the first Gregorian 1 January
+const gregorianAdoptionYear = 1873
+
+// eraSpan is the period of an era. Emperors succeeded during the day in 1912 and 1926, so the
+// last day of 明治 and 大正 is also the first day of the following era.
+type eraSpan struct {
+	era   jplaw.Era
+	first time.Time
+	// last is the zero time for the current era
+	last time.Time
+}
+
+// eraSpans lists the eras of the law data, oldest first
+var eraSpans = []eraSpan{
+	{era: jplaw.EraMeiji, first: time.Date(1868, 1, 1, 0, 0, 0, 0, time.UTC), last: time.Date(1912, 7, 30, 0, 0, 0, 0, time.UTC)},
+	{era: jplaw.EraTaisho, first: time.Date(1912, 7, 30, 0, 0, 0, 0, time.UTC), last: time.Date(1926, 12, 25, 0, 0, 0, 0, time.UTC)},
+	{era: jplaw.EraShowa, first: time.Date(1926, 12, 25, 0, 0, 0, 0, time.UTC), last: time.Date(1989, 1, 7, 0, 0, 0, 0, time.UTC)},
+	{era: jplaw.EraHeisei, first: time.Date(1989, 1, 8, 0, 0, 0, 0, time.UTC), last: time.Date(2019, 4, 30, 0, 0, 0, 0, time.UTC)},
+	{era: jplaw.EraReiwa, first: time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)},
+}
+
+// JapaneseDate is a date of the Japanese calendar. Month and Day are zero when unknown.
+type JapaneseDate struct {
+	Era   jplaw.Era
+	Year  int
+	Month int
+	Day   int
+}
+
+// promulgationDate returns the promulgation date of a law
+func promulgationDate(data *jplaw.Law) JapaneseDate {
+	return JapaneseDate{Era: data.Era, Year: data.Year, Month: data.PromulgateMonth, Day: data.PromulgateDay}
+}
+
+// span returns the period of the date's era
+func (d JapaneseDate) span() (eraSpan, bool) {
+	for _, span := range eraSpans {
+		if span.era == d.Era {
+			return span, true
+		}
+	}
+	return eraSpan{}, false
+}
+
+// GregorianYear returns the Gregorian year of the date (e.g. 2023 for 令和5年)
+func (d JapaneseDate) GregorianYear() (int, error) {
+	span, ok := d.span()
+	if !ok {
+		return 0, fmt.Errorf("unknown era %q", d.Era)
+	}
+	if d.Year < 1 {
+		return 0, fmt.Errorf("invalid year %d of %s", d.Year, getEraString(d.Era))
+	}
+	year := span.first.Year() + d.Year - 1
+	if !span.last.IsZero() && year > span.last.Year() {
+		return 0, fmt.Errorf("%s has no year %d", getEraString(d.Era), d.Year)
+	}
+	return year, nil
+}
+
+// Gregorian converts the date to the Gregorian calendar. It fails for dates outside their era,
+// such as 昭和64年1月8日 (which is 平成元年1月8日), and returns ErrLunisolarDate before 1873.
+func (d JapaneseDate) Gregorian() (time.Time, error) {
+	year, err := d.GregorianYear()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if year < gregorianAdoptionYear {
+		return time.Time{}, ErrLunisolarDate
+	}
+	t := time.Date(year, time.Month(d.Month), d.Day, 0, 0, 0, 0, time.UTC)
+	if d.Month < 1 || d.Day < 1 || t.Month() != time.Month(d.Month) || t.Day() != d.Day {
+		return time.Time{}, fmt.Errorf("invalid date %s", d.FormatJapanese())
+	}
+	span, _ := d.span()
+	if t.Before(span.first) || (!span.last.IsZero() && t.After(span.last)) {
+		return time.Time{}, fmt.Errorf("%s is outside the %s era", d.FormatJapanese(), getEraString(d.Era))
+	}
+	return t, nil
+}
+
+// FormatJapanese formats the date in the Japanese calendar, writing the first year as 元年
+// (e.g. 平成元年1月8日). Unknown months and days are left out.
+func (d JapaneseDate) FormatJapanese() string {
+	year := fmt.Sprintf("%d", d.Year)
+	if d.Year == 1 {
+		year = "元"
+	}
+	s := getEraString(d.Era) + year + "年"
+	if d.Month > 0 {
+		s += fmt.Sprintf("%d月", d.Month)
+		if d.Day > 0 {
+			s += fmt.Sprintf("%d日", d.Day)
+		}
+	}
+	return s
+}
+
+// FormatGregorian formats the date in the Gregorian calendar (e.g. 2023年4月1日). Lunisolar dates
+// and dates without a day show only the year; it returns an empty string for invalid dates.
+func (d JapaneseDate) FormatGregorian() string {
+	t, err := d.Gregorian()
+	if err == nil {
+		return fmt.Sprintf("%d年%d月%d日", t.Year(), int(t.Month()), t.Day())
+	}
+	year, yearErr := d.GregorianYear()
+	if yearErr != nil || (!errors.Is(err, ErrLunisolarDate) && d.Month > 0 && d.Day > 0) {
+		return ""
+	}
+	return fmt.Sprintf("%d年", year)
+}
+
+// ISO formats the date as YYYY-MM-DD, as YYYY when only the year converts, or returns an empty string
+func (d JapaneseDate) ISO() string {
+	if t, err := d.Gregorian(); err == nil {
+		return t.Format(time.DateOnly)
+	}
+	if gregorian := d.FormatGregorian(); gregorian != "" {
+		year, _ := d.GregorianYear()
+		return fmt.Sprintf("%04d", year)
+	}
+	return ""
+}
+
+// Format formats the date for a display mode, falling back to the Japanese calendar when the
+// date has no Gregorian equivalent
+func (d JapaneseDate) Format(display DateDisplay) string {
+	japanese, gregorian := d.FormatJapanese(), d.FormatGregorian()
+	switch {
+	case gregorian == "" || display == "" || display == DateDisplayJapanese:
+		return japanese
+	case display == DateDisplayGregorian:
+		return gregorian
+	default:
+		return fmt.Sprintf("%s（%s）", japanese, gregorian)
+	}
+}
@@ -0,0 +1,176 @@
+package jplaw2epub
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go.ngs.io/jplaw-xml"
+)
+
+func TestJapaneseDateGregorian(t *testing.T) {
+	tests := []struct {
+		name    string
+		date    JapaneseDate
+		want    time.Time
+		wantErr error
+	}{
+		{name: "reiwa", date: JapaneseDate{Era: jplaw.EraReiwa, Year: 5, Month: 4, Day: 1}, want: time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "last day of showa", date: JapaneseDate{Era: jplaw.EraShowa, Year: 64, Month: 1, Day: 7}, want: time.Date(1989, 1, 7, 0, 0, 0, 0, time.UTC)},
+		{name: "first day of heisei", date: JapaneseDate{Era: jplaw.EraHeisei, Year: 1, Month: 1, Day: 8}, want: time.Date(1989, 1, 8, 0, 0, 0, 0, time.UTC)},
+		{name: "showa after the succession", date: JapaneseDate{Era: jplaw.EraShowa, Year: 64, Month: 1, Day: 8}},
+		{name: "heisei before the succession", date: JapaneseDate{Era: jplaw.EraHeisei, Year: 1, Month: 1, Day: 7}},
+		{name: "last day of heisei", date: JapaneseDate{Era: jplaw.EraHeisei, Year: 31, Month: 4, Day: 30}, want: time.Date(2019, 4, 30, 0, 0, 0, 0, time.UTC)},
+		{name: "heisei after the succession", date: JapaneseDate{Era: jplaw.EraHeisei, Year: 31, Month: 5, Day: 1}},
+		{name: "succession day of taisho", date: JapaneseDate{Era: jplaw.EraTaisho, Year: 15, Month: 12, Day: 25}, want: time.Date(1926, 12, 25, 0, 0, 0, 0, time.UTC)},
+		{name: "succession day of showa", date: JapaneseDate{Era: jplaw.EraShowa, Year: 1, Month: 12, Day: 25}, want: time.Date(1926, 12, 25, 0, 0, 0, 0, time.UTC)},
+		{name: "year past the era", date: JapaneseDate{Era: jplaw.EraShowa, Year: 65, Month: 1, Day: 1}},
+		{name: "nonexistent day", date: JapaneseDate{Era: jplaw.EraReiwa, Year: 5, Month: 2, Day: 29}},
+		{name: "unknown day", date: JapaneseDate{Era: jplaw.EraReiwa, Year: 5}},
+		{name: "unknown era", date: JapaneseDate{Era: "Edo", Year: 5, Month: 1, Day: 1}},
+		{name: "lunisolar", date: JapaneseDate{Era: jplaw.EraMeiji, Year: 5, Month: 3, Day: 14}, wantErr: ErrLunisolarDate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.date.Gregorian()
+			if tt.want.IsZero() {
+				if err == nil {
+					t.Fatalf("Gregorian() = %v, want error", got)
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Errorf("Gregorian() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Gregorian() error = %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("Gregorian() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJapaneseDateFormat(t *testing.T) {
+	tests := []struct {
+		name      string
+		date      JapaneseDate
+		japanese  string
+		gregorian string
+		both      string
+		iso       string
+	}{
+		{
+			name:      "reiwa",
+			date:      JapaneseDate{Era: jplaw.EraReiwa, Year: 5, Month: 4, Day: 1},
+			japanese:  "令和5年4月1日",
+			gregorian: "2023年4月1日",
+			both:      "令和5年4月1日（2023年4月1日）",
+			iso:       "2023-04-01",
+		},
+		{
+			name:      "first year",
+			date:      JapaneseDate{Era: jplaw.EraHeisei, Year: 1, Month: 1, Day: 8},
+			japanese:  "平成元年1月8日",
+			gregorian: "1989年1月8日",
+			both:      "平成元年1月8日（1989年1月8日）",
+			iso:       "1989-01-08",
+		},
+		{
+			name:      "year only",
+			date:      JapaneseDate{Era: jplaw.EraShowa, Year: 22},
+			japanese:  "昭和22年",
+			gregorian: "1947年",
+			both:      "昭和22年（1947年）",
+			iso:       "1947",
+		},
+		{
+			name:      "lunisolar",
+			date:      JapaneseDate{Era: jplaw.EraMeiji, Year: 5, Month: 3, Day: 14},
+			japanese:  "明治5年3月14日",
+			gregorian: "1872年",
+			both:      "明治5年3月14日（1872年）",
+			iso:       "1872",
+		},
+		{
+			name:     "outside the era",
+			date:     JapaneseDate{Era: jplaw.EraShowa, Year: 64, Month: 1, Day: 8},
+			japanese: "昭和64年1月8日",
+			both:     "昭和64年1月8日",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.date.FormatJapanese(); got != tt.japanese {
+				t.Errorf("FormatJapanese() = %q, want %q", got, tt.japanese)
+			}
+			if got := tt.date.FormatGregorian(); got != tt.gregorian {
+				t.Errorf("FormatGregorian() = %q, want %q", got, tt.gregorian)
+			}
+			if got := tt.date.Format(DateDisplayBoth); got != tt.both {
+				t.Errorf("Format(both) = %q, want %q", got, tt.both)
+			}
+			if got := tt.date.ISO(); got != tt.iso {
+				t.Errorf("ISO() = %q, want %q", got, tt.iso)
+			}
+		})
+	}
+}
+
+func TestParseDateDisplay(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    DateDisplay
+		wantErr bool
+	}{
+		{input: "", want: DateDisplayJapanese},
+		{input: "japanese", want: DateDisplayJapanese},
+		{input: "gregorian", want: DateDisplayGregorian},
+		{input: "both", want: DateDisplayBoth},
+		{input: "western", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseDateDisplay(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDateDisplay() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseDateDisplay() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteEPUBDateDisplay(t *testing.T) {
+	tests := []struct {
+		display DateDisplay
+		want    string
+	}{
+		{display: "", want: "公布日: 令和5年4月1日"},
+		{display: DateDisplayGregorian, want: "公布日: 2023年4月1日"},
+		{display: DateDisplayBoth, want: "公布日: 令和5年4月1日（2023年4月1日）"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.display), func(t *testing.T) {
+			files := writeTestEPUB(t, &EPUBOptions{DateDisplay: tt.display, NoCover: true})
+			if !strings.Contains(files["EPUB/xhtml/title.xhtml"], tt.want) {
+				t.Errorf("title page missing %s:\n%s", tt.want, files["EPUB/xhtml/title.xhtml"])
+			}
+			if !strings.Contains(files[epubPackagePath], tt.want) {
+				t.Errorf("description missing %s:\n%s", tt.want, files[epubPackagePath])
+			}
+		})
+	}
+
+	if _, err := CreateEPUBFromXMLFileWithOptions(bytes.NewReader(readTestdata(t, "json/chapters.xml")), &EPUBOptions{DateDisplay: "western"}); err == nil {
+		t.Error("unknown date display should fail")
+	}
+}
@@ -0,0 +1,377 @@
+package jplaw2epub
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// definitionIndexFilename is the internal filename of the defined-terms index
+const definitionIndexFilename = "definitions.xhtml"
+
+// Definition patterns found in Japanese statutes
+var (
+	// 「〇〇」とは、…をいう。
+	definitionToHaPattern = regexp.MustCompile(`「([^「」]{1,30})」とは`)
+	// …（以下「〇〇」という。）
+	definitionAbbrevPattern = regexp.MustCompile(`以下[^「」。]{0,20}?「([^「」]{1,30})」という`)
+)
+
+// definitionListMarker marks a paragraph whose items each define a term (次の各号に掲げる用語の意義は…)
+const definitionListMarker = "用語の意義"
+
+// definedTerm is a term defined by the law and the article defining it
+type definedTerm struct {
+	term     string
+	reading  string
+	href     string
+	article  string
+	sentence string
+}
+
+// extractDefinitions scans article sentences for definition patterns.
+// Each term is reported once, at its first definition.
+func extractDefinitions(law *JSONLaw) []definedTerm {
+	var terms []definedTerm
+	seen := make(map[string]bool)
+
+	add := func(term definedTerm) {
+		if term.term == "" || seen[term.term] {
+			return
+		}
+		seen[term.term] = true
+		terms = append(terms, term)
+	}
+
+	scanParagraphs := func(paragraphs []JSONParagraph, article string) {
+		for i := range paragraphs {
+			for _, term := range paragraphDefinitions(&paragraphs[i], article) {
+				add(term)
+			}
+		}
+	}
+	scanArticles := func(articles []JSONArticle, prefix string) {
+		for i := range articles {
+			scanParagraphs(articles[i].Paragraphs, prefix+articles[i].Title.Text)
+		}
+	}
+
+	for _, chapter := range law.MainProvision.Chapters {
+		scanArticles(chapter.Articles, "")
+		for _, section := range chapter.Sections {
+			scanArticles(section.Articles, "")
+		}
+	}
+	scanArticles(law.MainProvision.Articles, "")
+	scanParagraphs(law.MainProvision.Paragraphs, "本文")
+
+	for _, suppl := range law.SupplProvisions {
+		prefix := suppl.Label.Text + "　"
+		for _, chapter := range suppl.Chapters {
+			scanArticles(chapter.Articles, prefix)
+		}
+		scanArticles(suppl.Articles, prefix)
+		scanParagraphs(suppl.Paragraphs, suppl.Label.Text)
+	}
+
+	return terms
+}
+
+// paragraphDefinitions extracts the terms defined in a paragraph and its items
+func paragraphDefinitions(para *JSONParagraph, article string) []definedTerm {
+	var terms []definedTerm
+
+	scan := func(text JSONText) {
+		for _, pattern := range []*regexp.Regexp{definitionToHaPattern, definitionAbbrevPattern} {
+			for _, loc := range pattern.FindAllStringSubmatchIndex(text.Text, -1) {
+				terms = append(terms, definedTerm{
+					term:     text.Text[loc[2]:loc[3]],
+					reading:  termReading(text, loc[2], loc[3]),
+					href:     para.Href,
+					article:  article,
+					sentence: text.Text,
+				})
+			}
+		}
+	}
+
+	listDefinition := false
+	for _, sentence := range para.Sentences {
+		scan(sentence)
+		listDefinition = listDefinition || strings.Contains(sentence.Text, definitionListMarker)
+	}
+
+	for i := range para.Items {
+		item := &para.Items[i]
+		if listDefinition && len(item.Sentences) > 0 {
+			// 一　〇〇　…をいう。 : the term precedes the first ideographic space
+			first := item.Sentences[0]
+			if end := strings.Index(first.Text, "　"); end > 0 && utf8.RuneCountInString(first.Text[:end]) <= 30 &&
+				!strings.ContainsAny(first.Text[:end], "、。") {
+				terms = append(terms, definedTerm{
+					term:     first.Text[:end],
+					reading:  termReading(first, 0, end),
+					href:     para.Href,
+					article:  article,
+					sentence: first.Text,
+				})
+			}
+		}
+		for _, sentence := range item.Sentences {
+			scan(sentence)
+		}
+	}
+
+	return terms
+}
+
+// termReading returns the hiragana reading of text[start:end] (byte offsets) using its ruby and kana.
+// It returns an empty string when part of the term has no known reading.
+func termReading(text JSONText, start, end int) string {
+	runes := []rune(text.Text)
+	from := utf8.RuneCountInString(text.Text[:start])
+	to := utf8.RuneCountInString(text.Text[:end])
+
+	rubyAt := make(map[int]JSONRuby)
+	for _, ruby := range text.Ruby {
+		rubyAt[ruby.Offset] = ruby
+	}
+
+	var reading strings.Builder
+	for i := from; i < to; {
+		if ruby, ok := rubyAt[i]; ok {
+			if baseLen := utf8.RuneCountInString(ruby.Base); baseLen > 0 && i+baseLen <= to {
+				reading.WriteString(toHiragana(ruby.Reading))
+				i += baseLen
+				continue
+			}
+		}
+		r := runes[i]
+		if !isKana(r) {
+			return ""
+		}
+		reading.WriteString(toHiragana(string(r)))
+		i++
+	}
+	return reading.String()
+}
+
+// isKana reports whether r is hiragana, katakana or the prolonged sound mark
+func isKana(r rune) bool {
+	return (r >= 'ぁ' && r <= 'ゖ') || (r >= 'ァ' && r <= 'ヶ') || r == 'ー'
+}
+
+// toHiragana converts katakana to hiragana
+func toHiragana(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'ァ' && r <= 'ヶ' {
+			return r - 0x60
+		}
+		return r
+	}, s)
+}
+
+// sortKey returns the key a term is ordered by in the index
+func (d *definedTerm) sortKey() string {
+	if d.reading != "" {
+		return d.reading
+	}
+	return d.term
+}
+
+// kanaRowLabels are the 行 headings of the index, by the first kana of each row
+var kanaRowLabels = []struct {
+	first rune
+	label string
+}{
+	{'ぁ', "あ行"}, {'か', "か行"}, {'さ', "さ行"}, {'た', "た行"}, {'な', "な行"},
+	{'は', "は行"}, {'ま', "ま行"}, {'ゃ', "や行"}, {'ら', "ら行"}, {'ゎ', "わ行"},
+}
+
+// kanaRow returns the 行 heading for a sort key, or その他 when it does not start with kana
+func kanaRow(key string) string {
+	r, _ := utf8.DecodeRuneInString(key)
+	if r < 'ぁ' || r > 'ゖ' {
+		return "その他"
+	}
+	label := kanaRowLabels[0].label
+	for _, row := range kanaRowLabels {
+		if r >= row.first {
+			label = row.label
+		}
+	}
+	return label
+}
+
+// sortDefinitions orders terms by reading, placing terms without a known reading last
+func sortDefinitions(terms []definedTerm) []definedTerm {
+	sorted := append([]definedTerm(nil), terms...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ri, rj := kanaRow(sorted[i].sortKey()) == "その他", kanaRow(sorted[j].sortKey()) == "その他"
+		if ri != rj {
+			return rj
+		}
+		return sorted[i].sortKey() < sorted[j].sortKey()
+	})
+	return sorted
+}
+
+// addDefinitionIndex adds the 定義語索引 chapter listing every defined term
+func addDefinitionIndex(book BookWriter, terms []definedTerm) error {
+	if len(terms) == 0 {
+		return nil
+	}
+
+	if _, err := book.AddSection(buildDefinitionIndexBody(terms), "定義語索引", definitionIndexFilename, ""); err != nil {
+		return fmt.Errorf("adding definition index section: %w", err)
+	}
+	return nil
+}
+
+// buildDefinitionIndexBody builds the index grouped by 行
+func buildDefinitionIndexBody(terms []definedTerm) string {
+	var body strings.Builder
+	body.WriteString(openSection("index", "definition-index", ""))
+	body.WriteString(`<h2>定義語索引</h2>`)
+
+	row := ""
+	for _, term := range sortDefinitions(terms) {
+		if r := kanaRow(term.sortKey()); r != row {
+			if row != "" {
+				body.WriteString(`</dl>`)
+			}
+			row = r
+			fmt.Fprintf(&body, `<h3>%s</h3><dl class="definition-index">`, row)
+		}
+
+		label := html.EscapeString(term.term)
+		if term.reading != "" && term.reading != toHiragana(term.term) {
+			label = fmt.Sprintf("<ruby>%s<rt>%s</rt></ruby>", label, html.EscapeString(term.reading))
+		}
+		fmt.Fprintf(&body, `<dt>%s</dt><dd><a href="%s">%s</a></dd>`,
+			label, html.EscapeString(term.href), html.EscapeString(term.article))
+	}
+	if row != "" {
+		body.WriteString(`</dl>`)
+	}
+	body.WriteString(htmlSectionEnd)
+
+	return body.String()
+}
+
+// definitionLinker is a BookWriter that links the first occurrence of each defined term
+// in sections following the definition back to the defining article
+type definitionLinker struct {
+	BookWriter
+	terms []definedTerm
+	added map[string]bool
+}
+
+// newDefinitionLinker wraps book, matching longer terms first so they take precedence over their prefixes
+func newDefinitionLinker(book BookWriter, terms []definedTerm) *definitionLinker {
+	sorted := append([]definedTerm(nil), terms...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return utf8.RuneCountInString(sorted[i].term) > utf8.RuneCountInString(sorted[j].term)
+	})
+	return &definitionLinker{BookWriter: book, terms: sorted, added: make(map[string]bool)}
+}
+
+// AddSection links defined terms in body before adding the section
+func (l *definitionLinker) AddSection(body, sectionTitle, internalFilename, internalCSSPath string) (string, error) {
+	body = l.link(body, internalFilename)
+	l.added[internalFilename] = true
+	return l.BookWriter.AddSection(body, sectionTitle, internalFilename, internalCSSPath)
+}
+
+// AddSubSection links defined terms in body before adding the subsection
+func (l *definitionLinker) AddSubSection(parentFilename, body, sectionTitle, internalFilename, internalCSSPath string) (string, error) {
+	body = l.link(body, internalFilename)
+	l.added[internalFilename] = true
+	return l.BookWriter.AddSubSection(parentFilename, body, sectionTitle, internalFilename, internalCSSPath)
+}
+
+// link wraps the first occurrence of each term defined in an earlier section
+func (l *definitionLinker) link(body, filename string) string {
+	for _, term := range l.terms {
+		if term.href == filename || !l.added[term.href] {
+			continue
+		}
+		anchor := fmt.Sprintf(`<a href="%s" class="defined-term">%s</a>`,
+			html.EscapeString(term.href), html.EscapeString(term.term))
+		body = replaceFirstInText(body, html.EscapeString(term.term), anchor)
+	}
+	return body
+}
+
+// linkSkippedElements are elements whose text is never linked
+var linkSkippedElements = map[string]bool{
+	"a": true, "ruby": true, "rt": true, "rp": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+// replaceFirstInText replaces the first occurrence of old in character data outside skipped elements
+func replaceFirstInText(body, old, replacement string) string {
+	return replaceFirstInTextFunc(body, old, func() string { return replacement })
+}
+
+// replaceFirstInTextFunc is like replaceFirstInText but only builds the replacement when a match is found
+func replaceFirstInTextFunc(body, old string, replacement func() string) string {
+	done := false
+	return mapTextSegments(body, func(text string) string {
+		if done {
+			return text
+		}
+		idx := strings.Index(text, old)
+		if idx < 0 {
+			return text
+		}
+		done = true
+		return text[:idx] + replacement() + text[idx+len(old):]
+	})
+}
+
+// mapTextSegments applies fn to each run of character data outside skipped elements
+func mapTextSegments(body string, fn func(text string) string) string {
+	var out strings.Builder
+	depth := 0
+	for i := 0; i < len(body); {
+		if body[i] == '<' {
+			end := strings.IndexByte(body[i:], '>')
+			if end < 0 {
+				out.WriteString(body[i:])
+				break
+			}
+			tag := body[i+1 : i+end]
+			closing := strings.HasPrefix(tag, "/")
+			name := strings.TrimPrefix(tag, "/")
+			if j := strings.IndexAny(name, " \t\n/"); j >= 0 {
+				name = name[:j]
+			}
+			if linkSkippedElements[strings.ToLower(name)] && !strings.HasSuffix(tag, "/") {
+				if closing {
+					depth--
+				} else {
+					depth++
+				}
+			}
+			out.WriteString(body[i : i+end+1])
+			i += end + 1
+			continue
+		}
+
+		next := strings.IndexByte(body[i:], '<')
+		if next < 0 {
+			next = len(body) - i
+		}
+		text := body[i : i+next]
+		if depth == 0 {
+			text = fn(text)
+		}
+		out.WriteString(text)
+		i += next
+	}
+	return out.String()
+}
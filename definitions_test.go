@@ -0,0 +1,304 @@
+package jplaw2epub
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.ngs.io/jplaw-xml"
+)
+
+// loadDefinitionsFixture loads the law used by the definition tests
+func loadDefinitionsFixture(t *testing.T) *JSONLaw {
+	t.Helper()
+
+	law, err := CreateJSONFromXMLPath(filepath.Join("testdata", "definitions.xml"))
+	if err != nil {
+		t.Fatalf("CreateJSONFromXMLPath() error = %v", err)
+	}
+	return law
+}
+
+// mustLoadTestdataLaw parses a law XML file from testdata
+func mustLoadTestdataLaw(t *testing.T, name string) *jplaw.Law {
+	t.Helper()
+
+	xmlFile, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("Failed to open input: %v", err)
+	}
+	defer xmlFile.Close()
+
+	data, err := loadXMLDataFromReader(xmlFile)
+	if err != nil {
+		t.Fatalf("loadXMLDataFromReader() error = %v", err)
+	}
+	return data
+}
+
+func TestExtractDefinitions(t *testing.T) {
+	terms := extractDefinitions(loadDefinitionsFixture(t))
+
+	want := []definedTerm{
+		{term: "事業者", href: "article-1.xhtml", article: "第二条", sentence: "事業者　事業を行う者をいう。"},
+		{term: "データベース", reading: "でーたべーす", href: "article-1.xhtml", article: "第二条", sentence: "データベース　情報の集合物をいう。"},
+		{term: "届出", href: "article-2.xhtml", article: "第三条", sentence: "この法律において「届出」とは、第五条の規定による届出をいう。"},
+		{term: "大臣", href: "article-3.xhtml", article: "第四条", sentence: "経済産業大臣（以下「大臣」という。）は、事業者に対し、届出を求めることができる。"},
+	}
+	if !reflect.DeepEqual(terms, want) {
+		t.Errorf("extractDefinitions() = %+v, want %+v", terms, want)
+	}
+}
+
+func TestParagraphDefinitions(t *testing.T) {
+	tests := []struct {
+		name  string
+		para  JSONParagraph
+		terms []string
+	}{
+		{
+			name:  "toha pattern",
+			para:  JSONParagraph{Sentences: []JSONText{{Text: "この法律において「個人情報」とは、生存する個人に関する情報をいう。"}}},
+			terms: []string{"個人情報"},
+		},
+		{
+			name:  "abbreviation pattern",
+			para:  JSONParagraph{Sentences: []JSONText{{Text: "内閣総理大臣（以下この条において「総理」という。）は"}}},
+			terms: []string{"総理"},
+		},
+		{
+			name:  "quoted text without definition",
+			para:  JSONParagraph{Sentences: []JSONText{{Text: "「届出」の文字を用いてはならない。"}}},
+			terms: nil,
+		},
+		{
+			name: "items outside a definition list are ignored",
+			para: JSONParagraph{
+				Sentences: []JSONText{{Text: "次に掲げる事項を届け出なければならない。"}},
+				Items:     []JSONItem{{Sentences: []JSONText{{Text: "氏名　又は名称"}}}},
+			},
+			terms: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, term := range paragraphDefinitions(&tt.para, "第一条") {
+				got = append(got, term.term)
+			}
+			if !reflect.DeepEqual(got, tt.terms) {
+				t.Errorf("paragraphDefinitions() = %v, want %v", got, tt.terms)
+			}
+		})
+	}
+}
+
+func TestTermReading(t *testing.T) {
+	tests := []struct {
+		name string
+		text JSONText
+		term string
+		want string
+	}{
+		{
+			name: "katakana",
+			text: JSONText{Text: "「データ」とは"},
+			term: "データ",
+			want: "でーた",
+		},
+		{
+			name: "ruby covers kanji",
+			text: JSONText{Text: "「瑕疵」とは", Ruby: []JSONRuby{{Offset: 1, Base: "瑕疵", Reading: "かし"}}},
+			term: "瑕疵",
+			want: "かし",
+		},
+		{
+			name: "ruby and kana mixed",
+			text: JSONText{Text: "「埋め立て」とは", Ruby: []JSONRuby{{Offset: 1, Base: "埋", Reading: "う"}, {Offset: 3, Base: "立", Reading: "た"}}},
+			term: "埋め立て",
+			want: "うめたて",
+		},
+		{
+			name: "kanji without ruby",
+			text: JSONText{Text: "「届出」とは"},
+			term: "届出",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := strings.Index(tt.text.Text, tt.term)
+			if got := termReading(tt.text, start, start+len(tt.term)); got != tt.want {
+				t.Errorf("termReading() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKanaRow(t *testing.T) {
+	tests := map[string]string{
+		"あいさつ": "あ行",
+		"がっこう": "か行",
+		"でーた":  "た行",
+		"ぽいんと": "は行",
+		"よやく":  "や行",
+		"をはり":  "わ行",
+		"届出":   "その他",
+		"":     "その他",
+	}
+	for key, want := range tests {
+		if got := kanaRow(key); got != want {
+			t.Errorf("kanaRow(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestBuildDefinitionIndexBody(t *testing.T) {
+	terms := []definedTerm{
+		{term: "届出", href: "article-2.xhtml", article: "第三条"},
+		{term: "瑕疵", reading: "かし", href: "article-4.xhtml", article: "第五条"},
+		{term: "データ", reading: "でーた", href: "article-1.xhtml", article: "第二条"},
+		{term: "あっせん", reading: "あっせん", href: "article-0.xhtml", article: "第一条"},
+	}
+
+	body := buildDefinitionIndexBody(terms)
+
+	want := `<section epub:type="index" class="definition-index"><h2>定義語索引</h2>` +
+		`<h3>あ行</h3><dl class="definition-index"><dt>あっせん</dt><dd><a href="article-0.xhtml">第一条</a></dd></dl>` +
+		`<h3>か行</h3><dl class="definition-index"><dt><ruby>瑕疵<rt>かし</rt></ruby></dt><dd><a href="article-4.xhtml">第五条</a></dd></dl>` +
+		`<h3>た行</h3><dl class="definition-index"><dt>データ</dt><dd><a href="article-1.xhtml">第二条</a></dd></dl>` +
+		`<h3>その他</h3><dl class="definition-index"><dt>届出</dt><dd><a href="article-2.xhtml">第三条</a></dd></dl></section>`
+	if body != want {
+		t.Errorf("buildDefinitionIndexBody() =\n%s\nwant\n%s", body, want)
+	}
+}
+
+func TestReplaceFirstInText(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "first occurrence only",
+			body: `<p>届出をし、届出を受ける。</p>`,
+			want: `<p><a>届出</a>をし、届出を受ける。</p>`,
+		},
+		{
+			name: "headings are skipped",
+			body: `<h4>届出</h4><p>届出</p>`,
+			want: `<h4>届出</h4><p><a>届出</a></p>`,
+		},
+		{
+			name: "existing links and ruby are skipped",
+			body: `<a href="x">届出</a><ruby>届出<rt>とどけで</rt></ruby><span>届出</span>`,
+			want: `<a href="x">届出</a><ruby>届出<rt>とどけで</rt></ruby><span><a>届出</a></span>`,
+		},
+		{
+			name: "attributes are not matched",
+			body: `<img alt="届出" /><p>なし</p>`,
+			want: `<img alt="届出" /><p>なし</p>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := replaceFirstInText(tt.body, "届出", "<a>届出</a>"); got != tt.want {
+				t.Errorf("replaceFirstInText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefinitionOptions(t *testing.T) {
+	data := mustLoadTestdataLaw(t, "definitions.xml")
+
+	doc := NewPDFDocument(data.LawBody.LawTitle.Content)
+	opts := &EPUBOptions{DefinitionIndex: true, DefinitionLinks: true}
+	if err := processChaptersWithOptions(doc, data, opts); err != nil {
+		t.Fatalf("processChaptersWithOptions() error = %v", err)
+	}
+
+	index, ok := doc.files[definitionIndexFilename]
+	if !ok {
+		t.Fatal("definition index section not added")
+	}
+	if doc.sections[len(doc.sections)-1] != index {
+		t.Error("definition index should be the last section")
+	}
+	if strings.Contains(index.body, "defined-term") {
+		t.Error("definition index should not link terms to themselves")
+	}
+
+	tests := []struct {
+		filename string
+		links    []string
+		absent   []string
+	}{
+		{
+			filename: "article-0.xhtml",
+			absent:   []string{"defined-term"},
+		},
+		{
+			filename: "article-3.xhtml",
+			links: []string{
+				`<a href="article-1.xhtml" class="defined-term">事業者</a>`,
+				`<a href="article-2.xhtml" class="defined-term">届出</a>`,
+			},
+			absent: []string{`class="defined-term">大臣`},
+		},
+		{
+			filename: "article-4.xhtml",
+			links: []string{
+				`<a href="article-1.xhtml" class="defined-term">事業者</a>`,
+				`<a href="article-3.xhtml" class="defined-term">大臣</a>`,
+				`<a href="article-2.xhtml" class="defined-term">届出</a>`,
+				`<a href="article-1.xhtml" class="defined-term">データベース</a>`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			section, ok := doc.files[tt.filename]
+			if !ok {
+				t.Fatalf("section %s not found", tt.filename)
+			}
+			for _, link := range tt.links {
+				if !strings.Contains(section.body, link) {
+					t.Errorf("body missing %s:\n%s", link, section.body)
+				}
+			}
+			for _, s := range tt.absent {
+				if strings.Contains(section.body, s) {
+					t.Errorf("body should not contain %s:\n%s", s, section.body)
+				}
+			}
+			if strings.Count(section.body, `class="defined-term">事業者`) > 1 {
+				t.Errorf("only the first occurrence should be linked:\n%s", section.body)
+			}
+		})
+	}
+}
+
+func TestDefinitionOptionsDisabled(t *testing.T) {
+	data := mustLoadTestdataLaw(t, "definitions.xml")
+
+	doc := NewPDFDocument(data.LawBody.LawTitle.Content)
+	if err := processChaptersWithOptions(doc, data, &EPUBOptions{}); err != nil {
+		t.Fatalf("processChaptersWithOptions() error = %v", err)
+	}
+
+	if _, ok := doc.files[definitionIndexFilename]; ok {
+		t.Error("definition index should not be added unless requested")
+	}
+	for name, section := range doc.files {
+		if strings.Contains(section.body, "defined-term") {
+			t.Errorf("%s should not contain definition links", name)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package jplaw2epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// epubPackagePath is the location of the package document written by go-epub
+const epubPackagePath = "EPUB/package.opf"
+
+// epubArchiveFile is a single entry of an EPUB container
+type epubArchiveFile struct {
+	name   string
+	method uint16
+	data   []byte
+}
+
+// epubArchive holds the entries of an EPUB container in their original order
+type epubArchive struct {
+	files []*epubArchiveFile
+}
+
+// readEPUBArchive loads all entries of an EPUB container into memory
+func readEPUBArchive(data []byte) (*epubArchive, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("opening EPUB archive: %w", err)
+	}
+
+	archive := &epubArchive{}
+	for _, f := range reader.File {
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("opening %s: %w", f.Name, err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", f.Name, err)
+		}
+		archive.files = append(archive.files, &epubArchiveFile{name: f.Name, method: f.Method, data: content})
+	}
+	return archive, nil
+}
+
+// file returns the entry with the given name, or nil
+func (a *epubArchive) file(name string) *epubArchiveFile {
+	for _, f := range a.files {
+		if f.name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// writeTo writes the archive keeping the mimetype entry first and uncompressed
+func (a *epubArchive) writeTo(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	for _, f := range a.files {
+		method := f.method
+		if f.name == "mimetype" {
+			method = zip.Store
+		}
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: method})
+		if err != nil {
+			return fmt.Errorf("adding %s: %w", f.name, err)
+		}
+		if _, err := fw.Write(f.data); err != nil {
+			return fmt.Errorf("writing %s: %w", f.name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("closing EPUB archive: %w", err)
+	}
+	return nil
+}
+
+// postProcessOptions holds what post-processing adds to the archive of a book
+type postProcessOptions struct {
+	metadata *packageMetadata
+	// font is an OpenType font embedded, subset to the text of the book
+	font []byte
+	// theme selects the theme stylesheet linked from the content documents
+	theme Theme
+	// scripted is set for books with the search page, the only content document with a script
+	scripted bool
+}
+
+// postProcessOptionsOf returns the post-processing opts asks for of a book created without
+// a Book, which has no package metadata
+func postProcessOptionsOf(opts *EPUBOptions) *postProcessOptions {
+	post := &postProcessOptions{}
+	if opts != nil {
+		post.theme = opts.Theme
+		post.font = opts.EmbedFont
+		post.scripted = opts.SearchIndex
+	}
+	return post
+}
+
+// needed reports whether post-processing changes the archive go-epub writes
+func (p *postProcessOptions) needed() bool {
+	return p != nil && (p.metadata != nil || p.theme != "" || p.font != nil || p.scripted)
+}
+
+// postProcessEPUB adds what opts holds to the archive go-epub wrote and writes the result to w
+func postProcessEPUB(data []byte, opts *postProcessOptions, w io.Writer) error {
+	archive, err := readEPUBArchive(data)
+	if err != nil {
+		return err
+	}
+
+	if opts != nil && opts.scripted {
+		if err := markScriptedContent(archive); err != nil {
+			return err
+		}
+	}
+	if opts != nil && opts.metadata != nil {
+		if err := addPackageMetadata(archive, opts.metadata); err != nil {
+			return err
+		}
+		if err := addAccessibility(archive, opts.metadata.lang); err != nil {
+			return err
+		}
+	}
+	if opts != nil && opts.theme != "" {
+		if err := addThemeStylesheet(archive, opts.theme); err != nil {
+			return err
+		}
+	}
+	if opts != nil && opts.font != nil {
+		if err := embedFont(archive, opts.font); err != nil {
+			return err
+		}
+	}
+
+	return archive.writeTo(w)
+}
+
+// markScriptedContent adds the scripted property to the manifest item of the search page
+func markScriptedContent(archive *epubArchive) error {
+	pkg := archive.file(epubPackagePath)
+	if pkg == nil {
+		return fmt.Errorf("package document %s not found", epubPackagePath)
+	}
+
+	href, err := relativeManifestHref(path.Join(path.Dir(epubPackagePath), "xhtml", searchPageFilename))
+	if err != nil {
+		return err
+	}
+	pkg.data = []byte(addManifestProperty(string(pkg.data), href, "scripted"))
+	return nil
+}
+
+// relativeManifestHref converts an archive path into a manifest href relative to the package document
+func relativeManifestHref(name string) (string, error) {
+	dir := path.Dir(epubPackagePath) + "/"
+	if !strings.HasPrefix(name, dir) {
+		return "", fmt.Errorf("content document %s is outside %s", name, dir)
+	}
+	return strings.TrimPrefix(name, dir), nil
+}
+
+// addManifestProperty adds a property to the manifest item with the given href
+func addManifestProperty(opf, href, property string) string {
+	itemPattern := regexp.MustCompile(`<item\b[^>]*\bhref="` + regexp.QuoteMeta(href) + `"[^>]*>`)
+	propsPattern := regexp.MustCompile(`\bproperties="([^"]*)"`)
+
+	return itemPattern.ReplaceAllStringFunc(opf, func(item string) string {
+		match := propsPattern.FindStringSubmatch(item)
+		if match == nil {
+			return strings.Replace(item, "<item", fmt.Sprintf(`<item properties=%q`, property), 1)
+		}
+		for _, p := range strings.Fields(match[1]) {
+			if p == property {
+				return item
+			}
+		}
+		return strings.Replace(item, match[0], fmt.Sprintf(`properties="%s %s"`, match[1], property), 1)
+	})
+}
@@ -0,0 +1,179 @@
+package jplaw2epub
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func TestAddManifestProperty(t *testing.T) {
+	tests := []struct {
+		name     string
+		opf      string
+		href     string
+		property string
+		want     string
+	}{
+		{
+			name:     "adds properties attribute",
+			opf:      `<item id="a" href="xhtml/a.xhtml" media-type="application/xhtml+xml"></item>`,
+			href:     "xhtml/a.xhtml",
+			property: "scripted",
+			want:     `<item properties="scripted" id="a" href="xhtml/a.xhtml" media-type="application/xhtml+xml"></item>`,
+		},
+		{
+			name:     "appends to existing properties",
+			opf:      `<item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"></item>`,
+			href:     "nav.xhtml",
+			property: "scripted",
+			want:     `<item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav scripted"></item>`,
+		},
+		{
+			name:     "does not duplicate",
+			opf:      `<item properties="scripted" id="a" href="xhtml/a.xhtml"></item>`,
+			href:     "xhtml/a.xhtml",
+			property: "scripted",
+			want:     `<item properties="scripted" id="a" href="xhtml/a.xhtml"></item>`,
+		},
+		{
+			name:     "other items untouched",
+			opf:      `<item id="b" href="xhtml/b.xhtml"></item>`,
+			href:     "xhtml/a.xhtml",
+			property: "scripted",
+			want:     `<item id="b" href="xhtml/b.xhtml"></item>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := addManifestProperty(tt.opf, tt.href, tt.property); got != tt.want {
+				t.Errorf("addManifestProperty() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteEPUBMarksScriptedSearchPage(t *testing.T) {
+	opts := &EPUBOptions{SearchIndex: true, NoCover: true}
+	book, err := CreateEPUBFromXMLPathWithOptions(filepath.Join("testdata", "json", "chapters.xml"), opts)
+	if err != nil {
+		t.Fatalf("CreateEPUBFromXMLPathWithOptions() error = %v", err)
+	}
+
+	destPath := filepath.Join(t.TempDir(), "law.epub")
+	if err := WriteEPUBWithOptions(book, destPath, opts); err != nil {
+		t.Fatalf("WriteEPUBWithOptions() error = %v", err)
+	}
+
+	files := readZipFiles(t, destPath)
+
+	opf := files[epubPackagePath]
+	if !strings.Contains(opf, `<item properties="scripted" id="search.xhtml" href="xhtml/search.xhtml"`) {
+		t.Errorf("search page not marked as scripted:\n%s", opf)
+	}
+	if strings.Count(opf, "scripted") != 1 {
+		t.Errorf("only the search page should be scripted:\n%s", opf)
+	}
+	if !strings.Contains(files["EPUB/xhtml/search.xhtml"], "jplawSearchIndex") {
+		t.Error("search page does not embed the index")
+	}
+}
+
+func TestWriteEPUBWithoutSearchIndex(t *testing.T) {
+	book, err := CreateEPUBFromXMLPath(filepath.Join("testdata", "json", "chapters.xml"))
+	if err != nil {
+		t.Fatalf("CreateEPUBFromXMLPath() error = %v", err)
+	}
+
+	destPath := filepath.Join(t.TempDir(), "law.epub")
+	if err := WriteEPUB(book, destPath); err != nil {
+		t.Fatalf("WriteEPUB() error = %v", err)
+	}
+
+	files := readZipFiles(t, destPath)
+	if _, ok := files["EPUB/xhtml/search.xhtml"]; ok {
+		t.Error("search page should not be added unless requested")
+	}
+	if strings.Contains(files[epubPackagePath], "scripted") {
+		t.Error("package document should not declare scripted content")
+	}
+}
+
+func TestPostProcessEPUBKeepsMimetypeFirst(t *testing.T) {
+	book, err := CreateEPUBFromXMLPath(filepath.Join("testdata", "json", "articles.xml"))
+	if err != nil {
+		t.Fatalf("CreateEPUBFromXMLPath() error = %v", err)
+	}
+
+	destPath := filepath.Join(t.TempDir(), "law.epub")
+	if err := WriteEPUB(book, destPath); err != nil {
+		t.Fatalf("WriteEPUB() error = %v", err)
+	}
+
+	reader, err := zip.OpenReader(destPath)
+	if err != nil {
+		t.Fatalf("Failed to open EPUB: %v", err)
+	}
+	defer reader.Close()
+
+	first := reader.File[0]
+	if first.Name != "mimetype" {
+		t.Fatalf("first entry = %q, want mimetype", first.Name)
+	}
+	if first.Method != zip.Store {
+		t.Errorf("mimetype method = %d, want Store", first.Method)
+	}
+}
+
+func TestPostProcessEPUBInvalidArchive(t *testing.T) {
+	if err := postProcessEPUB([]byte("not a zip"), nil, io.Discard); err == nil {
+		t.Error("postProcessEPUB() expected error for invalid archive")
+	}
+}
+
+func TestPostProcessOptionsNeeded(t *testing.T) {
+	tests := []struct {
+		name string
+		opts *EPUBOptions
+		want bool
+	}{
+		{name: "nil options", opts: nil, want: false},
+		{name: "no post-processing", opts: &EPUBOptions{NoCover: true, Validate: true}, want: false},
+		{name: "theme", opts: &EPUBOptions{Theme: ThemeSepia}, want: true},
+		{name: "embedded font", opts: &EPUBOptions{EmbedFont: goregular.TTF}, want: true},
+		{name: "search index", opts: &EPUBOptions{SearchIndex: true}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := postProcessOptionsOf(tt.opts).needed(); got != tt.want {
+				t.Errorf("needed() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+// readZipFiles reads every entry of a zip file into a map keyed by name
+func readZipFiles(t *testing.T, path string) map[string]string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	archive, err := readEPUBArchive(data)
+	if err != nil {
+		t.Fatalf("readEPUBArchive() error = %v", err)
+	}
+
+	files := make(map[string]string)
+	for _, f := range archive.files {
+		files[f.name] = string(f.data)
+	}
+	return files
+}
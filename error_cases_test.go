@@ -219,7 +219,7 @@ func TestWriteEPUB_DirectoryCreation(t *testing.T) {
 	tmpDir := t.TempDir()
 	nestedPath := filepath.Join(tmpDir, "nested", "dir", "test.epub")
 
-	err = WriteEPUB(book, nestedPath)
+	err = WriteEPUB(book.Epub, nestedPath)
 	if err != nil {
 		t.Errorf("WriteEPUB should create nested directories: %v", err)
 	}
@@ -0,0 +1,240 @@
+package jplaw2epub
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"go.ngs.io/jplaw-xml"
+)
+
+// ProvisionRange is an inclusive range of chapter or article numbers. From and To are equal
+// for a single number.
+type ProvisionRange struct {
+	From ProvisionNumber
+	To   ProvisionNumber
+}
+
+// ParseProvisionRanges parses a comma-separated list of numbers and ranges such as 1-20,35.
+// Branch numbers are written with _ or の within ranges (10_2-20 is 第十条の二から第二十条まで).
+func ParseProvisionRanges(s string) ([]ProvisionRange, error) {
+	var ranges []ProvisionRange
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '、' }) {
+		field = strings.TrimSuffix(strings.TrimSpace(field), "まで")
+		from, to, isRange := cutRange(field)
+		if !isRange {
+			to = from
+		}
+		fromNumber, err := ParseProvisionNumber(from)
+		if err != nil {
+			return nil, err
+		}
+		toNumber, err := ParseProvisionNumber(to)
+		if err != nil {
+			return nil, err
+		}
+		if fromNumber.Compare(toNumber) > 0 {
+			return nil, fmt.Errorf("invalid range %q: %s comes after %s", field, from, to)
+		}
+		ranges = append(ranges, ProvisionRange{From: fromNumber, To: toNumber})
+	}
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("no numbers in %q", s)
+	}
+	return ranges, nil
+}
+
+// cutRange splits a range at -, ~, 〜 or から
+func cutRange(s string) (from, to string, ok bool) {
+	for _, sep := range []string{"から", "〜", "~", "-"} {
+		if from, to, ok := strings.Cut(s, sep); ok {
+			return from, to, true
+		}
+	}
+	return s, "", false
+}
+
+// Contains reports whether n is within the range
+func (r ProvisionRange) Contains(n ProvisionNumber) bool {
+	return r.From.Compare(n) <= 0 && n.Compare(r.To) <= 0
+}
+
+// rangesContain reports whether any of ranges contains n
+func rangesContain(ranges []ProvisionRange, n ProvisionNumber) bool {
+	for _, r := range ranges {
+		if r.Contains(n) {
+			return true
+		}
+	}
+	return false
+}
+
+// isExcerpt reports whether opts select only part of a law
+func isExcerpt(opts *EPUBOptions) bool {
+	return opts != nil && (len(opts.Chapters) > 0 || len(opts.Articles) > 0 || opts.NoSupplProvisions || opts.NoAppendixes)
+}
+
+// errEmptyExcerpt is returned when the chapter and article selection matches nothing
+var errEmptyExcerpt = errors.New("the chapter and article selection matches no articles")
+
+// excerptLaw returns a copy of data holding only the parts opts select, or data itself
+// when opts select the whole law
+func excerptLaw(data *jplaw.Law, opts *EPUBOptions) (*jplaw.Law, error) {
+	if !isExcerpt(opts) {
+		return data, nil
+	}
+	excerpt := *data
+	body := &excerpt.LawBody
+
+	if len(opts.Chapters) > 0 || len(opts.Articles) > 0 {
+		mainProv, err := excerptMainProvision(&data.LawBody.MainProvision, opts)
+		if err != nil {
+			return nil, err
+		}
+		body.MainProvision = *mainProv
+	}
+	if opts.NoSupplProvisions {
+		body.SupplProvision = nil
+	}
+	if opts.NoAppendixes {
+		body.AppdxTable = nil
+		body.AppdxNote = nil
+		body.AppdxStyle = nil
+		body.AppdxFormat = nil
+		body.AppdxFig = nil
+	}
+	return &excerpt, nil
+}
+
+// excerptMainProvision keeps the selected chapters and articles of a main provision,
+// dropping chapters and sections left without articles
+func excerptMainProvision(mainProv *jplaw.MainProvision, opts *EPUBOptions) (*jplaw.MainProvision, error) {
+	if len(opts.Chapters) > 0 && len(mainProv.Chapter) == 0 {
+		return nil, errors.New("the law has no chapters to select")
+	}
+
+	keepArticles := func(articles []jplaw.Article) []jplaw.Article {
+		if len(opts.Articles) == 0 {
+			return articles
+		}
+		var kept []jplaw.Article
+		for i := range articles {
+			if number, ok := articleNumber(&articles[i]); ok && rangesContain(opts.Articles, number) {
+				kept = append(kept, articles[i])
+			}
+		}
+		return kept
+	}
+
+	result := &jplaw.MainProvision{}
+	count := 0
+	for i := range mainProv.Chapter {
+		chapter := mainProv.Chapter[i]
+		if len(opts.Chapters) > 0 {
+			if number, ok := chapterNumber(&chapter); !ok || !rangesContain(opts.Chapters, number) {
+				continue
+			}
+		}
+		chapter.Article = keepArticles(chapter.Article)
+		chapterCount := len(chapter.Article)
+		var sections []jplaw.Section
+		for j := range chapter.Section {
+			section := chapter.Section[j]
+			section.Article = keepArticles(section.Article)
+			if len(section.Article) > 0 {
+				sections = append(sections, section)
+				chapterCount += len(section.Article)
+			}
+		}
+		chapter.Section = sections
+		if chapterCount > 0 {
+			result.Chapter = append(result.Chapter, chapter)
+			count += chapterCount
+		}
+	}
+	result.Article = keepArticles(mainProv.Article)
+	count += len(result.Article)
+
+	if count == 0 {
+		return nil, errEmptyExcerpt
+	}
+	return result, nil
+}
+
+// chapterNumber returns the number of a chapter, from its Num attribute or else its title
+func chapterNumber(chapter *jplaw.Chapter) (ProvisionNumber, bool) {
+	if number, err := ParseProvisionNumber(chapter.Num); err == nil {
+		return number, true
+	}
+	if fields := strings.FieldsFunc(chapter.ChapterTitle.Content, unicode.IsSpace); len(fields) > 0 {
+		if number, err := ParseProvisionNumber(fields[0]); err == nil {
+			return number, true
+		}
+	}
+	return nil, false
+}
+
+// sectionNumber returns the number of a section, parsed from its title
+func sectionNumber(section *jplaw.Section) (ProvisionNumber, bool) {
+	if fields := strings.FieldsFunc(section.SectionTitle.Content, unicode.IsSpace); len(fields) > 0 {
+		if number, err := ParseProvisionNumber(fields[0]); err == nil {
+			return number, true
+		}
+	}
+	return nil, false
+}
+
+// formatProvisionNumber writes a number in kanji with its unit, e.g. 第十条の二
+func formatProvisionNumber(n ProvisionNumber, unit string) string {
+	if len(n) == 0 {
+		return ""
+	}
+	s := "第" + formatKanjiNumber(n[0]) + unit
+	for _, branch := range n[1:] {
+		s += "の" + formatKanjiNumber(branch)
+	}
+	return s
+}
+
+// formatProvisionRanges writes ranges as in legal text, e.g. 第一条から第二十条まで、第三十五条
+func formatProvisionRanges(ranges []ProvisionRange, unit string) string {
+	parts := make([]string, len(ranges))
+	for i, r := range ranges {
+		parts[i] = formatProvisionNumber(r.From, unit)
+		if r.From.Compare(r.To) != 0 {
+			parts[i] += "から" + formatProvisionNumber(r.To, unit) + "まで"
+		}
+	}
+	return strings.Join(parts, "、")
+}
+
+// excerptDescription describes the range of an excerpt for the title page, or returns an empty
+// string when opts select the whole law
+func excerptDescription(opts *EPUBOptions) string {
+	if !isExcerpt(opts) {
+		return ""
+	}
+
+	scope := "全文"
+	switch {
+	case len(opts.Chapters) > 0 && len(opts.Articles) > 0:
+		scope = formatProvisionRanges(opts.Chapters, "章") + "のうち" + formatProvisionRanges(opts.Articles, "条")
+	case len(opts.Chapters) > 0:
+		scope = formatProvisionRanges(opts.Chapters, "章")
+	case len(opts.Articles) > 0:
+		scope = formatProvisionRanges(opts.Articles, "条")
+	}
+
+	var excluded []string
+	if opts.NoSupplProvisions {
+		excluded = append(excluded, "附則")
+	}
+	if opts.NoAppendixes {
+		excluded = append(excluded, "別表等")
+	}
+	if len(excluded) > 0 {
+		scope += "（" + strings.Join(excluded, "及び") + "を除く。）"
+	}
+	return "抄録　収録範囲: " + scope
+}
@@ -0,0 +1,159 @@
+package jplaw2epub
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.ngs.io/jplaw-xml"
+)
+
+func TestParseProvisionRanges(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    []ProvisionRange
+		wantErr bool
+	}{
+		{
+			input: "1-20,35",
+			want: []ProvisionRange{
+				{From: ProvisionNumber{1}, To: ProvisionNumber{20}},
+				{From: ProvisionNumber{35}, To: ProvisionNumber{35}},
+			},
+		},
+		{
+			input: "10_2-20",
+			want:  []ProvisionRange{{From: ProvisionNumber{10, 2}, To: ProvisionNumber{20}}},
+		},
+		{
+			input: "第一条から第三条の二まで、第五条",
+			want: []ProvisionRange{
+				{From: ProvisionNumber{1}, To: ProvisionNumber{3, 2}},
+				{From: ProvisionNumber{5}, To: ProvisionNumber{5}},
+			},
+		},
+		{input: "20-1", wantErr: true},
+		{input: "1-x", wantErr: true},
+		{input: ",", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseProvisionRanges(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseProvisionRanges() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseProvisionRanges() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// excerptTitles lists the chapter, section and article titles of a main provision
+func excerptTitles(mainProv *jplaw.MainProvision) []string {
+	var titles []string
+	addArticles := func(articles []jplaw.Article) {
+		for i := range articles {
+			titles = append(titles, articles[i].ArticleTitle.Content)
+		}
+	}
+	for i := range mainProv.Chapter {
+		chapter := &mainProv.Chapter[i]
+		titles = append(titles, chapter.ChapterTitle.Content)
+		addArticles(chapter.Article)
+		for j := range chapter.Section {
+			titles = append(titles, chapter.Section[j].SectionTitle.Content)
+			addArticles(chapter.Section[j].Article)
+		}
+	}
+	addArticles(mainProv.Article)
+	return titles
+}
+
+func TestExcerptLaw(t *testing.T) {
+	data := mustLoadTestdataLaw(t, "json/chapters.xml")
+	whole := excerptTitles(&data.LawBody.MainProvision)
+
+	tests := []struct {
+		name    string
+		opts    *EPUBOptions
+		want    []string
+		wantErr error
+	}{
+		{name: "whole law", opts: &EPUBOptions{}, want: whole},
+		{name: "chapter", opts: &EPUBOptions{Chapters: []ProvisionRange{{From: ProvisionNumber{1}, To: ProvisionNumber{1}}}}, want: whole[:3]},
+		{name: "articles", opts: &EPUBOptions{Articles: []ProvisionRange{{From: ProvisionNumber{2}, To: ProvisionNumber{3}}}}, want: []string{whole[0], whole[2], whole[3], whole[4], whole[5]}},
+		{name: "nothing", opts: &EPUBOptions{Articles: []ProvisionRange{{From: ProvisionNumber{9}, To: ProvisionNumber{9}}}}, wantErr: errEmptyExcerpt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := excerptLaw(data, tt.opts)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("excerptLaw() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("excerptLaw() error = %v", err)
+			}
+			if titles := excerptTitles(&got.LawBody.MainProvision); !reflect.DeepEqual(titles, tt.want) {
+				t.Errorf("excerpt titles = %v, want %v", titles, tt.want)
+			}
+		})
+	}
+
+	if titles := excerptTitles(&data.LawBody.MainProvision); !reflect.DeepEqual(titles, whole) {
+		t.Errorf("excerptLaw() modified the law: %v", titles)
+	}
+
+	excerpt, err := excerptLaw(data, &EPUBOptions{NoSupplProvisions: true, NoAppendixes: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(excerpt.LawBody.SupplProvision) != 0 || len(excerpt.LawBody.AppdxTable) != 0 || len(data.LawBody.SupplProvision) == 0 {
+		t.Errorf("supplementary provisions and appendixes should be dropped from the excerpt only")
+	}
+}
+
+func TestExcerptDescription(t *testing.T) {
+	articles := []ProvisionRange{
+		{From: ProvisionNumber{1}, To: ProvisionNumber{20}},
+		{From: ProvisionNumber{35, 2}, To: ProvisionNumber{35, 2}},
+	}
+	tests := []struct {
+		name string
+		opts *EPUBOptions
+		want string
+	}{
+		{name: "whole law", opts: nil, want: ""},
+		{name: "articles", opts: &EPUBOptions{Articles: articles}, want: "抄録　収録範囲: 第一条から第二十条まで、第三十五条の二"},
+		{
+			name: "chapter and articles",
+			opts: &EPUBOptions{Chapters: []ProvisionRange{{From: ProvisionNumber{3}, To: ProvisionNumber{3}}}, Articles: articles},
+			want: "抄録　収録範囲: 第三章のうち第一条から第二十条まで、第三十五条の二",
+		},
+		{name: "exclusions", opts: &EPUBOptions{NoSupplProvisions: true, NoAppendixes: true}, want: "抄録　収録範囲: 全文（附則及び別表等を除く。）"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := excerptDescription(tt.opts); got != tt.want {
+				t.Errorf("excerptDescription() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteEPUBExcerpt(t *testing.T) {
+	files := writeTestEPUB(t, &EPUBOptions{Articles: []ProvisionRange{{From: ProvisionNumber{2}, To: ProvisionNumber{2}}}, NoCover: true})
+	if title := files["EPUB/xhtml/title.xhtml"]; !strings.Contains(title, "抄録　収録範囲: 第二条") {
+		t.Errorf("title page should record the excerpt:\n%s", title)
+	}
+	if nav := files["EPUB/nav.xhtml"]; strings.Contains(nav, "第一条") || !strings.Contains(nav, "第二条") {
+		t.Errorf("table of contents should only list the selected articles:\n%s", nav)
+	}
+}
@@ -0,0 +1,648 @@
+package jplaw2epub
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+
+	"golang.org/x/image/font/sfnt"
+)
+
+// Fonts are subset by keeping the outlines of the needed glyphs and numbering them anew: .notdef,
+// then the glyphs of the characters in code point order, then the glyphs these need (composite
+// components and vertical forms). The tables indexed by glyph are rewritten for the new numbering;
+// of the layout tables only the vertical substitutions are kept, in a GSUB built for the subset.
+
+// subsetTables lists the tables kept in a subset; the others refer to the original glyphs
+var subsetTables = map[string]bool{
+	"head": true, "hhea": true, "hmtx": true, "maxp": true, "name": true, "OS/2": true, "post": true,
+	"cmap": true, "glyf": true, "loca": true, "CFF ": true, "cvt ": true, "fpgm": true, "prep": true,
+	"gasp": true, "vhea": true, "vmtx": true, "VORG": true, "GSUB": true,
+}
+
+// errUnsupportedFont is returned for fonts the subsetter cannot rewrite
+var errUnsupportedFont = errors.New("unsupported font")
+
+// sfntTables holds the tables of an OpenType font
+type sfntTables struct {
+	version uint32
+	tables  map[string][]byte
+}
+
+// parseSFNTTables reads the table directory of an OpenType font, using the first font of a collection
+func parseSFNTTables(data []byte) (*sfntTables, error) {
+	start := 0
+	if len(data) >= 16 && string(data[:4]) == "ttcf" {
+		start = int(binary.BigEndian.Uint32(data[12:16]))
+	}
+	if len(data) < start+12 {
+		return nil, fmt.Errorf("%w: truncated table directory", errUnsupportedFont)
+	}
+
+	font := &sfntTables{version: binary.BigEndian.Uint32(data[start:]), tables: make(map[string][]byte)}
+	numTables := int(binary.BigEndian.Uint16(data[start+4:]))
+	for i := 0; i < numTables; i++ {
+		record := start + 12 + 16*i
+		if len(data) < record+16 {
+			return nil, fmt.Errorf("%w: truncated table directory", errUnsupportedFont)
+		}
+		tag := string(data[record : record+4])
+		offset := int(binary.BigEndian.Uint32(data[record+8:]))
+		length := int(binary.BigEndian.Uint32(data[record+12:]))
+		if offset < 0 || length < 0 || offset+length > len(data) {
+			return nil, fmt.Errorf("%w: table %s is out of bounds", errUnsupportedFont, tag)
+		}
+		font.tables[tag] = data[offset : offset+length]
+	}
+	return font, nil
+}
+
+// bytes serializes the font, updating the table checksums and the head checksum adjustment
+func (f *sfntTables) bytes() []byte {
+	tags := make([]string, 0, len(f.tables))
+	for tag := range f.tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	if head, ok := f.tables["head"]; ok && len(head) >= 12 {
+		head = slices.Clone(head)
+		binary.BigEndian.PutUint32(head[8:], 0)
+		f.tables["head"] = head
+	}
+
+	numTables := len(tags)
+	entrySelector := 0
+	for 1<<(entrySelector+1) <= numTables {
+		entrySelector++
+	}
+	searchRange := (1 << entrySelector) * 16
+
+	out := make([]byte, 12+16*numTables)
+	binary.BigEndian.PutUint32(out[0:], f.version)
+	binary.BigEndian.PutUint16(out[4:], uint16(numTables))
+	binary.BigEndian.PutUint16(out[6:], uint16(searchRange))
+	binary.BigEndian.PutUint16(out[8:], uint16(entrySelector))
+	binary.BigEndian.PutUint16(out[10:], uint16(numTables*16-searchRange))
+
+	headOffset := -1
+	for i, tag := range tags {
+		table := f.tables[tag]
+		if tag == "head" {
+			headOffset = len(out)
+		}
+		record := out[12+16*i:]
+		copy(record, tag)
+		binary.BigEndian.PutUint32(record[4:], sfntChecksum(table))
+		binary.BigEndian.PutUint32(record[8:], uint32(len(out)))
+		binary.BigEndian.PutUint32(record[12:], uint32(len(table)))
+		out = append(out, table...)
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+	}
+
+	if headOffset >= 0 && len(f.tables["head"]) >= 12 {
+		binary.BigEndian.PutUint32(out[headOffset+8:], 0xB1B0AFBA-sfntChecksum(out))
+	}
+	return out
+}
+
+// sfntChecksum sums data as big-endian uint32 values, zero-padded to a multiple of four bytes
+func sfntChecksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		var word [4]byte
+		copy(word[:], data[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
+
+// subsetFont returns a copy of an OpenType font (TrueType or CFF outlines) keeping only the
+// glyphs needed to render runes, and whether the result has CFF outlines
+func subsetFont(data []byte, runes []rune) ([]byte, bool, error) {
+	parsed, err := parseFont(data)
+	if err != nil {
+		return nil, false, err
+	}
+	font, err := parseSFNTTables(data)
+	if err != nil {
+		return nil, false, err
+	}
+	maxp := font.tables["maxp"]
+	if len(maxp) < 6 {
+		return nil, false, fmt.Errorf("%w: missing maxp table", errUnsupportedFont)
+	}
+	numGlyphs := int(binary.BigEndian.Uint16(maxp[4:]))
+
+	_, isCFF := font.tables["CFF "]
+	var glyphs [][]byte
+	switch {
+	case isCFF:
+	case font.tables["glyf"] != nil:
+		if glyphs, err = readGlyf(font, numGlyphs); err != nil {
+			return nil, false, err
+		}
+	default:
+		return nil, false, fmt.Errorf("%w: no glyf or CFF outlines", errUnsupportedFont)
+	}
+
+	// Map the runes to glyphs in code point order, after .notdef
+	runes = slices.Clone(runes)
+	slices.Sort(runes)
+	order := []uint16{0}
+	keep := map[uint16]bool{0: true}
+	add := func(gid uint16) {
+		if int(gid) < numGlyphs && !keep[gid] {
+			keep[gid] = true
+			order = append(order, gid)
+		}
+	}
+	cmap := make(map[rune]uint16)
+	var buf sfnt.Buffer
+	for _, r := range slices.Compact(runes) {
+		index, err := parsed.GlyphIndex(&buf, r)
+		if err != nil || index == 0 || int(index) >= numGlyphs {
+			continue
+		}
+		cmap[r] = uint16(index)
+		add(uint16(index))
+	}
+
+	// Then the glyphs the kept ones need
+	vertical := verticalSubstitutions(font.tables["GSUB"])
+	for i := 0; i < len(order); i++ {
+		if target, ok := vertical[order[i]]; ok {
+			add(target)
+		}
+		if glyphs != nil {
+			for _, component := range glyfComponents(glyphs[order[i]]) {
+				add(component)
+			}
+		}
+	}
+	newGID := make(map[uint16]uint16, len(order))
+	for i, gid := range order {
+		newGID[gid] = uint16(i)
+	}
+
+	if isCFF {
+		cff, err := subsetCFF(font.tables["CFF "], order)
+		if err != nil {
+			return nil, false, err
+		}
+		font.tables["CFF "] = cff
+	} else {
+		writeGlyf(font, glyphs, order, newGID)
+	}
+
+	if err := subsetMetrics(font, "hhea", "hmtx", order); err != nil {
+		return nil, false, err
+	}
+	if font.tables["vhea"] != nil && font.tables["vmtx"] != nil {
+		if err := subsetMetrics(font, "vhea", "vmtx", order); err != nil {
+			return nil, false, err
+		}
+	} else {
+		delete(font.tables, "vhea")
+		delete(font.tables, "vmtx")
+	}
+	maxp = slices.Clone(maxp)
+	binary.BigEndian.PutUint16(maxp[4:], uint16(len(order)))
+	font.tables["maxp"] = maxp
+	// Version 3 of post has no glyph names, which refer to the original glyphs
+	if post := font.tables["post"]; len(post) >= 32 {
+		post = slices.Clone(post[:32])
+		binary.BigEndian.PutUint32(post, 0x00030000)
+		font.tables["post"] = post
+	}
+	font.tables["VORG"] = subsetVORG(font.tables["VORG"], newGID)
+	font.tables["GSUB"] = buildVerticalGSUB(vertical, newGID)
+	font.tables["cmap"] = buildCmap(cmap, newGID)
+
+	for tag, table := range font.tables {
+		if !subsetTables[tag] || table == nil {
+			delete(font.tables, tag)
+		}
+	}
+	return font.bytes(), isCFF, nil
+}
+
+// subsetMetrics rewrites a hmtx or vmtx table for the glyphs of order, all with long metrics,
+// and updates the metric count of its hhea or vhea header
+func subsetMetrics(font *sfntTables, headerTag, metricsTag string, order []uint16) error {
+	header, metrics := font.tables[headerTag], font.tables[metricsTag]
+	if len(header) < 36 {
+		return fmt.Errorf("%w: missing %s table", errUnsupportedFont, headerTag)
+	}
+	numLong := readUint16(header, 34)
+	if numLong == 0 || len(metrics) < 4*numLong {
+		return fmt.Errorf("%w: truncated %s table", errUnsupportedFont, metricsTag)
+	}
+
+	out := make([]byte, 4*len(order))
+	for i, gid := range order {
+		// Glyphs after the long metrics share the advance of the last one
+		long := min(int(gid), numLong-1)
+		copy(out[4*i:], metrics[4*long:4*long+2])
+		bearing := 4*int(gid) + 2
+		if int(gid) >= numLong {
+			bearing = 4*numLong + 2*(int(gid)-numLong)
+		}
+		if bearing+2 <= len(metrics) {
+			copy(out[4*i+2:], metrics[bearing:bearing+2])
+		}
+	}
+
+	header = slices.Clone(header)
+	binary.BigEndian.PutUint16(header[34:], uint16(len(order)))
+	font.tables[headerTag] = header
+	font.tables[metricsTag] = out
+	return nil
+}
+
+// subsetVORG renumbers the vertical origins of a VORG table, or returns nil without one
+func subsetVORG(vorg []byte, newGID map[uint16]uint16) []byte {
+	if len(vorg) < 8 {
+		return nil
+	}
+	type origin struct{ gid, y uint16 }
+	var origins []origin
+	for i := 0; i < readUint16(vorg, 6) && 8+4*i+4 <= len(vorg); i++ {
+		if gid, ok := newGID[uint16(readUint16(vorg, 8+4*i))]; ok {
+			origins = append(origins, origin{gid, uint16(readUint16(vorg, 8+4*i+2))})
+		}
+	}
+	slices.SortFunc(origins, func(a, b origin) int { return int(a.gid) - int(b.gid) })
+
+	out := binary.BigEndian.AppendUint16(slices.Clone(vorg[:6]), uint16(len(origins)))
+	for _, o := range origins {
+		out = binary.BigEndian.AppendUint16(out, o.gid)
+		out = binary.BigEndian.AppendUint16(out, o.y)
+	}
+	return out
+}
+
+// verticalSubstitutions returns the single substitutions of the vert and vrt2 features of a GSUB
+// table, which replace punctuation and brackets with their vertical forms
+func verticalSubstitutions(gsub []byte) map[uint16]uint16 {
+	substitutions := make(map[uint16]uint16)
+	if len(gsub) < 10 {
+		return substitutions
+	}
+	u16 := func(off int) int { return readUint16(gsub, off) }
+	featureList, lookupList := u16(6), u16(8)
+
+	var lookups []int
+	for i := 0; i < u16(featureList); i++ {
+		record := featureList + 2 + 6*i
+		if record+6 > len(gsub) {
+			break
+		}
+		if tag := string(gsub[record : record+4]); tag != "vert" && tag != "vrt2" {
+			continue
+		}
+		feature := featureList + u16(record+4)
+		for j := 0; j < u16(feature+2); j++ {
+			lookups = append(lookups, u16(feature+4+2*j))
+		}
+	}
+
+	for _, index := range lookups {
+		if index >= u16(lookupList) {
+			continue
+		}
+		lookup := lookupList + u16(lookupList+2+2*index)
+		lookupType := u16(lookup)
+		for j := 0; j < u16(lookup+4); j++ {
+			subtable := lookup + u16(lookup+6+2*j)
+			switch {
+			case lookupType == 1:
+			case lookupType == 7 && u16(subtable) == 1 && u16(subtable+2) == 1 && subtable+8 <= len(gsub):
+				// Extension subtables point to the actual subtable with a 32-bit offset
+				subtable += int(binary.BigEndian.Uint32(gsub[subtable+4:]))
+			default:
+				continue
+			}
+			format := u16(subtable)
+			for i, glyph := range coverageGlyphs(gsub, subtable+u16(subtable+2)) {
+				var target uint16
+				switch {
+				case format == 1:
+					target = glyph + uint16(u16(subtable+4))
+				case format == 2 && i < u16(subtable+4):
+					target = uint16(u16(subtable + 6 + 2*i))
+				default:
+					continue
+				}
+				if _, ok := substitutions[glyph]; !ok {
+					substitutions[glyph] = target
+				}
+			}
+		}
+	}
+	return substitutions
+}
+
+// buildVerticalGSUB builds a GSUB table enabling the vertical substitutions between kept glyphs
+// with the vert and vrt2 features for all scripts, or returns nil when there are none
+func buildVerticalGSUB(vertical map[uint16]uint16, newGID map[uint16]uint16) []byte {
+	type substitution struct{ glyph, target uint16 }
+	var substitutions []substitution
+	for glyph, target := range vertical {
+		newGlyph, ok := newGID[glyph]
+		newTarget, ok2 := newGID[target]
+		if ok && ok2 {
+			substitutions = append(substitutions, substitution{newGlyph, newTarget})
+		}
+	}
+	if len(substitutions) == 0 {
+		return nil
+	}
+	slices.SortFunc(substitutions, func(a, b substitution) int { return int(a.glyph) - int(b.glyph) })
+
+	var out []byte
+	u16s := func(values ...int) {
+		for _, v := range values {
+			out = binary.BigEndian.AppendUint16(out, uint16(v))
+		}
+	}
+	const (
+		scriptList  = 10
+		featureList = 50
+		lookupList  = 70
+	)
+	u16s(1, 0, scriptList, featureList, lookupList)
+
+	// Every script record points to the same script, whose default language enables both features
+	scripts := []string{"DFLT", "hani", "kana", "latn"}
+	u16s(len(scripts))
+	for _, tag := range scripts {
+		out = append(out, tag...)
+		u16s(2 + 6*len(scripts))
+	}
+	u16s(4, 0)
+	u16s(0, 0xFFFF, 2, 0, 1)
+
+	// Both features use the single lookup
+	u16s(2)
+	for _, tag := range []string{"vert", "vrt2"} {
+		out = append(out, tag...)
+		u16s(14)
+	}
+	u16s(0, 1, 0)
+
+	// The lookup has one format 2 single substitution subtable, followed by its coverage
+	n := len(substitutions)
+	u16s(1, 4)
+	u16s(1, 0, 1, 8)
+	u16s(2, 6+2*n, n)
+	for _, s := range substitutions {
+		u16s(int(s.target))
+	}
+	u16s(1, n)
+	for _, s := range substitutions {
+		u16s(int(s.glyph))
+	}
+	return out
+}
+
+// coverageGlyphs returns the glyphs of an OpenType coverage table in coverage index order
+func coverageGlyphs(table []byte, off int) []uint16 {
+	u16 := func(off int) int { return readUint16(table, off) }
+
+	var glyphs []uint16
+	switch u16(off) {
+	case 1:
+		for i := 0; i < u16(off+2); i++ {
+			glyphs = append(glyphs, uint16(u16(off+4+2*i)))
+		}
+	case 2:
+		for i := 0; i < u16(off+2); i++ {
+			record := off + 4 + 6*i
+			for g := u16(record); g <= u16(record+2) && g < 0x10000; g++ {
+				glyphs = append(glyphs, uint16(g))
+			}
+		}
+	}
+	return glyphs
+}
+
+// readGlyf returns the TrueType outline of each glyph
+func readGlyf(font *sfntTables, numGlyphs int) ([][]byte, error) {
+	head, glyf, loca := font.tables["head"], font.tables["glyf"], font.tables["loca"]
+	if len(head) < 54 || loca == nil {
+		return nil, fmt.Errorf("%w: missing head or loca table", errUnsupportedFont)
+	}
+	longLoca := binary.BigEndian.Uint16(head[50:]) == 1
+	if (longLoca && len(loca) < 4*(numGlyphs+1)) || len(loca) < 2*(numGlyphs+1) {
+		return nil, fmt.Errorf("%w: truncated loca table", errUnsupportedFont)
+	}
+
+	glyphs := make([][]byte, numGlyphs)
+	for gid := range glyphs {
+		var start, end int
+		if longLoca {
+			start = int(binary.BigEndian.Uint32(loca[4*gid:]))
+			end = int(binary.BigEndian.Uint32(loca[4*gid+4:]))
+		} else {
+			start = 2 * int(binary.BigEndian.Uint16(loca[2*gid:]))
+			end = 2 * int(binary.BigEndian.Uint16(loca[2*gid+2:]))
+		}
+		if start > end || end > len(glyf) {
+			return nil, fmt.Errorf("%w: glyph %d is out of bounds", errUnsupportedFont, gid)
+		}
+		glyphs[gid] = glyf[start:end]
+	}
+	return glyphs, nil
+}
+
+// writeGlyf replaces glyf with the outlines of the glyphs of order, renumbering the components
+// of composite glyphs, and loca with their offsets in the long format
+func writeGlyf(font *sfntTables, glyphs [][]byte, order []uint16, newGID map[uint16]uint16) {
+	var glyf []byte
+	loca := make([]byte, 4*(len(order)+1))
+	for i, gid := range order {
+		binary.BigEndian.PutUint32(loca[4*i:], uint32(len(glyf)))
+		start := len(glyf)
+		glyf = append(glyf, glyphs[gid]...)
+		for _, off := range glyfComponentOffsets(glyphs[gid]) {
+			component := binary.BigEndian.Uint16(glyf[start+off:])
+			binary.BigEndian.PutUint16(glyf[start+off:], newGID[component])
+		}
+		for len(glyf)%4 != 0 {
+			glyf = append(glyf, 0)
+		}
+	}
+	binary.BigEndian.PutUint32(loca[4*len(order):], uint32(len(glyf)))
+
+	head := slices.Clone(font.tables["head"])
+	binary.BigEndian.PutUint16(head[50:], 1)
+	font.tables["head"] = head
+	font.tables["glyf"] = glyf
+	font.tables["loca"] = loca
+}
+
+// glyfComponents returns the glyphs referenced by a composite TrueType glyph
+func glyfComponents(data []byte) []uint16 {
+	var components []uint16
+	for _, off := range glyfComponentOffsets(data) {
+		components = append(components, binary.BigEndian.Uint16(data[off:]))
+	}
+	return components
+}
+
+// glyfComponentOffsets returns the offsets of the glyph indices in a composite TrueType glyph
+func glyfComponentOffsets(data []byte) []int {
+	if len(data) < 10 || int16(binary.BigEndian.Uint16(data)) >= 0 {
+		return nil
+	}
+	const (
+		argsAreWords    = 0x0001
+		haveScale       = 0x0008
+		moreComponents  = 0x0020
+		haveXYScale     = 0x0040
+		haveTwoByTwo    = 0x0080
+		componentHeader = 4
+	)
+
+	var offsets []int
+	for off := 10; off+componentHeader <= len(data); {
+		flags := binary.BigEndian.Uint16(data[off:])
+		offsets = append(offsets, off+2)
+		off += componentHeader
+		if flags&argsAreWords != 0 {
+			off += 4
+		} else {
+			off += 2
+		}
+		switch {
+		case flags&haveScale != 0:
+			off += 2
+		case flags&haveXYScale != 0:
+			off += 4
+		case flags&haveTwoByTwo != 0:
+			off += 8
+		}
+		if flags&moreComponents == 0 {
+			break
+		}
+	}
+	return offsets
+}
+
+// maxFormat4Segments is the number of segments, besides the final one, fitting in the 16-bit
+// length of a format 4 cmap subtable
+const maxFormat4Segments = (0xFFFF-16)/8 - 1
+
+// cmapGroup maps the consecutive characters first to last to consecutive glyphs from glyph
+type cmapGroup struct {
+	first, last rune
+	glyph       uint16
+}
+
+// cmapGroups maps the characters of cmap to the renumbered glyphs, merging consecutive characters
+// with consecutive glyphs into one group
+func cmapGroups(cmap map[rune]uint16, newGID map[uint16]uint16) []cmapGroup {
+	runes := make([]rune, 0, len(cmap))
+	for r := range cmap {
+		runes = append(runes, r)
+	}
+	slices.Sort(runes)
+
+	var groups []cmapGroup
+	for _, r := range runes {
+		glyph := newGID[cmap[r]]
+		if n := len(groups); n > 0 {
+			last := &groups[n-1]
+			if r == last.last+1 && int(glyph) == int(last.glyph)+int(r-last.first) {
+				last.last = r
+				continue
+			}
+		}
+		groups = append(groups, cmapGroup{first: r, last: r, glyph: glyph})
+	}
+	return groups
+}
+
+// buildCmap builds a cmap table with a format 4 subtable for the Basic Multilingual Plane and,
+// when it cannot hold all characters, a format 12 subtable for all of them
+func buildCmap(cmap map[rune]uint16, newGID map[uint16]uint16) []byte {
+	groups := cmapGroups(cmap, newGID)
+
+	// Format 4 takes the groups below U+FFFF while they fit, plus the required final segment
+	var segments []cmapGroup
+	complete := true
+	for _, g := range groups {
+		if g.first >= 0xFFFF || len(segments) == maxFormat4Segments {
+			complete = false
+			break
+		}
+		if g.last >= 0xFFFF {
+			g.last = 0xFFFE
+			complete = false
+		}
+		segments = append(segments, g)
+	}
+	segments = append(segments, cmapGroup{first: 0xFFFF, last: 0xFFFF, glyph: 0})
+
+	segCount := len(segments)
+	entrySelector := 0
+	for 1<<(entrySelector+1) <= segCount {
+		entrySelector++
+	}
+	searchRange := 2 * (1 << entrySelector)
+	format4 := make([]byte, 16+8*segCount)
+	binary.BigEndian.PutUint16(format4[0:], 4)
+	binary.BigEndian.PutUint16(format4[2:], uint16(len(format4)))
+	binary.BigEndian.PutUint16(format4[6:], uint16(2*segCount))
+	binary.BigEndian.PutUint16(format4[8:], uint16(searchRange))
+	binary.BigEndian.PutUint16(format4[10:], uint16(entrySelector))
+	binary.BigEndian.PutUint16(format4[12:], uint16(2*segCount-searchRange))
+	endCodes := format4[14:]
+	startCodes := format4[16+2*segCount:]
+	idDeltas := format4[16+4*segCount:]
+	for i, s := range segments {
+		binary.BigEndian.PutUint16(endCodes[2*i:], uint16(s.last))
+		binary.BigEndian.PutUint16(startCodes[2*i:], uint16(s.first))
+		binary.BigEndian.PutUint16(idDeltas[2*i:], s.glyph-uint16(s.first))
+	}
+
+	subtables := [][]byte{format4}
+	records := [][2]uint16{{3, 1}}
+	if !complete {
+		format12 := make([]byte, 16+12*len(groups))
+		binary.BigEndian.PutUint16(format12[0:], 12)
+		binary.BigEndian.PutUint32(format12[4:], uint32(len(format12)))
+		binary.BigEndian.PutUint32(format12[12:], uint32(len(groups)))
+		for i, g := range groups {
+			group := format12[16+12*i:]
+			binary.BigEndian.PutUint32(group[0:], uint32(g.first))
+			binary.BigEndian.PutUint32(group[4:], uint32(g.last))
+			binary.BigEndian.PutUint32(group[8:], uint32(g.glyph))
+		}
+		subtables = append(subtables, format12)
+		records = append(records, [2]uint16{3, 10})
+	}
+
+	out := make([]byte, 4+8*len(records))
+	binary.BigEndian.PutUint16(out[2:], uint16(len(records)))
+	for i, record := range records {
+		binary.BigEndian.PutUint16(out[4+8*i:], record[0])
+		binary.BigEndian.PutUint16(out[6+8*i:], record[1])
+		binary.BigEndian.PutUint32(out[8+8*i:], uint32(len(out)))
+		out = append(out, subtables[i]...)
+	}
+	return out
+}
+
+// readUint16 reads a big-endian uint16 at off, or returns 0 when off is out of bounds
+func readUint16(data []byte, off int) int {
+	if off < 0 || off+2 > len(data) {
+		return 0
+	}
+	return int(binary.BigEndian.Uint16(data[off:]))
+}
@@ -0,0 +1,491 @@
+package jplaw2epub
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+)
+
+// CFF Top DICT and Private DICT operators holding offsets
+const (
+	cffOpCharset     = 15
+	cffOpEncoding    = 16
+	cffOpCharStrings = 17
+	cffOpPrivate     = 18
+	cffOpSubrs       = 19
+	cffOpFDArray     = 12<<8 | 36
+	cffOpFDSelect    = 12<<8 | 37
+)
+
+// cffDictEntry is an operator of a CFF DICT with its operands
+type cffDictEntry struct {
+	op int
+	// raw holds the encoded operands, ints their values (reals are read as 0)
+	raw  []byte
+	ints []int
+}
+
+// cffDict is a parsed CFF DICT
+type cffDict []cffDictEntry
+
+// parseCFFDict parses DICT data
+func parseCFFDict(data []byte) (cffDict, error) {
+	var dict cffDict
+	var entry cffDictEntry
+	start := 0
+	for i := 0; i < len(data); {
+		b := int(data[i])
+		switch {
+		case b <= 21:
+			op := b
+			i++
+			if b == 12 {
+				if i >= len(data) {
+					return nil, fmt.Errorf("%w: truncated CFF DICT", errUnsupportedFont)
+				}
+				op = 12<<8 | int(data[i])
+				i++
+			}
+			entry.op = op
+			dict = append(dict, entry)
+			entry = cffDictEntry{}
+			start = i
+			continue
+		case b == 28 && i+3 <= len(data):
+			entry.ints = append(entry.ints, int(int16(binary.BigEndian.Uint16(data[i+1:]))))
+			i += 3
+		case b == 29 && i+5 <= len(data):
+			entry.ints = append(entry.ints, int(int32(binary.BigEndian.Uint32(data[i+1:]))))
+			i += 5
+		case b == 30:
+			// Real numbers end with a 0xf nibble
+			for i++; i < len(data) && data[i]&0x0f != 0x0f && data[i]&0xf0 != 0xf0; i++ {
+			}
+			i++
+			entry.ints = append(entry.ints, 0)
+		case b >= 32 && b <= 246:
+			entry.ints = append(entry.ints, b-139)
+			i++
+		case b >= 247 && b <= 250 && i+2 <= len(data):
+			entry.ints = append(entry.ints, (b-247)*256+int(data[i+1])+108)
+			i += 2
+		case b >= 251 && b <= 254 && i+2 <= len(data):
+			entry.ints = append(entry.ints, -(b-251)*256-int(data[i+1])-108)
+			i += 2
+		default:
+			return nil, fmt.Errorf("%w: invalid CFF DICT operand", errUnsupportedFont)
+		}
+		if i > len(data) {
+			return nil, fmt.Errorf("%w: truncated CFF DICT", errUnsupportedFont)
+		}
+		entry.raw = data[start:i]
+	}
+	return dict, nil
+}
+
+// get returns the operands of op
+func (d cffDict) get(op int) ([]int, bool) {
+	for _, entry := range d {
+		if entry.op == op {
+			return entry.ints, true
+		}
+	}
+	return nil, false
+}
+
+// without returns a copy of the DICT without the operator op
+func (d cffDict) without(op int) cffDict {
+	return slices.DeleteFunc(slices.Clone(d), func(entry cffDictEntry) bool { return entry.op == op })
+}
+
+// encode serializes the DICT, writing the operands of ops in offsets as 5-byte integers
+// so the encoded size does not depend on their values
+func (d cffDict) encode(offsets map[int][]int) []byte {
+	var out []byte
+	for _, entry := range d {
+		if values, ok := offsets[entry.op]; ok {
+			for _, v := range values {
+				out = append(out, 29, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+			}
+		} else {
+			out = append(out, entry.raw...)
+		}
+		if entry.op > 0xff {
+			out = append(out, 12, byte(entry.op))
+		} else {
+			out = append(out, byte(entry.op))
+		}
+	}
+	return out
+}
+
+// readCFFIndex reads an INDEX at off, returning its items and the offset following it
+func readCFFIndex(data []byte, off int) ([][]byte, int, error) {
+	if off+2 > len(data) {
+		return nil, 0, fmt.Errorf("%w: truncated CFF INDEX", errUnsupportedFont)
+	}
+	count := int(binary.BigEndian.Uint16(data[off:]))
+	if count == 0 {
+		return nil, off + 2, nil
+	}
+	if off+3 > len(data) {
+		return nil, 0, fmt.Errorf("%w: truncated CFF INDEX", errUnsupportedFont)
+	}
+	offSize := int(data[off+2])
+	if offSize < 1 || offSize > 4 || off+3+(count+1)*offSize > len(data) {
+		return nil, 0, fmt.Errorf("%w: invalid CFF INDEX", errUnsupportedFont)
+	}
+	readOffset := func(i int) int {
+		v := 0
+		for _, b := range data[off+3+i*offSize : off+3+(i+1)*offSize] {
+			v = v<<8 | int(b)
+		}
+		return v
+	}
+	base := off + 3 + (count+1)*offSize - 1
+	items := make([][]byte, count)
+	for i := range items {
+		start, end := base+readOffset(i), base+readOffset(i+1)
+		if start > end || end > len(data) {
+			return nil, 0, fmt.Errorf("%w: invalid CFF INDEX", errUnsupportedFont)
+		}
+		items[i] = data[start:end]
+	}
+	return items, base + readOffset(count), nil
+}
+
+// encodeCFFIndex serializes an INDEX with the smallest offset size
+func encodeCFFIndex(items [][]byte) []byte {
+	if len(items) == 0 {
+		return []byte{0, 0}
+	}
+	total := 1
+	for _, item := range items {
+		total += len(item)
+	}
+	offSize := 1
+	for total >= 1<<(8*offSize) {
+		offSize++
+	}
+
+	out := []byte{byte(len(items) >> 8), byte(len(items)), byte(offSize)}
+	writeOffset := func(v int) {
+		for i := offSize - 1; i >= 0; i-- {
+			out = append(out, byte(v>>(8*i)))
+		}
+	}
+	offset := 1
+	writeOffset(offset)
+	for _, item := range items {
+		offset += len(item)
+		writeOffset(offset)
+	}
+	for _, item := range items {
+		out = append(out, item...)
+	}
+	return out
+}
+
+// cffPrivate returns a Private DICT with its local subroutines, which follow it
+func cffPrivate(data []byte, size, off int) ([]byte, error) {
+	if off < 0 || size < 0 || off+size > len(data) {
+		return nil, fmt.Errorf("%w: Private DICT is out of bounds", errUnsupportedFont)
+	}
+	dict, err := parseCFFDict(data[off : off+size])
+	if err != nil {
+		return nil, err
+	}
+	subrs, ok := dict.get(cffOpSubrs)
+	if !ok || len(subrs) != 1 {
+		return data[off : off+size], nil
+	}
+	if subrs[0] < size {
+		return nil, fmt.Errorf("%w: local subroutines overlap the Private DICT", errUnsupportedFont)
+	}
+	_, end, err := readCFFIndex(data, off+subrs[0])
+	if err != nil {
+		return nil, err
+	}
+	return data[off:end], nil
+}
+
+// cffCharset returns the SID of each glyph, or its CID in CID-keyed fonts
+func cffCharset(data []byte, off, numGlyphs int) ([]int, error) {
+	ids := make([]int, numGlyphs)
+	switch {
+	case off == 0:
+		// In the ISOAdobe charset the SID of a glyph is its index
+		for gid := range ids {
+			ids[gid] = gid
+		}
+		return ids, nil
+	case off <= 2:
+		return nil, fmt.Errorf("%w: expert charsets", errUnsupportedFont)
+	case off >= len(data):
+		return nil, fmt.Errorf("%w: charset is out of bounds", errUnsupportedFont)
+	}
+
+	format := data[off]
+	pos := off + 1
+	for gid := 1; gid < numGlyphs; {
+		switch format {
+		case 0:
+			if pos+2 > len(data) {
+				return nil, fmt.Errorf("%w: truncated charset", errUnsupportedFont)
+			}
+			ids[gid] = readUint16(data, pos)
+			gid++
+			pos += 2
+		case 1, 2:
+			rangeSize := 3
+			if format == 2 {
+				rangeSize = 4
+			}
+			if pos+rangeSize > len(data) {
+				return nil, fmt.Errorf("%w: truncated charset", errUnsupportedFont)
+			}
+			first, nLeft := readUint16(data, pos), int(data[pos+2])
+			if format == 2 {
+				nLeft = readUint16(data, pos+2)
+			}
+			for i := 0; i <= nLeft && gid < numGlyphs; i++ {
+				ids[gid] = first + i
+				gid++
+			}
+			pos += rangeSize
+		default:
+			return nil, fmt.Errorf("%w: unknown charset format", errUnsupportedFont)
+		}
+	}
+	return ids, nil
+}
+
+// cffFDSelect returns the Font DICT of each glyph of a CID-keyed font
+func cffFDSelect(data []byte, off, numGlyphs int) ([]byte, error) {
+	if off >= len(data) {
+		return nil, fmt.Errorf("%w: FDSelect is out of bounds", errUnsupportedFont)
+	}
+	switch data[off] {
+	case 0:
+		if off+1+numGlyphs > len(data) {
+			return nil, fmt.Errorf("%w: truncated FDSelect", errUnsupportedFont)
+		}
+		return data[off+1 : off+1+numGlyphs], nil
+	case 3:
+		nRanges := readUint16(data, off+1)
+		if off+3+3*nRanges+2 > len(data) {
+			return nil, fmt.Errorf("%w: truncated FDSelect", errUnsupportedFont)
+		}
+		fds := make([]byte, numGlyphs)
+		for i := 0; i < nRanges; i++ {
+			// Each range ends where the next one, or the sentinel, starts
+			record := off + 3 + 3*i
+			for gid := readUint16(data, record); gid < readUint16(data, record+3) && gid < numGlyphs; gid++ {
+				fds[gid] = data[record+2]
+			}
+		}
+		return fds, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown FDSelect format", errUnsupportedFont)
+	}
+}
+
+// subsetCFF rebuilds a CFF table with the charstrings of the glyphs of order, renumbered by
+// their position in it
+func subsetCFF(data []byte, order []uint16) ([]byte, error) {
+	if len(data) < 4 || data[0] != 1 {
+		return nil, fmt.Errorf("%w: only CFF version 1 is supported", errUnsupportedFont)
+	}
+	hdrSize := int(data[2])
+	names, off, err := readCFFIndex(data, hdrSize)
+	if err != nil {
+		return nil, err
+	}
+	topDicts, off, err := readCFFIndex(data, off)
+	if err != nil {
+		return nil, err
+	}
+	if len(topDicts) != 1 {
+		return nil, fmt.Errorf("%w: CFF with %d fonts", errUnsupportedFont, len(topDicts))
+	}
+	stringsStart := off
+	_, off, err = readCFFIndex(data, off)
+	if err != nil {
+		return nil, err
+	}
+	_, off, err = readCFFIndex(data, off)
+	if err != nil {
+		return nil, err
+	}
+	// The String and Global Subr INDEXes are copied unchanged
+	stringsAndGlobalSubrs := data[stringsStart:off]
+
+	top, err := parseCFFDict(topDicts[0])
+	if err != nil {
+		return nil, err
+	}
+	charStringsOff, ok := top.get(cffOpCharStrings)
+	if !ok || len(charStringsOff) != 1 {
+		return nil, fmt.Errorf("%w: missing CharStrings", errUnsupportedFont)
+	}
+	charStrings, _, err := readCFFIndex(data, charStringsOff[0])
+	if err != nil {
+		return nil, err
+	}
+	numGlyphs := len(charStrings)
+
+	charsetOff := 0
+	if v, ok := top.get(cffOpCharset); ok && len(v) == 1 {
+		charsetOff = v[0]
+	}
+	ids, err := cffCharset(data, charsetOff, numGlyphs)
+	if err != nil {
+		return nil, err
+	}
+	var fds []byte
+	if v, ok := top.get(cffOpFDSelect); ok && len(v) == 1 {
+		if fds, err = cffFDSelect(data, v[0], numGlyphs); err != nil {
+			return nil, err
+		}
+	}
+
+	// The charset and FDSelect are written in format 0, listing every glyph
+	newCharStrings := make([][]byte, len(order))
+	charset := []byte{0}
+	fdSelect := []byte{0}
+	for i, gid := range order {
+		if int(gid) >= numGlyphs {
+			return nil, fmt.Errorf("%w: glyph %d is out of bounds", errUnsupportedFont, gid)
+		}
+		newCharStrings[i] = charStrings[gid]
+		if i > 0 {
+			charset = binary.BigEndian.AppendUint16(charset, uint16(ids[gid]))
+		}
+		if fds != nil {
+			fdSelect = append(fdSelect, fds[gid])
+		}
+	}
+
+	// The encoding maps character codes to the original glyphs; OpenType fonts use cmap instead
+	top = top.without(cffOpEncoding)
+	if _, ok := top.get(cffOpCharset); !ok {
+		top = append(top, cffDictEntry{op: cffOpCharset})
+	}
+
+	// Collect the blocks following the INDEXes, whose offsets are rewritten
+	type block struct {
+		op   int
+		data []byte
+	}
+	blocks := []block{{cffOpCharset, charset}}
+	if fds != nil {
+		blocks = append(blocks, block{cffOpFDSelect, fdSelect})
+	}
+	blocks = append(blocks, block{cffOpCharStrings, encodeCFFIndex(newCharStrings)})
+
+	var topPrivate []byte
+	var topPrivateSize int
+	if v, ok := top.get(cffOpPrivate); ok && len(v) == 2 {
+		if topPrivate, err = cffPrivate(data, v[0], v[1]); err != nil {
+			return nil, err
+		}
+		topPrivateSize = v[0]
+	}
+
+	// Font DICTs of CID-keyed fonts each have their own Private DICT
+	var fontDicts []cffDict
+	var fdPrivates [][]byte
+	var fdPrivateSizes []int
+	if v, ok := top.get(cffOpFDArray); ok && len(v) == 1 {
+		items, _, err := readCFFIndex(data, v[0])
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			fd, err := parseCFFDict(item)
+			if err != nil {
+				return nil, err
+			}
+			var private []byte
+			size := 0
+			if pv, ok := fd.get(cffOpPrivate); ok && len(pv) == 2 {
+				if private, err = cffPrivate(data, pv[0], pv[1]); err != nil {
+					return nil, err
+				}
+				size = pv[0]
+			}
+			fontDicts = append(fontDicts, fd)
+			fdPrivates = append(fdPrivates, private)
+			fdPrivateSizes = append(fdPrivateSizes, size)
+		}
+	}
+
+	// The encoded Top DICT size does not depend on the offsets, so it is measured with zeros
+	topOffsets := map[int][]int{}
+	for _, b := range blocks {
+		topOffsets[b.op] = []int{0}
+	}
+	if topPrivate != nil {
+		topOffsets[cffOpPrivate] = []int{0, 0}
+	}
+	if fontDicts != nil {
+		topOffsets[cffOpFDArray] = []int{0}
+	}
+	headerAndNames := append(append([]byte{}, data[:hdrSize]...), encodeCFFIndex(names)...)
+	topIndexSize := len(encodeCFFIndex([][]byte{top.encode(topOffsets)}))
+
+	pos := len(headerAndNames) + topIndexSize + len(stringsAndGlobalSubrs)
+	for _, b := range blocks {
+		topOffsets[b.op] = []int{pos}
+		pos += len(b.data)
+	}
+
+	// Lay out the FDArray, then the Private DICTs
+	fdOffsets := make([]map[int][]int, len(fontDicts))
+	fdArraySize := 0
+	if fontDicts != nil {
+		encoded := make([][]byte, len(fontDicts))
+		for i, fd := range fontDicts {
+			fdOffsets[i] = map[int][]int{}
+			if fdPrivates[i] != nil {
+				fdOffsets[i][cffOpPrivate] = []int{0, 0}
+			}
+			encoded[i] = fd.encode(fdOffsets[i])
+		}
+		topOffsets[cffOpFDArray] = []int{pos}
+		fdArraySize = len(encodeCFFIndex(encoded))
+		pos += fdArraySize
+	}
+	if topPrivate != nil {
+		topOffsets[cffOpPrivate] = []int{topPrivateSize, pos}
+		pos += len(topPrivate)
+	}
+	for i := range fontDicts {
+		if fdPrivates[i] != nil {
+			fdOffsets[i][cffOpPrivate] = []int{fdPrivateSizes[i], pos}
+			pos += len(fdPrivates[i])
+		}
+	}
+
+	out := headerAndNames
+	out = append(out, encodeCFFIndex([][]byte{top.encode(topOffsets)})...)
+	out = append(out, stringsAndGlobalSubrs...)
+	for _, b := range blocks {
+		out = append(out, b.data...)
+	}
+	if fontDicts != nil {
+		encoded := make([][]byte, len(fontDicts))
+		for i, fd := range fontDicts {
+			encoded[i] = fd.encode(fdOffsets[i])
+		}
+		out = append(out, encodeCFFIndex(encoded)...)
+	}
+	out = append(out, topPrivate...)
+	for _, private := range fdPrivates {
+		out = append(out, private...)
+	}
+	if len(out) != pos {
+		return nil, fmt.Errorf("%w: CFF layout mismatch", errUnsupportedFont)
+	}
+	return out, nil
+}
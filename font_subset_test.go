@@ -0,0 +1,219 @@
+package jplaw2epub
+
+import (
+	"bytes"
+	"encoding/binary"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// glyphSegments returns the number of outline segments of a glyph
+func glyphSegments(t *testing.T, f *sfnt.Font, index sfnt.GlyphIndex) int {
+	t.Helper()
+	var buf sfnt.Buffer
+	segments, err := f.LoadGlyph(&buf, index, fixed.I(16), nil)
+	if err != nil {
+		t.Fatalf("LoadGlyph(%d) error = %v", index, err)
+	}
+	return len(segments)
+}
+
+// glyphIndex returns the glyph of r in f
+func glyphIndex(t *testing.T, f *sfnt.Font, r rune) sfnt.GlyphIndex {
+	t.Helper()
+	var buf sfnt.Buffer
+	index, err := f.GlyphIndex(&buf, r)
+	if err != nil {
+		t.Fatalf("GlyphIndex(%q) error = %v", r, err)
+	}
+	return index
+}
+
+func TestSubsetFont(t *testing.T) {
+	cff, err := os.ReadFile(filepath.Join("testdata", "fonts", "CFFTest.otf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		font []byte
+		// keep is in code point order, the order of the glyphs in the subset
+		keep       []rune
+		dropped    rune
+		wantGlyphs int
+		wantCFF    bool
+	}{
+		{name: "TrueType", font: goregular.TTF, keep: []rune(" Lawé"), dropped: 'x', wantGlyphs: 6},
+		{name: "CFF", font: cff, keep: []rune("1中"), dropped: '0', wantGlyphs: 3, wantCFF: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original, err := parseFont(tt.font)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, isCFF, err := subsetFont(tt.font, tt.keep)
+			if err != nil {
+				t.Fatalf("subsetFont() error = %v", err)
+			}
+			if isCFF != tt.wantCFF {
+				t.Errorf("subsetFont() isCFF = %t, want %t", isCFF, tt.wantCFF)
+			}
+			subset, err := parseFont(data)
+			if err != nil {
+				t.Fatalf("parsing subset: %v", err)
+			}
+			if subset.NumGlyphs() != tt.wantGlyphs {
+				t.Errorf("NumGlyphs() = %d, want %d", subset.NumGlyphs(), tt.wantGlyphs)
+			}
+
+			var buf sfnt.Buffer
+			previous := sfnt.GlyphIndex(0)
+			for _, r := range tt.keep {
+				index, want := glyphIndex(t, subset, r), glyphIndex(t, original, r)
+				if index == 0 || index >= sfnt.GlyphIndex(subset.NumGlyphs()) {
+					t.Fatalf("glyph of %q = %d, want a glyph of the subset", r, index)
+				}
+				if index <= previous {
+					t.Errorf("glyph of %q = %d, want glyphs in code point order", r, index)
+				}
+				previous = index
+				if got, want := glyphSegments(t, subset, index), glyphSegments(t, original, want); got != want {
+					t.Errorf("glyph of %q has %d outline segments, want %d", r, got, want)
+				}
+				got, _ := subset.GlyphAdvance(&buf, index, fixed.I(16), 0)
+				wantAdvance, _ := original.GlyphAdvance(&buf, want, fixed.I(16), 0)
+				if got != wantAdvance {
+					t.Errorf("advance of %q = %v, want %v", r, got, wantAdvance)
+				}
+			}
+
+			if index := glyphIndex(t, subset, tt.dropped); index != 0 {
+				t.Errorf("dropped %q still maps to glyph %d", tt.dropped, index)
+			}
+			if sum := sfntChecksum(data); sum != 0xB1B0AFBA {
+				t.Errorf("font checksum = %#x, want 0xb1b0afba", sum)
+			}
+			if len(data) >= len(tt.font) {
+				t.Errorf("subset size = %d, want less than %d", len(data), len(tt.font))
+			}
+		})
+	}
+}
+
+func TestSubsetFontInvalid(t *testing.T) {
+	if _, _, err := subsetFont([]byte("not a font"), []rune("a")); err == nil {
+		t.Error("expected an error for invalid font data")
+	}
+}
+
+// identityGIDs maps the glyphs of cmap to themselves
+func identityGIDs(cmap map[rune]uint16) map[uint16]uint16 {
+	gids := make(map[uint16]uint16, len(cmap))
+	for _, gid := range cmap {
+		gids[gid] = gid
+	}
+	return gids
+}
+
+func TestBuildCmap(t *testing.T) {
+	alphabet := make(map[rune]uint16)
+	for r := 'a'; r <= 'z'; r++ {
+		alphabet[r] = uint16(r - 'a' + 1)
+	}
+	// Consecutive characters with glyphs that are not consecutive need a segment each
+	kanji := make(map[rune]uint16)
+	for i := 0; i < 10000; i++ {
+		kanji[0x4E00+rune(i)] = uint16(2*i + 1)
+	}
+
+	tests := []struct {
+		name         string
+		cmap         map[rune]uint16
+		wantSegments int
+		wantGroups   int
+	}{
+		{name: "ranges", cmap: alphabet, wantSegments: 2},
+		{name: "supplementary plane", cmap: map[rune]uint16{'a': 1, 0x20B9F: 2}, wantSegments: 2, wantGroups: 2},
+		{name: "more segments than format 4 holds", cmap: kanji, wantSegments: maxFormat4Segments + 1, wantGroups: 10000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmap := buildCmap(tt.cmap, identityGIDs(tt.cmap))
+			wantSubtables := 1
+			if tt.wantGroups > 0 {
+				wantSubtables = 2
+			}
+			if got := readUint16(cmap, 2); got != wantSubtables {
+				t.Fatalf("cmap subtables = %d, want %d", got, wantSubtables)
+			}
+
+			format4 := int(binary.BigEndian.Uint32(cmap[8:]))
+			if got := readUint16(cmap, format4+6) / 2; got != tt.wantSegments {
+				t.Errorf("format 4 segments = %d, want %d", got, tt.wantSegments)
+			}
+			if got, want := readUint16(cmap, format4+2), 16+8*tt.wantSegments; got != want {
+				t.Errorf("format 4 length = %d, want %d", got, want)
+			}
+
+			if tt.wantGroups > 0 {
+				if got := readUint16(cmap, 4+8+2); got != 10 {
+					t.Errorf("second subtable encoding = %d, want 10", got)
+				}
+				format12 := int(binary.BigEndian.Uint32(cmap[16:]))
+				if got := int(binary.BigEndian.Uint32(cmap[format12+12:])); got != tt.wantGroups {
+					t.Errorf("format 12 groups = %d, want %d", got, tt.wantGroups)
+				}
+			}
+		})
+	}
+}
+
+func TestVerticalGSUB(t *testing.T) {
+	vertical := map[uint16]uint16{3: 7, 4: 8, 5: 9}
+	// The glyph 8 is not kept, so the substitution of 4 is dropped
+	newGID := map[uint16]uint16{0: 0, 3: 1, 4: 2, 5: 3, 7: 4, 9: 5}
+
+	gsub := buildVerticalGSUB(vertical, newGID)
+	got := verticalSubstitutions(gsub)
+	want := map[uint16]uint16{1: 4, 3: 5}
+	if !maps.Equal(got, want) {
+		t.Errorf("verticalSubstitutions() = %v, want %v", got, want)
+	}
+
+	if gsub := buildVerticalGSUB(vertical, map[uint16]uint16{0: 0, 3: 1}); gsub != nil {
+		t.Errorf("buildVerticalGSUB() = %d bytes, want nil without substitutions", len(gsub))
+	}
+}
+
+func TestWriteGlyfRenumbersComponents(t *testing.T) {
+	simple := []byte{0, 1, 0, 0, 0, 0, 0, 10, 0, 10, 0, 0}
+	// A composite glyph with one component, glyph 1, with byte offsets
+	composite := []byte{0xff, 0xff, 0, 0, 0, 0, 0, 10, 0, 10, 0, 0, 0, 1, 0, 0}
+	glyphs := [][]byte{nil, simple, composite}
+
+	font := &sfntTables{tables: map[string][]byte{"head": make([]byte, 54)}}
+	order := []uint16{0, 2, 1}
+	writeGlyf(font, glyphs, order, map[uint16]uint16{0: 0, 2: 1, 1: 2})
+
+	got, err := readGlyf(font, len(order))
+	if err != nil {
+		t.Fatalf("readGlyf() error = %v", err)
+	}
+	if components := glyfComponents(got[1]); !slices.Equal(components, []uint16{2}) {
+		t.Errorf("components of the composite glyph = %v, want [2]", components)
+	}
+	if !bytes.Equal(got[2], simple) {
+		t.Errorf("glyph 2 = %v, want the simple glyph", got[2])
+	}
+}
@@ -0,0 +1,108 @@
+package jplaw2epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"unicode"
+)
+
+// Embedded font locations and names
+const (
+	embeddedFontFamily  = "jplaw2epub-embedded"
+	embeddedFontBase    = "EPUB/fonts/embedded"
+	embeddedFontCSSPath = "EPUB/css/fonts.css"
+)
+
+// embeddedFontCSS declares the embedded font and uses it for all text, falling back to the
+// fonts of the default stylesheet for characters it lacks
+const embeddedFontCSS = `@font-face {
+    font-family: "%s";
+    src: url("../fonts/%s");
+}
+
+body, rt {
+    font-family: "%s", "Hiragino Kaku Gothic ProN", "ヒラギノ角ゴ ProN W3", "Meiryo", "メイリオ", sans-serif;
+}
+`
+
+// embedFont adds font, subset to the characters of the content documents, with a stylesheet
+// linked from every content document
+func embedFont(archive *epubArchive, font []byte) error {
+	pkg := archive.file(epubPackagePath)
+	if pkg == nil {
+		return fmt.Errorf("package document %s not found", epubPackagePath)
+	}
+
+	runes, err := contentRunes(archive)
+	if err != nil {
+		return err
+	}
+	subset, isCFF, err := subsetFont(font, runes)
+	if err != nil {
+		return fmt.Errorf("subsetting font: %w", err)
+	}
+
+	fontPath, mediaType := embeddedFontBase+".ttf", "font/ttf"
+	if isCFF {
+		fontPath, mediaType = embeddedFontBase+".otf", "font/otf"
+	}
+	fontName := path.Base(fontPath)
+	css := fmt.Sprintf(embeddedFontCSS, embeddedFontFamily, fontName, embeddedFontFamily)
+
+	if err := addLinkedStylesheet(archive, embeddedFontCSSPath, "jplaw2epub-font-css", css); err != nil {
+		return err
+	}
+
+	fontHref, err := relativeManifestHref(fontPath)
+	if err != nil {
+		return err
+	}
+	item := fmt.Sprintf(`  <item id="jplaw2epub-font" href="%s" media-type="%s"></item>
+  </manifest>`, fontHref, mediaType)
+	pkg.data = bytes.Replace(pkg.data, []byte("</manifest>"), []byte(item), 1)
+
+	archive.files = append(archive.files, &epubArchiveFile{name: fontPath, method: zip.Deflate, data: subset})
+	return nil
+}
+
+// contentRunes returns the distinct characters, ruby included, of the content documents
+func contentRunes(archive *epubArchive) ([]rune, error) {
+	seen := make(map[rune]bool)
+	for _, f := range archive.files {
+		if path.Ext(f.name) != ".xhtml" {
+			continue
+		}
+		decoder := xml.NewDecoder(bytes.NewReader(f.data))
+		decoder.Strict = false
+		decoder.Entity = xml.HTMLEntity
+		for {
+			token, err := decoder.Token()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("reading text of %s: %w", f.name, err)
+			}
+			if text, ok := token.(xml.CharData); ok {
+				for _, r := range string(text) {
+					if !unicode.IsSpace(r) && !unicode.IsControl(r) {
+						seen[r] = true
+					}
+				}
+			}
+		}
+	}
+
+	runes := make([]rune, 0, len(seen))
+	for r := range seen {
+		runes = append(runes, r)
+	}
+	slices.Sort(runes)
+	return runes, nil
+}
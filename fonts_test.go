@@ -0,0 +1,85 @@
+package jplaw2epub
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func TestEmbedFont(t *testing.T) {
+	cff, err := os.ReadFile(filepath.Join("testdata", "fonts", "CFFTest.otf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		font      []byte
+		fontPath  string
+		mediaType string
+	}{
+		{name: "TrueType", font: goregular.TTF, fontPath: "EPUB/fonts/embedded.ttf", mediaType: "font/ttf"},
+		{name: "CFF", font: cff, fontPath: "EPUB/fonts/embedded.otf", mediaType: "font/otf"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := writeTestEPUB(t, &EPUBOptions{EmbedFont: tt.font, NoCover: true})
+
+			font, ok := files[tt.fontPath]
+			if !ok {
+				t.Fatalf("%s not found", tt.fontPath)
+			}
+			if len(font) >= len(tt.font) {
+				t.Errorf("embedded font size = %d, want a subset smaller than %d", len(font), len(tt.font))
+			}
+
+			opf := files[epubPackagePath]
+			for _, want := range []string{
+				`href="` + strings.TrimPrefix(tt.fontPath, "EPUB/") + `" media-type="` + tt.mediaType + `"`,
+				`href="css/fonts.css" media-type="text/css"`,
+			} {
+				if !strings.Contains(opf, want) {
+					t.Errorf("package document missing %s", want)
+				}
+			}
+
+			css := files[embeddedFontCSSPath]
+			if !strings.Contains(css, `src: url("../fonts/`+filepath.Base(tt.fontPath)+`")`) {
+				t.Errorf("font stylesheet does not reference the font:\n%s", css)
+			}
+			if !strings.Contains(files["EPUB/xhtml/article-0-0.xhtml"], `<link rel="stylesheet" type="text/css" href="../css/fonts.css"/>`) {
+				t.Error("content documents should link the font stylesheet")
+			}
+			if !strings.Contains(files["EPUB/nav.xhtml"], `href="css/fonts.css"`) {
+				t.Error("the navigation document should link the font stylesheet relative to its location")
+			}
+		})
+	}
+}
+
+func TestEmbedFontInvalid(t *testing.T) {
+	_, err := CreateEPUBFromXMLFileWithOptions(bytes.NewReader(readTestdata(t, "json/chapters.xml")), &EPUBOptions{EmbedFont: []byte("not a font")})
+	if err == nil {
+		t.Error("expected an error for an invalid font")
+	}
+}
+
+func TestContentRunes(t *testing.T) {
+	archive := &epubArchive{files: []*epubArchiveFile{
+		{name: "EPUB/xhtml/a.xhtml", data: []byte(`<html><head><title>法</title></head><body><p>第<ruby>一<rt>いち</rt></ruby>条&amp;</p></body></html>`)},
+		{name: "EPUB/css/styles.css", data: []byte("body { content: '無' }")},
+	}}
+
+	runes, err := contentRunes(archive)
+	if err != nil {
+		t.Fatalf("contentRunes() error = %v", err)
+	}
+	if got, want := string(runes), "&いち一条法第"; got != want {
+		t.Errorf("contentRunes() = %q, want %q", got, want)
+	}
+}
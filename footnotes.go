@@ -0,0 +1,176 @@
+package jplaw2epub
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// articleRefPattern matches references to articles such as 第十条 or 第十条の二
+var articleRefPattern = regexp.MustCompile(`第[〇一二三四五六七八九十百千]+条(?:の[〇一二三四五六七八九十百千]+)*`)
+
+// lawNameSuffixes end the name of another law, as in 民法第十条, whose articles are not in this book
+const lawNameSuffixes = "法令則約規同"
+
+// noteTarget is the content shown in a popup footnote
+type noteTarget struct {
+	href  string
+	label string
+	body  string
+}
+
+// noteRefWriter is a BookWriter that turns article references and defined terms
+// into EPUB3 noterefs with popup footnotes appended to the same document
+type noteRefWriter struct {
+	BookWriter
+	articles     map[string]*noteTarget
+	articleFiles map[string]bool
+	terms        []definedTerm
+	added        map[string]bool
+}
+
+// newNoteRefWriter wraps book with popup footnotes for the articles of the main provision and the defined terms
+func newNoteRefWriter(book BookWriter, law *JSONLaw, terms []definedTerm) *noteRefWriter {
+	w := &noteRefWriter{
+		BookWriter:   book,
+		articles:     make(map[string]*noteTarget),
+		articleFiles: make(map[string]bool),
+		added:        make(map[string]bool),
+	}
+
+	addArticles := func(articles []JSONArticle) {
+		for i := range articles {
+			article := &articles[i]
+			w.articleFiles[article.Href] = true
+			if _, exists := w.articles[article.Title.Text]; !exists {
+				w.articles[article.Title.Text] = articleNoteTarget(article)
+			}
+		}
+	}
+	for _, chapter := range law.MainProvision.Chapters {
+		addArticles(chapter.Articles)
+		for _, section := range chapter.Sections {
+			addArticles(section.Articles)
+		}
+	}
+	addArticles(law.MainProvision.Articles)
+
+	w.terms = append([]definedTerm(nil), terms...)
+	sort.SliceStable(w.terms, func(i, j int) bool {
+		return utf8.RuneCountInString(w.terms[i].term) > utf8.RuneCountInString(w.terms[j].term)
+	})
+	return w
+}
+
+// articleNoteTarget renders the text of an article for a popup
+func articleNoteTarget(article *JSONArticle) *noteTarget {
+	label := article.Title.Text
+	if article.Caption != nil {
+		label += article.Caption.Text
+	}
+
+	var body strings.Builder
+	for i := range article.Paragraphs {
+		para := &article.Paragraphs[i]
+		body.WriteString("<p>")
+		if para.Label != "" {
+			body.WriteString(html.EscapeString(para.Label) + "　")
+		}
+		for _, s := range para.Sentences {
+			body.WriteString(html.EscapeString(s.Text))
+		}
+		body.WriteString("</p>")
+		for j := range para.Items {
+			body.WriteString("<p>")
+			if para.Items[j].Label != "" {
+				body.WriteString(html.EscapeString(para.Items[j].Label) + "　")
+			}
+			for _, s := range para.Items[j].Sentences {
+				body.WriteString(html.EscapeString(s.Text))
+			}
+			body.WriteString("</p>")
+		}
+	}
+
+	return &noteTarget{href: article.Href, label: label, body: body.String()}
+}
+
+// AddSection adds popup footnotes to body before adding the section
+func (w *noteRefWriter) AddSection(body, sectionTitle, internalFilename, internalCSSPath string) (string, error) {
+	body = w.annotate(body, internalFilename)
+	w.added[internalFilename] = true
+	return w.BookWriter.AddSection(body, sectionTitle, internalFilename, internalCSSPath)
+}
+
+// AddSubSection adds popup footnotes to body before adding the subsection
+func (w *noteRefWriter) AddSubSection(parentFilename, body, sectionTitle, internalFilename, internalCSSPath string) (string, error) {
+	body = w.annotate(body, internalFilename)
+	w.added[internalFilename] = true
+	return w.BookWriter.AddSubSection(parentFilename, body, sectionTitle, internalFilename, internalCSSPath)
+}
+
+// annotate links references in body to footnotes appended at the end of the document
+func (w *noteRefWriter) annotate(body, filename string) string {
+	var notes []string
+	ids := make(map[string]string)
+
+	noteID := func(key string, target *noteTarget) string {
+		if id, ok := ids[key]; ok {
+			return id
+		}
+		id := fmt.Sprintf("note-%d", len(notes)+1)
+		ids[key] = id
+		notes = append(notes, fmt.Sprintf(`<aside epub:type="footnote" id="%s" class="popup-note">`+
+			`<p class="popup-note-title"><a href="%s">%s</a></p>%s</aside>`,
+			id, html.EscapeString(target.href), html.EscapeString(target.label), target.body))
+		return id
+	}
+	noteRef := func(id, text string) string {
+		return fmt.Sprintf(`<a epub:type="noteref" href="#%s" class="noteref">%s</a>`, id, text)
+	}
+
+	// Article references are only resolved within the main provision
+	if w.articleFiles[filename] {
+		body = mapTextSegments(body, func(text string) string {
+			var out strings.Builder
+			last := 0
+			for _, loc := range articleRefPattern.FindAllStringIndex(text, -1) {
+				ref := text[loc[0]:loc[1]]
+				target, ok := w.articles[ref]
+				if prev, _ := utf8.DecodeLastRuneInString(text[:loc[0]]); !ok || target.href == filename ||
+					strings.ContainsRune(lawNameSuffixes, prev) {
+					continue
+				}
+				out.WriteString(text[last:loc[0]])
+				out.WriteString(noteRef(noteID("article:"+ref, target), ref))
+				last = loc[1]
+			}
+			out.WriteString(text[last:])
+			return out.String()
+		})
+	}
+
+	for _, term := range w.terms {
+		if term.href == filename || !w.added[term.href] {
+			continue
+		}
+		term := term
+		escaped := html.EscapeString(term.term)
+		body = replaceFirstInTextFunc(body, escaped, func() string {
+			target := &noteTarget{
+				href:  term.href,
+				label: term.article,
+				body:  "<p>" + html.EscapeString(term.sentence) + "</p>",
+			}
+			return noteRef(noteID("term:"+term.term, target), escaped)
+		})
+	}
+
+	if len(notes) == 0 {
+		return body
+	}
+	return body + strings.Join(notes, "")
+}
@@ -0,0 +1,191 @@
+package jplaw2epub
+
+import (
+	"encoding/xml"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNoteRefWriterAnnotate(t *testing.T) {
+	law := &JSONLaw{
+		MainProvision: JSONProvision{
+			Articles: []JSONArticle{
+				{
+					Href:  "article-0.xhtml",
+					Title: JSONText{Text: "第一条"},
+					Paragraphs: []JSONParagraph{
+						{Sentences: []JSONText{{Text: "第二条の規定は、民法第二条に優先する。"}}},
+					},
+				},
+				{
+					Href:    "article-1.xhtml",
+					Title:   JSONText{Text: "第二条"},
+					Caption: &JSONText{Text: "（届出）"},
+					Paragraphs: []JSONParagraph{
+						{Sentences: []JSONText{{Text: "事業者は、届出をしなければならない。"}}},
+						{Label: "２", Sentences: []JSONText{{Text: "前項の届出は、書面で行う。"}}},
+					},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		filename string
+		body     string
+		want     string
+	}{
+		{
+			name:     "article reference becomes noteref",
+			filename: "article-0.xhtml",
+			body:     `<h3>第一条</h3><p>第二条の規定は、民法第二条に優先する。</p>`,
+			want: `<h3>第一条</h3><p><a epub:type="noteref" href="#note-1" class="noteref">第二条</a>の規定は、民法第二条に優先する。</p>` +
+				`<aside epub:type="footnote" id="note-1" class="popup-note"><p class="popup-note-title"><a href="article-1.xhtml">第二条（届出）</a></p>` +
+				`<p>事業者は、届出をしなければならない。</p><p>２　前項の届出は、書面で行う。</p></aside>`,
+		},
+		{
+			name:     "repeated references share one footnote",
+			filename: "article-0.xhtml",
+			body:     `<p>第二条又は第二条</p>`,
+			want: `<p><a epub:type="noteref" href="#note-1" class="noteref">第二条</a>又は<a epub:type="noteref" href="#note-1" class="noteref">第二条</a></p>` +
+				`<aside epub:type="footnote" id="note-1" class="popup-note"><p class="popup-note-title"><a href="article-1.xhtml">第二条（届出）</a></p>` +
+				`<p>事業者は、届出をしなければならない。</p><p>２　前項の届出は、書面で行う。</p></aside>`,
+		},
+		{
+			name:     "self references and unknown articles are left alone",
+			filename: "article-1.xhtml",
+			body:     `<p>第二条及び第九条</p>`,
+			want:     `<p>第二条及び第九条</p>`,
+		},
+		{
+			name:     "references outside the main provision are left alone",
+			filename: "suppl-provision-0.xhtml",
+			body:     `<p>第二条</p>`,
+			want:     `<p>第二条</p>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newNoteRefWriter(NewPDFDocument("test"), law, nil)
+			if got := w.annotate(tt.body, tt.filename); got != tt.want {
+				t.Errorf("annotate() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPopupNotesOption(t *testing.T) {
+	law := loadDefinitionsFixture(t)
+	data := mustLoadTestdataLaw(t, "definitions.xml")
+
+	doc := NewPDFDocument(law.Title.Text)
+	opts := &EPUBOptions{PopupNotes: true, DefinitionLinks: true}
+	if err := processChaptersWithOptions(doc, data, opts); err != nil {
+		t.Fatalf("processChaptersWithOptions() error = %v", err)
+	}
+
+	tests := []struct {
+		filename string
+		contains []string
+		absent   []string
+	}{
+		{
+			filename: "article-2.xhtml",
+			contains: []string{
+				`<a epub:type="noteref" href="#note-1" class="noteref">第五条</a>`,
+				`<aside epub:type="footnote" id="note-1" class="popup-note"><p class="popup-note-title"><a href="article-4.xhtml">第五条</a></p>`,
+			},
+			absent: []string{`class="noteref">事業者`},
+		},
+		{
+			filename: "article-3.xhtml",
+			contains: []string{
+				`<a epub:type="noteref" href="#note-1" class="noteref">事業者</a>`,
+				`<a epub:type="noteref" href="#note-2" class="noteref">届出</a>`,
+				`<p class="popup-note-title"><a href="article-1.xhtml">第二条</a></p><p>事業者　事業を行う者をいう。</p>`,
+			},
+			absent: []string{`class="noteref">大臣`},
+		},
+		{
+			filename: "article-4.xhtml",
+			contains: []string{
+				`<a epub:type="noteref" href="#note-1" class="noteref">データベース</a>`,
+				`<p>データベース　情報の集合物をいう。</p>`,
+				`class="noteref">大臣</a>`,
+			},
+			absent: []string{`class="defined-term"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			section, ok := doc.files[tt.filename]
+			if !ok {
+				t.Fatalf("section %s not found", tt.filename)
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(section.body, s) {
+					t.Errorf("body missing %s:\n%s", s, section.body)
+				}
+			}
+			for _, s := range tt.absent {
+				if strings.Contains(section.body, s) {
+					t.Errorf("body should not contain %s:\n%s", s, section.body)
+				}
+			}
+
+			// The annotated body must stay well-formed
+			decoder := xml.NewDecoder(strings.NewReader(
+				`<body xmlns:epub="http://www.idpf.org/2007/ops">` + section.body + `</body>`))
+			for {
+				if _, err := decoder.Token(); err != nil {
+					if err != io.EOF {
+						t.Errorf("annotated body is not well-formed: %v", err)
+					}
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestPopupNotesDisabled(t *testing.T) {
+	data := mustLoadTestdataLaw(t, "definitions.xml")
+
+	doc := NewPDFDocument("test")
+	if err := processChaptersWithOptions(doc, data, nil); err != nil {
+		t.Fatalf("processChaptersWithOptions() error = %v", err)
+	}
+
+	for name, section := range doc.files {
+		if strings.Contains(section.body, "noteref") || strings.Contains(section.body, "<aside") {
+			t.Errorf("%s should not contain popup notes without PopupNotes", name)
+		}
+	}
+}
+
+func TestPopupNotesEPUB(t *testing.T) {
+	book, err := CreateEPUBFromXMLPathWithOptions(filepath.Join("testdata", "definitions.xml"),
+		&EPUBOptions{PopupNotes: true, NoCover: true})
+	if err != nil {
+		t.Fatalf("CreateEPUBFromXMLPathWithOptions() error = %v", err)
+	}
+
+	destPath := filepath.Join(t.TempDir(), "law.epub")
+	if err := WriteEPUB(book, destPath); err != nil {
+		t.Fatalf("WriteEPUB() error = %v", err)
+	}
+
+	files := readZipFiles(t, destPath)
+	section := files["EPUB/xhtml/article-2.xhtml"]
+	if !strings.Contains(section, `xmlns:epub="http://www.idpf.org/2007/ops"`) {
+		t.Error("section does not declare the epub namespace")
+	}
+	if !strings.Contains(section, `epub:type="footnote"`) {
+		t.Error("section does not contain popup footnotes")
+	}
+}
@@ -1,14 +1,17 @@
 package jplaw2epub
 
 import (
+	"encoding/xml"
 	"fmt"
+	"html"
+	"io"
+	"strings"
 
-	"github.com/go-shiori/go-epub"
 	"go.ngs.io/jplaw-xml"
 )
 
 // processAppdxFormats processes appendix formats
-func processAppdxFormats(book *epub.Epub, formats []jplaw.AppdxFormat, imgProc ImageProcessorInterface) error {
+func processAppdxFormats(book BookWriter, formats []jplaw.AppdxFormat, imgProc ImageProcessorInterface) error {
 	if len(formats) == 0 {
 		return nil
 	}
@@ -23,15 +26,15 @@ func processAppdxFormats(book *epub.Epub, formats []jplaw.AppdxFormat, imgProc I
 }
 
 // processAppdxFormat processes a single appendix format
-func processAppdxFormat(book *epub.Epub, format *jplaw.AppdxFormat, idx int, imgProc ImageProcessorInterface) error {
+func processAppdxFormat(book BookWriter, format *jplaw.AppdxFormat, idx int, imgProc ImageProcessorInterface) error {
 	filename := fmt.Sprintf("appdx-format-%d.xhtml", idx)
-	body := ""
+	body := openSection("appendix", "appendix", "")
 
 	// Add title if present
 	title := "書式"
 	if format.AppdxFormatTitle != nil && format.AppdxFormatTitle.Content != "" {
 		title = format.AppdxFormatTitle.Content
-		body += fmt.Sprintf(`<div class="chapter-title">%s</div>`, processTextWithRuby(title, format.AppdxFormatTitle.Ruby))
+		body += headingHTML(headingLevelTop, "chapter-title", processTextWithRuby(title, format.AppdxFormatTitle.Ruby))
 	}
 
 	// Process related article number if present
@@ -45,6 +48,8 @@ func processAppdxFormat(book *epub.Epub, format *jplaw.AppdxFormat, idx int, img
 		body += processFormatStruct(&formatStruct, imgProc)
 	}
 
+	body += htmlSectionEnd
+
 	// Add the section to the book
 	_, err := book.AddSection(body, title, filename, "")
 	if err != nil {
@@ -76,36 +81,63 @@ func processFormatStruct(formatStruct *jplaw.FormatStruct, imgProc ImageProcesso
 	return body
 }
 
-// processFormat processes a Format element which contains raw XML
+// processFormat processes a Format element, whose content is raw XML
 func processFormat(format *jplaw.Format, imgProc ImageProcessorInterface) string {
 	body := `<div class="format-content">`
 
-	// Format.Content contains raw XML that may include Fig elements
-	// For now, we'll display it as-is or try to extract Fig elements
 	if format.Content != "" {
-		// Check if it contains Fig elements
-		if imgProc != nil && containsFigElement(format.Content) {
-			// Try to process embedded Fig elements
-			processedContent := processEmbeddedFigs(format.Content, imgProc)
-			body += processedContent
-		} else {
-			// Display as preformatted text
-			body += fmt.Sprintf(`<pre class="format-raw">%s</pre>`, format.Content)
-		}
+		body += processFormatContent(format.Content, imgProc)
 	}
 
 	body += htmlDivEnd
 	return body
 }
 
-// containsFigElement checks if the content contains Fig elements
-func containsFigElement(content string) bool {
-	return len(content) > 4 && content[:4] == "<Fig"
-}
+// processFormatContent renders the raw XML of a Format element. Its text is escaped into
+// preformatted blocks and its Fig elements become images; content that is not well-formed
+// is shown as text
+func processFormatContent(content string, imgProc ImageProcessorInterface) string {
+	var body, text strings.Builder
+	flush := func() {
+		if strings.TrimSpace(text.String()) != "" {
+			fmt.Fprintf(&body, `<pre class="format-raw">%s</pre>`, html.EscapeString(text.String()))
+		}
+		text.Reset()
+	}
+	raw := fmt.Sprintf(`<pre class="format-raw">%s</pre>`, html.EscapeString(content))
+
+	decoder := xml.NewDecoder(strings.NewReader(content))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return raw
+		}
+		switch t := token.(type) {
+		case xml.CharData:
+			text.Write(t)
+		case xml.StartElement:
+			if t.Name.Local != "Fig" {
+				continue
+			}
+			var fig jplaw.Fig
+			if err := decoder.DecodeElement(&fig, &t); err != nil {
+				return raw
+			}
+			if !hasImages(imgProc) {
+				continue
+			}
+			figHTML, err := imgProc.ProcessFigStruct(&jplaw.FigStruct{Fig: fig})
+			if err != nil {
+				continue
+			}
+			flush()
+			body.WriteString(figHTML)
+		}
+	}
+	flush()
 
-// processEmbeddedFigs processes Fig elements embedded in XML content
-func processEmbeddedFigs(content string, _ ImageProcessorInterface) string {
-	// For simplicity, just display the content
-	// In a real implementation, we'd parse the XML and extract Fig elements
-	return fmt.Sprintf(`<div class="embedded-content">%s</div>`, content)
+	return body.String()
 }
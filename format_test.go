@@ -1,6 +1,7 @@
 package jplaw2epub
 
 import (
+	"bytes"
 	"strings"
 	"testing"
 
@@ -230,7 +231,7 @@ func TestProcessFormat(t *testing.T) {
 			wantContains: []string{
 				`<div class="format-content">`,
 				`<pre class="format-raw">`,
-				"内容 <Fig src=\"image.jpg\"/> テキスト",
+				"内容  テキスト",
 				`</div>`,
 			},
 		},
@@ -260,86 +261,79 @@ func TestProcessFormat(t *testing.T) {
 	}
 }
 
-func TestContainsFigElement(t *testing.T) {
-	tests := []struct {
-		name    string
-		content string
-		want    bool
-	}{
-		{
-			name:    "starts with Fig element",
-			content: "<Fig src=\"image.jpg\"/> more text",
-			want:    true,
-		},
-		{
-			name:    "starts with Fig with attributes",
-			content: "<Fig src=\"test.png\" alt=\"description\"/>",
-			want:    true,
-		},
-		{
-			name:    "no Fig element",
-			content: "just plain text without images",
-			want:    false,
-		},
-		{
-			name:    "Fig in middle of content",
-			content: "text <Fig src=\"test.jpg\"/> more",
-			want:    false, // Only checks start
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if got := containsFigElement(tt.content); got != tt.want {
-				t.Errorf("containsFigElement() = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
-func TestProcessEmbeddedFigs(t *testing.T) {
+func TestProcessFormatContent(t *testing.T) {
 	tests := []struct {
 		name    string
 		content string
+		imgProc ImageProcessorInterface
 		want    string
 	}{
 		{
-			name:    "single Fig element",
-			content: "前文 <Fig src=\"image1.jpg\"/> 後文",
-			want:    `<div class="embedded-content">前文 <Fig src="image1.jpg"/> 後文</div>`,
+			name:    "text is escaped",
+			content: "氏名 &lt;署名&gt; &amp; 押印",
+			want:    `<pre class="format-raw">氏名 &lt;署名&gt; &amp; 押印</pre>`,
 		},
 		{
-			name:    "multiple Fig elements",
-			content: "<Fig src=\"img1.jpg\"/> text <Fig src=\"img2.png\"/>",
-			want:    `<div class="embedded-content"><Fig src="img1.jpg"/> text <Fig src="img2.png"/></div>`,
+			name:    "markup is not copied",
+			content: "<Sentence>記入例</Sentence><Remarks>注</Remarks>",
+			want:    `<pre class="format-raw">記入例注</pre>`,
 		},
 		{
-			name:    "Fig with various attributes",
-			content: "text <Fig src=\"test.jpg\" alt=\"desc\" width=\"100\"/> end",
-			want:    `<div class="embedded-content">text <Fig src="test.jpg" alt="desc" width="100"/> end</div>`,
+			name:    "Fig without images",
+			content: "前文 <Fig src=\"image1.jpg\"/> 後文",
+			want:    `<pre class="format-raw">前文  後文</pre>`,
 		},
 		{
-			name:    "no Fig elements",
-			content: "plain text without any figures",
-			want:    `<div class="embedded-content">plain text without any figures</div>`,
+			name:    "Fig with images",
+			content: "前文<Fig src=\"image1.jpg\"/>後文",
+			imgProc: &MockImageProcessor{ProcessFigStructHTML: `<img src="image1.png" alt=""/>`},
+			want:    `<pre class="format-raw">前文</pre><img src="image1.png" alt=""/><pre class="format-raw">後文</pre>`,
 		},
 		{
-			name:    "nested Fig-like text",
-			content: "text about <Figure> but not <Fig src=\"real.jpg\"/> tag",
-			want:    `<div class="embedded-content">text about <Figure> but not <Fig src="real.jpg"/> tag</div>`,
+			name:    "malformed content",
+			content: "text about <Figure> but not closed",
+			want:    `<pre class="format-raw">text about &lt;Figure&gt; but not closed</pre>`,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := processEmbeddedFigs(tt.content, nil)
+			got := processFormatContent(tt.content, tt.imgProc)
 			if got != tt.want {
-				t.Errorf("processEmbeddedFigs() = %q, want %q", got, tt.want)
+				t.Errorf("processFormatContent() = %q, want %q", got, tt.want)
 			}
 		})
 	}
 }
 
+func TestFormatStructValidates(t *testing.T) {
+	const law = `<Law Era="Reiwa" Year="5" Num="10" LawType="Act" Lang="ja">
+  <LawNum>令和五年法律第十号</LawNum>
+  <LawBody>
+    <LawTitle>書式法</LawTitle>
+    <MainProvision>
+      <Paragraph Num="1"><ParagraphNum/><ParagraphSentence><Sentence Num="1">別記様式による。</Sentence></ParagraphSentence></Paragraph>
+    </MainProvision>
+    <AppdxFormat>
+      <AppdxFormatTitle>別記様式</AppdxFormatTitle>
+      <FormatStruct>
+        <FormatStructTitle>申請書</FormatStructTitle>
+        <Format><Fig src="./pict/H001.pdf"/><Sentence>氏名 &lt;署名&gt; &amp; 押印</Sentence></Format>
+      </FormatStruct>
+    </AppdxFormat>
+  </LawBody>
+</Law>`
+
+	opts := &EPUBOptions{Validate: true, NoCover: true}
+	book, err := CreateEPUBFromXMLFileWithOptions(strings.NewReader(law), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteEPUBToWithOptions(book, new(bytes.Buffer), opts); err != nil {
+		t.Errorf("WriteEPUBToWithOptions() error = %v, want a valid EPUB", err)
+	}
+}
+
 func TestProcessFormatWithMockImageProcessor(t *testing.T) {
 	// Create a mock image processor
 	mock := &MockImageProcessor{
@@ -418,21 +412,3 @@ func BenchmarkProcessFormat(b *testing.B) {
 		_ = processFormat(format, nil)
 	}
 }
-
-func BenchmarkContainsFigElement(b *testing.B) {
-	content := "Long text with <Fig src=\"image.jpg\"/> embedded figure element and more text afterwards"
-
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		_ = containsFigElement(content)
-	}
-}
-
-func BenchmarkProcessEmbeddedFigs(b *testing.B) {
-	content := "<Fig src=\"1.jpg\"/> text <Fig src=\"2.jpg\"/> more <Fig src=\"3.jpg\"/> end"
-
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		_ = processEmbeddedFigs(content, nil)
-	}
-}
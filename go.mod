@@ -3,18 +3,22 @@ module go.ngs.io/jplaw2epub
 go 1.23.12
 
 require (
+	github.com/BurntSushi/toml v1.5.0
 	github.com/gen2brain/go-fitz v1.24.15
 	github.com/go-shiori/go-epub v1.2.1
+	github.com/gofrs/uuid/v5 v5.3.0
 	go.ngs.io/jplaw-api-v2 v0.0.3
 	go.ngs.io/jplaw-xml v0.0.5
+	golang.org/x/image v0.25.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/ebitengine/purego v0.8.4 // indirect
 	github.com/gabriel-vasile/mimetype v1.4.8 // indirect
-	github.com/gofrs/uuid/v5 v5.3.0 // indirect
 	github.com/jupiterrider/ffi v0.5.0 // indirect
 	github.com/vincent-petithory/dataurl v1.0.0 // indirect
 	golang.org/x/net v0.34.0 // indirect
 	golang.org/x/sys v0.33.0 // indirect
+	golang.org/x/text v0.23.0 // indirect
 )
@@ -2,6 +2,9 @@ package jplaw2epub
 
 import (
 	"fmt"
+	"html"
+	"regexp"
+	"strings"
 
 	"go.ngs.io/jplaw-xml"
 )
@@ -13,6 +16,8 @@ const (
 	htmlLI    = "<li>"
 	htmlLIEnd = "</li>"
 
+	htmlSectionEnd = "</section>"
+
 	// List style types
 	listStyleDisc     = "disc"
 	listStyleDecimal  = "decimal"
@@ -21,6 +26,50 @@ const (
 	listStyleHiragana = "hiragana-iroha"
 )
 
+// Heading levels. The law title on the title page is the only h1; chapters and top-level
+// provisions are h2, and each level of nesting below them adds one.
+const (
+	headingLevelTop     = 2
+	headingLevelChapter = 3
+	headingLevelSection = 4
+)
+
+// openSection returns an opening section tag with an optional epub:type, class and id
+func openSection(epubType, class, id string) string {
+	tag := "<section"
+	if epubType != "" {
+		tag += fmt.Sprintf(` epub:type="%s"`, epubType)
+	}
+	if class != "" {
+		tag += fmt.Sprintf(` class="%s"`, class)
+	}
+	if id != "" {
+		tag += fmt.Sprintf(` id="%s"`, id)
+	}
+	return tag + ">"
+}
+
+// headingHTML returns a heading of the given level with an optional class
+func headingHTML(level int, class, content string) string {
+	if class != "" {
+		return fmt.Sprintf(`<h%d class="%s">%s</h%d>`, level, class, content, level)
+	}
+	return fmt.Sprintf("<h%d>%s</h%d>", level, content, level)
+}
+
+// rubyTextPattern matches the reading and fallback parentheses of ruby annotations
+var rubyTextPattern = regexp.MustCompile(`(?s)<(rt|rp)\b[^>]*>.*?</(rt|rp)>`)
+
+// tagPattern matches a single tag
+var tagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// plainText returns the text of an HTML fragment without tags or ruby readings
+func plainText(fragment string) string {
+	text := rubyTextPattern.ReplaceAllString(fragment, "")
+	text = tagPattern.ReplaceAllString(text, "")
+	return strings.TrimSpace(html.UnescapeString(text))
+}
+
 // openListWithStyle returns an opening list tag with appropriate style
 func openListWithStyle(titles []string) string {
 	listStyle := getListStyleType(titles)
@@ -158,3 +158,23 @@ func TestHTMLConstants(t *testing.T) {
 		})
 	}
 }
+
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		fragment string
+		want     string
+	}{
+		{fragment: "図1", want: "図1"},
+		{fragment: "<ruby>瑕疵<rt>かし</rt></ruby>の図", want: "瑕疵の図"},
+		{fragment: "<ruby>図<rp>（</rp><rt>ず</rt><rp>）</rp></ruby>", want: "図"},
+		{fragment: " A &amp; B ", want: "A & B"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fragment, func(t *testing.T) {
+			if got := plainText(tt.fragment); got != tt.want {
+				t.Errorf("plainText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
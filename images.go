@@ -2,6 +2,7 @@ package jplaw2epub
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"fmt"
 	"image"
@@ -13,23 +14,30 @@ import (
 	"strings"
 
 	"github.com/gen2brain/go-fitz"
-	"github.com/go-shiori/go-epub"
 	lawapi "go.ngs.io/jplaw-api-v2"
 	"go.ngs.io/jplaw-xml"
 )
 
 // ImageProcessor handles image processing for EPUB
 type ImageProcessor struct {
+	ctx            context.Context
 	client         APIClient
 	revisionID     string
-	book           *epub.Epub
+	book           BookWriter
 	imageCache     map[string]string // maps src to EPUB internal path
 	maxImageHeight string            // maximum height for images (CSS value)
+	progress       *progressReporter
 }
 
 // NewImageProcessor creates a new image processor
-func NewImageProcessor(client APIClient, revisionID string, book *epub.Epub) *ImageProcessor {
+func NewImageProcessor(client APIClient, revisionID string, book BookWriter) *ImageProcessor {
+	return NewImageProcessorWithContext(context.Background(), client, revisionID, book)
+}
+
+// NewImageProcessorWithContext creates an image processor whose downloads are canceled with ctx
+func NewImageProcessorWithContext(ctx context.Context, client APIClient, revisionID string, book BookWriter) *ImageProcessor {
 	return &ImageProcessor{
+		ctx:            ctx,
 		client:         client,
 		revisionID:     revisionID,
 		book:           book,
@@ -45,6 +53,15 @@ func (ip *ImageProcessor) SetMaxImageHeight(height string) {
 
 // ProcessFigStruct processes a FigStruct and returns HTML
 func (ip *ImageProcessor) ProcessFigStruct(fig *jplaw.FigStruct) (string, error) {
+	ctx := ip.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return ip.ProcessFigStructContext(ctx, fig)
+}
+
+// ProcessFigStructContext processes a FigStruct, giving up on the download when ctx is done
+func (ip *ImageProcessor) ProcessFigStructContext(ctx context.Context, fig *jplaw.FigStruct) (string, error) {
 	if fig.Fig.Src == "" {
 		return "", nil
 	}
@@ -59,10 +76,11 @@ func (ip *ImageProcessor) ProcessFigStruct(fig *jplaw.FigStruct) (string, error)
 	}
 
 	// Download image
-	imageData, contentType, err := ip.downloadImage(fig.Fig.Src)
+	imageData, contentType, err := ip.downloadImage(ctx, fig.Fig.Src)
 	if err != nil {
 		return "", fmt.Errorf("downloading image %s: %w", fig.Fig.Src, err)
 	}
+	ip.progress.imageDownloaded(fig.Fig.Src)
 
 	// Convert to PNG if necessary
 	if !isPNG(contentType) {
@@ -80,17 +98,18 @@ func (ip *ImageProcessor) ProcessFigStruct(fig *jplaw.FigStruct) (string, error)
 
 	// Cache the path
 	ip.imageCache[fig.Fig.Src] = epubPath
+	ip.progress.imageConverted(fig.Fig.Src)
 
 	return ip.buildImageHTML(epubPath, fig), nil
 }
 
 // downloadImage downloads an image from the API
-func (ip *ImageProcessor) downloadImage(src string) (data []byte, contentType string, err error) {
+func (ip *ImageProcessor) downloadImage(ctx context.Context, src string) (data []byte, contentType string, err error) {
 	params := &lawapi.GetAttachmentParams{
 		Src: lawapi.StringPtr(src),
 	}
 
-	attachment, err := ip.client.GetAttachment(ip.revisionID, params)
+	attachment, err := getAttachment(ctx, ip.client, ip.revisionID, params)
 	if err != nil {
 		return nil, "", err
 	}
@@ -176,7 +195,7 @@ func (ip *ImageProcessor) buildImageHTML(epubPath string, fig *jplaw.FigStruct)
 	imgStyle := fmt.Sprintf("max-width: 100%%; max-height: %s; "+
 		"height: auto; display: block; margin: 0 auto; page-break-inside: avoid;",
 		ip.maxImageHeight)
-	html += fmt.Sprintf(`<img src=%q alt="Figure" style=%q />`, epubPath, imgStyle)
+	html += fmt.Sprintf(`<img src=%q alt="%s" style=%q />`, epubPath, escapeXMLText(figureAltText(fig)), imgStyle)
 
 	// Add remarks if present
 	for i := range fig.Remarks {
@@ -206,6 +225,35 @@ func (ip *ImageProcessor) buildImageHTML(epubPath string, fig *jplaw.FigStruct)
 	return html
 }
 
+// defaultFigureAltText is the alternative text of figures with neither a title nor remarks
+const defaultFigureAltText = "図"
+
+// figureAltText returns the alternative text of a figure: its title, or else the text of its remarks
+func figureAltText(fig *jplaw.FigStruct) string {
+	if fig.FigStructTitle != nil {
+		if title := plainText(processTextWithRuby(fig.FigStructTitle.Content, fig.FigStructTitle.Ruby)); title != "" {
+			return title
+		}
+	}
+
+	var parts []string
+	for i := range fig.Remarks {
+		remark := &fig.Remarks[i]
+		if label := strings.TrimSpace(remark.RemarksLabel.Content); label != "" {
+			parts = append(parts, label)
+		}
+		for j := range remark.Sentence {
+			if text := plainText(remark.Sentence[j].HTML()); text != "" {
+				parts = append(parts, text)
+			}
+		}
+	}
+	if len(parts) > 0 {
+		return strings.Join(parts, "　")
+	}
+	return defaultFigureAltText
+}
+
 // isPNG checks if the content type is PNG
 func isPNG(contentType string) bool {
 	return strings.Contains(strings.ToLower(contentType), "png")
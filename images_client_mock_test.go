@@ -2,6 +2,7 @@ package jplaw2epub
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"fmt"
 	"image"
@@ -88,6 +89,12 @@ func createBase64PNG() string {
 	return base64.StdEncoding.EncodeToString(data)
 }
 
+// createPNGAttachment returns PNG data as GetAttachment returns it, as a raw string
+func createPNGAttachment() string {
+	data, _ := createTestPNGData(10, 10, color.RGBA{255, 0, 0, 255})
+	return string(data)
+}
+
 const (
 	testRevisionID     = "test-revision"
 	defaultImageHeight = "80vh"
@@ -121,7 +128,7 @@ func TestImageProcessorWithMockClient(t *testing.T) {
 			wantHTMLContains: []string{
 				`<div class="figure"`,
 				`<img`,
-				`alt="Figure"`,
+				`alt="テスト画像"`,
 				`<p class="figure-title">テスト画像</p>`,
 			},
 		},
@@ -384,7 +391,7 @@ func TestDownloadImage(t *testing.T) {
 				revisionID: testRevisionID,
 			}
 
-			data, contentType, err := imgProc.downloadImage(tt.src)
+			data, contentType, err := imgProc.downloadImage(context.Background(), tt.src)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("downloadImage() error = %v, wantErr %v", err, tt.wantErr)
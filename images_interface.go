@@ -1,6 +1,10 @@
 package jplaw2epub
 
-import "go.ngs.io/jplaw-xml"
+import (
+	"context"
+
+	"go.ngs.io/jplaw-xml"
+)
 
 // ImageProcessorInterface defines the interface for image processing
 type ImageProcessorInterface interface {
@@ -8,5 +12,14 @@ type ImageProcessorInterface interface {
 	SetMaxImageHeight(height string)
 }
 
-// Ensure ImageProcessor implements ImageProcessorInterface
-var _ ImageProcessorInterface = (*ImageProcessor)(nil)
+// ContextImageProcessor is an ImageProcessorInterface that accepts a context per figure
+type ContextImageProcessor interface {
+	ImageProcessorInterface
+	ProcessFigStructContext(ctx context.Context, fig *jplaw.FigStruct) (string, error)
+}
+
+// Ensure ImageProcessor implements ImageProcessorInterface and ContextImageProcessor
+var (
+	_ ImageProcessorInterface = (*ImageProcessor)(nil)
+	_ ContextImageProcessor   = (*ImageProcessor)(nil)
+)
@@ -1,6 +1,7 @@
 package jplaw2epub
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -354,7 +355,7 @@ func TestCreateImageProcessorWithOptions(t *testing.T) {
 	}
 
 	t.Run("with nil options", func(t *testing.T) {
-		imgProc := createImageProcessor(book, nil)
+		imgProc := createImageProcessor(context.Background(), book, nil, nil)
 		if imgProc != nil {
 			t.Errorf("Expected nil processor for nil options, got %v", imgProc)
 		}
@@ -362,7 +363,7 @@ func TestCreateImageProcessorWithOptions(t *testing.T) {
 
 	t.Run("with empty options", func(t *testing.T) {
 		opts := &EPUBOptions{}
-		imgProc := createImageProcessor(book, opts)
+		imgProc := createImageProcessor(context.Background(), book, opts, nil)
 		if imgProc != nil {
 			t.Errorf("Expected nil processor for empty options, got %v", imgProc)
 		}
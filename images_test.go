@@ -157,7 +157,7 @@ func TestBuildImageHTML(t *testing.T) {
 			contains: []string{
 				`<div class="figure"`,
 				`src="images/test.png"`,
-				`alt="Figure"`,
+				`alt="図"`,
 				`</div>`,
 			},
 		},
@@ -173,7 +173,23 @@ func TestBuildImageHTML(t *testing.T) {
 			contains: []string{
 				`<p class="figure-title">図1 テスト画像</p>`,
 				`src="images/test.png"`,
-				`alt="Figure"`,
+				`alt="図1 テスト画像"`,
+			},
+		},
+		{
+			name:     "Figure with remarks",
+			epubPath: "images/test.png",
+			fig: &jplaw.FigStruct{
+				Fig: jplaw.Fig{Src: "./pict/test.pdf"},
+				Remarks: []jplaw.Remarks{
+					{
+						RemarksLabel: jplaw.RemarksLabel{Content: "備考"},
+						Sentence:     []jplaw.Sentence{createTestSentence("寸法は\"ミリメートル\"とする。")},
+					},
+				},
+			},
+			contains: []string{
+				`alt="備考　寸法は&#34;ミリメートル&#34;とする。"`,
 			},
 		},
 	}
@@ -0,0 +1,443 @@
+package jplaw2epub
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"go.ngs.io/jplaw-xml"
+)
+
+// Inspection is a summary of a law XML document, read element by element without converting it,
+// for troubleshooting unusual XML
+type Inspection struct {
+	Info    LawInfo        `json:"info"`
+	Outline []*OutlineNode `json:"outline"`
+	Figures []FigureRef    `json:"figures"`
+}
+
+// LawInfo is the title, number, promulgation date, element counts and structural features of a law
+type LawInfo struct {
+	Title   string `json:"title"`
+	LawNum  string `json:"lawNum"`
+	LawType string `json:"lawType,omitempty"`
+	Lang    string `json:"lang,omitempty"`
+	// Promulgated is the promulgation date in the Japanese calendar (e.g. 令和5年4月1日)
+	Promulgated string `json:"promulgated,omitempty"`
+	// PromulgatedISO is the promulgation date as YYYY-MM-DD, when it converts
+	PromulgatedISO string       `json:"promulgatedISO,omitempty"`
+	Counts         LawCounts    `json:"counts"`
+	Features       []LawFeature `json:"features"`
+}
+
+// LawCounts counts the elements of a law. Provisions quoted in table cells and amendment
+// provisions are not counted; their tables and figures are.
+type LawCounts struct {
+	Parts           int `json:"parts"`
+	Chapters        int `json:"chapters"`
+	Sections        int `json:"sections"`
+	Articles        int `json:"articles"`
+	Paragraphs      int `json:"paragraphs"`
+	Items           int `json:"items"`
+	SupplProvisions int `json:"supplProvisions"`
+	Appendixes      int `json:"appendixes"`
+	Tables          int `json:"tables"`
+	Figures         int `json:"figures"`
+}
+
+// LawFeature is a structural feature found in a law, such as sections or amendment provisions
+type LawFeature struct {
+	// Name is the XML element of the feature, or a description of a combination of elements
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	Count int    `json:"count"`
+	// Unsupported is set for elements that are not represented in the converted law
+	Unsupported bool `json:"unsupported,omitempty"`
+}
+
+// OutlineNode is a provision, division or appendix in the outline of a law
+type OutlineNode struct {
+	// Kind is main, part, chapter, section, subsection, division, article, paragraph, suppl,
+	// appdx-table, appdx-note, appdx-style, appdx-format or appdx-fig
+	Kind  string `json:"kind"`
+	Num   string `json:"num,omitempty"`
+	Title string `json:"title"`
+	// Caption is the caption of an article, or the amending law number of a supplementary provision
+	Caption string `json:"caption,omitempty"`
+	// Path is the position of the node, as in the JSON export
+	Path     string         `json:"path"`
+	Children []*OutlineNode `json:"children,omitempty"`
+}
+
+// FigureRef is a Fig element and where it occurs
+type FigureRef struct {
+	Src string `json:"src"`
+	// File is the image filename used inside the EPUB
+	File  string `json:"file"`
+	Title string `json:"title,omitempty"`
+	// Path is the position of the enclosing provision or appendix, as in the JSON export
+	Path string `json:"path"`
+	// Location names the enclosing provisions, e.g. 第二条 第2項
+	Location string `json:"location"`
+	// Element is the element containing the figure, e.g. Paragraph, TableColumn or Style
+	Element string `json:"element"`
+}
+
+// inspectSegments are the path segments of the elements that have a position in the law
+var inspectSegments = map[string]string{
+	"MainProvision":  "main",
+	"Part":           "part",
+	"Chapter":        "chapter",
+	"Section":        "section",
+	"Subsection":     "subsection",
+	"Division":       "division",
+	"Article":        "article",
+	"Paragraph":      "paragraph",
+	"Item":           "item",
+	"Subitem1":       "subitem1",
+	"Subitem2":       "subitem2",
+	"SupplProvision": "suppl",
+	"AppdxTable":     "appdx-table",
+	"AppdxNote":      "appdx-note",
+	"AppdxStyle":     "appdx-style",
+	"AppdxFormat":    "appdx-format",
+	"AppdxFig":       "appdx-fig",
+}
+
+// inspectTitles maps title elements to the element they title
+var inspectTitles = map[string]string{
+	"PartTitle":           "Part",
+	"ChapterTitle":        "Chapter",
+	"SectionTitle":        "Section",
+	"SubsectionTitle":     "Subsection",
+	"DivisionTitle":       "Division",
+	"ArticleTitle":        "Article",
+	"ArticleCaption":      "Article",
+	"ItemTitle":           "Item",
+	"Subitem1Title":       "Subitem1",
+	"Subitem2Title":       "Subitem2",
+	"SupplProvisionLabel": "SupplProvision",
+	"AppdxTableTitle":     "AppdxTable",
+	"AppdxNoteTitle":      "AppdxNote",
+	"AppdxStyleTitle":     "AppdxStyle",
+	"AppdxFormatTitle":    "AppdxFormat",
+	"AppdxFigTitle":       "AppdxFig",
+	"FigStructTitle":      "FigStruct",
+	"LawTitle":            "LawBody",
+	"LawNum":              "Law",
+}
+
+// inspectQuotes are the elements whose provisions are quoted text rather than part of the law
+var inspectQuotes = map[string]bool{"TableColumn": true, "NewProvision": true}
+
+// lawFeatures are the features reported by InspectXML, in order
+var lawFeatures = []LawFeature{
+	{Name: "TOC", Label: "table of contents (目次)", Unsupported: true},
+	{Name: "Preamble", Label: "preamble (前文)", Unsupported: true},
+	{Name: "EnactStatement", Label: "enact statement (制定文)"},
+	{Name: "Part", Label: "parts (編)", Unsupported: true},
+	{Name: "Chapter", Label: "chapters (章)"},
+	{Name: "Section", Label: "sections (節)"},
+	{Name: "Subsection", Label: "subsections (款)", Unsupported: true},
+	{Name: "Division", Label: "divisions (目)", Unsupported: true},
+	{Name: "Subitem1", Label: "subitems (号の細分)"},
+	{Name: "List", Label: "lists (列記)"},
+	{Name: "SupplProvision", Label: "supplementary provisions (附則)"},
+	{Name: "AmendProvision", Label: "amendment provisions (改正規定)"},
+	{Name: "TableStruct", Label: "tables (表)"},
+	{Name: "vertical tables", Label: "vertical tables (縦書きの表)"},
+	{Name: "provisions in tables", Label: "provisions in table cells (表中の条項)"},
+	{Name: "divisions in tables", Label: "chapters and sections in table cells (表中の章節)", Unsupported: true},
+	{Name: "Fig", Label: "figures (図)"},
+	{Name: "ArithFormula", Label: "formulas (算式)"},
+	{Name: "Ruby", Label: "ruby (ルビ)"},
+	{Name: "AppdxTable", Label: "appendix tables (別表)"},
+	{Name: "AppdxNote", Label: "appendix notes (別記)"},
+	{Name: "AppdxStyle", Label: "appendix styles (様式)"},
+	{Name: "AppdxFormat", Label: "appendix formats (書式)"},
+	{Name: "AppdxFig", Label: "appendix figures (別図)"},
+}
+
+// inspectFrame is an open element during inspection
+type inspectFrame struct {
+	name     string
+	path     string
+	label    string
+	title    string
+	node     *OutlineNode
+	quote    string
+	children map[string]int
+}
+
+// inspector collects an Inspection from the elements of a law
+type inspector struct {
+	result   Inspection
+	features map[string]int
+	date     JapaneseDate
+	stack    []*inspectFrame
+	text     *strings.Builder
+	textEnd  int
+	rt       int
+}
+
+// InspectXML reads a law XML document and summarizes its structure, outline and figures
+func InspectXML(xmlFile io.Reader) (*Inspection, error) {
+	in := &inspector{features: make(map[string]int)}
+	in.result.Outline = []*OutlineNode{}
+	in.result.Figures = []FigureRef{}
+
+	decoder := xml.NewDecoder(xmlFile)
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing XML: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			in.start(t)
+		case xml.EndElement:
+			in.end()
+		case xml.CharData:
+			if in.text != nil && in.rt == 0 {
+				in.text.Write(t)
+			}
+		}
+	}
+	if in.result.Info.LawNum == "" && in.result.Info.Title == "" && len(in.result.Outline) == 0 {
+		return nil, fmt.Errorf("no law found in XML")
+	}
+
+	info := &in.result.Info
+	if in.date.Era != "" {
+		info.Promulgated = in.date.FormatJapanese()
+		info.PromulgatedISO = in.date.ISO()
+	}
+	info.Features = []LawFeature{}
+	for _, feature := range lawFeatures {
+		if count := in.features[feature.Name]; count > 0 {
+			feature.Count = count
+			info.Features = append(info.Features, feature)
+		}
+	}
+	return &in.result, nil
+}
+
+// parent returns the innermost open element, or nil at the root
+func (in *inspector) parent() *inspectFrame {
+	if len(in.stack) == 0 {
+		return nil
+	}
+	return in.stack[len(in.stack)-1]
+}
+
+// start opens an element, placing it in the outline and counting it
+func (in *inspector) start(element xml.StartElement) {
+	name := element.Name.Local
+	attr := func(key string) string {
+		for _, a := range element.Attr {
+			if a.Name.Local == key {
+				return a.Value
+			}
+		}
+		return ""
+	}
+
+	frame := &inspectFrame{name: name, children: make(map[string]int)}
+	parent := in.parent()
+	if parent != nil {
+		frame.path, frame.label, frame.quote = parent.path, parent.label, parent.quote
+		parent.children[name]++
+	}
+	in.stack = append(in.stack, frame)
+	segment, positioned := inspectSegments[name]
+	switch {
+	case !positioned || frame.quote == "":
+		in.features[name]++
+	case frame.quote == "TableColumn" && (name == "Part" || name == "Article" || name == "Paragraph" || name == "Item"):
+		in.features["provisions in tables"]++
+	case frame.quote == "TableColumn" && (name == "Chapter" || name == "Section" || name == "Subsection" || name == "Division"):
+		// The parsed law keeps no divisions inside cells, so they are left out of the book
+		in.features["divisions in tables"]++
+	}
+	if inspectQuotes[name] && frame.quote == "" {
+		frame.quote = name
+	}
+
+	if name == "Rt" {
+		in.rt++
+	}
+	if owner, ok := inspectTitles[name]; ok && in.text == nil && parent != nil && parent.name == owner {
+		in.text = &strings.Builder{}
+		in.textEnd = len(in.stack)
+	}
+
+	switch name {
+	case "Law":
+		in.result.Info.LawType = attr("LawType")
+		in.result.Info.Lang = attr("Lang")
+		in.date.Era = jplaw.Era(attr("Era"))
+		in.date.Year, _ = strconv.Atoi(attr("Year"))
+		in.date.Month, _ = strconv.Atoi(attr("PromulgateMonth"))
+		in.date.Day, _ = strconv.Atoi(attr("PromulgateDay"))
+	case "Table":
+		if attr("WritingMode") == string(jplaw.WritingModeVertical) {
+			in.features["vertical tables"]++
+		}
+	case "TableStruct":
+		in.result.Info.Counts.Tables++
+	case "Fig":
+		in.addFigure(attr("src"))
+	}
+
+	if !positioned || frame.quote != "" {
+		return
+	}
+
+	if segment != "main" {
+		segment = fmt.Sprintf("%s-%d", segment, parent.children[name])
+	}
+	if frame.path != "" {
+		segment = frame.path + "/" + segment
+	}
+	frame.path = segment
+	in.count(name)
+
+	switch name {
+	case "Paragraph":
+		// Only paragraphs directly under a provision, without articles, are in the outline
+		frame.title = fmt.Sprintf("第%s項", attr("Num"))
+		in.appendLabel(frame, frame.title)
+		if parent.name != "MainProvision" && parent.name != "SupplProvision" {
+			return
+		}
+	case "Item", "Subitem1", "Subitem2":
+		return
+	case "MainProvision":
+		frame.title = "本則"
+	}
+
+	frame.node = &OutlineNode{
+		Kind:  inspectSegments[name],
+		Num:   attr("Num"),
+		Title: frame.title,
+		Path:  frame.path,
+	}
+	if name == "SupplProvision" {
+		frame.node.Caption = attr("AmendLawNum")
+	}
+	for i := len(in.stack) - 2; i >= 0; i-- {
+		if owner := in.stack[i].node; owner != nil {
+			owner.Children = append(owner.Children, frame.node)
+			return
+		}
+	}
+	in.result.Outline = append(in.result.Outline, frame.node)
+}
+
+// end closes the innermost element, storing the text of a title
+func (in *inspector) end() {
+	if len(in.stack) == 0 {
+		return
+	}
+	frame := in.stack[len(in.stack)-1]
+	if frame.name == "Rt" {
+		in.rt--
+	}
+	if in.text != nil && len(in.stack) == in.textEnd {
+		in.setTitle(frame.name, strings.TrimSpace(in.text.String()))
+		in.text = nil
+	}
+	in.stack = in.stack[:len(in.stack)-1]
+}
+
+// setTitle stores the text of a title element on the element it titles
+func (in *inspector) setTitle(name, text string) {
+	owner := in.stack[len(in.stack)-2]
+	switch name {
+	case "LawTitle":
+		in.result.Info.Title = text
+		return
+	case "LawNum":
+		in.result.Info.LawNum = text
+		return
+	case "ArticleCaption":
+		if owner.node != nil {
+			owner.node.Caption = text
+		}
+		return
+	case "SupplProvisionLabel":
+		text = strings.Join(strings.Fields(text), "")
+	}
+
+	owner.title = text
+	if owner.node != nil {
+		owner.node.Title = text
+	}
+	if owner.quote != "" || owner.name == "FigStruct" {
+		return
+	}
+	switch owner.name {
+	case "Item":
+		in.appendLabel(owner, "第"+text+"号")
+	case "Subitem1", "Subitem2":
+		in.appendLabel(owner, text)
+	default:
+		// Divisions are located by their number, e.g. 第一章 for 第一章　総則
+		if fields := strings.Fields(text); len(fields) > 0 {
+			in.appendLabel(owner, fields[0])
+		}
+	}
+}
+
+// appendLabel adds a part to the location of frame
+func (in *inspector) appendLabel(frame *inspectFrame, label string) {
+	if frame.label != "" {
+		label = frame.label + " " + label
+	}
+	frame.label = label
+}
+
+// count counts an element that is part of the law
+func (in *inspector) count(name string) {
+	counts := &in.result.Info.Counts
+	switch name {
+	case "Part":
+		counts.Parts++
+	case "Chapter":
+		counts.Chapters++
+	case "Section":
+		counts.Sections++
+	case "Article":
+		counts.Articles++
+	case "Paragraph":
+		counts.Paragraphs++
+	case "Item":
+		counts.Items++
+	case "SupplProvision":
+		counts.SupplProvisions++
+	case "AppdxTable", "AppdxNote", "AppdxStyle", "AppdxFormat", "AppdxFig":
+		counts.Appendixes++
+	}
+}
+
+// addFigure records a Fig element at the current position
+func (in *inspector) addFigure(src string) {
+	in.result.Info.Counts.Figures++
+	frame := in.parent()
+	figure := FigureRef{Src: src, File: generateImageFilename(src), Path: frame.path, Location: frame.label}
+	container := len(in.stack) - 2
+	if container >= 0 && in.stack[container].name == "FigStruct" {
+		figure.Title = in.stack[container].title
+		container--
+	}
+	if container >= 0 {
+		figure.Element = in.stack[container].name
+	}
+	in.result.Figures = append(in.result.Figures, figure)
+}
@@ -0,0 +1,168 @@
+package jplaw2epub
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInspectXML(t *testing.T) {
+	inspection, err := InspectXML(bytes.NewReader(readTestdata(t, "json/chapters.xml")))
+	if err != nil {
+		t.Fatalf("InspectXML() error = %v", err)
+	}
+
+	info := inspection.Info
+	if info.Title != "試験法" || info.LawNum != "令和五年法律第十号" || info.LawType != "Act" {
+		t.Errorf("info = %+v", info)
+	}
+	if info.Promulgated != "令和5年4月1日" || info.PromulgatedISO != "2023-04-01" {
+		t.Errorf("promulgated = %q, %q", info.Promulgated, info.PromulgatedISO)
+	}
+	wantCounts := LawCounts{
+		Chapters: 2, Sections: 1, Articles: 3, Paragraphs: 5, Items: 1,
+		SupplProvisions: 1, Tables: 1, Figures: 1,
+	}
+	if info.Counts != wantCounts {
+		t.Errorf("counts = %+v, want %+v", info.Counts, wantCounts)
+	}
+	var features []string
+	for _, feature := range info.Features {
+		features = append(features, feature.Name)
+	}
+	if want := []string{"Chapter", "Section", "Subitem1", "SupplProvision", "TableStruct", "Fig"}; !reflect.DeepEqual(features, want) {
+		t.Errorf("features = %v, want %v", features, want)
+	}
+
+	var outline []string
+	var walk func(nodes []*OutlineNode, depth int)
+	walk = func(nodes []*OutlineNode, depth int) {
+		for _, node := range nodes {
+			outline = append(outline, strings.Repeat("  ", depth)+node.Title+node.Caption+" "+node.Path)
+			walk(node.Children, depth+1)
+		}
+	}
+	walk(inspection.Outline, 0)
+	wantOutline := []string{
+		"本則 main",
+		"  第一章　総則 main/chapter-1",
+		"    第一条（目的） main/chapter-1/article-1",
+		"    第二条（定義） main/chapter-1/article-2",
+		"  第二章　試験 main/chapter-2",
+		"    第一節　通則 main/chapter-2/section-1",
+		"      第三条 main/chapter-2/section-1/article-1",
+		"附則 suppl-1",
+		"  第1項 suppl-1/paragraph-1",
+	}
+	if !reflect.DeepEqual(outline, wantOutline) {
+		t.Errorf("outline =\n%s\nwant\n%s", strings.Join(outline, "\n"), strings.Join(wantOutline, "\n"))
+	}
+
+	wantFigures := []FigureRef{{
+		Src:      "./pict/H0001.jpg",
+		File:     "H0001.png",
+		Title:    "様式図",
+		Path:     "main/chapter-1/article-2/paragraph-2",
+		Location: "第一章 第二条 第2項",
+		Element:  "Paragraph",
+	}}
+	if !reflect.DeepEqual(inspection.Figures, wantFigures) {
+		t.Errorf("figures = %+v, want %+v", inspection.Figures, wantFigures)
+	}
+}
+
+func TestInspectXMLUnusualStructure(t *testing.T) {
+	xml := `<Law Era="Showa" Year="64" PromulgateMonth="1" PromulgateDay="8">
+  <LawNum>昭和六十四年法律第一号</LawNum>
+  <LawBody>
+    <LawTitle>テスト<Ruby>法<Rt>ほう</Rt></Ruby></LawTitle>
+    <TOC><TOCLabel>目次</TOCLabel></TOC>
+    <MainProvision>
+      <Part Num="1">
+        <PartTitle>第一編　総則</PartTitle>
+        <Chapter Num="1">
+          <ChapterTitle>第一章　通則</ChapterTitle>
+          <Article Num="1">
+            <ArticleTitle>第一条</ArticleTitle>
+            <Paragraph Num="1">
+              <ParagraphSentence><Sentence>本文</Sentence></ParagraphSentence>
+              <Item Num="1">
+                <ItemTitle>一</ItemTitle>
+                <ItemSentence><Sentence>号</Sentence></ItemSentence>
+                <FigStruct><Fig src="./pict/item.jpg"/></FigStruct>
+              </Item>
+              <TableStruct>
+                <Table WritingMode="vertical">
+                  <TableRow><TableColumn>
+                    <Article Num="9"><ArticleTitle>第九条</ArticleTitle></Article>
+                    <Section Num="1"><SectionTitle>第一節</SectionTitle></Section>
+                    <Fig src="./pict/cell.jpg"/>
+                  </TableColumn></TableRow>
+                </Table>
+              </TableStruct>
+            </Paragraph>
+          </Article>
+        </Chapter>
+      </Part>
+    </MainProvision>
+    <AppdxStyle>
+      <AppdxStyleTitle>様式第一</AppdxStyleTitle>
+      <StyleStruct><Style><Fig src="./pict/style.pdf"/></Style></StyleStruct>
+    </AppdxStyle>
+  </LawBody>
+</Law>`
+	inspection, err := InspectXML(strings.NewReader(xml))
+	if err != nil {
+		t.Fatalf("InspectXML() error = %v", err)
+	}
+
+	if inspection.Info.Title != "テスト法" {
+		t.Errorf("title = %q, want ruby readings left out", inspection.Info.Title)
+	}
+	if inspection.Info.Promulgated != "昭和64年1月8日" || inspection.Info.PromulgatedISO != "" {
+		t.Errorf("promulgated = %q, %q; want no ISO date outside the era", inspection.Info.Promulgated, inspection.Info.PromulgatedISO)
+	}
+	if counts := inspection.Info.Counts; counts.Parts != 1 || counts.Articles != 1 || counts.Appendixes != 1 || counts.Figures != 3 {
+		t.Errorf("counts = %+v", counts)
+	}
+	unsupported := map[string]bool{}
+	for _, feature := range inspection.Info.Features {
+		if feature.Unsupported {
+			unsupported[feature.Name] = true
+		}
+	}
+	if !reflect.DeepEqual(unsupported, map[string]bool{"TOC": true, "Part": true, "divisions in tables": true}) {
+		t.Errorf("unsupported features = %v", unsupported)
+	}
+	names := map[string]bool{}
+	for _, feature := range inspection.Info.Features {
+		names[feature.Name] = true
+	}
+	for _, want := range []string{"vertical tables", "provisions in tables", "Ruby", "AppdxStyle"} {
+		if !names[want] {
+			t.Errorf("feature %q not detected in %+v", want, inspection.Info.Features)
+		}
+	}
+
+	var got []string
+	for _, figure := range inspection.Figures {
+		got = append(got, figure.Src+" "+figure.Path+" "+figure.Location+" "+figure.Element)
+	}
+	want := []string{
+		"./pict/item.jpg main/part-1/chapter-1/article-1/paragraph-1/item-1 第一編 第一章 第一条 第1項 第一号 Item",
+		"./pict/cell.jpg main/part-1/chapter-1/article-1/paragraph-1 第一編 第一章 第一条 第1項 TableColumn",
+		"./pict/style.pdf appdx-style-1 様式第一 Style",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("figures =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestInspectXMLErrors(t *testing.T) {
+	for _, input := range []string{"", "<Law><LawBody>", "<html><body/></html>"} {
+		if _, err := InspectXML(strings.NewReader(input)); err == nil {
+			t.Errorf("InspectXML(%q) error = nil, want error", input)
+		}
+	}
+}
@@ -65,7 +65,7 @@ func processItemWithImages(item *jplaw.Item, imgProc ImageProcessorInterface) st
 	// Process FigStruct if present
 	if len(item.FigStruct) > 0 {
 		for _, fig := range item.FigStruct {
-			if imgProc != nil {
+			if hasImages(imgProc) {
 				if html, err := imgProc.ProcessFigStruct(&fig); err == nil {
 					body += html
 				}
@@ -122,7 +122,7 @@ func processSubitem1WithImages(subitem *jplaw.Subitem1, imgProc ImageProcessorIn
 	// Process FigStruct if present
 	if len(subitem.FigStruct) > 0 {
 		for _, fig := range subitem.FigStruct {
-			if imgProc != nil {
+			if hasImages(imgProc) {
 				if html, err := imgProc.ProcessFigStruct(&fig); err == nil {
 					body += html
 				}
@@ -179,7 +179,7 @@ func processSubitem2WithImages(subitem *jplaw.Subitem2, imgProc ImageProcessorIn
 	// Process FigStruct if present
 	if len(subitem.FigStruct) > 0 {
 		for _, fig := range subitem.FigStruct {
-			if imgProc != nil {
+			if hasImages(imgProc) {
 				if html, err := imgProc.ProcessFigStruct(&fig); err == nil {
 					body += html
 				}
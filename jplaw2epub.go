@@ -14,6 +14,8 @@
 package jplaw2epub
 
 import (
+	"bytes"
+	"context"
 	"encoding/xml"
 	"fmt"
 	"io"
@@ -21,18 +23,87 @@ import (
 	"path/filepath"
 
 	"github.com/go-shiori/go-epub"
-	lawapi "go.ngs.io/jplaw-api-v2"
 	"go.ngs.io/jplaw-xml"
 )
 
 // EPUBOptions contains options for EPUB creation
 type EPUBOptions struct {
-	// APIClient is the jplaw API client for downloading images
-	APIClient *lawapi.Client
-	// RevisionID is the revision ID for fetching attachments
+	// APIClient is the jplaw API client for downloading images, usually a *lawapi.Client
+	APIClient APIClient
+	// RevisionID is the revision ID (lawID_date_revision) used as the book identifier and for fetching attachments
 	RevisionID string
 	// MaxImageHeight is the maximum height for images (e.g., "300px", "80vh", "50%")
 	MaxImageHeight string
+	// VerticalWriting lays out PDF output and the generated cover title in vertical (縦書き) columns
+	VerticalWriting bool
+	// SearchIndex adds a scripted full-text search page with a static keyword index fallback
+	SearchIndex bool
+	// DefinitionIndex adds a 定義語索引 chapter listing the terms the law defines
+	DefinitionIndex bool
+	// DefinitionLinks links the first occurrence of each defined term in later articles to its definition
+	DefinitionLinks bool
+	// PopupNotes turns article references (第十条) and defined terms into EPUB3 noterefs
+	// whose target text pops up in supporting readers. It takes precedence over DefinitionLinks.
+	PopupNotes bool
+	// Progress, when set, receives events as chapters, articles and images are processed.
+	// WriteEPUBWithOptions and WriteEPUBToWithOptions report packaging.
+	Progress ProgressFunc
+	// CoverImage is a PNG or JPEG image used as the cover instead of a generated one
+	CoverImage []byte
+	// CoverTemplate is a PNG or JPEG background the generated cover text is drawn on
+	CoverTemplate []byte
+	// CoverFont is the OpenType or TrueType font (or collection) of the generated cover.
+	// When empty, common system CJK fonts are tried. If none is found, creating the book fails
+	// with ErrNoCoverFont unless CoverImage or NoCover is set.
+	CoverFont []byte
+	// NoCover disables the cover
+	NoCover bool
+	// EmbedFont is an OpenType or TrueType font (or collection) embedded in the EPUB and used for
+	// all text. It is subset to the characters of the law, ruby included, when the EPUB is written.
+	EmbedFont []byte
+	// Theme selects the colours of the text and page: ThemeDefault, ThemeSepia or ThemeDark
+	Theme Theme
+	// Metadata replaces the title, author, publisher or description derived from the law
+	Metadata MetadataOverrides
+	// DateDisplay selects the calendar of the dates on the title page, cover and description:
+	// DateDisplayJapanese (和暦, the default), DateDisplayGregorian (西暦) or DateDisplayBoth
+	DateDisplay DateDisplay
+	// ArabicTOC shows the numbering of chapters and articles in the table of contents in
+	// Arabic numerals (第123条の2 instead of 第百二十三条の二)
+	ArabicTOC bool
+	// Chapters restricts the main provision to the chapters with these numbers
+	Chapters []ProvisionRange
+	// Articles restricts the main provision to the articles with these numbers
+	Articles []ProvisionRange
+	// NoSupplProvisions leaves out the supplementary provisions (附則)
+	NoSupplProvisions bool
+	// NoAppendixes leaves out the appendix tables, notes, styles, formats and figures
+	NoAppendixes bool
+	// WideTables selects the layout of tables with more than WideTableColumns columns:
+	// WideTableScroll (the default), WideTableStack or WideTableImage. WideTableImage draws
+	// the text with EmbedFont, CoverFont or a system CJK font, in that order.
+	WideTables WideTableStrategy
+	// WideTableColumns is the number of columns above which a table is wide (default 6)
+	WideTableColumns int
+	// Annotations are added to the articles of the main provision as commentary boxes,
+	// highlighted phrases and see-also links
+	Annotations Annotations
+	// AnnotationWarning, when set, receives the annotations whose article, phrase or see-also
+	// article is not in the law, as happens when an amendment deletes or renumbers them
+	AnnotationWarning func(warning AnnotationWarning)
+	// Validate checks the EPUB with ValidateEPUB before it is written. Problems are returned
+	// as a *ValidationError and nothing is written.
+	Validate bool
+}
+
+// Book is an EPUB created from a law by NewBookFromXMLFile, NewBookFromXMLPath or NewBookFromLaw.
+// Besides the go-epub book, it holds the package metadata, accessibility metadata, theme and
+// embedded font that its Write and WriteTo methods add to the archive. Like a go-epub book,
+// it can be written only once.
+type Book struct {
+	*epub.Epub
+	post postProcessOptions
+	opts *EPUBOptions
 }
 
 // CreateEPUBFromXMLFile creates an EPUB file from a jplaw XML file reader.
@@ -59,24 +130,30 @@ func CreateEPUBFromXMLFile(xmlFile io.Reader) (*epub.Epub, error) {
 
 // CreateEPUBFromXMLFileWithOptions creates an EPUB file with image support
 func CreateEPUBFromXMLFileWithOptions(xmlFile io.Reader, opts *EPUBOptions) (*epub.Epub, error) {
-	// Load and parse XML data
-	data, err := loadXMLDataFromReader(xmlFile)
-	if err != nil {
-		return nil, fmt.Errorf("loading XML data: %w", err)
-	}
-
-	// Create EPUB
-	book, err := createEPUBFromData(data)
-	if err != nil {
-		return nil, fmt.Errorf("creating EPUB: %w", err)
-	}
+	return CreateEPUBFromXMLFileWithContext(context.Background(), xmlFile, opts)
+}
 
-	// Process chapters and content
-	if err := processChaptersWithOptions(book, data, opts); err != nil {
-		return nil, fmt.Errorf("processing chapters: %w", err)
-	}
+// CreateEPUBFromXMLFileWithContext creates an EPUB file, stopping with ctx's error
+// when it is canceled or its deadline passes. Image downloads are abandoned as well.
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+//	defer cancel()
+//
+//	book, err := jplaw2epub.CreateEPUBFromXMLFileWithContext(ctx, xmlFile, opts)
+//	if errors.Is(err, context.DeadlineExceeded) {
+//		// the conversion took too long
+//	}
+func CreateEPUBFromXMLFileWithContext(ctx context.Context, xmlFile io.Reader, opts *EPUBOptions) (*epub.Epub, error) {
+	return bookEpub(NewBookFromXMLFile(ctx, xmlFile, opts))
+}
 
-	return book, nil
+// CreateEPUBFromLawWithContext creates an EPUB file from parsed law data, such as the
+// consolidated law returned by ApplyAmendments, honoring ctx. jplaw.Law does not hold the
+// amendment provisions (改正規定) of amending laws; convert their XML to include them.
+func CreateEPUBFromLawWithContext(ctx context.Context, data *jplaw.Law, opts *EPUBOptions) (*epub.Epub, error) {
+	return bookEpub(NewBookFromLaw(ctx, data, opts))
 }
 
 // CreateEPUBFromXMLPath creates an EPUB file from a jplaw XML file path.
@@ -97,19 +174,122 @@ func CreateEPUBFromXMLPath(xmlPath string) (*epub.Epub, error) {
 
 // CreateEPUBFromXMLPathWithOptions creates an EPUB file with image support
 func CreateEPUBFromXMLPathWithOptions(xmlPath string, opts *EPUBOptions) (*epub.Epub, error) {
+	return CreateEPUBFromXMLPathWithContext(context.Background(), xmlPath, opts)
+}
+
+// CreateEPUBFromXMLPathWithContext creates an EPUB file from a file path, honoring ctx
+func CreateEPUBFromXMLPathWithContext(ctx context.Context, xmlPath string, opts *EPUBOptions) (*epub.Epub, error) {
+	return bookEpub(NewBookFromXMLPath(ctx, xmlPath, opts))
+}
+
+// bookEpub returns the go-epub book of a Book, for the functions returning *epub.Epub
+func bookEpub(book *Book, err error) (*epub.Epub, error) {
+	if err != nil {
+		return nil, err
+	}
+	return book.Epub, nil
+}
+
+// NewBookFromXMLFile creates a Book from a jplaw XML file reader, honoring ctx. Unlike
+// CreateEPUBFromXMLFileWithContext, the book keeps opts and the metadata derived from the law,
+// which Book.Write and Book.WriteTo add to the archive.
+//
+// Example:
+//
+//	book, err := jplaw2epub.NewBookFromXMLFile(ctx, xmlFile, opts)
+//	if err != nil {
+//		return err
+//	}
+//	return book.Write("output.epub")
+func NewBookFromXMLFile(ctx context.Context, xmlFile io.Reader, opts *EPUBOptions) (*Book, error) {
+	// Load and parse XML data
+	data, amendments, err := loadLawFromReader(xmlFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading XML data: %w", err)
+	}
+
+	return createEPUBFromLaw(ctx, data, amendments, opts)
+}
+
+// NewBookFromXMLPath creates a Book from a jplaw XML file path, honoring ctx
+func NewBookFromXMLPath(ctx context.Context, xmlPath string, opts *EPUBOptions) (*Book, error) {
 	xmlFile, err := os.Open(xmlPath)
 	if err != nil {
 		return nil, fmt.Errorf("opening XML file: %w", err)
 	}
 	defer xmlFile.Close()
 
-	return CreateEPUBFromXMLFileWithOptions(xmlFile, opts)
+	return NewBookFromXMLFile(ctx, xmlFile, opts)
+}
+
+// NewBookFromLaw creates a Book from parsed law data, such as the consolidated law returned
+// by ApplyAmendments, honoring ctx. Like CreateEPUBFromLawWithContext, it cannot include the
+// amendment provisions of amending laws.
+func NewBookFromLaw(ctx context.Context, data *jplaw.Law, opts *EPUBOptions) (*Book, error) {
+	return createEPUBFromLaw(ctx, data, nil, opts)
+}
+
+// createEPUBFromLaw builds the EPUB for parsed law data and its amendment provisions
+func createEPUBFromLaw(ctx context.Context, data *jplaw.Law, amendments amendProvisionIndex, opts *EPUBOptions) (*Book, error) {
+	// Create EPUB
+	book, err := createEPUBFromData(data)
+	if err != nil {
+		return nil, fmt.Errorf("creating EPUB: %w", err)
+	}
+	book.opts = opts
+	if opts != nil && opts.RevisionID != "" {
+		book.SetIdentifier(lawIdentifier(data, opts.RevisionID))
+	}
+	if opts != nil && opts.DateDisplay != "" {
+		if _, err := ParseDateDisplay(string(opts.DateDisplay)); err != nil {
+			return nil, err
+		}
+		book.SetDescription(lawDescription(data, opts.DateDisplay))
+	}
+	if opts != nil {
+		theme, err := ParseTheme(string(opts.Theme))
+		if err != nil {
+			return nil, err
+		}
+		book.post.theme = theme
+		applyMetadataOverrides(book, opts.Metadata)
+	}
+	if err := addCover(book.Epub, data, opts); err != nil {
+		return nil, fmt.Errorf("adding cover: %w", err)
+	}
+	if opts != nil && len(opts.EmbedFont) > 0 {
+		if _, err := parseFont(opts.EmbedFont); err != nil {
+			return nil, fmt.Errorf("reading embedded font: %w", err)
+		}
+		book.post.font = opts.EmbedFont
+	}
+
+	// Keep only the selected parts of an excerpt
+	data, err = excerptLaw(data, opts)
+	if err != nil {
+		return nil, fmt.Errorf("selecting excerpt: %w", err)
+	}
+
+	// Process chapters and content
+	if err := processChaptersWithContext(ctx, book, data, amendments, opts); err != nil {
+		return nil, fmt.Errorf("processing chapters: %w", err)
+	}
+
+	if opts != nil && opts.SearchIndex {
+		if err := addSearchPage(withContext(ctx, book), data); err != nil {
+			return nil, fmt.Errorf("adding search page: %w", err)
+		}
+		book.post.scripted = true
+	}
+
+	return book, nil
 }
 
 // WriteEPUB writes the EPUB book to the specified path.
 //
 // The function ensures the directory exists before writing and returns an error
-// if the write operation fails.
+// if the write operation fails. Books created with a theme, an embedded font or a
+// search index need WriteEPUBWithOptions and the options they were created with.
 //
 // Example:
 //
@@ -118,49 +298,204 @@ func CreateEPUBFromXMLPathWithOptions(xmlPath string, opts *EPUBOptions) (*epub.
 //		return err
 //	}
 func WriteEPUB(book *epub.Epub, destPath string) error {
+	return WriteEPUBWithOptions(book, destPath, nil)
+}
+
+// WriteEPUBWithOptions writes the EPUB book to the specified path, adding the theme and
+// embedded font of opts, marking the search page as scripted when opts.SearchIndex is set,
+// and reporting packaging to opts.Progress when it is set. Without any of these the book
+// is written as go-epub writes it. The package and accessibility metadata derived from the
+// law need a Book.
+func WriteEPUBWithOptions(book *epub.Epub, destPath string, opts *EPUBOptions) error {
+	return writeEPUBFile(book, postProcessOptionsOf(opts), destPath, opts)
+}
+
+// WriteEPUBTo writes the EPUB book to w, such as an HTTP response or standard output.
+//
+// Example:
+//
+//	err := jplaw2epub.WriteEPUBTo(book, os.Stdout)
+//	if err != nil {
+//		return err
+//	}
+func WriteEPUBTo(book *epub.Epub, w io.Writer) error {
+	return WriteEPUBToWithOptions(book, w, nil)
+}
+
+// WriteEPUBToWithOptions writes the EPUB book to w like WriteEPUBWithOptions
+func WriteEPUBToWithOptions(book *epub.Epub, w io.Writer, opts *EPUBOptions) error {
+	return writeEPUB(book, postProcessOptionsOf(opts), w, opts)
+}
+
+// Write writes the book to the specified path with everything go-epub cannot express,
+// following the Validate and Progress options the book was created with. It replaces
+// the Write method of the embedded *epub.Epub.
+func (b *Book) Write(destPath string) error {
+	return writeEPUBFile(b.Epub, &b.post, destPath, b.opts)
+}
+
+// WriteTo writes the book to w like Write, returning the number of bytes written.
+// It replaces the WriteTo method of the embedded *epub.Epub.
+func (b *Book) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	err := writeEPUB(b.Epub, &b.post, cw, b.opts)
+	return cw.n, err
+}
+
+// countingWriter counts the bytes written to w
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+// Write writes p to the underlying writer and counts it
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// writeEPUBFile writes book, post-processed, to destPath
+func writeEPUBFile(book *epub.Epub, post *postProcessOptions, destPath string, opts *EPUBOptions) error {
 	// Ensure directory exists
-	dir := filepath.Dir(destPath)
-	if err := os.MkdirAll(dir, 0o755); err != nil {
-		return fmt.Errorf("creating directory: %w", err)
+	mkdir := func() error {
+		if err := os.MkdirAll(filepath.Dir(destPath), 0o755); err != nil {
+			return fmt.Errorf("creating directory: %w", err)
+		}
+		return nil
 	}
 
-	// Write EPUB
-	if err := book.Write(destPath); err != nil {
-		return fmt.Errorf("writing EPUB file: %w", err)
+	if opts != nil && opts.Validate {
+		// Nothing is written for a book with problems, so the file is created after the check
+		var buf bytes.Buffer
+		if err := packageEPUB(book, post, &buf, opts); err != nil {
+			return err
+		}
+		if err := mkdir(); err != nil {
+			return err
+		}
+		if err := os.WriteFile(destPath, buf.Bytes(), 0o644); err != nil {
+			return fmt.Errorf("writing EPUB file: %w", err)
+		}
+	} else {
+		if err := mkdir(); err != nil {
+			return err
+		}
+		f, err := os.Create(destPath)
+		if err != nil {
+			return fmt.Errorf("creating EPUB file: %w", err)
+		}
+		err = packageEPUB(book, post, f, opts)
+		if closeErr := f.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("writing EPUB file: %w", closeErr)
+		}
+		if err != nil {
+			os.Remove(destPath)
+			return err
+		}
 	}
 
+	reportPackaging(opts, ProgressDone, destPath)
+	return nil
+}
+
+// writeEPUB writes book, post-processed, to w
+func writeEPUB(book *epub.Epub, post *postProcessOptions, w io.Writer, opts *EPUBOptions) error {
+	if err := packageEPUB(book, post, w, opts); err != nil {
+		return err
+	}
+	reportPackaging(opts, ProgressDone, "")
+	return nil
+}
+
+// reportPackaging sends a packaging event to opts.Progress
+func reportPackaging(opts *EPUBOptions, eventType ProgressEventType, filename string) {
+	if opts != nil && opts.Progress != nil {
+		opts.Progress(ProgressEvent{Type: eventType, Filename: filename})
+	}
+}
+
+// packageEPUB writes book, post-processed, to w. With opts.Validate the book is checked in
+// memory first and nothing is written when it has problems.
+func packageEPUB(book *epub.Epub, post *postProcessOptions, w io.Writer, opts *EPUBOptions) error {
+	reportPackaging(opts, ProgressPackagingStarted, "")
+	if opts == nil || !opts.Validate {
+		return renderEPUB(book, post, w)
+	}
+
+	var buf bytes.Buffer
+	if err := renderEPUB(book, post, &buf); err != nil {
+		return err
+	}
+	if err := validateOutput(buf.Bytes(), opts); err != nil {
+		return err
+	}
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("writing EPUB: %w", err)
+	}
+	return nil
+}
+
+// renderEPUB serializes the book to w, through the post-processing when post asks for any
+func renderEPUB(book *epub.Epub, post *postProcessOptions, w io.Writer) error {
+	if !post.needed() {
+		if _, err := book.WriteTo(w); err != nil {
+			return fmt.Errorf("writing EPUB: %w", err)
+		}
+		return nil
+	}
+
+	// The archive is read back to be rewritten, so go-epub writes it to memory
+	var buf bytes.Buffer
+	if _, err := book.WriteTo(&buf); err != nil {
+		return fmt.Errorf("writing EPUB: %w", err)
+	}
+	if err := postProcessEPUB(buf.Bytes(), post, w); err != nil {
+		return fmt.Errorf("post-processing EPUB: %w", err)
+	}
 	return nil
 }
 
 // loadXMLDataFromReader loads XML data from an io.Reader
 func loadXMLDataFromReader(reader io.Reader) (*jplaw.Law, error) {
+	data, _, err := loadLawFromReader(reader)
+	return data, err
+}
+
+// loadLawFromReader loads XML data from an io.Reader with its amendment provisions
+func loadLawFromReader(reader io.Reader) (*jplaw.Law, amendProvisionIndex, error) {
 	byteValue, err := io.ReadAll(reader)
 	if err != nil {
-		return nil, fmt.Errorf("reading XML data: %w", err)
+		return nil, nil, fmt.Errorf("reading XML data: %w", err)
 	}
 
 	var data jplaw.Law
 	if err := xml.Unmarshal(byteValue, &data); err != nil {
-		return nil, fmt.Errorf("unmarshalling XML: %w", err)
+		return nil, nil, fmt.Errorf("unmarshalling XML: %w", err)
 	}
 
-	return &data, nil
+	amendments, err := decodeAmendProvisions(byteValue, &data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &data, amendments, nil
 }
 
 // createEPUBFromData creates and sets up EPUB from law data
-func createEPUBFromData(data *jplaw.Law) (*epub.Epub, error) {
+func createEPUBFromData(data *jplaw.Law) (*Book, error) {
 	if data.LawBody.LawTitle == nil {
 		return nil, fmt.Errorf("law title is required")
 	}
-	book, err := epub.NewEpub(data.LawBody.LawTitle.Content)
+	e, err := epub.NewEpub(data.LawBody.LawTitle.Content)
 	if err != nil {
 		return nil, fmt.Errorf("creating epub: %w", err)
 	}
+	book := &Book{Epub: e}
 
 	setupEPUBMetadata(book, data)
 
 	// Add CSS styles for proper formatting
-	if err := AddCSSToEPUB(book); err != nil {
+	if err := AddCSSToEPUB(book.Epub); err != nil {
 		return nil, fmt.Errorf("adding CSS to EPUB: %w", err)
 	}
 
@@ -168,12 +503,13 @@ func createEPUBFromData(data *jplaw.Law) (*epub.Epub, error) {
 }
 
 // createImageProcessor creates an image processor from options
-func createImageProcessor(book *epub.Epub, opts *EPUBOptions) ImageProcessorInterface {
+func createImageProcessor(ctx context.Context, book BookWriter, opts *EPUBOptions, progress *progressReporter) ImageProcessorInterface {
 	if opts == nil || opts.APIClient == nil || opts.RevisionID == "" {
 		return nil
 	}
 
-	imgProc := NewImageProcessor(opts.APIClient, opts.RevisionID, book)
+	imgProc := NewImageProcessorWithContext(ctx, opts.APIClient, opts.RevisionID, book)
+	imgProc.progress = progress
 	if opts.MaxImageHeight != "" {
 		imgProc.SetMaxImageHeight(opts.MaxImageHeight)
 	}
@@ -181,12 +517,66 @@ func createImageProcessor(book *epub.Epub, opts *EPUBOptions) ImageProcessorInte
 }
 
 // processChaptersWithOptions processes all chapters with image support
-func processChaptersWithOptions(book *epub.Epub, data *jplaw.Law, opts *EPUBOptions) error {
+func processChaptersWithOptions(book BookWriter, data *jplaw.Law, opts *EPUBOptions) error {
+	return processChaptersWithContext(context.Background(), book, data, nil, opts)
+}
+
+// processChaptersWithContext processes all chapters with the amendment provisions of their
+// paragraphs, stopping at the next section once ctx is done
+func processChaptersWithContext(ctx context.Context, book BookWriter, data *jplaw.Law, amendments amendProvisionIndex, opts *EPUBOptions) error {
+	book = withContext(ctx, book)
+	if opts != nil && opts.ArabicTOC {
+		book = &arabicTOCWriter{BookWriter: book}
+	}
+	book, err := newWideTableWriter(book, opts)
+	if err != nil {
+		return fmt.Errorf("laying out wide tables: %w", err)
+	}
+
+	var law *JSONLaw
+	if opts != nil && (opts.DefinitionIndex || opts.DefinitionLinks || opts.PopupNotes || len(opts.Annotations) > 0) {
+		law = NewJSONLaw(data)
+	}
+
+	// Report chapters, articles and images as they are added
+	var progress *progressReporter
+	if opts != nil && opts.Progress != nil {
+		progress = newProgressReporter(opts.Progress, data, law, opts.APIClient != nil && opts.RevisionID != "")
+		title := ""
+		if data.LawBody.LawTitle != nil {
+			title = jsonTitle(data.LawBody.LawTitle.Content, data.LawBody.LawTitle.Ruby).Text
+		}
+		progress.emit(ProgressStarted, title, "")
+		book = &progressBookWriter{BookWriter: book, progress: progress}
+	}
+
+	// Extract defined terms for the index, back-links and popup footnotes
+	var terms []definedTerm
+	indexBook := book
+	if opts != nil && (opts.DefinitionIndex || opts.DefinitionLinks || opts.PopupNotes) {
+		terms = extractDefinitions(law)
+		switch {
+		case opts.PopupNotes:
+			book = newNoteRefWriter(book, law, terms)
+		case opts.DefinitionLinks && len(terms) > 0:
+			book = newDefinitionLinker(book, terms)
+		}
+	}
+
+	// Annotate articles before their references and terms are linked, so highlights are not split
+	if opts != nil && len(opts.Annotations) > 0 {
+		book = newAnnotationWriter(book, data, law, opts.Annotations, opts.AnnotationWarning)
+	}
+
 	// Create image processor if API client is available
-	imgProc := createImageProcessor(book, opts)
+	imgProc := withAmendProvisions(createImageProcessor(ctx, book, opts, progress), amendments)
 
 	// Add title page as the first page
-	if err := addTitlePage(book, data); err != nil {
+	display := DateDisplayJapanese
+	if opts != nil && opts.DateDisplay != "" {
+		display = opts.DateDisplay
+	}
+	if err := addTitlePage(book, data, display, excerptDescription(opts)); err != nil {
 		return fmt.Errorf("adding title page: %w", err)
 	}
 
@@ -230,5 +620,12 @@ func processChaptersWithOptions(book *epub.Epub, data *jplaw.Law, opts *EPUBOpti
 		}
 	}
 
+	// Add the defined-terms index (定義語索引)
+	if opts != nil && opts.DefinitionIndex {
+		if err := addDefinitionIndex(indexBook, terms); err != nil {
+			return fmt.Errorf("adding definition index: %w", err)
+		}
+	}
+
 	return nil
 }
@@ -1,8 +1,13 @@
 package jplaw2epub
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"go.ngs.io/jplaw-xml"
@@ -120,6 +125,7 @@ func TestCreateEPUBFromXMLFileWithOptions(t *testing.T) {
 
 	opts := &EPUBOptions{
 		MaxImageHeight: "100px",
+		NoCover:        true,
 	}
 
 	book, err := CreateEPUBFromXMLFileWithOptions(xmlFile, opts)
@@ -195,6 +201,87 @@ func TestWriteEPUB(t *testing.T) {
 	}
 }
 
+func TestWriteEPUBTo(t *testing.T) {
+	book, err := CreateEPUBFromXMLFile(strings.NewReader(testXMLSimple))
+	if err != nil {
+		t.Fatalf("Failed to create EPUB: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteEPUBTo(book, &buf); err != nil {
+		t.Fatalf("WriteEPUBTo() error = %v", err)
+	}
+
+	// The streamed archive matches the file written by WriteEPUB
+	epubPath := filepath.Join(t.TempDir(), "test.epub")
+	if err := WriteEPUB(book, epubPath); err != nil {
+		t.Fatalf("WriteEPUB() error = %v", err)
+	}
+	files := readZipFiles(t, epubPath)
+
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("WriteEPUBTo() did not write a zip archive: %v", err)
+	}
+	if len(reader.File) != len(files) || reader.File[0].Name != "mimetype" {
+		t.Errorf("WriteEPUBTo() wrote %d entries starting with %s, want %d starting with mimetype",
+			len(reader.File), reader.File[0].Name, len(files))
+	}
+
+	if err := WriteEPUBTo(book, failingWriter{}); err == nil {
+		t.Error("WriteEPUBTo() should return the writer error")
+	}
+}
+
+func TestBookWrite(t *testing.T) {
+	book, err := NewBookFromXMLFile(context.Background(), strings.NewReader(testXMLSimple), nil)
+	if err != nil {
+		t.Fatalf("NewBookFromXMLFile() error = %v", err)
+	}
+	const accessMode = `<meta property="schema:accessMode">textual</meta>`
+
+	epubPath := filepath.Join(t.TempDir(), "test.epub")
+	if err := book.Write(epubPath); err != nil {
+		t.Fatalf("Book.Write() error = %v", err)
+	}
+	if opf := readZipFiles(t, epubPath)[epubPackagePath]; !strings.Contains(opf, accessMode) {
+		t.Errorf("Book.Write() should add the accessibility metadata:\n%s", opf)
+	}
+
+	var buf bytes.Buffer
+	n, err := book.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("Book.WriteTo() error = %v", err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("Book.WriteTo() = %d, wrote %d bytes", n, buf.Len())
+	}
+	archive, err := readEPUBArchive(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opf := string(archive.file(epubPackagePath).data); !strings.Contains(opf, accessMode) {
+		t.Errorf("Book.WriteTo() should add the accessibility metadata:\n%s", opf)
+	}
+
+	// The go-epub book alone is written as go-epub writes it
+	plainPath := filepath.Join(t.TempDir(), "plain.epub")
+	if err := WriteEPUB(book.Epub, plainPath); err != nil {
+		t.Fatalf("WriteEPUB() error = %v", err)
+	}
+	if opf := readZipFiles(t, plainPath)[epubPackagePath]; strings.Contains(opf, accessMode) {
+		t.Errorf("WriteEPUB() should not add the metadata of a Book:\n%s", opf)
+	}
+}
+
+// failingWriter is an io.Writer that always fails
+type failingWriter struct{}
+
+// Write returns an error
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
 func TestProcessChaptersWithOptions(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -0,0 +1,759 @@
+package jplaw2epub
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.ngs.io/jplaw-xml"
+)
+
+// JSONSchemaVersion identifies the layout of the exported JSON document.
+// It is incremented whenever a field is renamed or removed.
+const JSONSchemaVersion = "1"
+
+// JSONLaw is the root of the normalized JSON export of a law
+type JSONLaw struct {
+	SchemaVersion   string               `json:"schemaVersion"`
+	Title           JSONText             `json:"title"`
+	TitleKana       string               `json:"titleKana,omitempty"`
+	LawNum          string               `json:"lawNum"`
+	LawType         string               `json:"lawType,omitempty"`
+	Lang            string               `json:"lang,omitempty"`
+	Era             string               `json:"era,omitempty"`
+	Year            int                  `json:"year,omitempty"`
+	PromulgateMonth int                  `json:"promulgateMonth,omitempty"`
+	PromulgateDay   int                  `json:"promulgateDay,omitempty"`
+	MainProvision   JSONProvision        `json:"mainProvision"`
+	SupplProvisions []JSONSupplProvision `json:"supplProvisions,omitempty"`
+	Appendixes      []JSONAppendix       `json:"appendixes,omitempty"`
+}
+
+// JSONText is text with its ruby annotations resolved.
+// Offsets are counted in Unicode code points from the start of Text.
+type JSONText struct {
+	Text string     `json:"text"`
+	Ruby []JSONRuby `json:"ruby,omitempty"`
+}
+
+// JSONRuby is a ruby annotation over a span of JSONText
+type JSONRuby struct {
+	Offset  int    `json:"offset"`
+	Base    string `json:"base"`
+	Reading string `json:"reading"`
+}
+
+// JSONProvision holds the body of a main or supplementary provision
+type JSONProvision struct {
+	Chapters   []JSONChapter   `json:"chapters,omitempty"`
+	Articles   []JSONArticle   `json:"articles,omitempty"`
+	Paragraphs []JSONParagraph `json:"paragraphs,omitempty"`
+}
+
+// JSONChapter is a chapter (章)
+type JSONChapter struct {
+	Path     string        `json:"path"`
+	Href     string        `json:"href,omitempty"`
+	Title    JSONText      `json:"title"`
+	Sections []JSONSection `json:"sections,omitempty"`
+	Articles []JSONArticle `json:"articles,omitempty"`
+}
+
+// JSONSection is a section (節) within a chapter
+type JSONSection struct {
+	Path     string        `json:"path"`
+	Title    JSONText      `json:"title"`
+	Articles []JSONArticle `json:"articles,omitempty"`
+}
+
+// JSONArticle is an article (条)
+type JSONArticle struct {
+	Path       string          `json:"path"`
+	Href       string          `json:"href"`
+	Num        string          `json:"num,omitempty"`
+	Title      JSONText        `json:"title"`
+	Caption    *JSONText       `json:"caption,omitempty"`
+	Paragraphs []JSONParagraph `json:"paragraphs"`
+}
+
+// JSONParagraph is a paragraph (項)
+type JSONParagraph struct {
+	Path      string       `json:"path"`
+	Href      string       `json:"href"`
+	Num       int          `json:"num,omitempty"`
+	Label     string       `json:"label,omitempty"`
+	Sentences []JSONText   `json:"sentences"`
+	Items     []JSONItem   `json:"items,omitempty"`
+	Tables    []JSONTable  `json:"tables,omitempty"`
+	Figures   []JSONFigure `json:"figures,omitempty"`
+	Lists     []JSONList   `json:"lists,omitempty"`
+}
+
+// JSONItem is an item (号) or one of its nested subitems
+type JSONItem struct {
+	Path      string       `json:"path"`
+	Href      string       `json:"href"`
+	Label     string       `json:"label,omitempty"`
+	Sentences []JSONText   `json:"sentences"`
+	Figures   []JSONFigure `json:"figures,omitempty"`
+	Subitems  []JSONItem   `json:"subitems,omitempty"`
+}
+
+// JSONList is an entry of a List element and its sublists
+type JSONList struct {
+	Sentences []JSONText `json:"sentences"`
+	Children  []JSONList `json:"children,omitempty"`
+}
+
+// JSONTable is a table with its cells and spans
+type JSONTable struct {
+	Title       *JSONText    `json:"title,omitempty"`
+	WritingMode string       `json:"writingMode,omitempty"`
+	HeaderRows  [][]JSONCell `json:"headerRows,omitempty"`
+	Rows        [][]JSONCell `json:"rows"`
+	Remarks     []JSONText   `json:"remarks,omitempty"`
+}
+
+// JSONCell is a table cell. Rowspan and Colspan are always at least 1.
+type JSONCell struct {
+	Header  bool       `json:"header,omitempty"`
+	Rowspan int        `json:"rowspan"`
+	Colspan int        `json:"colspan"`
+	Align   string     `json:"align,omitempty"`
+	Valign  string     `json:"valign,omitempty"`
+	Content []JSONText `json:"content"`
+}
+
+// JSONFigure is a reference to an image attachment
+type JSONFigure struct {
+	Src     string     `json:"src"`
+	File    string     `json:"file"`
+	Title   *JSONText  `json:"title,omitempty"`
+	Remarks []JSONText `json:"remarks,omitempty"`
+}
+
+// JSONSupplProvision is a supplementary provision (附則)
+type JSONSupplProvision struct {
+	Path        string   `json:"path"`
+	Href        string   `json:"href"`
+	Label       JSONText `json:"label"`
+	AmendLawNum string   `json:"amendLawNum,omitempty"`
+	JSONProvision
+}
+
+// JSONAppendix is an appendix table, note, style or format
+type JSONAppendix struct {
+	Path              string       `json:"path"`
+	Href              string       `json:"href"`
+	Kind              string       `json:"kind"`
+	Title             *JSONText    `json:"title,omitempty"`
+	RelatedArticleNum string       `json:"relatedArticleNum,omitempty"`
+	Tables            []JSONTable  `json:"tables,omitempty"`
+	Figures           []JSONFigure `json:"figures,omitempty"`
+}
+
+// CreateJSONFromXMLFile parses a jplaw XML file and converts it into the JSON export model
+func CreateJSONFromXMLFile(xmlFile io.Reader) (*JSONLaw, error) {
+	data, err := loadXMLDataFromReader(xmlFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading XML data: %w", err)
+	}
+	if data.LawBody.LawTitle == nil {
+		return nil, fmt.Errorf("law title is required")
+	}
+	return NewJSONLaw(data), nil
+}
+
+// CreateJSONFromXMLPath converts the jplaw XML file at the given path into the JSON export model
+func CreateJSONFromXMLPath(xmlPath string) (*JSONLaw, error) {
+	xmlFile, err := os.Open(xmlPath)
+	if err != nil {
+		return nil, fmt.Errorf("opening XML file: %w", err)
+	}
+	defer xmlFile.Close()
+
+	return CreateJSONFromXMLFile(xmlFile)
+}
+
+// WriteJSONTo writes the JSON export as indented JSON to w
+func WriteJSONTo(doc *JSONLaw, w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("encoding JSON: %w", err)
+	}
+	return nil
+}
+
+// WriteJSON writes the JSON export to the specified path
+func WriteJSON(doc *JSONLaw, destPath string) error {
+	if err := os.MkdirAll(filepath.Dir(destPath), 0o755); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+
+	f, err := os.Create(destPath)
+	if err != nil {
+		return fmt.Errorf("creating JSON file: %w", err)
+	}
+
+	if err := WriteJSONTo(doc, f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writing JSON file: %w", err)
+	}
+	return nil
+}
+
+// NewJSONLaw converts parsed law data into the JSON export model.
+// Href values name the EPUB section file the element is rendered into.
+func NewJSONLaw(data *jplaw.Law) *JSONLaw {
+	doc := &JSONLaw{
+		SchemaVersion:   JSONSchemaVersion,
+		LawNum:          data.LawNum,
+		LawType:         string(data.LawType),
+		Lang:            string(data.Lang),
+		Era:             string(data.Era),
+		Year:            data.Year,
+		PromulgateMonth: data.PromulgateMonth,
+		PromulgateDay:   data.PromulgateDay,
+	}
+	if title := data.LawBody.LawTitle; title != nil {
+		doc.Title = jsonTitle(title.Content, title.Ruby)
+		doc.TitleKana = title.Kana
+	}
+
+	doc.MainProvision = jsonMainProvision(&data.LawBody.MainProvision)
+
+	for i := range data.LawBody.SupplProvision {
+		doc.SupplProvisions = append(doc.SupplProvisions, jsonSupplProvision(&data.LawBody.SupplProvision[i], i))
+	}
+
+	doc.Appendixes = jsonAppendixes(&data.LawBody)
+	return doc
+}
+
+// jsonMainProvision converts the main provision, following the EPUB section layout
+func jsonMainProvision(mainProv *jplaw.MainProvision) JSONProvision {
+	var prov JSONProvision
+
+	if len(mainProv.Chapter) > 0 {
+		for i := range mainProv.Chapter {
+			prov.Chapters = append(prov.Chapters, jsonChapter(&mainProv.Chapter[i], i, "main"))
+		}
+		return prov
+	}
+
+	if len(mainProv.Article) > 0 {
+		for i := range mainProv.Article {
+			path := fmt.Sprintf("main/article-%d", i+1)
+			href := fmt.Sprintf("article-%d.xhtml", i)
+			prov.Articles = append(prov.Articles, jsonArticle(&mainProv.Article[i], path, href))
+		}
+		return prov
+	}
+
+	for i := range mainProv.Paragraph {
+		href := "main-content.xhtml"
+		if len(mainProv.Paragraph) > 1 {
+			href = fmt.Sprintf("paragraph-%d.xhtml", i)
+		}
+		path := fmt.Sprintf("main/paragraph-%d", i+1)
+		prov.Paragraphs = append(prov.Paragraphs, jsonParagraph(&mainProv.Paragraph[i], path, href))
+	}
+	return prov
+}
+
+// jsonChapter converts a main provision chapter and its articles
+func jsonChapter(chapter *jplaw.Chapter, chapterIdx int, parentPath string) JSONChapter {
+	path := fmt.Sprintf("%s/chapter-%d", parentPath, chapterIdx+1)
+	result := JSONChapter{
+		Path:  path,
+		Href:  fmt.Sprintf("chapter-%d.xhtml", chapterIdx),
+		Title: jsonTitle(chapter.ChapterTitle.Content, chapter.ChapterTitle.Ruby),
+	}
+
+	for j := range chapter.Article {
+		articlePath := fmt.Sprintf("%s/article-%d", path, j+1)
+		result.Articles = append(result.Articles,
+			jsonArticle(&chapter.Article[j], articlePath, buildArticleFilename(chapterIdx, -1, j)))
+	}
+
+	for s := range chapter.Section {
+		section := &chapter.Section[s]
+		sectionPath := fmt.Sprintf("%s/section-%d", path, s+1)
+		jsonSection := JSONSection{
+			Path:  sectionPath,
+			Title: jsonTitle(section.SectionTitle.Content, section.SectionTitle.Ruby),
+		}
+		for j := range section.Article {
+			articlePath := fmt.Sprintf("%s/article-%d", sectionPath, j+1)
+			jsonSection.Articles = append(jsonSection.Articles,
+				jsonArticle(&section.Article[j], articlePath, buildArticleFilename(chapterIdx, s, j)))
+		}
+		result.Sections = append(result.Sections, jsonSection)
+	}
+
+	return result
+}
+
+// jsonArticle converts an article
+func jsonArticle(article *jplaw.Article, path, href string) JSONArticle {
+	result := JSONArticle{
+		Path:       path,
+		Href:       href,
+		Num:        article.Num,
+		Paragraphs: []JSONParagraph{},
+	}
+	if article.ArticleTitle != nil {
+		result.Title = jsonTitle(article.ArticleTitle.Content, article.ArticleTitle.Ruby)
+	}
+	if article.ArticleCaption != nil {
+		caption := jsonTitle(article.ArticleCaption.Content, article.ArticleCaption.Ruby)
+		result.Caption = &caption
+	}
+
+	for i := range article.Paragraph {
+		paraPath := fmt.Sprintf("%s/paragraph-%d", path, i+1)
+		result.Paragraphs = append(result.Paragraphs, jsonParagraph(&article.Paragraph[i], paraPath, href))
+	}
+	return result
+}
+
+// jsonParagraph converts a paragraph and its items, tables, figures and lists
+func jsonParagraph(para *jplaw.Paragraph, path, href string) JSONParagraph {
+	result := JSONParagraph{
+		Path:      path,
+		Href:      href,
+		Num:       para.Num,
+		Label:     para.ParagraphNum.Content,
+		Sentences: jsonSentences(para.ParagraphSentence.Sentence),
+		Figures:   jsonFigures(para.FigStruct),
+		Tables:    jsonTables(para.TableStruct),
+	}
+
+	for i := range para.Item {
+		itemPath := fmt.Sprintf("%s/item-%d", path, i+1)
+		result.Items = append(result.Items, jsonItem(&para.Item[i], itemPath, href))
+	}
+
+	for i := range para.List {
+		result.Lists = append(result.Lists, jsonList(&para.List[i]))
+	}
+	return result
+}
+
+// jsonItem converts an item with its nested subitems
+func jsonItem(item *jplaw.Item, path, href string) JSONItem {
+	result := JSONItem{
+		Path:      path,
+		Href:      href,
+		Sentences: jsonSentences(item.ItemSentence.Sentence),
+		Figures:   jsonFigures(item.FigStruct),
+	}
+	if item.ItemTitle != nil {
+		result.Label = item.ItemTitle.Content
+	}
+	for i := range item.ItemSentence.Column {
+		result.Sentences = append(result.Sentences, jsonSentences(item.ItemSentence.Column[i].Sentence)...)
+	}
+
+	for i := range item.Subitem1 {
+		sub := &item.Subitem1[i]
+		subPath := fmt.Sprintf("%s/subitem1-%d", path, i+1)
+		subResult := JSONItem{
+			Path:      subPath,
+			Href:      href,
+			Sentences: jsonSentences(sub.Subitem1Sentence.Sentence),
+			Figures:   jsonFigures(sub.FigStruct),
+		}
+		if sub.Subitem1Title != nil {
+			subResult.Label = sub.Subitem1Title.Content
+		}
+
+		for j := range sub.Subitem2 {
+			sub2 := &sub.Subitem2[j]
+			sub2Result := JSONItem{
+				Path:      fmt.Sprintf("%s/subitem2-%d", subPath, j+1),
+				Href:      href,
+				Sentences: jsonSentences(sub2.Subitem2Sentence.Sentence),
+				Figures:   jsonFigures(sub2.FigStruct),
+			}
+			if sub2.Subitem2Title != nil {
+				sub2Result.Label = sub2.Subitem2Title.Content
+			}
+			subResult.Subitems = append(subResult.Subitems, sub2Result)
+		}
+
+		result.Subitems = append(result.Subitems, subResult)
+	}
+	return result
+}
+
+// jsonList converts a List element and its sublists
+func jsonList(list *jplaw.List) JSONList {
+	result := JSONList{Sentences: jsonSentences(list.ListSentence.Sentence)}
+	for i := range list.Sublist1 {
+		sub1 := &list.Sublist1[i]
+		child1 := JSONList{Sentences: jsonSentences(sub1.Sublist1Sentence.Sentence)}
+		for j := range sub1.Sublist2 {
+			sub2 := &sub1.Sublist2[j]
+			child2 := JSONList{Sentences: jsonSentences(sub2.Sublist2Sentence.Sentence)}
+			for k := range sub2.Sublist3 {
+				child2.Children = append(child2.Children, JSONList{
+					Sentences: jsonSentences(sub2.Sublist3[k].Sublist3Sentence.Sentence),
+				})
+			}
+			child1.Children = append(child1.Children, child2)
+		}
+		result.Children = append(result.Children, child1)
+	}
+	return result
+}
+
+// jsonTables converts table structures
+func jsonTables(tables []jplaw.TableStruct) []JSONTable {
+	var result []JSONTable
+	for i := range tables {
+		ts := &tables[i]
+		table := JSONTable{
+			WritingMode: string(ts.Table.WritingMode),
+			Rows:        [][]JSONCell{},
+			Remarks:     jsonRemarks(ts.Remarks),
+		}
+		if ts.TableStructTitle != nil {
+			title := jsonTitle(ts.TableStructTitle.Content, ts.TableStructTitle.Ruby)
+			table.Title = &title
+		}
+
+		for r := range ts.Table.TableHeaderRow {
+			var row []JSONCell
+			for c := range ts.Table.TableHeaderRow[r].TableHeaderColumn {
+				col := &ts.Table.TableHeaderRow[r].TableHeaderColumn[c]
+				row = append(row, JSONCell{
+					Header:  true,
+					Rowspan: 1,
+					Colspan: 1,
+					Content: []JSONText{jsonTitle(col.Content, col.Ruby)},
+				})
+			}
+			table.HeaderRows = append(table.HeaderRows, row)
+		}
+
+		for r := range ts.Table.TableRow {
+			row := []JSONCell{}
+			for c := range ts.Table.TableRow[r].TableColumn {
+				row = append(row, jsonCell(&ts.Table.TableRow[r].TableColumn[c]))
+			}
+			table.Rows = append(table.Rows, row)
+		}
+
+		result = append(result, table)
+	}
+	return result
+}
+
+// jsonCell converts a table body cell, resolving its spans
+func jsonCell(col *jplaw.TableColumn) JSONCell {
+	cell := JSONCell{
+		Rowspan: 1,
+		Colspan: 1,
+		Align:   col.Align,
+		Valign:  col.Valign,
+		Content: jsonSentences(col.Sentence),
+	}
+	if span := parseSpan(col.Rowspan); span != nil {
+		cell.Rowspan = *span
+	}
+	if span := parseSpan(col.Colspan); span != nil {
+		cell.Colspan = *span
+	}
+	for i := range col.Column {
+		cell.Content = append(cell.Content, jsonSentences(col.Column[i].Sentence)...)
+	}
+	for i := range col.Part {
+		part := &col.Part[i]
+		if part.PartTitle.Content != "" {
+			cell.Content = append(cell.Content, jsonTitle(part.PartTitle.Content, part.PartTitle.Ruby))
+		}
+		for j := range part.Article {
+			cell.Content = append(cell.Content, jsonArticleTexts(jsonArticle(&part.Article[j], "", ""))...)
+		}
+	}
+	for i := range col.Article {
+		cell.Content = append(cell.Content, jsonArticleTexts(jsonArticle(&col.Article[i], "", ""))...)
+	}
+	for i := range col.Paragraph {
+		para := jsonParagraph(&col.Paragraph[i], "", "")
+		cell.Content = append(cell.Content, jsonParagraphTexts(&para)...)
+	}
+	for i := range col.Item {
+		cell.Content = append(cell.Content, jsonItemTexts([]JSONItem{jsonItem(&col.Item[i], "", "")})...)
+	}
+	return cell
+}
+
+// jsonArticleTexts flattens an article nested in a table cell into its title, caption and sentences
+func jsonArticleTexts(article JSONArticle) []JSONText {
+	var texts []JSONText
+	if article.Title.Text != "" {
+		texts = append(texts, article.Title)
+	}
+	if article.Caption != nil {
+		texts = append(texts, *article.Caption)
+	}
+	for i := range article.Paragraphs {
+		texts = append(texts, jsonParagraphTexts(&article.Paragraphs[i])...)
+	}
+	return texts
+}
+
+// jsonParagraphTexts flattens a paragraph nested in a table cell into its sentences and items
+func jsonParagraphTexts(para *JSONParagraph) []JSONText {
+	return append(append([]JSONText{}, para.Sentences...), jsonItemTexts(para.Items)...)
+}
+
+// jsonItemTexts flattens items and their subitems into their sentences
+func jsonItemTexts(items []JSONItem) []JSONText {
+	var texts []JSONText
+	for i := range items {
+		texts = append(texts, items[i].Sentences...)
+		texts = append(texts, jsonItemTexts(items[i].Subitems)...)
+	}
+	return texts
+}
+
+// jsonFigures converts figure structures into image references
+func jsonFigures(figs []jplaw.FigStruct) []JSONFigure {
+	var result []JSONFigure
+	for i := range figs {
+		fig := &figs[i]
+		if fig.Fig.Src == "" {
+			continue
+		}
+		figure := JSONFigure{
+			Src:     fig.Fig.Src,
+			File:    generateImageFilename(fig.Fig.Src),
+			Remarks: jsonRemarks(fig.Remarks),
+		}
+		if fig.FigStructTitle != nil {
+			title := jsonTitle(fig.FigStructTitle.Content, fig.FigStructTitle.Ruby)
+			figure.Title = &title
+		}
+		result = append(result, figure)
+	}
+	return result
+}
+
+// jsonRemarks flattens remarks into text entries
+func jsonRemarks(remarks []jplaw.Remarks) []JSONText {
+	var result []JSONText
+	for i := range remarks {
+		if remarks[i].RemarksLabel.Content != "" {
+			result = append(result, jsonTitle(remarks[i].RemarksLabel.Content, remarks[i].RemarksLabel.Ruby))
+		}
+		result = append(result, jsonSentences(remarks[i].Sentence)...)
+	}
+	return result
+}
+
+// jsonSupplProvision converts a supplementary provision
+func jsonSupplProvision(provision *jplaw.SupplProvision, idx int) JSONSupplProvision {
+	path := fmt.Sprintf("suppl-%d", idx+1)
+	href := fmt.Sprintf("suppl-provision-%d.xhtml", idx)
+	result := JSONSupplProvision{
+		Path:        path,
+		Href:        href,
+		Label:       jsonTitle(getSupplProvisionTitle(provision), provision.SupplProvisionLabel.Ruby),
+		AmendLawNum: provision.AmendLawNum,
+	}
+
+	for i := range provision.Chapter {
+		chapter := &provision.Chapter[i]
+		chapterPath := fmt.Sprintf("%s/chapter-%d", path, i+1)
+		jsonChapter := JSONChapter{
+			Path:  chapterPath,
+			Title: jsonTitle(chapter.ChapterTitle.Content, chapter.ChapterTitle.Ruby),
+		}
+		for j := range chapter.Article {
+			jsonChapter.Articles = append(jsonChapter.Articles,
+				jsonArticle(&chapter.Article[j], fmt.Sprintf("%s/article-%d", chapterPath, j+1), href))
+		}
+		result.Chapters = append(result.Chapters, jsonChapter)
+	}
+
+	for i := range provision.Article {
+		result.Articles = append(result.Articles,
+			jsonArticle(&provision.Article[i], fmt.Sprintf("%s/article-%d", path, i+1), href))
+	}
+
+	for i := range provision.Paragraph {
+		result.Paragraphs = append(result.Paragraphs,
+			jsonParagraph(&provision.Paragraph[i], fmt.Sprintf("%s/paragraph-%d", path, i+1), href))
+	}
+	return result
+}
+
+// jsonAppendixes converts appendix notes, tables, styles and formats in EPUB order
+func jsonAppendixes(body *jplaw.LawBody) []JSONAppendix {
+	var result []JSONAppendix
+
+	for i := range body.AppdxNote {
+		appdx := &body.AppdxNote[i]
+		entry := JSONAppendix{
+			Path:    fmt.Sprintf("appdx-note-%d", i+1),
+			Href:    fmt.Sprintf("appdx-note-%d.xhtml", i),
+			Kind:    "note",
+			Tables:  jsonTables(appdx.TableStruct),
+			Figures: jsonFigures(appdx.FigStruct),
+		}
+		if appdx.AppdxNoteTitle != nil {
+			title := jsonTitle(appdx.AppdxNoteTitle.Content, appdx.AppdxNoteTitle.Ruby)
+			entry.Title = &title
+		}
+		if appdx.RelatedArticleNum != nil {
+			entry.RelatedArticleNum = appdx.RelatedArticleNum.Content
+		}
+		result = append(result, entry)
+	}
+
+	for i := range body.AppdxTable {
+		appdx := &body.AppdxTable[i]
+		entry := JSONAppendix{
+			Path:   fmt.Sprintf("appdx-table-%d", i+1),
+			Href:   fmt.Sprintf("appdx-table-%d.xhtml", i),
+			Kind:   "table",
+			Tables: jsonTables(appdx.TableStruct),
+		}
+		if appdx.AppdxTableTitle != nil {
+			title := jsonTitle(appdx.AppdxTableTitle.Content, appdx.AppdxTableTitle.Ruby)
+			entry.Title = &title
+		}
+		if appdx.RelatedArticleNum != nil {
+			entry.RelatedArticleNum = appdx.RelatedArticleNum.Content
+		}
+		result = append(result, entry)
+	}
+
+	for i := range body.AppdxStyle {
+		appdx := &body.AppdxStyle[i]
+		entry := JSONAppendix{
+			Path: fmt.Sprintf("appdx-style-%d", i+1),
+			Href: fmt.Sprintf("appdx-style-%d.xhtml", i),
+			Kind: "style",
+		}
+		for j := range appdx.StyleStruct {
+			entry.Figures = append(entry.Figures, jsonEmbeddedFigures(appdx.StyleStruct[j].Style.Content)...)
+		}
+		if appdx.AppdxStyleTitle != nil {
+			title := jsonTitle(appdx.AppdxStyleTitle.Content, appdx.AppdxStyleTitle.Ruby)
+			entry.Title = &title
+		}
+		if appdx.RelatedArticleNum != nil {
+			entry.RelatedArticleNum = appdx.RelatedArticleNum.Content
+		}
+		result = append(result, entry)
+	}
+
+	for i := range body.AppdxFormat {
+		appdx := &body.AppdxFormat[i]
+		entry := JSONAppendix{
+			Path: fmt.Sprintf("appdx-format-%d", i+1),
+			Href: fmt.Sprintf("appdx-format-%d.xhtml", i),
+			Kind: "format",
+		}
+		for j := range appdx.FormatStruct {
+			entry.Figures = append(entry.Figures, jsonEmbeddedFigures(appdx.FormatStruct[j].Format.Content)...)
+		}
+		if appdx.AppdxFormatTitle != nil {
+			title := jsonTitle(appdx.AppdxFormatTitle.Content, appdx.AppdxFormatTitle.Ruby)
+			entry.Title = &title
+		}
+		if appdx.RelatedArticleNum != nil {
+			entry.RelatedArticleNum = appdx.RelatedArticleNum.Content
+		}
+		result = append(result, entry)
+	}
+
+	return result
+}
+
+// jsonEmbeddedFigures extracts Fig references from raw Style or Format content
+func jsonEmbeddedFigures(content string) []JSONFigure {
+	var result []JSONFigure
+	for _, match := range figSrcPattern.FindAllStringSubmatch(content, -1) {
+		result = append(result, JSONFigure{Src: match[1], File: generateImageFilename(match[1])})
+	}
+	return result
+}
+
+// jsonSentences converts sentences using their rendered HTML so ruby is resolved consistently
+func jsonSentences(sentences []jplaw.Sentence) []JSONText {
+	result := []JSONText{}
+	for i := range sentences {
+		result = append(result, jsonTextFromHTML(sentences[i].HTML()))
+	}
+	return result
+}
+
+// jsonTitle converts title content and its ruby
+func jsonTitle(content string, rubies []jplaw.Ruby) JSONText {
+	return jsonTextFromHTML(processTextWithRuby(content, rubies))
+}
+
+// jsonTextFromHTML resolves rendered inline HTML into plain text and ruby annotations
+func jsonTextFromHTML(fragment string) JSONText {
+	decoder := xml.NewDecoder(strings.NewReader("<span>" + fragment + "</span>"))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+
+	var text, base, reading strings.Builder
+	offset := 0
+	inRuby, inRt := false, false
+	var rubies []JSONRuby
+
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "ruby":
+				inRuby = true
+				offset = len([]rune(text.String()))
+				base.Reset()
+				reading.Reset()
+			case "rt":
+				inRt = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "ruby":
+				inRuby = false
+				rubies = append(rubies, JSONRuby{Offset: offset, Base: base.String(), Reading: reading.String()})
+			case "rt":
+				inRt = false
+			}
+		case xml.CharData:
+			switch {
+			case inRt:
+				reading.WriteString(string(t))
+			case inRuby:
+				base.WriteString(string(t))
+				text.WriteString(string(t))
+			default:
+				text.WriteString(string(t))
+			}
+		}
+	}
+
+	return JSONText{Text: text.String(), Ruby: rubies}
+}
@@ -0,0 +1,236 @@
+package jplaw2epub
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	jplaw "go.ngs.io/jplaw-xml"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files in testdata")
+
+func TestJSONExportGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "json", "*.xml"))
+	if err != nil {
+		t.Fatalf("Failed to list golden inputs: %v", err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("No golden inputs found in testdata/json")
+	}
+
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".xml")
+		t.Run(name, func(t *testing.T) {
+			doc, err := CreateJSONFromXMLPath(input)
+			if err != nil {
+				t.Fatalf("CreateJSONFromXMLPath() error = %v", err)
+			}
+
+			var got bytes.Buffer
+			if err := WriteJSONTo(doc, &got); err != nil {
+				t.Fatalf("WriteJSONTo() error = %v", err)
+			}
+
+			goldenPath := strings.TrimSuffix(input, ".xml") + ".golden.json"
+			if *updateGolden {
+				if err := os.WriteFile(goldenPath, got.Bytes(), 0o644); err != nil {
+					t.Fatalf("Failed to update golden file: %v", err)
+				}
+			}
+
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("Failed to read golden file (run with -update to create): %v", err)
+			}
+			if !bytes.Equal(got.Bytes(), want) {
+				t.Errorf("JSON output differs from %s (run with -update to regenerate)\ngot:\n%s", goldenPath, got.String())
+			}
+		})
+	}
+}
+
+func TestJSONExportHrefsMatchEPUB(t *testing.T) {
+	xmlFile, err := os.Open(filepath.Join("testdata", "json", "chapters.xml"))
+	if err != nil {
+		t.Fatalf("Failed to open input: %v", err)
+	}
+	defer xmlFile.Close()
+
+	data, err := loadXMLDataFromReader(xmlFile)
+	if err != nil {
+		t.Fatalf("loadXMLDataFromReader() error = %v", err)
+	}
+	doc := NewJSONLaw(data)
+
+	// PDFDocument records the section filenames passed by the EPUB traversal
+	book := NewPDFDocument(data.LawBody.LawTitle.Content)
+	if err := processChaptersWithOptions(book, data, nil); err != nil {
+		t.Fatalf("processChaptersWithOptions() error = %v", err)
+	}
+
+	var hrefs []string
+	for _, chapter := range doc.MainProvision.Chapters {
+		hrefs = append(hrefs, chapter.Href)
+		for _, article := range chapter.Articles {
+			hrefs = append(hrefs, article.Href)
+		}
+		for _, section := range chapter.Sections {
+			for _, article := range section.Articles {
+				hrefs = append(hrefs, article.Href)
+			}
+		}
+	}
+	for _, suppl := range doc.SupplProvisions {
+		hrefs = append(hrefs, suppl.Href)
+	}
+
+	for _, href := range hrefs {
+		if _, ok := book.files[href]; !ok {
+			t.Errorf("href %q does not match any EPUB section file", href)
+		}
+	}
+}
+
+func TestJSONTextFromHTML(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  JSONText
+	}{
+		{
+			name:  "plain text",
+			input: "この法律は",
+			want:  JSONText{Text: "この法律は"},
+		},
+		{
+			name:  "entities are decoded",
+			input: "A&amp;B",
+			want:  JSONText{Text: "A&B"},
+		},
+		{
+			name:  "inline ruby",
+			input: "規程<ruby>令<rt>れい</rt></ruby>の<ruby>適用<rt>てきよう</rt></ruby>",
+			want: JSONText{
+				Text: "規程令の適用",
+				Ruby: []JSONRuby{
+					{Offset: 2, Base: "令", Reading: "れい"},
+					{Offset: 4, Base: "適用", Reading: "てきよう"},
+				},
+			},
+		},
+		{
+			name:  "multiple rt are joined",
+			input: "<ruby>振<rt>ふ</rt><rt>り</rt></ruby>",
+			want: JSONText{
+				Text: "振",
+				Ruby: []JSONRuby{{Offset: 0, Base: "振", Reading: "ふり"}},
+			},
+		},
+		{
+			name:  "other markup is dropped",
+			input: `<span class="x">第<sup>一</sup>条</span>`,
+			want:  JSONText{Text: "第一条"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := jsonTextFromHTML(tt.input)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("jsonTextFromHTML() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONCell(t *testing.T) {
+	col := &jplaw.TableColumn{
+		Part: []jplaw.Part{{
+			PartTitle: jplaw.PartTitle{Content: "改正前"},
+			Article: []jplaw.Article{{
+				ArticleTitle: &jplaw.ArticleTitle{Content: "第一条"},
+				Paragraph: []jplaw.Paragraph{{
+					ParagraphSentence: jplaw.ParagraphSentence{Sentence: []jplaw.Sentence{createTestSentence("旧規定")}},
+					Item: []jplaw.Item{{
+						ItemSentence: jplaw.ItemSentence{Sentence: []jplaw.Sentence{createTestSentence("旧第一号")}},
+					}},
+				}},
+			}},
+		}},
+		Paragraph: []jplaw.Paragraph{{
+			ParagraphSentence: jplaw.ParagraphSentence{Sentence: []jplaw.Sentence{createTestSentence("直接の項")}},
+		}},
+		Item: []jplaw.Item{{
+			ItemSentence: jplaw.ItemSentence{Sentence: []jplaw.Sentence{createTestSentence("直接の号")}},
+		}},
+	}
+
+	cell := jsonCell(col)
+
+	var got []string
+	for _, text := range cell.Content {
+		got = append(got, text.Text)
+	}
+	want := []string{"改正前", "第一条", "旧規定", "旧第一号", "直接の項", "直接の号"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("jsonCell() content = %v, want %v", got, want)
+	}
+}
+
+func TestCreateJSONFromXMLFileErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{
+			name:  "invalid XML",
+			input: "<Law><LawBody>",
+		},
+		{
+			name:  "missing title",
+			input: `<Law><LawNum>令和元年法律第一号</LawNum><LawBody><MainProvision/></LawBody></Law>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := CreateJSONFromXMLFile(strings.NewReader(tt.input)); err == nil {
+				t.Error("CreateJSONFromXMLFile() expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	doc, err := CreateJSONFromXMLPath(filepath.Join("testdata", "json", "articles.xml"))
+	if err != nil {
+		t.Fatalf("CreateJSONFromXMLPath() error = %v", err)
+	}
+
+	destPath := filepath.Join(t.TempDir(), "out", "law.json")
+	if err := WriteJSON(doc, destPath); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+
+	raw, err := os.ReadFile(destPath)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+
+	var decoded JSONLaw
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+	if decoded.SchemaVersion != JSONSchemaVersion {
+		t.Errorf("schemaVersion = %q, want %q", decoded.SchemaVersion, JSONSchemaVersion)
+	}
+	if !reflect.DeepEqual(&decoded, doc) {
+		t.Error("Decoded JSON does not round-trip to the exported document")
+	}
+}
@@ -0,0 +1,100 @@
+package jplaw2epub
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	lawapi "go.ngs.io/jplaw-api-v2"
+)
+
+// ErrLawNotFound is returned by a LawFetcher when no law matches the requested ID and date
+var ErrLawNotFound = errors.New("law not found")
+
+// LawFetcher looks up and fetches the XML of laws. Looking up the revision is expected to be
+// much cheaper than fetching the law, so that cached conversions need no download.
+type LawFetcher interface {
+	// LawRevision returns the revision ID of the law identified by lawID as of a date.
+	// An empty asof (YYYY-MM-DD) means the current revision.
+	LawRevision(ctx context.Context, lawID, asof string) (string, error)
+	// FetchLaw fetches the XML of a law revision
+	FetchLaw(ctx context.Context, revisionID string) ([]byte, error)
+}
+
+// LawDataClient is the part of the law API client used to look up and fetch laws
+type LawDataClient interface {
+	GetLaws(params *lawapi.GetLawsParams) (*lawapi.LawsResponse, error)
+	GetLawData(lawIDOrNumOrRevisionID string, params *lawapi.GetLawDataParams) (*lawapi.LawDataResponse, error)
+}
+
+// Ensure lawapi.Client implements LawDataClient
+var _ LawDataClient = (*lawapi.Client)(nil)
+
+// APILawFetcher fetches laws through the 法令API client
+type APILawFetcher struct {
+	client LawDataClient
+}
+
+// Ensure APILawFetcher implements LawFetcher
+var _ LawFetcher = (*APILawFetcher)(nil)
+
+// NewLawFetcher creates a fetcher using client, usually a *lawapi.Client
+func NewLawFetcher(client LawDataClient) *APILawFetcher {
+	return &APILawFetcher{client: client}
+}
+
+// LawRevision looks up the revision in force on asof in the law list, without the law text
+func (f *APILawFetcher) LawRevision(ctx context.Context, lawID, asof string) (string, error) {
+	params := &lawapi.GetLawsParams{LawId: lawapi.StringPtr(lawID)}
+	if asof != "" {
+		params.Asof = lawapi.StringPtr(asof)
+	}
+	resp, err := callWithContext(ctx, func() (*lawapi.LawsResponse, error) {
+		return f.client.GetLaws(params)
+	})
+	if err != nil {
+		return "", fmt.Errorf("looking up %s: %w", lawID, err)
+	}
+	if resp != nil {
+		for _, law := range resp.Laws {
+			if law.RevisionInfo != nil && law.RevisionInfo.LawRevisionId != "" {
+				return law.RevisionInfo.LawRevisionId, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("looking up %s: %w", lawID, ErrLawNotFound)
+}
+
+// FetchLaw fetches the full text of a revision as XML
+func (f *APILawFetcher) FetchLaw(ctx context.Context, revisionID string) ([]byte, error) {
+	params := &lawapi.GetLawDataParams{LawFullTextFormat: lawapi.StringPtr("xml")}
+	resp, err := callWithContext(ctx, func() (*lawapi.LawDataResponse, error) {
+		return f.client.GetLawData(revisionID, params)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", revisionID, err)
+	}
+	if resp == nil || resp.LawFullText == nil {
+		return nil, fmt.Errorf("fetching %s: %w", revisionID, ErrLawNotFound)
+	}
+	return decodeLawFullText(*resp.LawFullText)
+}
+
+// decodeLawFullText returns the XML of law_full_text, which the API encodes in base64
+func decodeLawFullText(text string) ([]byte, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, fmt.Errorf("law data has no full text")
+	}
+	if strings.HasPrefix(text, "<") {
+		return []byte(text), nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(text)
+	if err != nil {
+		return nil, fmt.Errorf("decoding law full text: %w", err)
+	}
+	return data, nil
+}
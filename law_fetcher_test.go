@@ -0,0 +1,148 @@
+package jplaw2epub
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	lawapi "go.ngs.io/jplaw-api-v2"
+)
+
+// mockLawDataClient returns fixed law list and law data responses and records the parameters
+type mockLawDataClient struct {
+	laws     *lawapi.LawsResponse
+	lawsErr  error
+	data     *lawapi.LawDataResponse
+	dataErr  error
+	delay    time.Duration
+	lawsArgs []*lawapi.GetLawsParams
+	dataIDs  []string
+	dataArgs []*lawapi.GetLawDataParams
+}
+
+// GetLaws returns the configured law list or error
+func (m *mockLawDataClient) GetLaws(params *lawapi.GetLawsParams) (*lawapi.LawsResponse, error) {
+	m.lawsArgs = append(m.lawsArgs, params)
+	time.Sleep(m.delay)
+	return m.laws, m.lawsErr
+}
+
+// GetLawData returns the configured law data or error
+func (m *mockLawDataClient) GetLawData(lawIDOrNumOrRevisionID string, params *lawapi.GetLawDataParams) (*lawapi.LawDataResponse, error) {
+	m.dataIDs = append(m.dataIDs, lawIDOrNumOrRevisionID)
+	m.dataArgs = append(m.dataArgs, params)
+	return m.data, m.dataErr
+}
+
+func TestAPILawFetcherLawRevision(t *testing.T) {
+	client := &mockLawDataClient{laws: &lawapi.LawsResponse{Laws: []lawapi.LawItem{{
+		LawInfo:      &lawapi.LawInfo{LawId: "405AC0000000088"},
+		RevisionInfo: &lawapi.RevisionInfo{LawRevisionId: "rev1"},
+	}}}}
+	fetcher := NewLawFetcher(client)
+
+	revisionID, err := fetcher.LawRevision(context.Background(), "405AC0000000088", "2024-04-01")
+	if err != nil {
+		t.Fatalf("LawRevision() error = %v", err)
+	}
+	if revisionID != "rev1" {
+		t.Errorf("LawRevision() = %q, want rev1", revisionID)
+	}
+	params := client.lawsArgs[0]
+	if params.LawId == nil || *params.LawId != "405AC0000000088" || params.Asof == nil || *params.Asof != "2024-04-01" {
+		t.Errorf("GetLaws() params = %+v", params)
+	}
+
+	if _, err := fetcher.LawRevision(context.Background(), "405AC0000000088", ""); err != nil {
+		t.Fatalf("LawRevision() error = %v", err)
+	}
+	if client.lawsArgs[1].Asof != nil {
+		t.Errorf("an empty date should not be sent, asof = %q", *client.lawsArgs[1].Asof)
+	}
+	if len(client.dataIDs) != 0 {
+		t.Errorf("looking up a revision should not fetch law data, calls = %v", client.dataIDs)
+	}
+}
+
+func TestAPILawFetcherFetchLaw(t *testing.T) {
+	const lawXML = `<Law><LawBody><LawTitle>テスト法</LawTitle></LawBody></Law>`
+
+	tests := []struct {
+		name     string
+		fullText string
+	}{
+		{name: "base64 encoded", fullText: base64.StdEncoding.EncodeToString([]byte(lawXML))},
+		{name: "plain XML", fullText: lawXML},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockLawDataClient{data: &lawapi.LawDataResponse{LawFullText: lawapi.StringPtr(tt.fullText)}}
+			xmlData, err := NewLawFetcher(client).FetchLaw(context.Background(), "rev1")
+			if err != nil {
+				t.Fatalf("FetchLaw() error = %v", err)
+			}
+			if string(xmlData) != lawXML {
+				t.Errorf("FetchLaw() = %q", xmlData)
+			}
+			if len(client.dataIDs) != 1 || client.dataIDs[0] != "rev1" {
+				t.Errorf("GetLawData() IDs = %v", client.dataIDs)
+			}
+			if format := client.dataArgs[0].LawFullTextFormat; format == nil || *format != "xml" {
+				t.Errorf("law_full_text_format = %v, want xml", format)
+			}
+		})
+	}
+}
+
+func TestAPILawFetcherErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		client   *mockLawDataClient
+		lookup   bool
+		notFound bool
+	}{
+		{name: "lookup failure", client: &mockLawDataClient{lawsErr: errors.New("connection refused")}, lookup: true},
+		{name: "no laws", client: &mockLawDataClient{laws: &lawapi.LawsResponse{}}, lookup: true, notFound: true},
+		{name: "no revision", client: &mockLawDataClient{laws: &lawapi.LawsResponse{Laws: []lawapi.LawItem{{}}}}, lookup: true, notFound: true},
+		{name: "fetch failure", client: &mockLawDataClient{dataErr: errors.New("connection reset")}},
+		{name: "no response", client: &mockLawDataClient{}, notFound: true},
+		{name: "no full text", client: &mockLawDataClient{data: &lawapi.LawDataResponse{}}, notFound: true},
+		{name: "empty full text", client: &mockLawDataClient{data: &lawapi.LawDataResponse{LawFullText: lawapi.StringPtr(" ")}}},
+		{name: "invalid base64", client: &mockLawDataClient{data: &lawapi.LawDataResponse{LawFullText: lawapi.StringPtr("%%%")}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetcher := NewLawFetcher(tt.client)
+			var err error
+			if tt.lookup {
+				_, err = fetcher.LawRevision(context.Background(), "X", "")
+			} else {
+				_, err = fetcher.FetchLaw(context.Background(), "rev1")
+			}
+			if err == nil {
+				t.Fatal("error = nil, want error")
+			}
+			if errors.Is(err, ErrLawNotFound) != tt.notFound {
+				t.Errorf("errors.Is(err, ErrLawNotFound) = %v, want %v", !tt.notFound, tt.notFound)
+			}
+		})
+	}
+}
+
+func TestAPILawFetcherContext(t *testing.T) {
+	client := &mockLawDataClient{laws: &lawapi.LawsResponse{}, delay: time.Second}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := NewLawFetcher(client).LawRevision(ctx, "X", ""); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("LawRevision() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("LawRevision() returned after %v, want it to stop at the deadline", elapsed)
+	}
+}
@@ -3,12 +3,11 @@ package jplaw2epub
 import (
 	"fmt"
 
-	"github.com/go-shiori/go-epub"
 	"go.ngs.io/jplaw-xml"
 )
 
 // processMainProvision processes the main provision content
-func processMainProvision(book *epub.Epub, mainProv *jplaw.MainProvision, imgProc ImageProcessorInterface) error {
+func processMainProvision(book BookWriter, mainProv *jplaw.MainProvision, imgProc ImageProcessorInterface) error {
 	if len(mainProv.Chapter) > 0 {
 		// Process chapters
 		for i := range mainProv.Chapter {
@@ -26,7 +25,7 @@ func processMainProvision(book *epub.Epub, mainProv *jplaw.MainProvision, imgPro
 			article := &mainProv.Article[i]
 			articleFilename := fmt.Sprintf("article-%d.xhtml", i)
 			articleTitle := buildArticleTitle(article)
-			body := buildArticleBodyWithImages(article, articleTitle, imgProc)
+			body := buildArticleBodyWithImages(article, articleTitle, headingLevelTop, imgProc)
 
 			articleTitlePlain := getArticleTitlePlain(article)
 			_, err := book.AddSection(body, articleTitlePlain, articleFilename, "")
@@ -54,9 +53,16 @@ func processMainProvision(book *epub.Epub, mainProv *jplaw.MainProvision, imgPro
 				paragraphTitle = fmt.Sprintf("第%d項", paragraph.Num)
 			}
 
-			// Build paragraph body
-			body := fmt.Sprintf("<h3>%s</h3>", paragraphTitle)
+			// Build paragraph body. Paragraphs are the divisions of provisions without articles,
+			// anchored by their number like articles (e.g. para-2)
+			number := paragraph.Num
+			if number == 0 {
+				number = i + 1
+			}
+			body := openSection("division", "paragraph", fmt.Sprintf("para-%d", number))
+			body += headingHTML(headingLevelTop, "", paragraphTitle)
 			body += processParagraphWithImages(paragraph, imgProc)
+			body += htmlSectionEnd
 
 			_, err := book.AddSection(body, paragraphTitle, paragraphFilename, "")
 			if err != nil {
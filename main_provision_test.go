@@ -1,6 +1,8 @@
 package jplaw2epub
 
 import (
+	"bytes"
+	"strings"
 	"testing"
 
 	"github.com/go-shiori/go-epub"
@@ -207,3 +209,34 @@ func TestProcessMainProvisionWithItems(t *testing.T) {
 	// we'll just verify that the function didn't error
 	// Test passed if no error occurred - main provision with items was processed successfully
 }
+
+func TestProcessMainProvisionParagraphSections(t *testing.T) {
+	paragraph := func(num int, content string) jplaw.Paragraph {
+		return jplaw.Paragraph{
+			Num:               num,
+			ParagraphNum:      jplaw.ParagraphNum{Content: content},
+			ParagraphSentence: jplaw.ParagraphSentence{Sentence: []jplaw.Sentence{{Content: "内容"}}},
+		}
+	}
+	book, err := epub.NewEpub("Test Book")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mainProv := &jplaw.MainProvision{Paragraph: []jplaw.Paragraph{paragraph(1, "１"), paragraph(2, "２")}}
+	if err := processMainProvision(book, mainProv, nil); err != nil {
+		t.Fatalf("processMainProvision() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := book.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	archive, err := readEPUBArchive(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<section epub:type="division" class="paragraph" id="para-2">`
+	if f := archive.file("EPUB/xhtml/paragraph-1.xhtml"); f == nil || !strings.Contains(string(f.data), want) {
+		t.Errorf("second paragraph section missing %q", want)
+	}
+}
@@ -1,22 +1,207 @@
 package jplaw2epub
 
 import (
+	"bytes"
+	"encoding/xml"
 	"fmt"
+	"regexp"
+	"strings"
 
-	"github.com/go-shiori/go-epub"
+	"github.com/gofrs/uuid/v5"
 	"go.ngs.io/jplaw-xml"
 )
 
 // setupEPUBMetadata sets up the basic EPUB metadata
-func setupEPUBMetadata(book *epub.Epub, data *jplaw.Law) {
+func setupEPUBMetadata(book *Book, data *jplaw.Law) {
 	book.SetAuthor(data.LawNum)
 	book.SetLang(string(data.Lang))
+	book.SetIdentifier(lawIdentifier(data, ""))
 
-	// Set description
-	eraStr := getEraString(data.Era)
-	description := fmt.Sprintf("公布日: %s %d年%d月%d日", eraStr, data.Year, data.PromulgateMonth, data.PromulgateDay)
+	book.SetDescription(lawDescription(data, DateDisplayJapanese))
+
+	book.post.metadata = newPackageMetadata(data)
+}
+
+// MetadataOverrides replace the package metadata derived from the law. Empty fields keep
+// the derived values.
+type MetadataOverrides struct {
+	Title       string `json:"title" yaml:"title"`
+	Author      string `json:"author" yaml:"author"`
+	Publisher   string `json:"publisher" yaml:"publisher"`
+	Description string `json:"description" yaml:"description"`
+}
+
+// applyMetadataOverrides replaces the metadata of book with the non-empty overrides
+func applyMetadataOverrides(book *Book, overrides MetadataOverrides) {
+	if overrides.Title != "" {
+		book.SetTitle(overrides.Title)
+	}
+	if overrides.Author != "" {
+		book.SetAuthor(overrides.Author)
+	}
+	if overrides.Description != "" {
+		book.SetDescription(overrides.Description)
+	}
+	if overrides.Publisher != "" {
+		book.post.metadata.publisher = overrides.Publisher
+	}
+}
+
+// lawDescription returns the dc:description of a law, showing dates as display selects
+func lawDescription(data *jplaw.Law, display DateDisplay) string {
+	description := "公布日: " + promulgationDate(data).Format(display)
 	description += fmt.Sprintf("\n法令番号: %s", data.LawNum)
 	lawTitleWithRuby := processTextWithRuby(data.LawBody.LawTitle.Content, data.LawBody.LawTitle.Ruby)
 	description += fmt.Sprintf("\n現行法令名: %s %s", lawTitleWithRuby, data.LawBody.LawTitle.Kana)
-	book.SetDescription(description)
+	return description
+}
+
+// lawIdentifier returns a stable dc:identifier for a law.
+// A revision ID (lawID_date_revision) identifies the exact text; otherwise the e-Gov law ID is
+// derived from the law number attributes, falling back to a name-based UUID of the law number.
+func lawIdentifier(data *jplaw.Law, revisionID string) string {
+	if revisionID != "" {
+		return "urn:jplaw:" + revisionID
+	}
+	if lawID := deriveLawID(data); lawID != "" {
+		return "urn:jplaw:" + lawID
+	}
+	return "urn:uuid:" + uuid.NewV5(uuid.NamespaceURL, "urn:jplaw:lawnum:"+data.LawNum).String()
+}
+
+// deriveLawID builds the e-Gov law ID (e.g. 405AC0000000088) for law types whose ID only
+// depends on the era, year and number. It returns an empty string for other law types.
+func deriveLawID(data *jplaw.Law) string {
+	eraCodes := map[jplaw.Era]int{
+		jplaw.EraMeiji:  1,
+		jplaw.EraTaisho: 2,
+		jplaw.EraShowa:  3,
+		jplaw.EraHeisei: 4,
+		jplaw.EraReiwa:  5,
+	}
+	eraCode, ok := eraCodes[data.Era]
+	if !ok || data.Year <= 0 || data.Year > 99 {
+		return ""
+	}
+
+	switch data.LawType {
+	case "Constitution":
+		return fmt.Sprintf("%d%02dCONSTITUTION", eraCode, data.Year)
+	case "Act":
+		return fmt.Sprintf("%d%02dAC%010d", eraCode, data.Year, data.Num)
+	case "CabinetOrder":
+		return fmt.Sprintf("%d%02dCO%010d", eraCode, data.Year, data.Num)
+	case "ImperialOrder":
+		return fmt.Sprintf("%d%02dIO%010d", eraCode, data.Year, data.Num)
+	default:
+		return ""
+	}
+}
+
+// lawTypeNames maps law types to the names used as dc:subject
+var lawTypeNames = map[jplaw.LawType]string{
+	"Constitution":         "憲法",
+	"Act":                  "法律",
+	"CabinetOrder":         "政令",
+	"ImperialOrder":        "勅令",
+	"MinisterialOrdinance": "府省令",
+	"Rule":                 "規則",
+	"Misc":                 "その他",
+}
+
+// lawIssuerPattern extracts the issuing body from law numbers such as 平成二十七年総務省令第二十四号
+var lawIssuerPattern = regexp.MustCompile(`^(?:明治|大正|昭和|平成|令和)[元〇一二三四五六七八九十百]+年(.+?[省府庁会院所])(?:令|規則|告示|訓令)第`)
+
+// lawPromulgator returns the body promulgating a law, or an empty string when unknown
+func lawPromulgator(data *jplaw.Law) string {
+	switch data.LawType {
+	case "Constitution", "ImperialOrder":
+		return "天皇"
+	case "Act":
+		return "国会"
+	case "CabinetOrder":
+		return "内閣"
+	}
+	if match := lawIssuerPattern.FindStringSubmatch(data.LawNum); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// packageMetadata holds the EPUB3 metadata go-epub cannot express.
+// It is added to the package document when the book is written.
+type packageMetadata struct {
+	// date is the promulgation date as YYYY-MM-DD, or YYYY when only the year converts
+	date      string
+	subject   string
+	publisher string
+	// titleKana is the reading of the title, used for the file-as and alternate-script refinements
+	titleKana string
+	// lang is the language of the law, tagged on content documents
+	lang string
+}
+
+// newPackageMetadata collects the package metadata of a law
+func newPackageMetadata(data *jplaw.Law) *packageMetadata {
+	meta := &packageMetadata{
+		subject:   lawTypeNames[data.LawType],
+		publisher: lawPromulgator(data),
+		lang:      string(data.Lang),
+	}
+	meta.date = promulgationDate(data).ISO()
+	if data.LawBody.LawTitle != nil {
+		meta.titleKana = data.LawBody.LawTitle.Kana
+	}
+	return meta
+}
+
+// addPackageMetadata adds the package metadata to the package document
+func addPackageMetadata(archive *epubArchive, meta *packageMetadata) error {
+	pkg := archive.file(epubPackagePath)
+	if pkg == nil {
+		return fmt.Errorf("package document %s not found", epubPackagePath)
+	}
+
+	var elements []string
+	addElement := func(format, value string) {
+		if value != "" {
+			elements = append(elements, fmt.Sprintf(format, escapeXMLText(value)))
+		}
+	}
+	addElement("<dc:date>%s</dc:date>", meta.date)
+	addElement("<dc:subject>%s</dc:subject>", meta.subject)
+	addElement("<dc:publisher>%s</dc:publisher>", meta.publisher)
+
+	opf := string(pkg.data)
+	if meta.titleKana != "" && strings.Contains(opf, "<dc:title>") {
+		opf = strings.Replace(opf, "<dc:title>", `<dc:title id="title">`, 1)
+		addElement(`<meta refines="#title" property="file-as">%s</meta>`, meta.titleKana)
+		addElement(`<meta refines="#title" property="alternate-script" xml:lang="ja-Hrkt">%s</meta>`, meta.titleKana)
+	}
+	if len(elements) == 0 {
+		return nil
+	}
+
+	opf, err := insertPackageMetadata(opf, elements)
+	if err != nil {
+		return err
+	}
+	pkg.data = []byte(opf)
+	return nil
+}
+
+// insertPackageMetadata adds elements at the end of the metadata of a package document
+func insertPackageMetadata(opf string, elements []string) (string, error) {
+	end := strings.Index(opf, "</metadata>")
+	if end < 0 {
+		return "", fmt.Errorf("metadata element not found in %s", epubPackagePath)
+	}
+	return opf[:end] + "  " + strings.Join(elements, "\n    ") + "\n  " + opf[end:], nil
+}
+
+// escapeXMLText escapes s for use as XML character data
+func escapeXMLText(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
 }
@@ -1,6 +1,11 @@
 package jplaw2epub
 
 import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/go-shiori/go-epub"
@@ -34,7 +39,7 @@ func TestSetupEPUBMetadata(t *testing.T) {
 			expectedAuthor: "平成二十七年総務省令第二十四号",
 			expectedLang:   "ja",
 			expectedInDesc: []string{
-				"公布日: 平成 27年3月26日",
+				"公布日: 平成27年3月26日",
 				"法令番号: 平成二十七年総務省令第二十四号",
 				"現行法令名: 放送法及び電波法の一部を改正する法律",
 			},
@@ -68,7 +73,7 @@ func TestSetupEPUBMetadata(t *testing.T) {
 			expectedAuthor: "令和五年法律第一号",
 			expectedLang:   "ja",
 			expectedInDesc: []string{
-				"公布日: 令和 5年1月15日",
+				"公布日: 令和5年1月15日",
 				"法令番号: 令和五年法律第一号",
 				"特別措置法",
 			},
@@ -84,7 +89,7 @@ func TestSetupEPUBMetadata(t *testing.T) {
 			}
 
 			// Apply metadata
-			setupEPUBMetadata(book, tt.data)
+			setupEPUBMetadata(&Book{Epub: book}, tt.data)
 
 			// Since we can't easily inspect metadata without writing to disk,
 			// we'll just verify that the function executed successfully
@@ -93,3 +98,118 @@ func TestSetupEPUBMetadata(t *testing.T) {
 		})
 	}
 }
+
+func TestLawIdentifier(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       *jplaw.Law
+		revisionID string
+		want       string
+	}{
+		{
+			name:       "revision ID",
+			data:       &jplaw.Law{Era: "Reiwa", Year: 5, Num: 10, LawType: "Act"},
+			revisionID: "505AC0000000010_20230401_000000000000000",
+			want:       "urn:jplaw:505AC0000000010_20230401_000000000000000",
+		},
+		{name: "act", data: &jplaw.Law{Era: "Heisei", Year: 5, Num: 88, LawType: "Act"}, want: "urn:jplaw:405AC0000000088"},
+		{name: "cabinet order", data: &jplaw.Law{Era: "Showa", Year: 25, Num: 338, LawType: "CabinetOrder"}, want: "urn:jplaw:325CO0000000338"},
+		{name: "constitution", data: &jplaw.Law{Era: "Showa", Year: 21, LawType: "Constitution"}, want: "urn:jplaw:321CONSTITUTION"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lawIdentifier(tt.data, tt.revisionID); got != tt.want {
+				t.Errorf("lawIdentifier() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("ministerial ordinance is stable", func(t *testing.T) {
+		data := &jplaw.Law{Era: "Heisei", Year: 27, Num: 24, LawType: "MinisterialOrdinance", LawNum: "平成二十七年総務省令第二十四号"}
+		first := lawIdentifier(data, "")
+		if !strings.HasPrefix(first, "urn:uuid:") || first != lawIdentifier(data, "") {
+			t.Errorf("lawIdentifier() = %q, want a stable urn:uuid", first)
+		}
+		other := &jplaw.Law{Era: "Heisei", Year: 27, Num: 25, LawType: "MinisterialOrdinance", LawNum: "平成二十七年総務省令第二十五号"}
+		if lawIdentifier(other, "") == first {
+			t.Error("different laws should have different identifiers")
+		}
+	})
+}
+
+func TestNewPackageMetadata(t *testing.T) {
+	tests := []struct {
+		name string
+		data *jplaw.Law
+		want packageMetadata
+	}{
+		{
+			name: "act",
+			data: &jplaw.Law{
+				Era: "Reiwa", Year: 5, PromulgateMonth: 4, PromulgateDay: 1, LawType: "Act", LawNum: "令和五年法律第十号",
+				LawBody: jplaw.LawBody{LawTitle: &jplaw.LawTitle{Content: "試験法", Kana: "しけんほう"}},
+			},
+			want: packageMetadata{date: "2023-04-01", subject: "法律", publisher: "国会", titleKana: "しけんほう"},
+		},
+		{
+			name: "ministerial ordinance",
+			data: &jplaw.Law{Era: "Heisei", Year: 27, PromulgateMonth: 3, PromulgateDay: 26, LawType: "MinisterialOrdinance", LawNum: "平成二十七年総務省令第二十四号"},
+			want: packageMetadata{date: "2015-03-26", subject: "府省令", publisher: "総務省"},
+		},
+		{
+			name: "joint ordinance",
+			data: &jplaw.Law{Era: "Heisei", Year: 12, PromulgateMonth: 12, PromulgateDay: 27, LawType: "MinisterialOrdinance", LawNum: "平成十二年総務省・経済産業省令第一号"},
+			want: packageMetadata{date: "2000-12-27", subject: "府省令", publisher: "総務省・経済産業省"},
+		},
+		{
+			name: "rule without promulgation day",
+			data: &jplaw.Law{Era: "Showa", Year: 22, LawType: "Rule", LawNum: "昭和二十二年最高裁判所規則第六号"},
+			want: packageMetadata{date: "1947", subject: "規則", publisher: "最高裁判所"},
+		},
+		{
+			name: "unknown era",
+			data: &jplaw.Law{LawType: "Misc", LawNum: "告示"},
+			want: packageMetadata{subject: "その他"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newPackageMetadata(tt.data); *got != tt.want {
+				t.Errorf("newPackageMetadata() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteEPUBPackageMetadata(t *testing.T) {
+	opts := &EPUBOptions{RevisionID: "505AC0000000010_20230401_000000000000000", NoCover: true}
+	book, err := NewBookFromXMLFile(context.Background(), bytes.NewReader(readTestdata(t, "json/chapters.xml")), opts)
+	if err != nil {
+		t.Fatalf("NewBookFromXMLFile() error = %v", err)
+	}
+	destPath := filepath.Join(t.TempDir(), "law.epub")
+	if err := book.Write(destPath); err != nil {
+		t.Fatalf("Book.Write() error = %v", err)
+	}
+
+	opf := readZipFiles(t, destPath)[epubPackagePath]
+	for _, want := range []string{
+		`<dc:identifier id="pub-id">urn:jplaw:505AC0000000010_20230401_000000000000000</dc:identifier>`,
+		`<dc:title id="title">試験法</dc:title>`,
+		`<dc:date>2023-04-01</dc:date>`,
+		`<dc:subject>法律</dc:subject>`,
+		`<dc:publisher>国会</dc:publisher>`,
+		`<meta refines="#title" property="file-as">しけんほう</meta>`,
+		`<meta refines="#title" property="alternate-script" xml:lang="ja-Hrkt">しけんほう</meta>`,
+		`property="dcterms:modified"`,
+	} {
+		if !strings.Contains(opf, want) {
+			t.Errorf("package document missing %s:\n%s", want, opf)
+		}
+	}
+	if err := xml.Unmarshal([]byte(opf), new(struct{})); err != nil {
+		t.Errorf("package document is not well-formed: %v", err)
+	}
+}
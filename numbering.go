@@ -0,0 +1,261 @@
+package jplaw2epub
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go.ngs.io/jplaw-xml"
+)
+
+// ProvisionNumber is the structured number of an article, paragraph or item: the main number
+// followed by its の branch numbers, e.g. [123 2] for 第百二十三条の二
+type ProvisionNumber []int
+
+// kanjiDigits are the values of the kanji digits
+var kanjiDigits = map[rune]int{
+	'〇': 0, '零': 0, '一': 1, '二': 2, '三': 3, '四': 4, '五': 5, '六': 6, '七': 7, '八': 8, '九': 9,
+}
+
+// kanjiSmallUnits and kanjiLargeUnits are the multipliers within and between groups of four digits
+var (
+	kanjiSmallUnits = map[rune]int{'十': 10, '百': 100, '千': 1000}
+	kanjiLargeUnits = map[rune]int{'万': 10000, '億': 100000000}
+)
+
+// irohaOrder is the order of the katakana labels of subitems (イ, ロ, ハ...)
+const irohaOrder = "イロハニホヘトチリヌルヲワカヨタレソツネナラムウヰノオクヤマケフコエテアサキユメミシヱヒモセスン"
+
+// provisionUnits are the units written after a number, as in 第三章 or 第十条の二
+const provisionUnits = "編章節款目条項号"
+
+// ParseKanjiNumber parses a number written in kanji numerals, either with units (百二十三)
+// or digit by digit (一二三). Arabic digits, full-width included, are accepted too.
+func ParseKanjiNumber(s string) (int, error) {
+	if s == "" {
+		return 0, fmt.Errorf("empty number")
+	}
+	if n, ok := parseArabicNumber(s); ok {
+		return n, nil
+	}
+
+	hasUnits := strings.ContainsFunc(s, func(r rune) bool {
+		return kanjiSmallUnits[r] != 0 || kanjiLargeUnits[r] != 0
+	})
+	total, group, digit := 0, 0, -1
+	for _, r := range s {
+		switch {
+		case !hasUnits:
+			d, ok := kanjiDigits[r]
+			if !ok {
+				return 0, fmt.Errorf("invalid kanji number %q", s)
+			}
+			total = total*10 + d
+		case kanjiSmallUnits[r] != 0:
+			if digit == 0 {
+				return 0, fmt.Errorf("invalid kanji number %q", s)
+			}
+			// 十, 百 and 千 are written without a leading 一
+			group += max(digit, 1) * kanjiSmallUnits[r]
+			digit = -1
+		case kanjiLargeUnits[r] != 0:
+			if digit < 0 && group == 0 {
+				return 0, fmt.Errorf("invalid kanji number %q", s)
+			}
+			total += (group + max(digit, 0)) * kanjiLargeUnits[r]
+			group, digit = 0, -1
+		default:
+			d, ok := kanjiDigits[r]
+			if !ok || digit > 0 {
+				return 0, fmt.Errorf("invalid kanji number %q", s)
+			}
+			digit = d
+		}
+	}
+	if hasUnits {
+		total += group + max(digit, 0)
+	}
+	return total, nil
+}
+
+// parseArabicNumber parses a number of ASCII or full-width digits
+func parseArabicNumber(s string) (int, bool) {
+	n := 0
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			n = n*10 + int(r-'0')
+		case r >= '０' && r <= '９':
+			n = n*10 + int(r-'０')
+		default:
+			return 0, false
+		}
+	}
+	return n, true
+}
+
+// parseLabelNumber parses one part of a provision number: kanji or Arabic numerals,
+// or an iroha katakana label
+func parseLabelNumber(s string) (int, error) {
+	if utf8.RuneCountInString(s) == 1 {
+		r, _ := utf8.DecodeRuneInString(s)
+		if i := strings.IndexRune(irohaOrder, r); i >= 0 {
+			return utf8.RuneCountInString(irohaOrder[:i]) + 1, nil
+		}
+	}
+	return ParseKanjiNumber(s)
+}
+
+// ParseProvisionNumber parses the number of an article, paragraph or item. It accepts titles
+// and labels such as 第百二十三条の二, 十二の二, （３） and ロ, as well as the Arabic forms
+// 123_2 (the Num attribute) and 123の2. A hyphen marks a range, as in ParseProvisionRanges,
+// so 123-2 is rejected rather than read as a branch number.
+func ParseProvisionNumber(s string) (ProvisionNumber, error) {
+	if strings.ContainsAny(s, "-－") {
+		return nil, fmt.Errorf("invalid provision number %q: write branch numbers with _ or の", s)
+	}
+	label := strings.TrimSpace(s)
+	label = strings.TrimPrefix(strings.TrimSuffix(label, "）"), "（")
+	label = strings.TrimPrefix(strings.TrimSuffix(label, ")"), "(")
+	label = strings.TrimPrefix(label, "第")
+
+	parts := strings.FieldsFunc(label, func(r rune) bool {
+		return r == 'の' || r == '_'
+	})
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("invalid provision number %q", s)
+	}
+	number := make(ProvisionNumber, 0, len(parts))
+	for _, part := range parts {
+		part = strings.TrimRight(part, provisionUnits)
+		n, err := parseLabelNumber(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid provision number %q: %w", s, err)
+		}
+		number = append(number, n)
+	}
+	return number, nil
+}
+
+// String formats the number as in the Num attribute of the law XML (e.g. 123_2)
+func (n ProvisionNumber) String() string {
+	parts := make([]string, len(n))
+	for i, part := range n {
+		parts[i] = strconv.Itoa(part)
+	}
+	return strings.Join(parts, "_")
+}
+
+// Compare orders numbers as provisions are ordered in a law: 第十条 < 第十条の二 < 第十一条.
+// It returns -1, 0 or 1.
+func (n ProvisionNumber) Compare(other ProvisionNumber) int {
+	for i := 0; i < len(n) && i < len(other); i++ {
+		if n[i] != other[i] {
+			if n[i] < other[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case len(n) < len(other):
+		return -1
+	case len(n) > len(other):
+		return 1
+	default:
+		return 0
+	}
+}
+
+// articleNumber returns the number of an article, parsed from its title or else its Num attribute
+func articleNumber(article *jplaw.Article) (ProvisionNumber, bool) {
+	if article.ArticleTitle != nil {
+		if number, err := ParseProvisionNumber(article.ArticleTitle.Content); err == nil {
+			return number, true
+		}
+	}
+	if number, err := ParseProvisionNumber(article.Num); err == nil {
+		return number, true
+	}
+	return nil, false
+}
+
+// articleAnchor returns the stable id of an article heading (e.g. art-123_2), or an empty
+// string when the article has no number
+func articleAnchor(article *jplaw.Article) string {
+	number, ok := articleNumber(article)
+	if !ok {
+		return ""
+	}
+	return "art-" + number.String()
+}
+
+// FindArticle returns the article of the main provision with the given number, or nil
+func FindArticle(law *JSONLaw, number ProvisionNumber) *JSONArticle {
+	find := func(articles []JSONArticle) *JSONArticle {
+		for i := range articles {
+			label := articles[i].Title.Text
+			if label == "" {
+				label = articles[i].Num
+			}
+			if n, err := ParseProvisionNumber(label); err == nil && n.Compare(number) == 0 {
+				return &articles[i]
+			}
+		}
+		return nil
+	}
+
+	for i := range law.MainProvision.Chapters {
+		chapter := &law.MainProvision.Chapters[i]
+		if article := find(chapter.Articles); article != nil {
+			return article
+		}
+		for j := range chapter.Sections {
+			if article := find(chapter.Sections[j].Articles); article != nil {
+				return article
+			}
+		}
+	}
+	return find(law.MainProvision.Articles)
+}
+
+// kanjiNumberingPattern matches the kanji numbering of a title, such as 第三章 or 第百二十三条の二
+var kanjiNumberingPattern = regexp.MustCompile(`第[〇一二三四五六七八九十百千万]+[` + provisionUnits + `]?(?:の[〇一二三四五六七八九十百千万]+)*`)
+
+// kanjiNumeralPattern matches a run of kanji numerals
+var kanjiNumeralPattern = regexp.MustCompile(`[〇一二三四五六七八九十百千万]+`)
+
+// arabicNumbering rewrites the numbering at the start of a title in Arabic numerals
+// (e.g. 第百二十三条の二 見出し -> 第123条の2 見出し), leaving the rest of the title as is
+func arabicNumbering(title string) string {
+	loc := kanjiNumberingPattern.FindStringIndex(title)
+	if loc == nil || strings.ContainsAny(title[:loc[0]], " 　（(") {
+		return title
+	}
+	numbering := kanjiNumeralPattern.ReplaceAllStringFunc(title[loc[0]:loc[1]], func(kanji string) string {
+		n, err := ParseKanjiNumber(kanji)
+		if err != nil {
+			return kanji
+		}
+		return strconv.Itoa(n)
+	})
+	return title[:loc[0]] + numbering + title[loc[1]:]
+}
+
+// arabicTOCWriter is a BookWriter that shows the numbering of section titles in the
+// table of contents in Arabic numerals
+type arabicTOCWriter struct {
+	BookWriter
+}
+
+// AddSection adds a section with an Arabic-numbered title
+func (w *arabicTOCWriter) AddSection(body, sectionTitle, internalFilename, internalCSSPath string) (string, error) {
+	return w.BookWriter.AddSection(body, arabicNumbering(sectionTitle), internalFilename, internalCSSPath)
+}
+
+// AddSubSection adds a subsection with an Arabic-numbered title
+func (w *arabicTOCWriter) AddSubSection(parentFilename, body, sectionTitle, internalFilename, internalCSSPath string) (string, error) {
+	return w.BookWriter.AddSubSection(parentFilename, body, arabicNumbering(sectionTitle), internalFilename, internalCSSPath)
+}
@@ -0,0 +1,169 @@
+package jplaw2epub
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.ngs.io/jplaw-xml"
+)
+
+func TestParseKanjiNumber(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{input: "一", want: 1},
+		{input: "十", want: 10},
+		{input: "十二", want: 12},
+		{input: "二十", want: 20},
+		{input: "百二十三", want: 123},
+		{input: "千五", want: 1005},
+		{input: "三千二百", want: 3200},
+		{input: "一万二千", want: 12000},
+		{input: "二〇", want: 20},
+		{input: "一二三", want: 123},
+		{input: "123", want: 123},
+		{input: "１２", want: 12},
+		{input: "", wantErr: true},
+		{input: "一二十", wantErr: true},
+		{input: "条", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseKanjiNumber(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseKanjiNumber() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseKanjiNumber() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseProvisionNumber(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    ProvisionNumber
+		wantErr bool
+	}{
+		{input: "第百二十三条の二", want: ProvisionNumber{123, 2}},
+		{input: "第十条の二の三", want: ProvisionNumber{10, 2, 3}},
+		{input: "第三項", want: ProvisionNumber{3}},
+		{input: "２", want: ProvisionNumber{2}},
+		{input: "十二の二", want: ProvisionNumber{12, 2}},
+		{input: "（３）", want: ProvisionNumber{3}},
+		{input: "(一)", want: ProvisionNumber{1}},
+		{input: "ロ", want: ProvisionNumber{2}},
+		{input: "123-2", wantErr: true},
+		{input: "123_2", want: ProvisionNumber{123, 2}},
+		{input: "123の2", want: ProvisionNumber{123, 2}},
+		{input: "", wantErr: true},
+		{input: "第二条から第四条まで", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseProvisionNumber(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseProvisionNumber() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParseProvisionNumber() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProvisionNumberCompare(t *testing.T) {
+	var numbers []ProvisionNumber
+	for _, title := range []string{"第十一条", "第十条の二", "第二条", "第十条", "第十条の二の二", "第十条の十"} {
+		number, err := ParseProvisionNumber(title)
+		if err != nil {
+			t.Fatal(err)
+		}
+		numbers = append(numbers, number)
+	}
+	slices.SortFunc(numbers, ProvisionNumber.Compare)
+
+	var got []string
+	for _, number := range numbers {
+		got = append(got, number.String())
+	}
+	want := []string{"2", "10", "10_2", "10_2_2", "10_10", "11"}
+	if !slices.Equal(got, want) {
+		t.Errorf("sorted numbers = %v, want %v", got, want)
+	}
+}
+
+func TestArticleAnchor(t *testing.T) {
+	tests := []struct {
+		name    string
+		article *jplaw.Article
+		want    string
+	}{
+		{name: "title", article: &jplaw.Article{ArticleTitle: &jplaw.ArticleTitle{Content: "第百二十三条の二"}}, want: "art-123_2"},
+		{name: "num attribute", article: &jplaw.Article{Num: "5_3", ArticleTitle: &jplaw.ArticleTitle{Content: "第二条から第四条まで"}}, want: "art-5_3"},
+		{name: "no number", article: &jplaw.Article{}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := articleAnchor(tt.article); got != tt.want {
+				t.Errorf("articleAnchor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArabicNumbering(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{title: "第百二十三条の二 （定義）", want: "第123条の2 （定義）"},
+		{title: "第三章 一般的な規定", want: "第3章 一般的な規定"},
+		{title: "別表第一", want: "別表第1"},
+		{title: "附則", want: "附則"},
+		{title: "第2項", want: "第2項"},
+		{title: "目的 第一条関係", want: "目的 第一条関係"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			if got := arabicNumbering(tt.title); got != tt.want {
+				t.Errorf("arabicNumbering() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindArticle(t *testing.T) {
+	law, err := CreateJSONFromXMLFile(bytes.NewReader(readTestdata(t, "json/chapters.xml")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	article := FindArticle(law, ProvisionNumber{2})
+	if article == nil || article.Title.Text != "第二条" {
+		t.Fatalf("FindArticle(2) = %+v, want 第二条", article)
+	}
+	if article := FindArticle(law, ProvisionNumber{2, 1}); article != nil {
+		t.Errorf("FindArticle(2-1) = %+v, want nil", article)
+	}
+}
+
+func TestWriteEPUBArabicTOC(t *testing.T) {
+	files := writeTestEPUB(t, &EPUBOptions{ArabicTOC: true, NoCover: true})
+	nav := files["EPUB/nav.xhtml"]
+	if !strings.Contains(nav, "第2条") || strings.Contains(nav, "第二条") {
+		t.Errorf("table of contents should number articles in Arabic numerals:\n%s", nav)
+	}
+	if article := files["EPUB/xhtml/article-0-1.xhtml"]; !strings.Contains(article, `<section class="article" id="art-2"><h3>第二条`) {
+		t.Errorf("article heading should keep its kanji title and have an anchor:\n%s", article)
+	}
+}
@@ -39,7 +39,7 @@ func processParagraphWithImages(para *jplaw.Paragraph, imgProc ImageProcessorInt
 		// Process FigStruct if present
 		if len(para.FigStruct) > 0 {
 			for _, fig := range para.FigStruct {
-				if p.imageProcessor != nil {
+				if hasImages(p.imageProcessor) {
 					if html, err := p.imageProcessor.ProcessFigStruct(&fig); err == nil {
 						p.body += html
 					}
@@ -62,6 +62,8 @@ func processParagraphWithImages(para *jplaw.Paragraph, imgProc ImageProcessorInt
 			p.body += processLists(para.List)
 		}
 
+		p.body += processAmendProvisions(amendProvisionsOf(p.imageProcessor, para), p.imageProcessor)
+
 		p.body += htmlLIEnd
 		p.body += htmlOLEnd
 	} else {
@@ -111,7 +113,7 @@ func (p *paragraphProcessor) processNumberedParagraph(para *jplaw.Paragraph, idx
 	// Process FigStruct if present
 	if len(para.FigStruct) > 0 {
 		for _, fig := range para.FigStruct {
-			if p.imageProcessor != nil {
+			if hasImages(p.imageProcessor) {
 				if html, err := p.imageProcessor.ProcessFigStruct(&fig); err == nil {
 					p.body += html
 				}
@@ -134,6 +136,8 @@ func (p *paragraphProcessor) processNumberedParagraph(para *jplaw.Paragraph, idx
 		p.body += processLists(para.List)
 	}
 
+	p.body += processAmendProvisions(amendProvisionsOf(p.imageProcessor, para), p.imageProcessor)
+
 	p.body += htmlLIEnd
 }
 
@@ -164,7 +168,7 @@ func (p *paragraphProcessor) processRegularParagraph(para *jplaw.Paragraph) {
 	// Process FigStruct if present
 	if len(para.FigStruct) > 0 {
 		for _, fig := range para.FigStruct {
-			if p.imageProcessor != nil {
+			if hasImages(p.imageProcessor) {
 				if html, err := p.imageProcessor.ProcessFigStruct(&fig); err == nil {
 					p.body += html
 				}
@@ -186,6 +190,8 @@ func (p *paragraphProcessor) processRegularParagraph(para *jplaw.Paragraph) {
 	if len(para.List) > 0 {
 		p.body += processLists(para.List)
 	}
+
+	p.body += processAmendProvisions(amendProvisionsOf(p.imageProcessor, para), p.imageProcessor)
 }
 
 // processLists processes List elements
@@ -0,0 +1,362 @@
+package jplaw2epub
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"go.ngs.io/jplaw-xml"
+)
+
+// PDF font settings. The Japanese fonts are referenced rather than embedded,
+// so conforming readers substitute an installed Mincho face.
+const (
+	pdfFontName       = "KozMinPr6N-Regular"
+	pdfFontDescriptor = "<< /Type /FontDescriptor /FontName /" + pdfFontName +
+		" /Flags 6 /FontBBox [-437 -340 1147 1317] /ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 742 /StemV 86 >>"
+)
+
+// pdfSection is a section added to a PDFDocument
+type pdfSection struct {
+	title    string
+	filename string
+	body     string
+	children []*pdfSection
+}
+
+// PDFDocument is a print-oriented rendering of a law.
+//
+// It receives the same sections as the EPUB output through the BookWriter
+// interface and lays them out on A4 pages with running headers, page numbers
+// and bookmarks mirroring the table of contents.
+type PDFDocument struct {
+	title    string
+	author   string
+	vertical bool
+	sections []*pdfSection
+	files    map[string]*pdfSection
+	images   map[string][]byte
+}
+
+// NewPDFDocument creates an empty PDF document with the given title
+func NewPDFDocument(title string) *PDFDocument {
+	return &PDFDocument{
+		title:  title,
+		files:  make(map[string]*pdfSection),
+		images: make(map[string][]byte),
+	}
+}
+
+// Title returns the document title
+func (d *PDFDocument) Title() string {
+	return d.title
+}
+
+// SetAuthor sets the author recorded in the document information
+func (d *PDFDocument) SetAuthor(author string) {
+	d.author = author
+}
+
+// SetVerticalWriting switches the body text to vertical (縦書き) columns
+func (d *PDFDocument) SetVerticalWriting(vertical bool) {
+	d.vertical = vertical
+}
+
+// AddSection adds a top-level section and returns its internal filename
+func (d *PDFDocument) AddSection(body, sectionTitle, internalFilename, _ string) (string, error) {
+	return d.addSection("", body, sectionTitle, internalFilename)
+}
+
+// AddSubSection adds a section nested under an existing section
+func (d *PDFDocument) AddSubSection(parentFilename, body, sectionTitle, internalFilename, _ string) (string, error) {
+	return d.addSection(parentFilename, body, sectionTitle, internalFilename)
+}
+
+// addSection registers a section under the given parent
+func (d *PDFDocument) addSection(parentFilename, body, sectionTitle, internalFilename string) (string, error) {
+	if internalFilename == "" {
+		internalFilename = fmt.Sprintf("section%04d.xhtml", len(d.files)+1)
+	}
+	if _, exists := d.files[internalFilename]; exists {
+		return "", fmt.Errorf("filename already used: %s", internalFilename)
+	}
+
+	section := &pdfSection{
+		title:    sectionTitle,
+		filename: internalFilename,
+		body:     body,
+	}
+
+	if parentFilename == "" {
+		d.sections = append(d.sections, section)
+	} else {
+		parent, ok := d.files[parentFilename]
+		if !ok {
+			return "", fmt.Errorf("parent section does not exist: %s", parentFilename)
+		}
+		parent.children = append(parent.children, section)
+	}
+	d.files[internalFilename] = section
+
+	return internalFilename, nil
+}
+
+// AddImage adds an image from a data URL or local file and returns the path used to reference it
+func (d *PDFDocument) AddImage(source, imageFilename string) (string, error) {
+	var data []byte
+	if strings.HasPrefix(source, "data:") {
+		idx := strings.Index(source, ";base64,")
+		if idx < 0 {
+			return "", fmt.Errorf("unsupported data URL for image %s", imageFilename)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(source[idx+len(";base64,"):])
+		if err != nil {
+			return "", fmt.Errorf("decoding image %s: %w", imageFilename, err)
+		}
+		data = decoded
+	} else {
+		content, err := os.ReadFile(source)
+		if err != nil {
+			return "", fmt.Errorf("reading image %s: %w", source, err)
+		}
+		data = content
+	}
+
+	if imageFilename == "" {
+		imageFilename = path.Base(source)
+	}
+	internalPath := "../images/" + imageFilename
+	if _, exists := d.images[internalPath]; exists {
+		return "", fmt.Errorf("filename already used: %s", imageFilename)
+	}
+	d.images[internalPath] = data
+
+	return internalPath, nil
+}
+
+// WriteTo lays out the document and writes the PDF to w
+func (d *PDFDocument) WriteTo(w io.Writer) (int64, error) {
+	data, err := d.render()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// render lays out the document and serializes it as PDF
+func (d *PDFDocument) render() ([]byte, error) {
+	layout := newPDFLayout(d)
+	layout.run()
+
+	w := newPDFWriter()
+	catalogNum := w.allocate()
+	pagesNum := w.allocate()
+	infoNum := w.allocate()
+	descriptorNum := w.allocate()
+	cidFontNum := w.allocate()
+	fontHNum := w.allocate()
+	fontVNum := w.allocate()
+
+	pageNums := make([]int, len(layout.pages))
+	contentNums := make([]int, len(layout.pages))
+	for i := range layout.pages {
+		pageNums[i] = w.allocate()
+		contentNums[i] = w.allocate()
+	}
+	imageNums := make([]int, len(layout.images))
+	for i := range layout.images {
+		imageNums[i] = w.allocate()
+	}
+	outlineNums := make(map[*pdfOutlineEntry]int)
+	outlinesNum := 0
+	if len(layout.outline) > 0 {
+		outlinesNum = w.allocate()
+		allocateOutline(w, layout.outline, outlineNums)
+	}
+
+	// Fonts: one CID font shared by horizontal and vertical encodings
+	w.object(descriptorNum, pdfFontDescriptor)
+	w.object(cidFontNum, fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType0 /BaseFont /%s "+
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (Japan1) /Supplement 6 >> "+
+		"/FontDescriptor %s /DW 1000 /W [1 95 500 231 632 500] >>", pdfFontName, pdfRef(descriptorNum)))
+	for _, font := range []struct {
+		num      int
+		encoding string
+	}{{fontHNum, "UniJIS-UTF16-H"}, {fontVNum, "UniJIS-UTF16-V"}} {
+		w.object(font.num, fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s-%s /Encoding /%s /DescendantFonts [%s] >>",
+			pdfFontName, font.encoding, font.encoding, pdfRef(cidFontNum)))
+	}
+
+	// Pages
+	kids := make([]string, len(pageNums))
+	for i, page := range layout.pages {
+		kids[i] = pdfRef(pageNums[i])
+
+		var xobjects strings.Builder
+		for _, idx := range page.images {
+			fmt.Fprintf(&xobjects, " /Im%d %s", idx, pdfRef(imageNums[idx]))
+		}
+		resources := fmt.Sprintf("/Font << /%s %s /%s %s >>",
+			pdfFontHorizontal, pdfRef(fontHNum), pdfFontVertical, pdfRef(fontVNum))
+		if xobjects.Len() > 0 {
+			resources += " /XObject <<" + xobjects.String() + " >>"
+		}
+
+		w.object(pageNums[i], fmt.Sprintf("<< /Type /Page /Parent %s /MediaBox [0 0 %s %s] /Resources << %s >> /Contents %s >>",
+			pdfRef(pagesNum), pdfNum(pdfPageWidth), pdfNum(pdfPageHeight), resources, pdfRef(contentNums[i])))
+		if err := w.stream(contentNums[i], "", page.content.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+	w.object(pagesNum, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+
+	// Images are stored as raw RGB, already compressed by the layout
+	for i, img := range layout.images {
+		w.rawStream(imageNums[i], fmt.Sprintf(" /Type /XObject /Subtype /Image /Width %d /Height %d "+
+			"/ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /FlateDecode", img.width, img.height), img.data)
+	}
+
+	// Bookmarks
+	catalog := fmt.Sprintf("/Type /Catalog /Pages %s", pdfRef(pagesNum))
+	if outlinesNum != 0 {
+		writeOutline(w, layout.outline, outlinesNum, outlineNums, pageNums)
+		w.object(outlinesNum, fmt.Sprintf("<< /Type /Outlines /First %s /Last %s /Count %d >>",
+			pdfRef(outlineNums[layout.outline[0]]), pdfRef(outlineNums[layout.outline[len(layout.outline)-1]]), len(layout.outline)))
+		catalog += fmt.Sprintf(" /Outlines %s /PageMode /UseOutlines", pdfRef(outlinesNum))
+	}
+	if d.vertical {
+		catalog += " /ViewerPreferences << /Direction /R2L >>"
+	}
+	w.object(catalogNum, "<< "+catalog+" >>")
+
+	info := fmt.Sprintf("/Title %s /Producer (jplaw2epub)", pdfTextString(d.title))
+	if d.author != "" {
+		info += " /Author " + pdfTextString(d.author)
+	}
+	w.object(infoNum, "<< "+info+" >>")
+
+	return w.finish(catalogNum, infoNum)
+}
+
+// allocateOutline reserves object numbers for outline entries, depth first
+func allocateOutline(w *pdfWriter, entries []*pdfOutlineEntry, nums map[*pdfOutlineEntry]int) {
+	for _, entry := range entries {
+		nums[entry] = w.allocate()
+		allocateOutline(w, entry.children, nums)
+	}
+}
+
+// writeOutline writes outline entries linked to their siblings and parent
+func writeOutline(w *pdfWriter, entries []*pdfOutlineEntry, parentNum int, nums map[*pdfOutlineEntry]int, pageNums []int) {
+	for i, entry := range entries {
+		dict := fmt.Sprintf("/Title %s /Parent %s /Dest [%s /XYZ 0 %s null]",
+			pdfTextString(entry.title), pdfRef(parentNum), pdfRef(pageNums[entry.page]), pdfNum(entry.top))
+		if i > 0 {
+			dict += " /Prev " + pdfRef(nums[entries[i-1]])
+		}
+		if i < len(entries)-1 {
+			dict += " /Next " + pdfRef(nums[entries[i+1]])
+		}
+		if len(entry.children) > 0 {
+			// Nested entries start collapsed
+			dict += fmt.Sprintf(" /First %s /Last %s /Count %d",
+				pdfRef(nums[entry.children[0]]), pdfRef(nums[entry.children[len(entry.children)-1]]), -len(entry.children))
+			writeOutline(w, entry.children, nums[entry], nums, pageNums)
+		}
+		w.object(nums[entry], "<< "+dict+" >>")
+	}
+}
+
+// CreatePDFFromXMLFile creates a PDF document from a jplaw XML file reader
+func CreatePDFFromXMLFile(xmlFile io.Reader) (*PDFDocument, error) {
+	return CreatePDFFromXMLFileWithOptions(xmlFile, nil)
+}
+
+// CreatePDFFromXMLFileWithOptions creates a PDF document with image and layout options
+func CreatePDFFromXMLFileWithOptions(xmlFile io.Reader, opts *EPUBOptions) (*PDFDocument, error) {
+	return CreatePDFFromXMLFileWithContext(context.Background(), xmlFile, opts)
+}
+
+// CreatePDFFromXMLFileWithContext creates a PDF document, stopping with ctx's error when it is done
+func CreatePDFFromXMLFileWithContext(ctx context.Context, xmlFile io.Reader, opts *EPUBOptions) (*PDFDocument, error) {
+	data, amendments, err := loadLawFromReader(xmlFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading XML data: %w", err)
+	}
+
+	doc, err := createPDFFromData(data, opts)
+	if err != nil {
+		return nil, fmt.Errorf("creating PDF: %w", err)
+	}
+
+	data, err = excerptLaw(data, opts)
+	if err != nil {
+		return nil, fmt.Errorf("selecting excerpt: %w", err)
+	}
+
+	if err := processChaptersWithContext(ctx, doc, data, amendments, opts); err != nil {
+		return nil, fmt.Errorf("processing chapters: %w", err)
+	}
+
+	return doc, nil
+}
+
+// CreatePDFFromXMLPath creates a PDF document from a jplaw XML file path
+func CreatePDFFromXMLPath(xmlPath string) (*PDFDocument, error) {
+	return CreatePDFFromXMLPathWithOptions(xmlPath, nil)
+}
+
+// CreatePDFFromXMLPathWithOptions creates a PDF document from a file path with options
+func CreatePDFFromXMLPathWithOptions(xmlPath string, opts *EPUBOptions) (*PDFDocument, error) {
+	xmlFile, err := os.Open(xmlPath)
+	if err != nil {
+		return nil, fmt.Errorf("opening XML file: %w", err)
+	}
+	defer xmlFile.Close()
+
+	return CreatePDFFromXMLFileWithOptions(xmlFile, opts)
+}
+
+// WritePDF writes the PDF document to the specified path
+func WritePDF(doc *PDFDocument, destPath string) error {
+	dir := filepath.Dir(destPath)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+
+	f, err := os.Create(destPath)
+	if err != nil {
+		return fmt.Errorf("creating PDF file: %w", err)
+	}
+
+	if _, err := doc.WriteTo(f); err != nil {
+		f.Close()
+		return fmt.Errorf("writing PDF file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writing PDF file: %w", err)
+	}
+
+	return nil
+}
+
+// createPDFFromData creates and sets up a PDF document from law data
+func createPDFFromData(data *jplaw.Law, opts *EPUBOptions) (*PDFDocument, error) {
+	if data.LawBody.LawTitle == nil {
+		return nil, fmt.Errorf("law title is required")
+	}
+
+	doc := NewPDFDocument(data.LawBody.LawTitle.Content)
+	doc.SetAuthor(data.LawNum)
+	if opts != nil {
+		doc.SetVerticalWriting(opts.VerticalWriting)
+	}
+
+	return doc, nil
+}
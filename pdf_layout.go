@@ -0,0 +1,632 @@
+package jplaw2epub
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// A4 page geometry and typography for PDF output (in points)
+const (
+	pdfPageWidth     = 595.28
+	pdfPageHeight    = 841.89
+	pdfMarginX       = 56.69
+	pdfMarginTop     = 70.87
+	pdfMarginBottom  = 70.87
+	pdfBodyFontSize  = 10.5
+	pdfHeaderSize    = 8.0
+	pdfLineSpacing   = 1.7
+	pdfBlockSpacing  = 0.4
+	pdfMaxImageShare = 0.5
+)
+
+// Font resource names used in page content streams
+const (
+	pdfFontHorizontal = "F1"
+	pdfFontVertical   = "F2"
+)
+
+// Separator placed between table cells when tables are flattened into text
+const pdfCellSeparator = "　｜　"
+
+// pdfBlockKind identifies the kind of content block extracted from a section body
+type pdfBlockKind int
+
+const (
+	pdfBlockText pdfBlockKind = iota
+	pdfBlockHeading
+	pdfBlockImage
+)
+
+// pdfBlock is a unit of content laid out on PDF pages
+type pdfBlock struct {
+	kind   pdfBlockKind
+	text   string
+	level  int
+	indent int
+	src    string
+}
+
+// pdfListState tracks list numbering while extracting blocks
+type pdfListState struct {
+	style   string
+	counter int
+}
+
+// pdfBlockExtractor converts XHTML section bodies into layout blocks
+type pdfBlockExtractor struct {
+	blocks      []pdfBlock
+	text        strings.Builder
+	cell        strings.Builder
+	cells       []string
+	marker      string
+	heading     int
+	headingDivs []bool
+	skip        int
+	inCell      bool
+	lists       []pdfListState
+	quotes      int
+}
+
+// extractPDFBlocks converts a section body into layout blocks.
+// The body is parsed leniently so that raw content which is not well-formed XML
+// still produces readable output.
+func extractPDFBlocks(body string) []pdfBlock {
+	decoder := xml.NewDecoder(strings.NewReader("<body>" + body + "</body>"))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	e := &pdfBlockExtractor{}
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			// io.EOF or a syntax error: keep what has been extracted so far
+			break
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			e.start(&t)
+		case xml.EndElement:
+			e.end(strings.ToLower(t.Name.Local))
+		case xml.CharData:
+			e.characters(string(t))
+		}
+	}
+	e.flush()
+
+	return e.blocks
+}
+
+// start handles an opening tag
+func (e *pdfBlockExtractor) start(t *xml.StartElement) {
+	name := strings.ToLower(t.Name.Local)
+	switch name {
+	case "rt", "rp":
+		e.skip++
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		e.flush()
+		e.heading = int(name[1] - '0')
+		if strings.Contains(xmlAttr(t, "class"), "chapter-title") {
+			e.heading = 1
+		}
+	case "div":
+		e.flush()
+		isHeading := strings.Contains(xmlAttr(t, "class"), "chapter-title")
+		e.headingDivs = append(e.headingDivs, isHeading)
+		if isHeading {
+			e.heading = 1
+		}
+	case "blockquote":
+		e.flush()
+		e.quotes++
+	case "p", "br", "table", "section":
+		e.flush()
+		if e.inCell {
+			e.cell.WriteString(" ")
+		}
+	case "ol", "ul":
+		e.flush()
+		style := listStyleDisc
+		if name == "ol" {
+			style = listStyleDecimal
+			if s := parseListStyleType(xmlAttr(t, "style")); s != "" {
+				style = s
+			}
+		}
+		e.lists = append(e.lists, pdfListState{style: style})
+	case "li":
+		e.flush()
+		if len(e.lists) > 0 {
+			list := &e.lists[len(e.lists)-1]
+			list.counter++
+			// Items inside table cells become part of the cell text
+			if e.inCell {
+				e.cell.WriteString(" " + listMarker(list.style, list.counter))
+			} else {
+				e.marker = listMarker(list.style, list.counter)
+			}
+		}
+	case "tr":
+		e.flush()
+		e.cells = nil
+	case "td", "th":
+		e.inCell = true
+		e.cell.Reset()
+	case "img":
+		e.flush()
+		e.blocks = append(e.blocks, pdfBlock{kind: pdfBlockImage, src: xmlAttr(t, "src")})
+	}
+}
+
+// end handles a closing tag
+func (e *pdfBlockExtractor) end(name string) {
+	switch name {
+	case "rt", "rp":
+		if e.skip > 0 {
+			e.skip--
+		}
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		e.flush()
+		e.heading = 0
+	case "div":
+		e.flush()
+		if n := len(e.headingDivs); n > 0 {
+			if e.headingDivs[n-1] {
+				e.heading = 0
+			}
+			e.headingDivs = e.headingDivs[:n-1]
+		}
+	case "p", "li", "caption":
+		e.flush()
+	case "blockquote":
+		e.flush()
+		if e.quotes > 0 {
+			e.quotes--
+		}
+	case "ol", "ul":
+		e.flush()
+		if len(e.lists) > 0 {
+			e.lists = e.lists[:len(e.lists)-1]
+		}
+	case "td", "th":
+		e.cells = append(e.cells, collapseSpaces(e.cell.String()))
+		e.inCell = false
+	case "tr":
+		if len(e.cells) > 0 {
+			e.blocks = append(e.blocks, pdfBlock{
+				kind:   pdfBlockText,
+				text:   strings.Join(e.cells, pdfCellSeparator),
+				indent: len(e.lists) + e.quotes,
+			})
+		}
+		e.cells = nil
+	}
+}
+
+// characters handles character data
+func (e *pdfBlockExtractor) characters(text string) {
+	if e.skip > 0 {
+		return
+	}
+	if e.inCell {
+		e.cell.WriteString(text)
+		return
+	}
+	e.text.WriteString(text)
+}
+
+// flush emits the pending text as a block
+func (e *pdfBlockExtractor) flush() {
+	text := collapseSpaces(e.text.String())
+	e.text.Reset()
+	if text == "" {
+		return
+	}
+
+	block := pdfBlock{
+		kind:   pdfBlockText,
+		text:   e.marker + text,
+		indent: len(e.lists) + e.quotes,
+	}
+	e.marker = ""
+	if e.heading > 0 {
+		block.kind = pdfBlockHeading
+		block.level = e.heading
+	}
+	e.blocks = append(e.blocks, block)
+}
+
+// xmlAttr returns the value of the named attribute
+func xmlAttr(t *xml.StartElement, name string) string {
+	for _, attr := range t.Attr {
+		if strings.EqualFold(attr.Name.Local, name) {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// parseListStyleType extracts the list-style-type value from an inline style
+func parseListStyleType(style string) string {
+	for _, decl := range strings.Split(style, ";") {
+		parts := strings.SplitN(decl, ":", 2)
+		if len(parts) == 2 && strings.TrimSpace(parts[0]) == "list-style-type" {
+			return strings.TrimSpace(parts[1])
+		}
+	}
+	return ""
+}
+
+// collapseSpaces collapses runs of ASCII whitespace and trims the result.
+// Ideographic spaces are significant in law text and are kept.
+func collapseSpaces(s string) string {
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	}), " ")
+}
+
+// listMarker returns the printed marker for the n-th item of a list with the given style
+func listMarker(style string, n int) string {
+	const katakanaIroha = "イロハニホヘトチリヌルヲワカヨタレソツネナラムウヰノオクヤマケフコエテアサキユメミシヱヒモセス"
+	const hiraganaIroha = "いろはにほへとちりぬるをわかよたれそつねならむうゐのおくやまけふこえてあさきゆめみしゑひもせす"
+
+	switch style {
+	case listStyleCJK:
+		return formatKanjiNumber(n) + "　"
+	case listStyleKatakana:
+		if r := []rune(katakanaIroha); n <= len(r) {
+			return string(r[n-1]) + "　"
+		}
+	case listStyleHiragana:
+		if r := []rune(hiraganaIroha); n <= len(r) {
+			return string(r[n-1]) + "　"
+		}
+	case listStyleDisc:
+		return "・"
+	}
+	return strconv.Itoa(n) + "　"
+}
+
+// pdfCharAdvance returns the advance of a character in em units
+func pdfCharAdvance(r rune, vertical bool) float64 {
+	if vertical {
+		return 1
+	}
+	if r < 0x80 || (r >= 0xFF61 && r <= 0xFF9F) {
+		return 0.5
+	}
+	return 1
+}
+
+// pdfTextWidth returns the horizontal width of text at the given font size
+func pdfTextWidth(text string, fontSize float64) float64 {
+	width := 0.0
+	for _, r := range text {
+		width += pdfCharAdvance(r, false) * fontSize
+	}
+	return width
+}
+
+// isLineStartProhibited reports whether a character must not begin a line (行頭禁則)
+func isLineStartProhibited(r rune) bool {
+	return strings.ContainsRune("、。，．・：；？！）」』】〕〉》］｝ー々ぁぃぅぇぉっゃゅょァィゥェォッャュョ", r)
+}
+
+// wrapPDFText breaks text into lines no longer than limit, hanging prohibited characters
+func wrapPDFText(text string, limit, fontSize float64, vertical bool) []string {
+	var lines []string
+	var line []rune
+	width := 0.0
+
+	for _, r := range text {
+		advance := pdfCharAdvance(r, vertical) * fontSize
+		if width+advance > limit && len(line) > 0 && !isLineStartProhibited(r) {
+			lines = append(lines, string(line))
+			line = line[:0]
+			width = 0
+		}
+		line = append(line, r)
+		width += advance
+	}
+	if len(line) > 0 {
+		lines = append(lines, string(line))
+	}
+
+	return lines
+}
+
+// pdfHeadingSize returns the font size for a heading level
+func pdfHeadingSize(level int) float64 {
+	switch level {
+	case 1:
+		return 16
+	case 2:
+		return 14
+	case 3:
+		return 12
+	default:
+		return 11
+	}
+}
+
+// pdfPage holds the content of a single laid-out page
+type pdfPage struct {
+	content bytes.Buffer
+	images  []int
+	label   string
+}
+
+// pdfOutlineEntry is a bookmark pointing into the laid-out pages
+type pdfOutlineEntry struct {
+	title    string
+	page     int
+	top      float64
+	children []*pdfOutlineEntry
+}
+
+// pdfImage is an image XObject ready to be written
+type pdfImage struct {
+	width  int
+	height int
+	data   []byte
+}
+
+// pdfLayout lays out the sections of a PDFDocument on A4 pages
+type pdfLayout struct {
+	doc        *PDFDocument
+	vertical   bool
+	pages      []*pdfPage
+	images     []*pdfImage
+	imageIndex map[string]int
+	outline    []*pdfOutlineEntry
+	x, y       float64
+	empty      bool
+}
+
+// newPDFLayout creates a layout for the document
+func newPDFLayout(doc *PDFDocument) *pdfLayout {
+	return &pdfLayout{
+		doc:        doc,
+		vertical:   doc.vertical,
+		imageIndex: make(map[string]int),
+	}
+}
+
+// run lays out all sections and draws the running headers and footers
+func (l *pdfLayout) run() {
+	l.newPage()
+	for _, section := range l.doc.sections {
+		l.outline = append(l.outline, l.layoutSection(section, 0))
+	}
+	l.drawHeadersAndFooters()
+}
+
+// contentWidth returns the width of the text area
+func (l *pdfLayout) contentWidth() float64 {
+	return pdfPageWidth - 2*pdfMarginX
+}
+
+// contentHeight returns the height of the text area
+func (l *pdfLayout) contentHeight() float64 {
+	return pdfPageHeight - pdfMarginTop - pdfMarginBottom
+}
+
+// current returns the page being laid out
+func (l *pdfLayout) current() *pdfPage {
+	return l.pages[len(l.pages)-1]
+}
+
+// newPage starts a new page and resets the cursor
+func (l *pdfLayout) newPage() {
+	l.pages = append(l.pages, &pdfPage{})
+	l.x = pdfPageWidth - pdfMarginX
+	l.y = pdfPageHeight - pdfMarginTop
+	l.empty = true
+}
+
+// layoutSection lays out a section and its subsections, returning its bookmark
+func (l *pdfLayout) layoutSection(section *pdfSection, depth int) *pdfOutlineEntry {
+	// Top-level sections start on a fresh page
+	if depth == 0 && !l.empty {
+		l.newPage()
+	}
+
+	page := l.current()
+	if page.label == "" {
+		page.label = section.title
+	}
+
+	entry := &pdfOutlineEntry{
+		title: section.title,
+		page:  len(l.pages) - 1,
+		top:   pdfPageHeight,
+	}
+	if !l.vertical {
+		entry.top = l.y
+	}
+
+	for _, block := range extractPDFBlocks(section.body) {
+		l.layoutBlock(&block)
+	}
+
+	for _, child := range section.children {
+		entry.children = append(entry.children, l.layoutSection(child, depth+1))
+	}
+
+	return entry
+}
+
+// layoutBlock lays out a single block
+func (l *pdfLayout) layoutBlock(block *pdfBlock) {
+	if block.kind == pdfBlockImage {
+		l.layoutImage(block.src)
+		return
+	}
+
+	fontSize := pdfBodyFontSize
+	bold := false
+	if block.kind == pdfBlockHeading {
+		fontSize = pdfHeadingSize(block.level)
+		bold = true
+	}
+	lineHeight := fontSize * pdfLineSpacing
+	indent := float64(block.indent) * fontSize
+
+	if l.vertical {
+		lines := wrapPDFText(block.text, l.contentHeight()-indent, fontSize, true)
+		for _, line := range lines {
+			if l.x-lineHeight < pdfMarginX {
+				l.newPage()
+			}
+			top := pdfPageHeight - pdfMarginTop - indent
+			l.drawText(pdfFontVertical, fontSize, l.x-lineHeight/2, top, line, bold)
+			l.x -= lineHeight
+		}
+		l.x -= fontSize * pdfBlockSpacing
+		return
+	}
+
+	lines := wrapPDFText(block.text, l.contentWidth()-indent, fontSize, false)
+	for _, line := range lines {
+		if l.y-lineHeight < pdfMarginBottom {
+			l.newPage()
+		}
+		l.drawText(pdfFontHorizontal, fontSize, pdfMarginX+indent, l.y-fontSize, line, bold)
+		l.y -= lineHeight
+	}
+	l.y -= fontSize * pdfBlockSpacing
+}
+
+// layoutImage places an image previously registered with AddImage
+func (l *pdfLayout) layoutImage(src string) {
+	idx, ok := l.loadImage(src)
+	if !ok {
+		return
+	}
+	img := l.images[idx]
+
+	maxWidth, maxHeight := l.contentWidth(), l.contentHeight()*pdfMaxImageShare
+	if l.vertical {
+		maxWidth, maxHeight = l.contentWidth()*pdfMaxImageShare, l.contentHeight()
+	}
+	scale := math.Min(maxWidth/float64(img.width), maxHeight/float64(img.height))
+	scale = math.Min(scale, 1)
+	width, height := float64(img.width)*scale, float64(img.height)*scale
+
+	var x, y float64
+	if l.vertical {
+		if l.x-width < pdfMarginX {
+			l.newPage()
+		}
+		x = l.x - width
+		y = pdfPageHeight - pdfMarginTop - height
+		l.x -= width + pdfBodyFontSize
+	} else {
+		if l.y-height < pdfMarginBottom {
+			l.newPage()
+		}
+		x = pdfMarginX + (l.contentWidth()-width)/2
+		y = l.y - height
+		l.y -= height + pdfBodyFontSize
+	}
+
+	page := l.current()
+	page.images = append(page.images, idx)
+	fmt.Fprintf(&page.content, "q %s 0 0 %s %s %s cm /Im%d Do Q\n",
+		pdfNum(width), pdfNum(height), pdfNum(x), pdfNum(y), idx)
+	l.empty = false
+}
+
+// loadImage decodes an image added to the document and converts it to an XObject
+func (l *pdfLayout) loadImage(src string) (int, bool) {
+	if idx, ok := l.imageIndex[src]; ok {
+		return idx, true
+	}
+
+	data, ok := l.doc.images[src]
+	if !ok {
+		return 0, false
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return 0, false
+	}
+
+	bounds := img.Bounds()
+	var rgb bytes.Buffer
+	zw := zlib.NewWriter(&rgb)
+	row := make([]byte, 0, bounds.Dx()*3)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		row = row[:0]
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			// Composite onto white so transparent areas print as paper
+			row = append(row, blendWhite(r, a), blendWhite(g, a), blendWhite(b, a))
+		}
+		if _, err := zw.Write(row); err != nil {
+			return 0, false
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return 0, false
+	}
+
+	l.images = append(l.images, &pdfImage{width: bounds.Dx(), height: bounds.Dy(), data: rgb.Bytes()})
+	idx := len(l.images) - 1
+	l.imageIndex[src] = idx
+	return idx, true
+}
+
+// blendWhite composites a premultiplied 16-bit channel onto a white background
+func blendWhite(c, a uint32) byte {
+	return byte((c + (0xffff - a)) >> 8)
+}
+
+// drawText writes a single line of text to the current page
+func (l *pdfLayout) drawText(font string, fontSize, x, y float64, text string, bold bool) {
+	page := l.current()
+	page.content.WriteString("BT ")
+	if bold {
+		// Simulate bold by stroking the glyph outlines
+		fmt.Fprintf(&page.content, "2 Tr %s w ", pdfNum(fontSize/30))
+	}
+	fmt.Fprintf(&page.content, "/%s %s Tf 1 0 0 1 %s %s Tm %s Tj",
+		font, pdfNum(fontSize), pdfNum(x), pdfNum(y), pdfHexString(text))
+	if bold {
+		page.content.WriteString(" 0 Tr")
+	}
+	page.content.WriteString(" ET\n")
+	l.empty = false
+}
+
+// drawHeadersAndFooters adds the law title, current article label and page number to every page
+func (l *pdfLayout) drawHeadersAndFooters() {
+	label := ""
+	headerY := pdfPageHeight - pdfMarginTop/2
+	for i, page := range l.pages {
+		if page.label != "" {
+			label = page.label
+		}
+
+		fmt.Fprintf(&page.content, "BT /%s %s Tf 1 0 0 1 %s %s Tm %s Tj ET\n",
+			pdfFontHorizontal, pdfNum(pdfHeaderSize), pdfNum(pdfMarginX), pdfNum(headerY), pdfHexString(l.doc.title))
+		if label != "" {
+			x := pdfPageWidth - pdfMarginX - pdfTextWidth(label, pdfHeaderSize)
+			fmt.Fprintf(&page.content, "BT /%s %s Tf 1 0 0 1 %s %s Tm %s Tj ET\n",
+				pdfFontHorizontal, pdfNum(pdfHeaderSize), pdfNum(x), pdfNum(headerY), pdfHexString(label))
+		}
+		fmt.Fprintf(&page.content, "0.5 w %s %s m %s %s l S\n",
+			pdfNum(pdfMarginX), pdfNum(headerY-4), pdfNum(pdfPageWidth-pdfMarginX), pdfNum(headerY-4))
+
+		pageNum := fmt.Sprintf("- %d -", i+1)
+		x := (pdfPageWidth - pdfTextWidth(pageNum, pdfHeaderSize)) / 2
+		fmt.Fprintf(&page.content, "BT /%s %s Tf 1 0 0 1 %s %s Tm %s Tj ET\n",
+			pdfFontHorizontal, pdfNum(pdfHeaderSize), pdfNum(x), pdfNum(pdfMarginBottom/2), pdfHexString(pageNum))
+	}
+}
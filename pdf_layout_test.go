@@ -0,0 +1,119 @@
+package jplaw2epub
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractPDFBlocks(t *testing.T) {
+	body := `<section epub:type="chapter"><h2 class="chapter-title">第一章　総則</h2></section>` +
+		`<section class="article"><h3>第一条 <ruby>目的<rt>もくてき</rt></ruby></h3>` +
+		`<ol style="list-style-type: cjk-ideographic;"><li>最初の号</li><li>次の号</li></ol>` +
+		`<div class="table-container"><table><tbody><tr><td>区分</td><td>金額</td></tr>` +
+		`<tr><td><p class="article-title">第二条</p><ol><li>旧の号</li></ol></td><td>新</td></tr></tbody></table></div>` +
+		`<p>表の後の文</p><blockquote class="new-provision"><p>新しい条</p></blockquote><img src="../images/fig.png" alt="Figure" /></section>`
+
+	blocks := extractPDFBlocks(body)
+
+	want := []pdfBlock{
+		{kind: pdfBlockHeading, text: "第一章　総則", level: 1},
+		{kind: pdfBlockHeading, text: "第一条 目的", level: 3},
+		{kind: pdfBlockText, text: "一　最初の号", indent: 1},
+		{kind: pdfBlockText, text: "二　次の号", indent: 1},
+		{kind: pdfBlockText, text: "区分" + pdfCellSeparator + "金額"},
+		{kind: pdfBlockText, text: "第二条 1　旧の号" + pdfCellSeparator + "新"},
+		{kind: pdfBlockText, text: "表の後の文"},
+		{kind: pdfBlockText, text: "新しい条", indent: 1},
+		{kind: pdfBlockImage, src: "../images/fig.png"},
+	}
+
+	if len(blocks) != len(want) {
+		t.Fatalf("extractPDFBlocks() returned %d blocks, want %d: %+v", len(blocks), len(want), blocks)
+	}
+	for i := range want {
+		if blocks[i] != want[i] {
+			t.Errorf("block %d = %+v, want %+v", i, blocks[i], want[i])
+		}
+	}
+}
+
+func TestExtractPDFBlocksMalformed(t *testing.T) {
+	// Raw format content may not be well-formed; extraction must not lose the text
+	blocks := extractPDFBlocks(`<pre class="format-raw">記入例 <Fig src="a.pdf"></pre><p>続き`)
+	var texts []string
+	for _, b := range blocks {
+		texts = append(texts, b.text)
+	}
+	joined := strings.Join(texts, "|")
+	if !strings.Contains(joined, "記入例") {
+		t.Errorf("extractPDFBlocks() lost text from malformed body: %q", joined)
+	}
+}
+
+func TestListMarker(t *testing.T) {
+	tests := []struct {
+		style string
+		n     int
+		want  string
+	}{
+		{listStyleCJK, 3, "三　"},
+		{listStyleKatakana, 2, "ロ　"},
+		{listStyleHiragana, 1, "い　"},
+		{listStyleDecimal, 12, "12　"},
+		{listStyleDisc, 5, "・"},
+	}
+
+	for _, tt := range tests {
+		if got := listMarker(tt.style, tt.n); got != tt.want {
+			t.Errorf("listMarker(%q, %d) = %q, want %q", tt.style, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestWrapPDFText(t *testing.T) {
+	// Five full-width characters per line at 10pt in a 50pt column
+	lines := wrapPDFText("あいうえおかきくけこさ", 50, 10, false)
+	if len(lines) != 3 || lines[0] != "あいうえお" || lines[2] != "さ" {
+		t.Errorf("wrapPDFText() = %q", lines)
+	}
+
+	// Half-width characters take half the space
+	lines = wrapPDFText("abcdefghij", 50, 10, false)
+	if len(lines) != 1 {
+		t.Errorf("wrapPDFText() half-width = %q, want a single line", lines)
+	}
+
+	// Punctuation must not start a line; it hangs at the end of the previous one
+	lines = wrapPDFText("あいうえお。かき", 50, 10, false)
+	if lines[0] != "あいうえお。" {
+		t.Errorf("wrapPDFText() kinsoku = %q", lines)
+	}
+}
+
+func TestPDFLayoutPagination(t *testing.T) {
+	doc := NewPDFDocument("テスト法")
+	body := strings.Repeat("<p>"+strings.Repeat("条文の本文です。", 20)+"</p>", 60)
+	if _, err := doc.AddSection(body, "第一章", "chapter-0.xhtml", ""); err != nil {
+		t.Fatalf("AddSection() error = %v", err)
+	}
+	if _, err := doc.AddSection("<p>短い</p>", "第二章", "chapter-1.xhtml", ""); err != nil {
+		t.Fatalf("AddSection() error = %v", err)
+	}
+
+	layout := newPDFLayout(doc)
+	layout.run()
+
+	if len(layout.pages) < 3 {
+		t.Fatalf("expected long content to span several pages, got %d", len(layout.pages))
+	}
+	if len(layout.outline) != 2 {
+		t.Fatalf("expected 2 outline entries, got %d", len(layout.outline))
+	}
+	// Top-level sections start on a new page
+	if layout.outline[1].page != len(layout.pages)-1 || layout.outline[0].page == layout.outline[1].page {
+		t.Errorf("second chapter starts on page %d of %d", layout.outline[1].page, len(layout.pages))
+	}
+	if layout.pages[len(layout.pages)-1].label != "第二章" {
+		t.Errorf("header label = %q, want 第二章", layout.pages[len(layout.pages)-1].label)
+	}
+}
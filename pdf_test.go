@@ -0,0 +1,168 @@
+package jplaw2epub
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testPDFXML = `<?xml version="1.0" encoding="UTF-8"?>
+<Law Era="Reiwa" Year="1" Num="1" LawType="Act" Lang="ja">
+  <LawNum>令和元年法律第一号</LawNum>
+  <LawBody>
+    <LawTitle>テスト法</LawTitle>
+    <MainProvision>
+      <Chapter Num="1">
+        <ChapterTitle>第一章　総則</ChapterTitle>
+        <Article Num="1">
+          <ArticleTitle>第一条</ArticleTitle>
+          <Paragraph Num="1">
+            <ParagraphSentence>
+              <Sentence>この法律は、テストを目的とする。</Sentence>
+            </ParagraphSentence>
+          </Paragraph>
+        </Article>
+        <Article Num="2">
+          <ArticleTitle>第二条</ArticleTitle>
+          <Paragraph Num="1">
+            <ParagraphSentence>
+              <Sentence>この法律において用語の意義は、次のとおりとする。</Sentence>
+            </ParagraphSentence>
+          </Paragraph>
+        </Article>
+      </Chapter>
+    </MainProvision>
+  </LawBody>
+</Law>`
+
+func TestCreatePDFFromXMLFile(t *testing.T) {
+	doc, err := CreatePDFFromXMLFile(strings.NewReader(testPDFXML))
+	if err != nil {
+		t.Fatalf("CreatePDFFromXMLFile() error = %v", err)
+	}
+	if doc.Title() != "テスト法" {
+		t.Errorf("Title() = %q, want テスト法", doc.Title())
+	}
+
+	var buf bytes.Buffer
+	if _, err := doc.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"%PDF-1.7",
+		"/MediaBox [0 0 595.28 841.89]",
+		"/Encoding /UniJIS-UTF16-H",
+		"/Type /Outlines",
+		"/PageMode /UseOutlines",
+		pdfTextString("第一章　総則"),
+		pdfTextString("第一条"),
+		"%%EOF",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("PDF output missing %q", want)
+		}
+	}
+
+	// Article bookmarks are nested under the chapter bookmark
+	if !strings.Contains(out, "/Count -2") {
+		t.Error("expected chapter bookmark with two collapsed children")
+	}
+}
+
+func TestCreatePDFVerticalWriting(t *testing.T) {
+	doc, err := CreatePDFFromXMLFileWithOptions(strings.NewReader(testPDFXML), &EPUBOptions{VerticalWriting: true})
+	if err != nil {
+		t.Fatalf("CreatePDFFromXMLFileWithOptions() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := doc.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "/Direction /R2L") {
+		t.Error("vertical PDF should set right-to-left reading direction")
+	}
+}
+
+func TestCreatePDFRequiresTitle(t *testing.T) {
+	xml := `<Law Era="Reiwa" Year="1"><LawNum>x</LawNum><LawBody><MainProvision/></LawBody></Law>`
+	if _, err := CreatePDFFromXMLFile(strings.NewReader(xml)); err == nil {
+		t.Error("expected error for law without title")
+	}
+}
+
+func TestPDFDocumentSections(t *testing.T) {
+	doc := NewPDFDocument("テスト")
+
+	parent, err := doc.AddSection("<p>章</p>", "第一章", "chapter-0.xhtml", "")
+	if err != nil {
+		t.Fatalf("AddSection() error = %v", err)
+	}
+	if _, err := doc.AddSubSection(parent, "<p>条</p>", "第一条", "article-0-0.xhtml", ""); err != nil {
+		t.Fatalf("AddSubSection() error = %v", err)
+	}
+	if _, err := doc.AddSection("<p>重複</p>", "重複", "chapter-0.xhtml", ""); err == nil {
+		t.Error("expected error for duplicate filename")
+	}
+	if _, err := doc.AddSubSection("missing.xhtml", "<p></p>", "x", "x.xhtml", ""); err == nil {
+		t.Error("expected error for missing parent")
+	}
+	if len(doc.sections) != 1 || len(doc.sections[0].children) != 1 {
+		t.Errorf("unexpected section tree: %+v", doc.sections)
+	}
+}
+
+func TestPDFDocumentImages(t *testing.T) {
+	doc := NewPDFDocument("図")
+
+	pngData, err := createTestPNGData(4, 2, color.RGBA{0, 0, 255, 255})
+	if err != nil {
+		t.Fatalf("creating PNG: %v", err)
+	}
+	dataURL := "data:image/png;base64," + base64.StdEncoding.EncodeToString(pngData)
+
+	src, err := doc.AddImage(dataURL, "fig.png")
+	if err != nil {
+		t.Fatalf("AddImage() error = %v", err)
+	}
+	if src != "../images/fig.png" {
+		t.Errorf("AddImage() path = %q", src)
+	}
+	if _, err := doc.AddSection(`<img src="`+src+`" alt="Figure" />`, "附図", "fig.xhtml", ""); err != nil {
+		t.Fatalf("AddSection() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := doc.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "/Subtype /Image /Width 4 /Height 2") {
+		t.Error("expected embedded image XObject")
+	}
+}
+
+func TestWritePDF(t *testing.T) {
+	doc, err := CreatePDFFromXMLFile(strings.NewReader(testPDFXML))
+	if err != nil {
+		t.Fatalf("CreatePDFFromXMLFile() error = %v", err)
+	}
+
+	destPath := filepath.Join(t.TempDir(), "out", "law.pdf")
+	if err := WritePDF(doc, destPath); err != nil {
+		t.Fatalf("WritePDF() error = %v", err)
+	}
+
+	data, err := os.ReadFile(destPath)
+	if err != nil {
+		t.Fatalf("reading PDF: %v", err)
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		t.Error("written file is not a PDF")
+	}
+}
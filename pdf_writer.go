@@ -0,0 +1,108 @@
+package jplaw2epub
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// pdfWriter serializes numbered PDF objects and builds the cross-reference table
+type pdfWriter struct {
+	buf     bytes.Buffer
+	offsets map[int]int
+	next    int
+}
+
+// newPDFWriter creates a writer with the PDF header already written
+func newPDFWriter() *pdfWriter {
+	w := &pdfWriter{
+		offsets: make(map[int]int),
+		next:    1,
+	}
+	w.buf.WriteString("%PDF-1.7\n%\xE2\xE3\xCF\xD3\n")
+	return w
+}
+
+// allocate reserves an object number
+func (w *pdfWriter) allocate() int {
+	num := w.next
+	w.next++
+	return num
+}
+
+// object writes a non-stream object
+func (w *pdfWriter) object(num int, body string) {
+	w.offsets[num] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n%s\nendobj\n", num, body)
+}
+
+// stream writes a Flate-compressed stream object
+func (w *pdfWriter) stream(num int, dict string, data []byte) error {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	if _, err := zw.Write(data); err != nil {
+		return fmt.Errorf("compressing stream: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("compressing stream: %w", err)
+	}
+
+	w.rawStream(num, dict+" /Filter /FlateDecode", compressed.Bytes())
+	return nil
+}
+
+// rawStream writes a stream object whose data is already encoded as described by dict
+func (w *pdfWriter) rawStream(num int, dict string, data []byte) {
+	w.offsets[num] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n<<%s /Length %d>>\nstream\n", num, dict, len(data))
+	w.buf.Write(data)
+	w.buf.WriteString("\nendstream\nendobj\n")
+}
+
+// finish writes the cross-reference table and trailer and returns the document bytes
+func (w *pdfWriter) finish(root, info int) ([]byte, error) {
+	for i := 1; i < w.next; i++ {
+		if _, ok := w.offsets[i]; !ok {
+			return nil, fmt.Errorf("PDF object %d was allocated but never written", i)
+		}
+	}
+
+	xref := w.buf.Len()
+	fmt.Fprintf(&w.buf, "xref\n0 %d\n0000000000 65535 f \n", w.next)
+	for i := 1; i < w.next; i++ {
+		fmt.Fprintf(&w.buf, "%010d 00000 n \n", w.offsets[i])
+	}
+	fmt.Fprintf(&w.buf, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		w.next, root, info, xref)
+
+	return w.buf.Bytes(), nil
+}
+
+// pdfRef formats an indirect object reference
+func pdfRef(num int) string {
+	return strconv.Itoa(num) + " 0 R"
+}
+
+// pdfNum formats a number for use in PDF content
+func pdfNum(f float64) string {
+	return strconv.FormatFloat(f, 'f', 2, 64)
+}
+
+// pdfHexString encodes text as UTF-16BE for fonts using a UniJIS-UTF16 CMap
+func pdfHexString(s string) string {
+	var b strings.Builder
+	b.WriteByte('<')
+	for _, u := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", u)
+	}
+	b.WriteByte('>')
+	return b.String()
+}
+
+// pdfTextString encodes a PDF text string (outline titles, document info) as UTF-16BE with BOM
+func pdfTextString(s string) string {
+	return "<FEFF" + strings.TrimPrefix(pdfHexString(s), "<")
+}
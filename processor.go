@@ -3,18 +3,17 @@ package jplaw2epub
 import (
 	"fmt"
 
-	"github.com/go-shiori/go-epub"
 	"go.ngs.io/jplaw-xml"
 )
 
 // processArticles processes a slice of articles and adds them to the EPUB
-func processArticles(book *epub.Epub, articles []jplaw.Article, parentFilename string, chapterIdx, sectionIdx int) error {
+func processArticles(book BookWriter, articles []jplaw.Article, parentFilename string, chapterIdx, sectionIdx int) error {
 	return processArticlesWithImages(book, articles, parentFilename, chapterIdx, sectionIdx, nil)
 }
 
 // processArticlesWithImages processes articles with image support
 func processArticlesWithImages(
-	book *epub.Epub,
+	book BookWriter,
 	articles []jplaw.Article,
 	parentFilename string,
 	chapterIdx, sectionIdx int,
@@ -29,13 +28,13 @@ func processArticlesWithImages(
 }
 
 // processArticle processes a single article
-func processArticle(book *epub.Epub, article *jplaw.Article, parentFilename string, chapterIdx, sectionIdx, articleIdx int) error {
+func processArticle(book BookWriter, article *jplaw.Article, parentFilename string, chapterIdx, sectionIdx, articleIdx int) error {
 	return processArticleWithImages(book, article, parentFilename, chapterIdx, sectionIdx, articleIdx, nil)
 }
 
 // processArticleWithImages processes a single article with image support
 func processArticleWithImages(
-	book *epub.Epub,
+	book BookWriter,
 	article *jplaw.Article,
 	parentFilename string,
 	chapterIdx, sectionIdx, articleIdx int,
@@ -43,7 +42,11 @@ func processArticleWithImages(
 ) error {
 	subFilename := buildArticleFilename(chapterIdx, sectionIdx, articleIdx)
 	articleTitle := buildArticleTitle(article)
-	body := buildArticleBodyWithImages(article, articleTitle, imgProc)
+	level := headingLevelChapter
+	if sectionIdx >= 0 {
+		level = headingLevelSection
+	}
+	body := buildArticleBodyWithImages(article, articleTitle, level, imgProc)
 
 	articleTitlePlain := getArticleTitlePlain(article)
 	_, err := book.AddSubSection(parentFilename, body, articleTitlePlain, subFilename, "")
@@ -61,15 +64,18 @@ func buildArticleFilename(chapterIdx, sectionIdx, articleIdx int) string {
 	return fmt.Sprintf("article-%d-%d.xhtml", chapterIdx, articleIdx)
 }
 
-// buildArticleBody builds the HTML body for an article
-func buildArticleBody(article *jplaw.Article, articleTitle string) string {
-	return buildArticleBodyWithImages(article, articleTitle, nil)
+// buildArticleBody builds the HTML body for an article with a heading of the given level
+func buildArticleBody(article *jplaw.Article, articleTitle string, level int) string {
+	return buildArticleBodyWithImages(article, articleTitle, level, nil)
 }
 
-// buildArticleBodyWithImages builds the HTML body for an article with image support
-func buildArticleBodyWithImages(article *jplaw.Article, articleTitle string, imgProc ImageProcessorInterface) string {
-	body := fmt.Sprintf("<h3>%s</h3>", articleTitle)
+// buildArticleBodyWithImages builds the HTML body for an article with image support. The article
+// is wrapped in a section whose id is derived from the article number.
+func buildArticleBodyWithImages(article *jplaw.Article, articleTitle string, level int, imgProc ImageProcessorInterface) string {
+	body := openSection("", "article", articleAnchor(article))
+	body += headingHTML(level, "", articleTitle)
 	body += processParagraphsWithImages(article.Paragraph, imgProc)
+	body += htmlSectionEnd
 	return body
 }
 
@@ -61,10 +61,10 @@ func TestBuildArticleBody(t *testing.T) {
 	}
 
 	articleTitle := "第一条"
-	got := buildArticleBody(article, articleTitle)
+	got := buildArticleBody(article, articleTitle, headingLevelChapter)
 
 	expectedParts := []string{
-		"<h3>第一条</h3>",
+		`<section class="article" id="art-1"><h3>第一条</h3>`,
 		"条文の内容。",
 	}
 
@@ -0,0 +1,227 @@
+package jplaw2epub
+
+import (
+	"fmt"
+	"strings"
+
+	jplaw "go.ngs.io/jplaw-xml"
+)
+
+// ProgressEventType identifies a stage of a conversion
+type ProgressEventType string
+
+// Progress event types, in the order they are reported
+const (
+	// ProgressStarted is reported once with the expected totals
+	ProgressStarted ProgressEventType = "started"
+	// ProgressChapterStarted is reported when a chapter (章) of the main provision is added
+	ProgressChapterStarted ProgressEventType = "chapter_started"
+	// ProgressArticleDone is reported when an article (条) of the main provision has been added
+	ProgressArticleDone ProgressEventType = "article_done"
+	// ProgressImageDownloaded is reported when an image attachment has been downloaded
+	ProgressImageDownloaded ProgressEventType = "image_downloaded"
+	// ProgressImageConverted is reported when an image has been converted and added to the book
+	ProgressImageConverted ProgressEventType = "image_converted"
+	// ProgressPackagingStarted is reported when the EPUB archive starts being written
+	ProgressPackagingStarted ProgressEventType = "packaging_started"
+	// ProgressDone is reported when the EPUB archive has been written
+	ProgressDone ProgressEventType = "done"
+)
+
+// ProgressEvent describes a step of a conversion.
+// The counters hold the running totals at the time of the event; packaging events carry none.
+type ProgressEvent struct {
+	Type ProgressEventType `json:"type"`
+	// Title is the chapter or article title, or the source of an image
+	Title string `json:"title,omitempty"`
+	// Filename is the section file the event relates to
+	Filename string `json:"filename,omitempty"`
+	// Articles is the number of main provision articles added so far, out of ArticlesTotal
+	Articles      int `json:"articles,omitempty"`
+	ArticlesTotal int `json:"articlesTotal,omitempty"`
+	// Images is the number of images added so far, out of the expected ImagesTotal
+	Images      int `json:"images,omitempty"`
+	ImagesTotal int `json:"imagesTotal,omitempty"`
+}
+
+// Fraction returns the completed share of articles and images, between 0 and 1
+func (e *ProgressEvent) Fraction() float64 {
+	if e.Type == ProgressPackagingStarted || e.Type == ProgressDone {
+		return 1
+	}
+	total := e.ArticlesTotal + e.ImagesTotal
+	if total == 0 {
+		return 0
+	}
+	done := min(e.Articles, e.ArticlesTotal) + min(e.Images, e.ImagesTotal)
+	return float64(done) / float64(total)
+}
+
+// ProgressFunc receives progress events. It is called synchronously from the converting goroutine.
+type ProgressFunc func(event ProgressEvent)
+
+// progressReporter keeps the running counters and forwards events to a ProgressFunc.
+// Its methods do nothing on a nil reporter.
+type progressReporter struct {
+	fn       ProgressFunc
+	state    ProgressEvent
+	articles map[string]string
+}
+
+// newProgressReporter creates a reporter for data, or returns nil when fn is nil. The main
+// provision articles are listed from data; law is the JSON model of data, only needed to count
+// the images of conversions downloading them, and built here when it is nil.
+func newProgressReporter(fn ProgressFunc, data *jplaw.Law, law *JSONLaw, withImages bool) *progressReporter {
+	if fn == nil {
+		return nil
+	}
+
+	p := &progressReporter{fn: fn, articles: make(map[string]string)}
+	addArticle := func(article *jplaw.Article, filename string) {
+		title := ""
+		if article.ArticleTitle != nil {
+			title = jsonTitle(article.ArticleTitle.Content, article.ArticleTitle.Ruby).Text
+		}
+		p.articles[filename] = title
+	}
+	mainProv := &data.LawBody.MainProvision
+	for c := range mainProv.Chapter {
+		chapter := &mainProv.Chapter[c]
+		for j := range chapter.Article {
+			addArticle(&chapter.Article[j], buildArticleFilename(c, -1, j))
+		}
+		for s := range chapter.Section {
+			for j := range chapter.Section[s].Article {
+				addArticle(&chapter.Section[s].Article[j], buildArticleFilename(c, s, j))
+			}
+		}
+	}
+	if len(mainProv.Chapter) == 0 {
+		for i := range mainProv.Article {
+			addArticle(&mainProv.Article[i], fmt.Sprintf("article-%d.xhtml", i))
+		}
+	}
+
+	p.state.ArticlesTotal = len(p.articles)
+	if withImages {
+		if law == nil {
+			law = NewJSONLaw(data)
+		}
+		p.state.ImagesTotal = countImages(law)
+	}
+	return p
+}
+
+// countImages returns the number of distinct image sources referenced by law
+func countImages(law *JSONLaw) int {
+	srcs := make(map[string]bool)
+	addFigures := func(figures []JSONFigure) {
+		for _, fig := range figures {
+			if fig.Src != "" {
+				srcs[fig.Src] = true
+			}
+		}
+	}
+	var addItems func(items []JSONItem)
+	addItems = func(items []JSONItem) {
+		for i := range items {
+			addFigures(items[i].Figures)
+			addItems(items[i].Subitems)
+		}
+	}
+	addParagraphs := func(paragraphs []JSONParagraph) {
+		for i := range paragraphs {
+			addFigures(paragraphs[i].Figures)
+			addItems(paragraphs[i].Items)
+		}
+	}
+	addArticles := func(articles []JSONArticle) {
+		for i := range articles {
+			addParagraphs(articles[i].Paragraphs)
+		}
+	}
+	addProvision := func(provision *JSONProvision) {
+		for _, chapter := range provision.Chapters {
+			addArticles(chapter.Articles)
+			for _, section := range chapter.Sections {
+				addArticles(section.Articles)
+			}
+		}
+		addArticles(provision.Articles)
+		addParagraphs(provision.Paragraphs)
+	}
+
+	addProvision(&law.MainProvision)
+	for i := range law.SupplProvisions {
+		addProvision(&law.SupplProvisions[i].JSONProvision)
+	}
+	for i := range law.Appendixes {
+		addFigures(law.Appendixes[i].Figures)
+	}
+	return len(srcs)
+}
+
+// emit reports an event of the given type with the current counters
+func (p *progressReporter) emit(eventType ProgressEventType, title, filename string) {
+	if p == nil {
+		return
+	}
+	event := p.state
+	event.Type = eventType
+	event.Title = title
+	event.Filename = filename
+	p.fn(event)
+}
+
+// section reports chapters and articles as their sections are added
+func (p *progressReporter) section(title, filename string) {
+	if p == nil {
+		return
+	}
+	if strings.HasPrefix(filename, "chapter-") {
+		p.emit(ProgressChapterStarted, title, filename)
+		return
+	}
+	if article, ok := p.articles[filename]; ok {
+		p.state.Articles++
+		p.emit(ProgressArticleDone, article, filename)
+	}
+}
+
+// imageDownloaded reports a downloaded attachment
+func (p *progressReporter) imageDownloaded(src string) {
+	p.emit(ProgressImageDownloaded, src, "")
+}
+
+// imageConverted reports an image added to the book
+func (p *progressReporter) imageConverted(src string) {
+	if p == nil {
+		return
+	}
+	p.state.Images++
+	p.emit(ProgressImageConverted, src, "")
+}
+
+// progressBookWriter is a BookWriter that reports chapters and articles to a progressReporter
+type progressBookWriter struct {
+	BookWriter
+	progress *progressReporter
+}
+
+// AddSection adds the section and reports it
+func (w *progressBookWriter) AddSection(body, sectionTitle, internalFilename, internalCSSPath string) (string, error) {
+	filename, err := w.BookWriter.AddSection(body, sectionTitle, internalFilename, internalCSSPath)
+	if err == nil {
+		w.progress.section(sectionTitle, internalFilename)
+	}
+	return filename, err
+}
+
+// AddSubSection adds the subsection and reports it
+func (w *progressBookWriter) AddSubSection(parentFilename, body, sectionTitle, internalFilename, internalCSSPath string) (string, error) {
+	filename, err := w.BookWriter.AddSubSection(parentFilename, body, sectionTitle, internalFilename, internalCSSPath)
+	if err == nil {
+		w.progress.section(sectionTitle, internalFilename)
+	}
+	return filename, err
+}
@@ -0,0 +1,110 @@
+package jplaw2epub
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestProgressEvents(t *testing.T) {
+	var events []ProgressEvent
+	client := &MockAPIClient{GetAttachmentData: map[string]string{"./pict/H0001.jpg": createPNGAttachment()}}
+	opts := &EPUBOptions{
+		APIClient:  client,
+		RevisionID: "rev",
+		NoCover:    true,
+		Progress:   func(event ProgressEvent) { events = append(events, event) },
+	}
+
+	book, err := CreateEPUBFromXMLFileWithOptions(bytes.NewReader(readTestdata(t, "json/chapters.xml")), opts)
+	if err != nil {
+		t.Fatalf("CreateEPUBFromXMLFileWithOptions() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := WriteEPUBToWithOptions(book, &buf, opts); err != nil {
+		t.Fatalf("WriteEPUBToWithOptions() error = %v", err)
+	}
+
+	var types []ProgressEventType
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	want := []ProgressEventType{
+		ProgressStarted,
+		ProgressChapterStarted, ProgressArticleDone, ProgressImageDownloaded, ProgressImageConverted, ProgressArticleDone,
+		ProgressChapterStarted, ProgressArticleDone,
+		ProgressPackagingStarted, ProgressDone,
+	}
+	if !reflect.DeepEqual(types, want) {
+		t.Fatalf("event types = %v, want %v", types, want)
+	}
+
+	started := events[0]
+	if started.ArticlesTotal != 3 || started.ImagesTotal != 1 {
+		t.Errorf("started totals = %d articles, %d images, want 3 and 1", started.ArticlesTotal, started.ImagesTotal)
+	}
+	if events[1].Filename != "chapter-0.xhtml" || events[2].Filename != "article-0-0.xhtml" || events[2].Articles != 1 {
+		t.Errorf("unexpected chapter/article events: %+v, %+v", events[1], events[2])
+	}
+	if events[3].Title != "./pict/H0001.jpg" || events[4].Images != 1 {
+		t.Errorf("unexpected image events: %+v, %+v", events[3], events[4])
+	}
+
+	last := events[7]
+	if last.Articles != 3 || last.Fraction() != 1 {
+		t.Errorf("last article event = %+v, fraction %v", last, last.Fraction())
+	}
+}
+
+func TestProgressWithoutImages(t *testing.T) {
+	var events []ProgressEvent
+	opts := &EPUBOptions{NoCover: true, Progress: func(event ProgressEvent) { events = append(events, event) }}
+
+	book, err := CreateEPUBFromXMLPathWithOptions(filepath.Join("testdata", "definitions.xml"), opts)
+	if err != nil {
+		t.Fatalf("CreateEPUBFromXMLPathWithOptions() error = %v", err)
+	}
+	destPath := filepath.Join(t.TempDir(), "law.epub")
+	if err := WriteEPUBWithOptions(book, destPath, opts); err != nil {
+		t.Fatalf("WriteEPUBWithOptions() error = %v", err)
+	}
+
+	if events[0].ImagesTotal != 0 {
+		t.Errorf("images should not be expected without an API client, got %d", events[0].ImagesTotal)
+	}
+	articles := 0
+	for _, event := range events {
+		if event.Type == ProgressArticleDone {
+			articles++
+		}
+	}
+	if articles != events[0].ArticlesTotal || articles == 0 {
+		t.Errorf("article_done events = %d, want %d", articles, events[0].ArticlesTotal)
+	}
+	if done := events[len(events)-1]; done.Type != ProgressDone || done.Filename != destPath {
+		t.Errorf("last event = %+v, want done for %s", done, destPath)
+	}
+}
+
+func TestProgressEventFraction(t *testing.T) {
+	tests := []struct {
+		name  string
+		event ProgressEvent
+		want  float64
+	}{
+		{name: "nothing expected", event: ProgressEvent{Type: ProgressStarted}, want: 0},
+		{name: "half of the articles", event: ProgressEvent{Type: ProgressArticleDone, Articles: 2, ArticlesTotal: 4}, want: 0.5},
+		{name: "articles and images", event: ProgressEvent{Type: ProgressImageConverted, Articles: 1, ArticlesTotal: 2, Images: 2, ImagesTotal: 2}, want: 0.75},
+		{name: "more images than expected", event: ProgressEvent{Type: ProgressImageConverted, Images: 3, ImagesTotal: 1}, want: 1},
+		{name: "packaging", event: ProgressEvent{Type: ProgressPackagingStarted}, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.event.Fraction(); got != tt.want {
+				t.Errorf("Fraction() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
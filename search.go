@@ -0,0 +1,338 @@
+package jplaw2epub
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"go.ngs.io/jplaw-xml"
+)
+
+// searchPageFilename is the internal filename of the search page
+const searchPageFilename = "search.xhtml"
+
+// quotedTermPattern matches terms quoted with 「」, which is how laws introduce defined terms
+var quotedTermPattern = regexp.MustCompile(`「([^「」]{2,20})」`)
+
+// searchDoc is a searchable unit of text linked to an EPUB section.
+// Text holds newline-separated segments; bigrams never span two segments.
+type searchDoc struct {
+	href     string
+	title    string
+	text     string
+	keywords []string
+}
+
+// searchIndex is the compact n-gram index embedded in the search page.
+// Docs holds [href, title] pairs; Grams maps each normalized bigram to sorted doc numbers.
+type searchIndex struct {
+	Docs  [][2]string      `json:"d"`
+	Grams map[string][]int `json:"g"`
+}
+
+// addSearchPage adds the search page with the embedded index to the book
+func addSearchPage(book BookWriter, data *jplaw.Law) error {
+	docs := collectSearchDocs(NewJSONLaw(data))
+	if len(docs) == 0 {
+		return nil
+	}
+
+	body, err := buildSearchPageBody(docs)
+	if err != nil {
+		return err
+	}
+
+	if _, err := book.AddSection(body, "検索", searchPageFilename, ""); err != nil {
+		return fmt.Errorf("adding search section: %w", err)
+	}
+	return nil
+}
+
+// collectSearchDocs gathers one search document per article, or per paragraph when a provision has no articles
+func collectSearchDocs(law *JSONLaw) []searchDoc {
+	var docs []searchDoc
+
+	addArticles := func(articles []JSONArticle, prefix string) {
+		for i := range articles {
+			docs = append(docs, articleSearchDoc(&articles[i], prefix))
+		}
+	}
+
+	for _, chapter := range law.MainProvision.Chapters {
+		addArticles(chapter.Articles, "")
+		for _, section := range chapter.Sections {
+			addArticles(section.Articles, "")
+		}
+	}
+	addArticles(law.MainProvision.Articles, "")
+	for i := range law.MainProvision.Paragraphs {
+		para := &law.MainProvision.Paragraphs[i]
+		title := "本文"
+		if len(law.MainProvision.Paragraphs) > 1 {
+			title = fmt.Sprintf("第%d項", i+1)
+		}
+		docs = append(docs, searchDoc{href: para.Href, title: title, text: paragraphSearchText(para)})
+	}
+
+	for _, suppl := range law.SupplProvisions {
+		prefix := suppl.Label.Text + "　"
+		for _, chapter := range suppl.Chapters {
+			addArticles(chapter.Articles, prefix)
+		}
+		addArticles(suppl.Articles, prefix)
+		if len(suppl.Paragraphs) > 0 {
+			var text strings.Builder
+			for i := range suppl.Paragraphs {
+				text.WriteString(paragraphSearchText(&suppl.Paragraphs[i]))
+			}
+			docs = append(docs, searchDoc{href: suppl.Href, title: suppl.Label.Text, text: text.String()})
+		}
+	}
+
+	return docs
+}
+
+// articleSearchDoc builds the search document of an article
+func articleSearchDoc(article *JSONArticle, prefix string) searchDoc {
+	doc := searchDoc{
+		href:  article.Href,
+		title: prefix + article.Title.Text,
+	}
+	if article.Caption != nil {
+		doc.title += article.Caption.Text
+		if caption := strings.Trim(article.Caption.Text, "（）()"); caption != "" {
+			doc.keywords = append(doc.keywords, caption)
+		}
+	}
+
+	var text strings.Builder
+	for i := range article.Paragraphs {
+		text.WriteString(paragraphSearchText(&article.Paragraphs[i]))
+	}
+	doc.text = text.String()
+
+	for _, match := range quotedTermPattern.FindAllStringSubmatch(doc.text, -1) {
+		doc.keywords = append(doc.keywords, match[1])
+	}
+	return doc
+}
+
+// paragraphSearchText joins the sentences of a paragraph, its items and tables as text segments
+func paragraphSearchText(para *JSONParagraph) string {
+	var b strings.Builder
+	writeText := func(texts []JSONText) {
+		for _, t := range texts {
+			b.WriteString(t.Text)
+			b.WriteString("\n")
+		}
+	}
+	writeText(para.Sentences)
+	var writeItems func(items []JSONItem)
+	writeItems = func(items []JSONItem) {
+		for i := range items {
+			writeText(items[i].Sentences)
+			writeItems(items[i].Subitems)
+		}
+	}
+	writeItems(para.Items)
+	for _, table := range para.Tables {
+		for _, row := range table.Rows {
+			for _, cell := range row {
+				writeText(cell.Content)
+			}
+		}
+	}
+	return b.String()
+}
+
+// buildSearchIndex builds the bigram index over the search documents
+func buildSearchIndex(docs []searchDoc) *searchIndex {
+	index := &searchIndex{Grams: make(map[string][]int)}
+	for i, doc := range docs {
+		index.Docs = append(index.Docs, [2]string{doc.href, doc.title})
+		for _, gram := range searchBigrams(doc.title + "\n" + doc.text) {
+			postings := index.Grams[gram]
+			if len(postings) == 0 || postings[len(postings)-1] != i {
+				index.Grams[gram] = append(postings, i)
+			}
+		}
+	}
+	return index
+}
+
+// searchBigrams returns the distinct bigrams of each normalized text segment
+func searchBigrams(text string) []string {
+	seen := make(map[string]bool)
+	var grams []string
+	for _, segment := range strings.Split(text, "\n") {
+		runes := []rune(normalizeSearchText(segment))
+		for i := 0; i+1 < len(runes); i++ {
+			gram := string(runes[i : i+2])
+			if !seen[gram] {
+				seen[gram] = true
+				grams = append(grams, gram)
+			}
+		}
+	}
+	return grams
+}
+
+// normalizeSearchText folds full-width ASCII, lowercases and removes whitespace.
+// The embedded script applies the same normalization to each whitespace-separated query term.
+func normalizeSearchText(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if r >= 0xFF01 && r <= 0xFF5E {
+			r -= 0xFEE0
+		}
+		if unicode.IsSpace(r) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// buildKeywordIndex maps keywords to the documents that define or caption them
+func buildKeywordIndex(docs []searchDoc) ([]string, map[string][]int) {
+	refs := make(map[string][]int)
+	for i, doc := range docs {
+		for _, keyword := range doc.keywords {
+			postings := refs[keyword]
+			if len(postings) == 0 || postings[len(postings)-1] != i {
+				refs[keyword] = append(postings, i)
+			}
+		}
+	}
+
+	keywords := make([]string, 0, len(refs))
+	for keyword := range refs {
+		keywords = append(keywords, keyword)
+	}
+	sort.Strings(keywords)
+	return keywords, refs
+}
+
+// buildSearchPageBody builds the search page with the scripted search form and the static keyword index
+func buildSearchPageBody(docs []searchDoc) (string, error) {
+	indexJSON, err := json.Marshal(buildSearchIndex(docs))
+	if err != nil {
+		return "", fmt.Errorf("encoding search index: %w", err)
+	}
+
+	var body strings.Builder
+	body.WriteString(`<h2>検索</h2>`)
+	body.WriteString(`<form id="search-form" class="search-form" hidden="hidden" action="#">`)
+	body.WriteString(`<input id="search-query" type="search" placeholder="検索語" aria-label="検索語" />`)
+	body.WriteString(`</form>`)
+	body.WriteString(`<p id="search-status" class="search-status"></p>`)
+	body.WriteString(`<ol id="search-results" class="search-results"></ol>`)
+
+	keywords, refs := buildKeywordIndex(docs)
+	if len(keywords) > 0 {
+		body.WriteString(`<h3>索引</h3><ul class="keyword-index">`)
+		for _, keyword := range keywords {
+			body.WriteString(`<li>`)
+			body.WriteString(html.EscapeString(keyword))
+			body.WriteString(`　`)
+			for j, docIdx := range refs[keyword] {
+				if j > 0 {
+					body.WriteString(`、`)
+				}
+				fmt.Fprintf(&body, `<a href="%s">%s</a>`,
+					html.EscapeString(docs[docIdx].href), html.EscapeString(docs[docIdx].title))
+			}
+			body.WriteString(`</li>`)
+		}
+		body.WriteString(`</ul>`)
+	}
+
+	body.WriteString(`<script type="text/javascript">//<![CDATA[` + "\n")
+	fmt.Fprintf(&body, "var jplawSearchIndex = %s;\n", indexJSON)
+	body.WriteString(searchScript)
+	body.WriteString(`//]]></script>`)
+
+	return body.String(), nil
+}
+
+// searchScript runs queries against jplawSearchIndex by intersecting bigram postings
+const searchScript = `(function () {
+  var index = jplawSearchIndex;
+  var form = document.getElementById("search-form");
+  var input = document.getElementById("search-query");
+  var status = document.getElementById("search-status");
+  var results = document.getElementById("search-results");
+
+  function normalize(term) {
+    return Array.from(term).map(function (ch) {
+      var code = ch.codePointAt(0);
+      if (code >= 0xFF01 && code <= 0xFF5E) {
+        ch = String.fromCodePoint(code - 0xFEE0);
+      }
+      return ch.toLowerCase();
+    });
+  }
+
+  function intersect(a, b) {
+    return a === null ? b : a.filter(function (doc) { return b.indexOf(doc) >= 0; });
+  }
+
+  function postingsFor(chars) {
+    if (chars.length === 1) {
+      var union = {};
+      Object.keys(index.g).forEach(function (gram) {
+        if (Array.from(gram).indexOf(chars[0]) >= 0) {
+          index.g[gram].forEach(function (doc) { union[doc] = true; });
+        }
+      });
+      return Object.keys(union).map(Number).sort(function (a, b) { return a - b; });
+    }
+    var found = null;
+    for (var i = 0; i + 1 < chars.length && (found === null || found.length > 0); i++) {
+      found = intersect(found, index.g[chars[i] + chars[i + 1]] || []);
+    }
+    return found;
+  }
+
+  function search(query) {
+    var found = null;
+    query.split(/\s+/).forEach(function (term) {
+      if (term !== "") {
+        found = intersect(found, postingsFor(normalize(term)));
+      }
+    });
+    return found;
+  }
+
+  function run() {
+    while (results.firstChild) {
+      results.removeChild(results.firstChild);
+    }
+    var docs = search(input.value);
+    if (docs === null) {
+      status.textContent = "";
+      return;
+    }
+    status.textContent = docs.length + "件";
+    docs.forEach(function (doc) {
+      var item = document.createElement("li");
+      var link = document.createElement("a");
+      link.setAttribute("href", index.d[doc][0]);
+      link.textContent = index.d[doc][1];
+      item.appendChild(link);
+      results.appendChild(item);
+    });
+  }
+
+  form.removeAttribute("hidden");
+  form.addEventListener("submit", function (event) {
+    event.preventDefault();
+    run();
+  });
+  input.addEventListener("input", run);
+})();
+`
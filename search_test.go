@@ -0,0 +1,184 @@
+package jplaw2epub
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeSearchText(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "japanese text", input: "試験法", want: "試験法"},
+		{name: "full-width ascii is folded", input: "ＥＰＵＢ１２", want: "epub12"},
+		{name: "whitespace is removed", input: "第一章　総則 本文", want: "第一章総則本文"},
+		{name: "empty", input: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeSearchText(tt.input); got != tt.want {
+				t.Errorf("normalizeSearchText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchBigrams(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{name: "distinct bigrams", input: "試験試験", want: []string{"試験", "験試"}},
+		{name: "segments do not join", input: "法律\n試験", want: []string{"法律", "試験"}},
+		{name: "single character", input: "法", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := searchBigrams(tt.input); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("searchBigrams() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildSearchIndex(t *testing.T) {
+	docs := []searchDoc{
+		{href: "article-0.xhtml", title: "第一条", text: "試験を行う。\n"},
+		{href: "article-1.xhtml", title: "第二条", text: "受験者の試験\n"},
+	}
+
+	index := buildSearchIndex(docs)
+
+	wantDocs := [][2]string{{"article-0.xhtml", "第一条"}, {"article-1.xhtml", "第二条"}}
+	if !reflect.DeepEqual(index.Docs, wantDocs) {
+		t.Errorf("Docs = %v, want %v", index.Docs, wantDocs)
+	}
+
+	postings := map[string][]int{
+		"試験": {0, 1},
+		"受験": {1},
+		"第一": {0},
+		"条試": nil,
+	}
+	for gram, want := range postings {
+		if got := index.Grams[gram]; !reflect.DeepEqual(got, want) {
+			t.Errorf("Grams[%q] = %v, want %v", gram, got, want)
+		}
+	}
+}
+
+func TestCollectSearchDocs(t *testing.T) {
+	law, err := CreateJSONFromXMLPath(filepath.Join("testdata", "json", "chapters.xml"))
+	if err != nil {
+		t.Fatalf("CreateJSONFromXMLPath() error = %v", err)
+	}
+
+	docs := collectSearchDocs(law)
+
+	wantHrefs := []string{"article-0-0.xhtml", "article-0-1.xhtml", "article-1-0-0.xhtml", "suppl-provision-0.xhtml"}
+	var hrefs []string
+	for _, doc := range docs {
+		hrefs = append(hrefs, doc.href)
+	}
+	if !reflect.DeepEqual(hrefs, wantHrefs) {
+		t.Fatalf("hrefs = %v, want %v", hrefs, wantHrefs)
+	}
+
+	if docs[0].title != "第一条（目的）" {
+		t.Errorf("title = %q, want %q", docs[0].title, "第一条（目的）")
+	}
+	if !reflect.DeepEqual(docs[0].keywords, []string{"目的"}) {
+		t.Errorf("keywords = %v, want [目的]", docs[0].keywords)
+	}
+	if !strings.Contains(docs[1].text, "本試験を受ける者") {
+		t.Errorf("subitem text missing from %q", docs[1].text)
+	}
+	if !strings.Contains(docs[1].text, "口述") {
+		t.Errorf("table text missing from %q", docs[1].text)
+	}
+}
+
+func TestBuildKeywordIndex(t *testing.T) {
+	docs := []searchDoc{
+		{href: "a.xhtml", keywords: []string{"目的", "受験者"}},
+		{href: "b.xhtml", keywords: []string{"受験者", "受験者"}},
+	}
+
+	keywords, refs := buildKeywordIndex(docs)
+
+	if want := []string{"受験者", "目的"}; !reflect.DeepEqual(keywords, want) {
+		t.Errorf("keywords = %v, want %v", keywords, want)
+	}
+	if want := []int{0, 1}; !reflect.DeepEqual(refs["受験者"], want) {
+		t.Errorf("refs[受験者] = %v, want %v", refs["受験者"], want)
+	}
+}
+
+func TestBuildSearchPageBody(t *testing.T) {
+	docs := []searchDoc{
+		{href: "article-0.xhtml", title: "第一条（目的）", text: "「<試験>」を行う。\n", keywords: []string{"目的"}},
+	}
+
+	body, err := buildSearchPageBody(docs)
+	if err != nil {
+		t.Fatalf("buildSearchPageBody() error = %v", err)
+	}
+
+	for _, want := range []string{
+		`<form id="search-form"`,
+		`hidden="hidden"`,
+		`<ul class="keyword-index"><li>目的　<a href="article-0.xhtml">第一条（目的）</a></li></ul>`,
+		`<script type="text/javascript">`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body missing %q", want)
+		}
+	}
+
+	// The page must stay well-formed XHTML with the embedded index
+	decoder := xml.NewDecoder(strings.NewReader("<body>" + body + "</body>"))
+	var script string
+	inScript := false
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			if err != io.EOF {
+				t.Fatalf("search page is not well-formed: %v", err)
+			}
+			break
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			inScript = tok.Name.Local == "script"
+		case xml.CharData:
+			if inScript {
+				script += string(tok)
+			}
+		case xml.EndElement:
+			inScript = false
+		}
+	}
+
+	start := strings.Index(script, "var jplawSearchIndex = ")
+	end := strings.Index(script, ";\n")
+	if start < 0 || end < start {
+		t.Fatalf("index assignment not found in script")
+	}
+	var index searchIndex
+	if err := json.Unmarshal([]byte(script[start+len("var jplawSearchIndex = "):end]), &index); err != nil {
+		t.Fatalf("embedded index is not valid JSON: %v", err)
+	}
+	if len(index.Grams["試験"]) != 1 {
+		t.Errorf("embedded index missing bigram postings: %v", index.Grams)
+	}
+}
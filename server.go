@@ -0,0 +1,714 @@
+package jplaw2epub
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Server defaults
+const (
+	DefaultMaxRequestBytes = 32 << 20
+	DefaultCacheEntries    = 64
+	DefaultRevisionTTL     = 10 * time.Minute
+)
+
+// lawIDPattern matches law IDs, law numbers in ID form and revision IDs
+var lawIDPattern = regexp.MustCompile(`^[0-9A-Za-z_]+$`)
+
+// errServerBusy is returned when every conversion slot is in use
+var errServerBusy = errors.New("too many concurrent conversions")
+
+// ServerOptions configures the HTTP conversion service
+type ServerOptions struct {
+	// Fetcher fetches laws for GET /laws/{lawID}.epub. The endpoint is disabled when nil.
+	Fetcher LawFetcher
+	// APIClient downloads images. Images are skipped when nil.
+	APIClient APIClient
+	// MaxImageHeight is the maximum height for images (e.g., "300px", "80vh", "50%")
+	MaxImageHeight string
+	// MaxRequestBytes limits the size of posted XML, DefaultMaxRequestBytes when zero
+	MaxRequestBytes int64
+	// MaxConcurrent limits simultaneous conversions, the number of CPUs when zero
+	MaxConcurrent int
+	// CacheEntries is the number of converted EPUBs kept in memory,
+	// DefaultCacheEntries when zero. A negative value disables the cache.
+	CacheEntries int
+	// RevisionTTL is how long the revision of a law looked up for GET /laws/{lawID}.epub is reused,
+	// DefaultRevisionTTL when zero. A negative value looks the revision up for every request.
+	RevisionTTL time.Duration
+	// ConversionTimeout bounds each conversion, including image downloads. Zero means no limit.
+	ConversionTimeout time.Duration
+	// CoverFont and CoverTemplate are passed to EPUBOptions for the generated covers
+	CoverFont     []byte
+	CoverTemplate []byte
+	// NoCover disables the covers, which need CoverFont or a system CJK font
+	NoCover bool
+}
+
+// Server is an http.Handler converting law XML into EPUB.
+//
+// Endpoints:
+//
+//	POST /convert                        converts the posted XML
+//	GET  /laws/{lawID}.epub?asof=DATE    fetches a law and converts it
+//	GET  /healthz                        reports liveness
+//	GET  /metrics                        exposes counters in the Prometheus text format
+//
+// A converted book is packaged once and streamed to the responses of all requests waiting
+// for it while it is written; only the cache holds complete EPUBs, which are served with
+// http.ServeContent and so support range requests. Every response carries an ETag derived
+// from the cache key, answering conditional requests without a conversion.
+type Server struct {
+	opts      ServerOptions
+	mux       *http.ServeMux
+	slots     chan struct{}
+	cache     *epubCache
+	revisions *revisionCache
+	flight    conversionGroup
+
+	requests    atomic.Int64
+	conversions atomic.Int64
+	failures    atomic.Int64
+	cacheHits   atomic.Int64
+	cacheMisses atomic.Int64
+	rejected    atomic.Int64
+	inFlight    atomic.Int64
+}
+
+// NewServer creates a conversion server
+func NewServer(opts ServerOptions) *Server {
+	if opts.MaxRequestBytes <= 0 {
+		opts.MaxRequestBytes = DefaultMaxRequestBytes
+	}
+	if opts.MaxConcurrent <= 0 {
+		opts.MaxConcurrent = runtime.NumCPU()
+	}
+	if opts.CacheEntries == 0 {
+		opts.CacheEntries = DefaultCacheEntries
+	}
+	if opts.RevisionTTL == 0 {
+		opts.RevisionTTL = DefaultRevisionTTL
+	}
+
+	s := &Server{
+		opts:      opts,
+		mux:       http.NewServeMux(),
+		slots:     make(chan struct{}, opts.MaxConcurrent),
+		cache:     newEPUBCache(opts.CacheEntries),
+		revisions: newRevisionCache(opts.RevisionTTL),
+	}
+	s.mux.HandleFunc("POST /convert", s.handleConvert)
+	s.mux.HandleFunc("GET /laws/{file}", s.handleLaw)
+	s.mux.HandleFunc("GET /healthz", s.handleHealth)
+	s.mux.HandleFunc("GET /metrics", s.handleMetrics)
+	return s
+}
+
+// ServeHTTP dispatches a request to the endpoints
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.requests.Add(1)
+	s.mux.ServeHTTP(w, r)
+}
+
+// conversionOptions are the per-request options selected by query parameters
+type conversionOptions struct {
+	images          bool
+	search          bool
+	definitions     bool
+	definitionLinks bool
+	popupNotes      bool
+}
+
+// parseConversionOptions reads the conversion options from the query string
+func parseConversionOptions(r *http.Request) (*conversionOptions, error) {
+	query := r.URL.Query()
+	parse := func(name string, def bool) (bool, error) {
+		value := query.Get(name)
+		if value == "" {
+			return def, nil
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return false, fmt.Errorf("invalid %s parameter: %q", name, value)
+		}
+		return b, nil
+	}
+
+	opts := &conversionOptions{}
+	fields := []struct {
+		name   string
+		def    bool
+		target *bool
+	}{
+		{"images", true, &opts.images},
+		{"search", false, &opts.search},
+		{"definitions", false, &opts.definitions},
+		{"definition-links", false, &opts.definitionLinks},
+		{"popup-notes", false, &opts.popupNotes},
+	}
+	for _, f := range fields {
+		b, err := parse(f.name, f.def)
+		if err != nil {
+			return nil, err
+		}
+		*f.target = b
+	}
+	return opts, nil
+}
+
+// key identifies the options in cache keys
+func (o *conversionOptions) key() string {
+	return fmt.Sprintf("images=%t,search=%t,definitions=%t,definition-links=%t,popup-notes=%t",
+		o.images, o.search, o.definitions, o.definitionLinks, o.popupNotes)
+}
+
+// epubOptions builds the EPUB options for a conversion of revisionID
+func (s *Server) epubOptions(o *conversionOptions, revisionID string) *EPUBOptions {
+	opts := &EPUBOptions{
+		MaxImageHeight:  s.opts.MaxImageHeight,
+		SearchIndex:     o.search,
+		DefinitionIndex: o.definitions,
+		DefinitionLinks: o.definitionLinks,
+		PopupNotes:      o.popupNotes,
+		RevisionID:      revisionID,
+		CoverFont:       s.opts.CoverFont,
+		CoverTemplate:   s.opts.CoverTemplate,
+		NoCover:         s.opts.NoCover,
+	}
+	if o.images && s.opts.APIClient != nil && revisionID != "" {
+		opts.APIClient = s.opts.APIClient
+	}
+	return opts
+}
+
+// handleConvert converts XML posted in the request body.
+// The optional revision parameter enables image downloads.
+func (s *Server) handleConvert(w http.ResponseWriter, r *http.Request) {
+	convOpts, err := parseConversionOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	revisionID := r.URL.Query().Get("revision")
+	if revisionID != "" && !lawIDPattern.MatchString(revisionID) {
+		http.Error(w, "invalid revision parameter", http.StatusBadRequest)
+		return
+	}
+
+	xmlData, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.opts.MaxRequestBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "reading request body failed", http.StatusBadRequest)
+		return
+	}
+
+	sum := sha256.Sum256(xmlData)
+	key := "sha256:" + hex.EncodeToString(sum[:]) + "|revision=" + revisionID + "|" + convOpts.key()
+	load := func(context.Context) ([]byte, error) {
+		return xmlData, nil
+	}
+	s.serveEPUB(w, r, key, "law.epub", load, s.epubOptions(convOpts, revisionID))
+}
+
+// handleLaw converts a law fetched from the Fetcher. The revision in force is looked up first,
+// so that the law is only downloaded when no conversion of the revision is cached.
+func (s *Server) handleLaw(w http.ResponseWriter, r *http.Request) {
+	if s.opts.Fetcher == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	lawID, ok := strings.CutSuffix(r.PathValue("file"), ".epub")
+	if !ok || !lawIDPattern.MatchString(lawID) {
+		http.NotFound(w, r)
+		return
+	}
+
+	asof := r.URL.Query().Get("asof")
+	if asof != "" {
+		if _, err := time.Parse(time.DateOnly, asof); err != nil {
+			http.Error(w, "asof must be a date in YYYY-MM-DD format", http.StatusBadRequest)
+			return
+		}
+	}
+
+	convOpts, err := parseConversionOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	revisionID, err := s.lawRevision(r.Context(), lawID, asof)
+	if err != nil {
+		status, err := fetchErrorStatus(err)
+		if status != http.StatusNotFound {
+			s.failures.Add(1)
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	key := "revision=" + revisionID + "|" + convOpts.key()
+	load := func(ctx context.Context) ([]byte, error) {
+		return s.opts.Fetcher.FetchLaw(ctx, revisionID)
+	}
+	s.serveEPUB(w, r, key, lawID+".epub", load, s.epubOptions(convOpts, revisionID))
+}
+
+// lawRevision returns the revision of a law in force on asof, reusing lookups for RevisionTTL
+func (s *Server) lawRevision(ctx context.Context, lawID, asof string) (string, error) {
+	key := lawID + "|" + asof
+	if revisionID, ok := s.revisions.get(key); ok {
+		return revisionID, nil
+	}
+
+	revisionID, err := s.opts.Fetcher.LawRevision(ctx, lawID, asof)
+	if err != nil {
+		return "", err
+	}
+	s.revisions.put(key, revisionID)
+	return revisionID, nil
+}
+
+// fetchErrorStatus returns the HTTP status and message for an error of the Fetcher
+func fetchErrorStatus(err error) (int, error) {
+	if errors.Is(err, ErrLawNotFound) {
+		return http.StatusNotFound, err
+	}
+	return http.StatusBadGateway, fmt.Errorf("fetching law: %w", err)
+}
+
+// statusError is an error with the HTTP status of the response
+type statusError struct {
+	status int
+	err    error
+}
+
+// Error returns the message of the wrapped error
+func (e *statusError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the wrapped error
+func (e *statusError) Unwrap() error {
+	return e.err
+}
+
+// serveEPUB serves the EPUB for key from the cache, or converts the XML returned by load.
+// Concurrent requests for the same key share one conversion, which is canceled when all of
+// them have gone.
+func (s *Server) serveEPUB(w http.ResponseWriter, r *http.Request, key, filename string, load func(context.Context) ([]byte, error), opts *EPUBOptions) {
+	sum := sha256.Sum256([]byte(key))
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/epub+zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if data, ok := s.cache.get(key); ok {
+		s.cacheHits.Add(1)
+		http.ServeContent(w, r, filename, time.Time{}, bytes.NewReader(data))
+		return
+	}
+	s.cacheMisses.Add(1)
+
+	convert := func(ctx context.Context) (*Book, error) {
+		return s.convert(ctx, load, opts)
+	}
+	written, err := s.flight.do(r.Context(), key, w, convert, func(book *Book, w io.Writer) error {
+		return s.pack(key, book, w)
+	})
+	switch {
+	case err == nil, r.Context().Err() != nil:
+		// Done, or the client has gone and nobody reads the response
+		return
+	case written > 0:
+		// Abort the connection so that the truncated EPUB is not taken for a complete one
+		panic(http.ErrAbortHandler)
+	}
+
+	status := http.StatusInternalServerError
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		status = statusErr.status
+	}
+	if status == http.StatusServiceUnavailable {
+		w.Header().Set("Retry-After", "1")
+	}
+	w.Header().Del("ETag")
+	w.Header().Del("Content-Disposition")
+	http.Error(w, err.Error(), status)
+}
+
+// pack writes book to w and, unless the cache is disabled, caches the EPUB for key
+func (s *Server) pack(key string, book *Book, w io.Writer) error {
+	var buf *bytes.Buffer
+	if s.opts.CacheEntries > 0 {
+		buf = new(bytes.Buffer)
+		w = io.MultiWriter(w, buf)
+	}
+
+	if _, err := book.WriteTo(w); err != nil {
+		if !errors.Is(err, errNoWaiters) {
+			s.failures.Add(1)
+		}
+		return err
+	}
+	if buf != nil {
+		s.cache.put(key, buf.Bytes())
+	}
+	return nil
+}
+
+// convert loads the XML and converts it in one of the limited slots, returning a *statusError
+// on failure. The conversion stops when ctx is canceled or ConversionTimeout passes.
+func (s *Server) convert(ctx context.Context, load func(context.Context) ([]byte, error), opts *EPUBOptions) (*Book, error) {
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		s.rejected.Add(1)
+		return nil, &statusError{http.StatusServiceUnavailable, errServerBusy}
+	}
+	defer func() { <-s.slots }()
+
+	s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+
+	if s.opts.ConversionTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.opts.ConversionTimeout)
+		defer cancel()
+	}
+
+	xmlData, err := load(ctx)
+	if err != nil {
+		status, err := fetchErrorStatus(err)
+		if status != http.StatusNotFound {
+			s.failures.Add(1)
+		}
+		return nil, &statusError{status, err}
+	}
+
+	data, amendments, err := loadLawFromReader(bytes.NewReader(xmlData))
+	if err != nil {
+		s.failures.Add(1)
+		return nil, &statusError{http.StatusBadRequest, fmt.Errorf("loading XML data: %w", err)}
+	}
+
+	book, err := createEPUBFromLaw(ctx, data, amendments, opts)
+	if err != nil {
+		s.failures.Add(1)
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			return nil, &statusError{http.StatusGatewayTimeout, fmt.Errorf("conversion timed out: %w", err)}
+		case errors.Is(err, context.Canceled):
+			return nil, &statusError{http.StatusServiceUnavailable, fmt.Errorf("conversion canceled: %w", err)}
+		case errors.Is(err, ErrNoCoverFont):
+			return nil, &statusError{http.StatusInternalServerError, err}
+		}
+		return nil, &statusError{http.StatusUnprocessableEntity, err}
+	}
+
+	s.conversions.Add(1)
+	return book, nil
+}
+
+// errNoWaiters stops packaging a book when every request waiting for it has gone
+var errNoWaiters = errors.New("no request waits for the EPUB")
+
+// conversionGroup runs one conversion per key for the requests waiting for it
+type conversionGroup struct {
+	mu          sync.Mutex
+	conversions map[string]*conversion
+}
+
+// conversion is a conversion shared by the requests for one key. The book is packaged once,
+// written to the responses of the requests waiting when packaging starts.
+type conversion struct {
+	mu        sync.Mutex
+	cancel    context.CancelFunc
+	streams   map[*epubStream]bool
+	abandoned bool
+	done      chan struct{}
+	err       error
+}
+
+// epubStream is the response of a request waiting for a conversion
+type epubStream struct {
+	w       io.Writer
+	written int64
+	err     error
+}
+
+// do converts and packs the book for key, writing the EPUB to w. A conversion already running
+// for key is joined unless its packaging has started. do returns the number of bytes written
+// to w, and the error of ctx when ctx is done first; the conversion is canceled when no
+// request waits for it anymore.
+func (g *conversionGroup) do(ctx context.Context, key string, w io.Writer, convert func(context.Context) (*Book, error), pack func(*Book, io.Writer) error) (int64, error) {
+	stream := &epubStream{w: w}
+
+	g.mu.Lock()
+	if g.conversions == nil {
+		g.conversions = make(map[string]*conversion)
+	}
+	c := g.conversions[key]
+	if c == nil || !c.join(stream) {
+		convCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		c = &conversion{cancel: cancel, streams: map[*epubStream]bool{stream: true}, done: make(chan struct{})}
+		g.conversions[key] = c
+		go func() {
+			defer close(c.done)
+			defer cancel()
+			book, err := convert(convCtx)
+			// Requests arriving from now on would miss the start of the EPUB
+			g.forget(key, c)
+			if err == nil {
+				err = pack(book, c)
+			}
+			c.mu.Lock()
+			c.err = err
+			c.mu.Unlock()
+		}()
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+		if c.leave(stream) {
+			g.forget(key, c)
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return stream.written, ctx.Err()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if stream.err != nil {
+		return stream.written, stream.err
+	}
+	return stream.written, c.err
+}
+
+// forget removes c from the running conversions unless another one has replaced it
+func (g *conversionGroup) forget(key string, c *conversion) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.conversions[key] == c {
+		delete(g.conversions, key)
+	}
+}
+
+// join adds stream to the waiting requests, reporting false when all of them have gone
+func (c *conversion) join(stream *epubStream) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.abandoned {
+		return false
+	}
+	c.streams[stream] = true
+	return true
+}
+
+// leave removes stream from the waiting requests, canceling the conversion and
+// reporting true when it was the last one
+func (c *conversion) leave(stream *epubStream) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.streams, stream)
+	if len(c.streams) > 0 || c.abandoned {
+		return false
+	}
+	c.abandoned = true
+	c.cancel()
+	return true
+}
+
+// Write writes p to the responses of the waiting requests, dropping those that fail.
+// It returns errNoWaiters when none is left.
+func (c *conversion) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for stream := range c.streams {
+		n, err := stream.w.Write(p)
+		stream.written += int64(n)
+		if err != nil {
+			stream.err = err
+			delete(c.streams, stream)
+		}
+	}
+	if len(c.streams) == 0 {
+		c.abandoned = true
+		return 0, errNoWaiters
+	}
+	return len(p), nil
+}
+
+// handleHealth reports that the server is up
+func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "ok")
+}
+
+// handleMetrics writes the counters in the Prometheus text exposition format
+func (s *Server) handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	metrics := []struct {
+		name  string
+		kind  string
+		help  string
+		value int64
+	}{
+		{"jplaw2epub_requests_total", "counter", "HTTP requests received.", s.requests.Load()},
+		{"jplaw2epub_conversions_total", "counter", "Successful conversions.", s.conversions.Load()},
+		{"jplaw2epub_conversion_failures_total", "counter", "Failed fetches and conversions.", s.failures.Load()},
+		{"jplaw2epub_cache_hits_total", "counter", "Responses served from the cache.", s.cacheHits.Load()},
+		{"jplaw2epub_cache_misses_total", "counter", "Responses that required a conversion.", s.cacheMisses.Load()},
+		{"jplaw2epub_rejected_total", "counter", "Conversions rejected by the concurrency limit.", s.rejected.Load()},
+		{"jplaw2epub_conversions_in_flight", "gauge", "Conversions in progress.", s.inFlight.Load()},
+		{"jplaw2epub_cache_entries", "gauge", "EPUBs held in the cache.", int64(s.cache.len())},
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	for _, m := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %d\n", m.name, m.help, m.name, m.kind, m.name, m.value)
+	}
+}
+
+// epubCache is a least-recently-used cache of converted EPUBs
+type epubCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+// epubCacheEntry is an element of epubCache.order
+type epubCacheEntry struct {
+	key  string
+	data []byte
+}
+
+// newEPUBCache creates a cache holding up to capacity EPUBs; it stores nothing when capacity is not positive
+func newEPUBCache(capacity int) *epubCache {
+	return &epubCache{capacity: capacity, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// get returns the cached EPUB for key
+func (c *epubCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*epubCacheEntry).data, true
+}
+
+// put stores data for key, evicting the least recently used entries
+func (c *epubCache) put(key string, data []byte) {
+	if c.capacity <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*epubCacheEntry).data = data
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&epubCacheEntry{key: key, data: data})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*epubCacheEntry).key)
+	}
+}
+
+// len returns the number of cached EPUBs
+func (c *epubCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// maxRevisionEntries bounds the revision cache; expired entries are dropped beyond it
+const maxRevisionEntries = 4096
+
+// revisionCache keeps the revisions of laws looked up by ID and date for a limited time
+type revisionCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]revisionCacheEntry
+}
+
+// revisionCacheEntry is a value of revisionCache.entries
+type revisionCacheEntry struct {
+	revisionID string
+	expires    time.Time
+}
+
+// newRevisionCache creates a cache keeping revisions for ttl; it stores nothing when ttl is not positive
+func newRevisionCache(ttl time.Duration) *revisionCache {
+	return &revisionCache{ttl: ttl, entries: make(map[string]revisionCacheEntry)}
+}
+
+// get returns the revision for key unless it has expired
+func (c *revisionCache) get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return "", false
+	}
+	return entry.revisionID, true
+}
+
+// put stores the revision for key, dropping expired entries when the cache is full
+func (c *revisionCache) put(key, revisionID string) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if len(c.entries) >= maxRevisionEntries {
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxRevisionEntries {
+			clear(c.entries)
+		}
+	}
+	c.entries[key] = revisionCacheEntry{revisionID: revisionID, expires: now.Add(c.ttl)}
+}
@@ -0,0 +1,446 @@
+package jplaw2epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockLawFetcher serves fixed law XML and records the requests
+type mockLawFetcher struct {
+	xml      []byte
+	revision string
+	err      error
+	fetchErr error
+	// block delays FetchLaw until it is closed or the context is done
+	block chan struct{}
+
+	mu      sync.Mutex
+	lookups []string
+	fetches []string
+}
+
+// LawRevision returns the configured revision or error
+func (m *mockLawFetcher) LawRevision(_ context.Context, lawID, asof string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lookups = append(m.lookups, lawID+"@"+asof)
+	if m.err != nil {
+		return "", m.err
+	}
+	return m.revision, nil
+}
+
+// FetchLaw returns the configured XML or error
+func (m *mockLawFetcher) FetchLaw(ctx context.Context, revisionID string) ([]byte, error) {
+	if m.block != nil {
+		select {
+		case <-m.block:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.fetches = append(m.fetches, revisionID)
+	if m.fetchErr != nil {
+		return nil, m.fetchErr
+	}
+	return m.xml, nil
+}
+
+// readTestdata reads a file from testdata
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", name, err)
+	}
+	return data
+}
+
+// assertEPUBResponse checks that rec holds a readable EPUB
+func assertEPUBResponse(t *testing.T, rec *httptest.ResponseRecorder) {
+	t.Helper()
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/epub+zip" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	body := rec.Body.Bytes()
+	reader, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("response is not a zip archive: %v", err)
+	}
+	if len(reader.File) == 0 || reader.File[0].Name != "mimetype" {
+		t.Error("mimetype should be the first entry")
+	}
+}
+
+// metricValue returns the value of a metric from the /metrics output
+func metricValue(t *testing.T, s *Server, name string) string {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		if value, ok := strings.CutPrefix(line, name+" "); ok {
+			return value
+		}
+	}
+	t.Fatalf("metric %s not found:\n%s", name, rec.Body.String())
+	return ""
+}
+
+func TestServerConvert(t *testing.T) {
+	s := NewServer(ServerOptions{NoCover: true})
+	xmlData := readTestdata(t, "definitions.xml")
+
+	post := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/convert"+query, bytes.NewReader(xmlData)))
+		return rec
+	}
+
+	first := post("")
+	assertEPUBResponse(t, first)
+	if cd := first.Header().Get("Content-Disposition"); cd != `attachment; filename="law.epub"` {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+
+	second := post("")
+	assertEPUBResponse(t, second)
+	if !bytes.Equal(first.Body.Bytes(), second.Body.Bytes()) {
+		t.Error("cached response differs from the first conversion")
+	}
+
+	assertEPUBResponse(t, post("?definitions=true"))
+
+	if got := metricValue(t, s, "jplaw2epub_conversions_total"); got != "2" {
+		t.Errorf("conversions = %s, want 2", got)
+	}
+	if got := metricValue(t, s, "jplaw2epub_cache_hits_total"); got != "1" {
+		t.Errorf("cache hits = %s, want 1", got)
+	}
+}
+
+func TestServerConvertErrors(t *testing.T) {
+	xmlData := readTestdata(t, "definitions.xml")
+
+	tests := []struct {
+		name   string
+		opts   ServerOptions
+		busy   bool
+		query  string
+		body   string
+		status int
+	}{
+		{name: "body too large", opts: ServerOptions{MaxRequestBytes: 100, NoCover: true}, body: string(xmlData), status: http.StatusRequestEntityTooLarge},
+		{name: "invalid XML", body: "<Law>", status: http.StatusBadRequest},
+		{name: "missing title", body: "<Law><LawBody></LawBody></Law>", status: http.StatusUnprocessableEntity},
+		{name: "invalid option", query: "?search=maybe", body: string(xmlData), status: http.StatusBadRequest},
+		{name: "invalid revision", query: "?revision=../x", body: string(xmlData), status: http.StatusBadRequest},
+		{name: "all slots in use", opts: ServerOptions{MaxConcurrent: 1, NoCover: true}, busy: true, body: string(xmlData), status: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer(tt.opts)
+			if tt.busy {
+				s.slots <- struct{}{}
+			}
+
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/convert"+tt.query, strings.NewReader(tt.body)))
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			if tt.busy && rec.Header().Get("Retry-After") == "" {
+				t.Error("busy response should set Retry-After")
+			}
+		})
+	}
+}
+
+func TestServerLaw(t *testing.T) {
+	fetcher := &mockLawFetcher{xml: readTestdata(t, "json/chapters.xml"), revision: "rev1"}
+	client := &MockAPIClient{GetAttachmentData: map[string]string{"./pict/H0001.jpg": createPNGAttachment()}}
+	s := NewServer(ServerOptions{Fetcher: fetcher, APIClient: client, NoCover: true})
+
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	rec := get("/laws/405AC0000000088.epub?asof=2024-04-01")
+	assertEPUBResponse(t, rec)
+	if cd := rec.Header().Get("Content-Disposition"); cd != `attachment; filename="405AC0000000088.epub"` {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+	if len(fetcher.lookups) != 1 || fetcher.lookups[0] != "405AC0000000088@2024-04-01" {
+		t.Errorf("revision lookups = %v", fetcher.lookups)
+	}
+	if len(fetcher.fetches) != 1 || fetcher.fetches[0] != "rev1" {
+		t.Errorf("fetches = %v", fetcher.fetches)
+	}
+	if len(client.GetAttachmentCalls) != 1 || client.GetAttachmentCalls[0].RevisionID != "rev1" {
+		t.Errorf("attachment calls = %+v", client.GetAttachmentCalls)
+	}
+
+	// Another ID resolving to the same revision is served from the cache
+	assertEPUBResponse(t, get("/laws/405AC0000000088_20240401_000000000000000.epub"))
+	if got := metricValue(t, s, "jplaw2epub_cache_hits_total"); got != "1" {
+		t.Errorf("cache hits = %s, want 1", got)
+	}
+	if len(fetcher.fetches) != 1 {
+		t.Errorf("cache hits should not fetch the law, fetches = %v", fetcher.fetches)
+	}
+
+	// Revisions are reused until RevisionTTL passes
+	assertEPUBResponse(t, get("/laws/405AC0000000088.epub?asof=2024-04-01"))
+	if len(fetcher.lookups) != 2 {
+		t.Errorf("revision lookups = %v, want the first lookup reused", fetcher.lookups)
+	}
+
+	// Different options need their own conversion
+	assertEPUBResponse(t, get("/laws/405AC0000000088.epub?images=false"))
+	if len(client.GetAttachmentCalls) != 1 {
+		t.Errorf("images=false should not download images, calls = %d", len(client.GetAttachmentCalls))
+	}
+	if got := metricValue(t, s, "jplaw2epub_conversions_total"); got != "2" {
+		t.Errorf("conversions = %s, want 2", got)
+	}
+}
+
+func TestServerLawErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		fetcher LawFetcher
+		target  string
+		status  int
+	}{
+		{name: "no fetcher", target: "/laws/X.epub", status: http.StatusNotFound},
+		{name: "missing extension", fetcher: &mockLawFetcher{}, target: "/laws/X", status: http.StatusNotFound},
+		{name: "invalid law ID", fetcher: &mockLawFetcher{}, target: "/laws/a.b.epub", status: http.StatusNotFound},
+		{name: "invalid date", fetcher: &mockLawFetcher{}, target: "/laws/X.epub?asof=20240401", status: http.StatusBadRequest},
+		{name: "unknown law", fetcher: &mockLawFetcher{err: fmt.Errorf("looking up X: %w", ErrLawNotFound)}, target: "/laws/X.epub", status: http.StatusNotFound},
+		{name: "lookup failure", fetcher: &mockLawFetcher{err: errors.New("connection refused")}, target: "/laws/X.epub", status: http.StatusBadGateway},
+		{name: "fetch failure", fetcher: &mockLawFetcher{revision: "rev1", fetchErr: errors.New("connection reset")}, target: "/laws/X.epub", status: http.StatusBadGateway},
+		{name: "invalid XML", fetcher: &mockLawFetcher{revision: "rev1", xml: []byte("<html/>")}, target: "/laws/X.epub", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer(ServerOptions{Fetcher: tt.fetcher, NoCover: true})
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestServerLawConcurrentMisses(t *testing.T) {
+	fetcher := &mockLawFetcher{xml: readTestdata(t, "json/chapters.xml"), revision: "rev1", block: make(chan struct{})}
+	s := NewServer(ServerOptions{Fetcher: fetcher, MaxConcurrent: 1, NoCover: true})
+
+	const clients = 4
+	var wg sync.WaitGroup
+	recs := make([]*httptest.ResponseRecorder, clients)
+	for i := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			recs[i] = httptest.NewRecorder()
+			s.ServeHTTP(recs[i], httptest.NewRequest(http.MethodGet, "/laws/X.epub", nil))
+		}()
+	}
+	for metricValue(t, s, "jplaw2epub_cache_misses_total") != strconv.Itoa(clients) {
+		time.Sleep(time.Millisecond)
+	}
+	close(fetcher.block)
+	wg.Wait()
+
+	// Every request receives the EPUB packaged once
+	for _, rec := range recs {
+		assertEPUBResponse(t, rec)
+		if !bytes.Equal(rec.Body.Bytes(), recs[0].Body.Bytes()) {
+			t.Error("shared conversion responses differ")
+		}
+	}
+	if len(fetcher.fetches) != 1 {
+		t.Errorf("fetches = %v, want one shared fetch", fetcher.fetches)
+	}
+	if got := metricValue(t, s, "jplaw2epub_conversions_total"); got != "1" {
+		t.Errorf("conversions = %s, want 1", got)
+	}
+	if got := metricValue(t, s, "jplaw2epub_rejected_total"); got != "0" {
+		t.Errorf("rejected = %s, want 0", got)
+	}
+}
+
+func TestRevisionCache(t *testing.T) {
+	cache := newRevisionCache(time.Hour)
+	cache.put("X|", "rev1")
+	if got, ok := cache.get("X|"); !ok || got != "rev1" {
+		t.Errorf("get() = %q, %t", got, ok)
+	}
+	if _, ok := cache.get("X|2024-04-01"); ok {
+		t.Error("get() of another date should miss")
+	}
+
+	cache.entries["X|"] = revisionCacheEntry{revisionID: "rev1", expires: time.Now().Add(-time.Second)}
+	if _, ok := cache.get("X|"); ok {
+		t.Error("expired entries should miss")
+	}
+
+	disabled := newRevisionCache(-1)
+	disabled.put("X|", "rev1")
+	if _, ok := disabled.get("X|"); ok {
+		t.Error("a negative TTL should disable the cache")
+	}
+}
+
+func TestServerStreaming(t *testing.T) {
+	ts := httptest.NewServer(NewServer(ServerOptions{NoCover: true}))
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/convert", "application/xml", bytes.NewReader(readTestdata(t, "definitions.xml")))
+	if err != nil {
+		t.Fatalf("POST error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading response: %v", err)
+	}
+	if len(resp.TransferEncoding) == 0 || resp.TransferEncoding[0] != "chunked" {
+		t.Errorf("Transfer-Encoding = %v, want a chunked response", resp.TransferEncoding)
+	}
+	if _, err := zip.NewReader(bytes.NewReader(body), int64(len(body))); err != nil {
+		t.Errorf("response is not a zip archive: %v", err)
+	}
+
+	// Cached EPUBs support range requests to resume interrupted downloads
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/convert", bytes.NewReader(readTestdata(t, "definitions.xml")))
+	req.Header.Set("Range", "bytes=0-9")
+	partial, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("range request error = %v", err)
+	}
+	defer partial.Body.Close()
+	chunk, _ := io.ReadAll(partial.Body)
+	if partial.StatusCode != http.StatusPartialContent || !bytes.Equal(chunk, body[:10]) {
+		t.Errorf("range response = %d %q", partial.StatusCode, chunk)
+	}
+
+	// Conditional requests are answered from the ETag
+	req, _ = http.NewRequest(http.MethodPost, ts.URL+"/convert", bytes.NewReader(readTestdata(t, "definitions.xml")))
+	req.Header.Set("If-None-Match", resp.Header.Get("ETag"))
+	cached, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("conditional request error = %v", err)
+	}
+	defer cached.Body.Close()
+	if cached.StatusCode != http.StatusNotModified {
+		t.Errorf("conditional response status = %d, want 304", cached.StatusCode)
+	}
+}
+
+func TestServerCancelsAbandonedConversions(t *testing.T) {
+	fetcher := &mockLawFetcher{xml: readTestdata(t, "json/chapters.xml"), revision: "rev1", block: make(chan struct{})}
+	s := NewServer(ServerOptions{Fetcher: fetcher, NoCover: true})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		req := httptest.NewRequest(http.MethodGet, "/laws/X.epub", nil).WithContext(ctx)
+		s.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+	waitForMetric(t, s, "jplaw2epub_conversions_in_flight", "1")
+	cancel()
+	<-done
+
+	// The conversion stops although the fetch never completes
+	waitForMetric(t, s, "jplaw2epub_conversions_in_flight", "0")
+
+	// A later request starts a new conversion
+	close(fetcher.block)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/laws/X.epub", nil))
+	assertEPUBResponse(t, rec)
+}
+
+// waitForMetric waits until the metric name has value
+func waitForMetric(t *testing.T, s *Server, name, value string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for metricValue(t, s, name) != value {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s = %s, want %s", name, metricValue(t, s, name), value)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestServerHealthAndMetrics(t *testing.T) {
+	s := NewServer(ServerOptions{})
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "ok\n" {
+		t.Errorf("healthz = %d %q", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), "# TYPE jplaw2epub_requests_total counter\njplaw2epub_requests_total 2\n") {
+		t.Errorf("metrics output:\n%s", rec.Body.String())
+	}
+}
+
+func TestEPUBCache(t *testing.T) {
+	cache := newEPUBCache(2)
+	cache.put("a", []byte("A"))
+	cache.put("b", []byte("B"))
+	cache.get("a")
+	cache.put("c", []byte("C"))
+
+	if _, ok := cache.get("b"); ok {
+		t.Error("least recently used entry should be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := cache.get(key); !ok {
+			t.Errorf("entry %s should be cached", key)
+		}
+	}
+
+	disabled := newEPUBCache(-1)
+	disabled.put("a", []byte("A"))
+	if disabled.len() != 0 {
+		t.Error("disabled cache should store nothing")
+	}
+}
@@ -62,11 +62,13 @@ func (sp *StyleProcessor) ProcessStyleStruct(style *jplaw.StyleStruct) string {
 	return html
 }
 
+// figSrcPattern matches Fig elements embedded in raw Style or Format content
+var figSrcPattern = regexp.MustCompile(`<Fig\s+src="([^"]+)"\s*/>`)
+
 // processStyleContent processes the inner XML content of Style element
 func (sp *StyleProcessor) processStyleContent(content string) string {
 	// Extract Fig elements from the content
-	figPattern := regexp.MustCompile(`<Fig\s+src="([^"]+)"\s*/>`)
-	matches := figPattern.FindAllStringSubmatch(content, -1)
+	matches := figSrcPattern.FindAllStringSubmatch(content, -1)
 
 	if len(matches) == 0 {
 		// No Fig elements, return content as-is (might contain other HTML)
@@ -84,7 +86,7 @@ func (sp *StyleProcessor) processStyleContent(content string) string {
 				Fig: jplaw.Fig{Src: src},
 			}
 
-			if sp.imageProcessor != nil {
+			if hasImages(sp.imageProcessor) {
 				if imgHTML, err := sp.imageProcessor.ProcessFigStruct(fig); err == nil {
 					html += imgHTML
 				}
@@ -97,7 +99,7 @@ func (sp *StyleProcessor) processStyleContent(content string) string {
 	}
 
 	// Check if there's any other content besides Fig elements
-	remainingContent := figPattern.ReplaceAllString(content, "")
+	remainingContent := figSrcPattern.ReplaceAllString(content, "")
 	remainingContent = strings.TrimSpace(remainingContent)
 	if remainingContent != "" {
 		html += fmt.Sprintf(`<div class="style-content">%s</div>`, remainingContent)
@@ -107,6 +107,22 @@ h1, h2, h3, h4, h5, h6 {
     border-left: 2px solid #e0e0e0;
 }
 
+/* Amendment provisions */
+.amend-provision {
+    margin: 0.5em 0;
+}
+
+.new-provision {
+    margin: 0.5em 0 0.5em 2em;
+    padding-left: 1em;
+    border-left: 2px solid #ccc;
+}
+
+.provision-title {
+    font-weight: bold;
+    margin: 0.3em 0;
+}
+
 /* Table styles */
 .table-struct {
     margin: 1em 0;
@@ -192,17 +208,85 @@ strong {
     min-height: 100px;
 }
 
-/* Part and article references in tables */
+/* Parts and articles in tables and amendments */
 .part-title {
     font-weight: bold;
     margin: 0.3em 0;
 }
 
-.article-ref {
+.nested-article {
     margin: 0.2em 0;
     font-size: 0.95em;
 }
 
+.nested-article .article-title {
+    font-weight: bold;
+    margin: 0.2em 0;
+}
+
+/* Search page */
+.search-form input {
+    width: 100%;
+    font-size: 1em;
+}
+
+.search-results li, .keyword-index li {
+    margin: 0.3em 0;
+}
+
+.keyword-index {
+    list-style: none;
+    padding-left: 0;
+}
+
+/* Defined-terms index */
+.definition-index dt {
+    font-weight: bold;
+    margin-top: 0.5em;
+}
+
+.definition-index dd {
+    margin-left: 2em;
+}
+
+a.defined-term {
+    color: inherit;
+    text-decoration: underline dotted;
+}
+
+/* Popup footnotes */
+a.noteref {
+    color: inherit;
+    text-decoration: underline dotted;
+}
+
+.popup-note {
+    font-size: 0.9em;
+    border-top: 1px solid #ccc;
+    margin-top: 1em;
+}
+
+.popup-note-title {
+    font-weight: bold;
+}
+
+.annotation {
+    font-size: 0.9em;
+    border: 1px solid #d4c27a;
+    background-color: #fdf9e6;
+    padding: 0.3em 0.8em;
+    margin: 1em 0;
+}
+
+.annotation-see-also {
+    font-size: 0.95em;
+}
+
+mark.annotation-highlight {
+    background-color: #fff2a8;
+    color: inherit;
+}
+
 /* Print and e-reader specific styles */
 @media print, screen and (max-device-width: 1024px) {
     .figure img {
@@ -3,14 +3,13 @@ package jplaw2epub
 import (
 	"fmt"
 
-	"github.com/go-shiori/go-epub"
 	"go.ngs.io/jplaw-xml"
 )
 
 const defaultSupplProvisionTitle = "附則"
 
 // processSupplProvisions processes supplementary provisions
-func processSupplProvisions(book *epub.Epub, provisions []jplaw.SupplProvision, imgProc ImageProcessorInterface) error {
+func processSupplProvisions(book BookWriter, provisions []jplaw.SupplProvision, imgProc ImageProcessorInterface) error {
 	if len(provisions) == 0 {
 		return nil
 	}
@@ -25,7 +24,7 @@ func processSupplProvisions(book *epub.Epub, provisions []jplaw.SupplProvision,
 }
 
 // processSupplProvision processes a single supplementary provision
-func processSupplProvision(book *epub.Epub, provision *jplaw.SupplProvision, idx int, imgProc ImageProcessorInterface) error {
+func processSupplProvision(book BookWriter, provision *jplaw.SupplProvision, idx int, imgProc ImageProcessorInterface) error {
 	filename := fmt.Sprintf("suppl-provision-%d.xhtml", idx)
 
 	// Build the body content
@@ -49,11 +48,11 @@ func processSupplProvision(book *epub.Epub, provision *jplaw.SupplProvision, idx
 
 // buildSupplProvisionBody builds the HTML body for a supplementary provision
 func buildSupplProvisionBody(provision *jplaw.SupplProvision, imgProc ImageProcessorInterface) string {
-	var body string
+	body := openSection("chapter", "suppl-provision", "")
 
 	// Add title
 	title := getSupplProvisionTitle(provision)
-	body += fmt.Sprintf(`<div class="chapter-title">%s</div>`, processTextWithRuby(title, provision.SupplProvisionLabel.Ruby))
+	body += headingHTML(headingLevelTop, "chapter-title", processTextWithRuby(title, provision.SupplProvisionLabel.Ruby))
 
 	// Add amendment law number if present
 	if provision.AmendLawNum != "" {
@@ -64,7 +63,7 @@ func buildSupplProvisionBody(provision *jplaw.SupplProvision, imgProc ImageProce
 	body += processSupplProvisionChapters(provision, imgProc)
 
 	// Process direct articles
-	body += processSupplProvisionArticles(provision.Article, imgProc)
+	body += processSupplProvisionArticles(provision.Article, headingLevelChapter, imgProc)
 
 	// Process direct paragraphs
 	if len(provision.Paragraph) > 0 {
@@ -74,6 +73,7 @@ func buildSupplProvisionBody(provision *jplaw.SupplProvision, imgProc ImageProce
 	// Process appendixes
 	body += processSupplProvisionAppendixes(provision, imgProc)
 
+	body += htmlSectionEnd
 	return body
 }
 
@@ -94,14 +94,20 @@ func processSupplProvisionChapters(provision *jplaw.SupplProvision, imgProc Imag
 	var body string
 	for i := range provision.Chapter {
 		chapterTitle := processTextWithRuby(provision.Chapter[i].ChapterTitle.Content, provision.Chapter[i].ChapterTitle.Ruby)
-		body += fmt.Sprintf(`<h3>%s</h3>`, chapterTitle)
-		body += processSupplProvisionArticles(provision.Chapter[i].Article, imgProc)
+		id := ""
+		if number, ok := chapterNumber(&provision.Chapter[i]); ok {
+			id = "chap-" + number.String()
+		}
+		body += openSection("subchapter", "chapter", id)
+		body += headingHTML(headingLevelChapter, "", chapterTitle)
+		body += processSupplProvisionArticles(provision.Chapter[i].Article, headingLevelSection, imgProc)
+		body += htmlSectionEnd
 	}
 	return body
 }
 
-// processSupplProvisionArticles processes articles
-func processSupplProvisionArticles(articles []jplaw.Article, imgProc ImageProcessorInterface) string {
+// processSupplProvisionArticles processes articles with headings of the given level
+func processSupplProvisionArticles(articles []jplaw.Article, level int, imgProc ImageProcessorInterface) string {
 	if len(articles) == 0 {
 		return ""
 	}
@@ -110,7 +116,7 @@ func processSupplProvisionArticles(articles []jplaw.Article, imgProc ImageProces
 	for i := range articles {
 		article := &articles[i]
 		articleTitle := buildArticleTitle(article)
-		body += buildArticleBodyWithImages(article, articleTitle, imgProc)
+		body += buildArticleBodyWithImages(article, articleTitle, level, imgProc)
 	}
 	return body
 }
@@ -23,20 +23,20 @@ func processTableStructs(tables []jplaw.TableStruct, imgProc ImageProcessorInter
 }
 
 // processTableStructWithImages processes a single table structure with image support
-func processTableStructWithImages(tableStruct *jplaw.TableStruct, _ ImageProcessorInterface) string {
+func processTableStructWithImages(tableStruct *jplaw.TableStruct, imgProc ImageProcessorInterface) string {
 	var body strings.Builder
 
-	// Add table title if present
+	// The table title becomes the caption of the table
+	caption := ""
 	if tableStruct.TableStructTitle != nil {
-		titleHTML := processTextWithRuby(
+		caption = processTextWithRuby(
 			tableStruct.TableStructTitle.Content,
 			tableStruct.TableStructTitle.Ruby,
 		)
-		body.WriteString(fmt.Sprintf(`<div class="table-title">%s</div>`, titleHTML))
 	}
 
 	// Process the table
-	body.WriteString(processTable(&tableStruct.Table))
+	body.WriteString(processTable(&tableStruct.Table, caption, imgProc))
 
 	// Process remarks if present
 	for i := range tableStruct.Remarks {
@@ -54,8 +54,8 @@ const (
 	borderStyleDouble = "double"
 )
 
-// processTable processes a table element
-func processTable(table *jplaw.Table) string {
+// processTable processes a table element, adding caption as its caption when not empty
+func processTable(table *jplaw.Table, caption string, imgProc ImageProcessorInterface) string {
 	var body strings.Builder
 
 	// Determine table class based on writing mode
@@ -65,6 +65,9 @@ func processTable(table *jplaw.Table) string {
 	}
 
 	body.WriteString(fmt.Sprintf(`<div class="table-container"><table class=%q>`, tableClass))
+	if caption != "" {
+		body.WriteString(fmt.Sprintf(`<caption class="table-title">%s</caption>`, caption))
+	}
 
 	// Process header rows
 	if len(table.TableHeaderRow) > 0 {
@@ -78,7 +81,7 @@ func processTable(table *jplaw.Table) string {
 	// Process regular rows
 	body.WriteString("<tbody>")
 	for i := range table.TableRow {
-		body.WriteString(processTableRow(&table.TableRow[i]))
+		body.WriteString(processTableRow(&table.TableRow[i], imgProc))
 	}
 	body.WriteString("</tbody>")
 
@@ -100,27 +103,31 @@ func processTableHeaderRow(row *jplaw.TableHeaderRow) string {
 }
 
 // processTableRow processes a table row
-func processTableRow(row *jplaw.TableRow) string {
+func processTableRow(row *jplaw.TableRow, imgProc ImageProcessorInterface) string {
 	var body strings.Builder
 	body.WriteString("<tr>")
 
 	for i := range row.TableColumn {
-		body.WriteString(processTableColumn(&row.TableColumn[i]))
+		body.WriteString(processTableColumn(&row.TableColumn[i], imgProc))
 	}
 
 	body.WriteString("</tr>")
 	return body.String()
 }
 
-// processTableHeaderColumn processes a table header column
+// processTableHeaderColumn processes a table header column. Header rows only head the
+// columns below them, so cells are scoped to their column.
 func processTableHeaderColumn(col *jplaw.TableHeaderColumn) string {
-	// TableHeaderColumn has different structure - it has Content directly
+	// TableHeaderColumn has different structure - it has Content directly. The law XML schema
+	// gives it no border, span or alignment attributes; those only exist on TableColumn.
 	content := processTextWithRuby(col.Content, col.Ruby)
-	return fmt.Sprintf("<th>%s</th>", content)
+	return fmt.Sprintf(`<th scope="col">%s</th>`, content)
 }
 
-// processTableColumn processes a table column
-func processTableColumn(col *jplaw.TableColumn) string {
+// processTableColumn processes a table column, rendering structural content such as
+// articles, paragraphs and items in full. The parsed law keeps no chapters or sections
+// inside cells, so those are not rendered; InspectXML reports them as unsupported.
+func processTableColumn(col *jplaw.TableColumn, imgProc ImageProcessorInterface) string {
 	// Build style from border attributes (all strings)
 	style := buildCellStyle(col.BorderTop, col.BorderBottom, col.BorderLeft, col.BorderRight)
 	// Convert string span values to int for the helper function
@@ -142,8 +149,15 @@ func processTableColumn(col *jplaw.TableColumn) string {
 
 	// Process parts
 	for i := range col.Part {
-		content.WriteString(processPartElement(&col.Part[i]))
+		content.WriteString(processPartElement(&col.Part[i], imgProc))
+	}
+
+	// Process articles, paragraphs and items placed directly in the cell
+	for i := range col.Article {
+		content.WriteString(processNestedArticle(&col.Article[i], imgProc))
 	}
+	content.WriteString(processParagraphsWithImages(col.Paragraph, imgProc))
+	content.WriteString(processItemsWithImages(col.Item, imgProc))
 
 	return fmt.Sprintf("<td%s>%s</td>", attrs, content.String())
 }
@@ -163,8 +177,9 @@ func processColumnElement(col *jplaw.Column) string {
 	return content.String()
 }
 
-// processPartElement processes a part element within a table cell
-func processPartElement(part *jplaw.Part) string {
+// processPartElement processes a part element within a table cell, rendering its title and
+// articles, the only content the parsed law keeps of it
+func processPartElement(part *jplaw.Part, imgProc ImageProcessorInterface) string {
 	var content strings.Builder
 
 	// Process part title if present
@@ -175,16 +190,26 @@ func processPartElement(part *jplaw.Part) string {
 
 	// Process articles
 	for i := range part.Article {
-		// For simplicity, just show article titles in tables
-		if part.Article[i].ArticleTitle != nil {
-			content.WriteString(fmt.Sprintf(`<div class="article-ref">%s</div>`,
-				html.EscapeString(part.Article[i].ArticleTitle.Content)))
-		}
+		content.WriteString(processNestedArticle(&part.Article[i], imgProc))
 	}
 
 	return content.String()
 }
 
+// processNestedArticle processes an article quoted within other content, such as a table cell.
+// Comparison tables repeat the same article in several cells, so the article gets no id and its
+// title is not a heading.
+func processNestedArticle(article *jplaw.Article, imgProc ImageProcessorInterface) string {
+	var content strings.Builder
+	content.WriteString(`<div class="nested-article">`)
+	if article.ArticleTitle != nil {
+		content.WriteString(fmt.Sprintf(`<p class="article-title">%s</p>`, buildArticleTitle(article)))
+	}
+	content.WriteString(processParagraphsWithImages(article.Paragraph, imgProc))
+	content.WriteString("</div>")
+	return content.String()
+}
+
 // parseSpan converts a span string to an int pointer
 func parseSpan(span string) *int {
 	if span == "" {
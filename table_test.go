@@ -34,7 +34,7 @@ func TestProcessTableStructs(t *testing.T) {
 					},
 				},
 			},
-			contains: []string{"Test Table", "Cell 1", "table"},
+			contains: []string{`<table class="law-table"><caption class="table-title">Test Table</caption>`, "Cell 1"},
 		},
 	}
 
@@ -89,13 +89,13 @@ func TestProcessTable(t *testing.T) {
 					},
 				},
 			},
-			contains: []string{"<thead", "Header", "Cell"},
+			contains: []string{"<thead", `<th scope="col">Header</th>`, "Cell"},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := processTable(tt.table)
+			result := processTable(tt.table, "", nil)
 
 			for _, expected := range tt.contains {
 				if !strings.Contains(result, expected) {
@@ -132,7 +132,7 @@ func TestProcessTableRow(t *testing.T) {
 		},
 	}
 
-	result := processTableRow(row)
+	result := processTableRow(row, nil)
 
 	expected := []string{"<tr>", "<td", "Cell 1", "Cell 2"}
 	for _, exp := range expected {
@@ -173,11 +173,36 @@ func TestProcessTableColumn(t *testing.T) {
 			},
 			contains: []string{"Cell"},
 		},
+		{
+			name: "Column with article",
+			col: &jplaw.TableColumn{
+				Article: []jplaw.Article{{
+					ArticleTitle: &jplaw.ArticleTitle{Content: "第二条"},
+					Paragraph: []jplaw.Paragraph{{
+						ParagraphSentence: jplaw.ParagraphSentence{Sentence: []jplaw.Sentence{createTestSentence("旧規定の本文")}},
+					}},
+				}},
+			},
+			contains: []string{`<div class="nested-article">`, `<p class="article-title">第二条</p>`, "旧規定の本文"},
+		},
+		{
+			name: "Column with paragraph and items",
+			col: &jplaw.TableColumn{
+				Paragraph: []jplaw.Paragraph{{
+					ParagraphSentence: jplaw.ParagraphSentence{Sentence: []jplaw.Sentence{createTestSentence("次に掲げる者")}},
+				}},
+				Item: []jplaw.Item{{
+					ItemTitle:    &jplaw.ItemTitle{Content: "一"},
+					ItemSentence: jplaw.ItemSentence{Sentence: []jplaw.Sentence{createTestSentence("直接の号")}},
+				}},
+			},
+			contains: []string{"次に掲げる者", "<li", "直接の号"},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := processTableColumn(tt.col)
+			result := processTableColumn(tt.col, nil)
 
 			for _, expected := range tt.contains {
 				if !strings.Contains(result, expected) {
@@ -203,12 +228,31 @@ func TestProcessColumnElement(t *testing.T) {
 func TestProcessPartElement(t *testing.T) {
 	part := &jplaw.Part{
 		PartTitle: jplaw.PartTitle{Content: "Part Title"},
+		Article: []jplaw.Article{{
+			ArticleTitle: &jplaw.ArticleTitle{Content: "第一条"},
+			ArticleCaption: &jplaw.ArticleCaption{
+				Content: "（趣旨）",
+				Ruby:    []jplaw.Ruby{{Content: "趣旨", Rt: []jplaw.Rt{{Content: "しゅし"}}}},
+			},
+			Paragraph: []jplaw.Paragraph{{
+				ParagraphSentence: jplaw.ParagraphSentence{Sentence: []jplaw.Sentence{createTestSentence("この法律の趣旨")}},
+				Item: []jplaw.Item{{
+					ItemTitle:    &jplaw.ItemTitle{Content: "一"},
+					ItemSentence: jplaw.ItemSentence{Sentence: []jplaw.Sentence{createTestSentence("第一号の文")}},
+				}},
+			}},
+		}},
 	}
 
-	result := processPartElement(part)
+	result := processPartElement(part, nil)
 
-	if !strings.Contains(result, "Part Title") {
-		t.Errorf("Expected result to contain 'Part Title', got %s", result)
+	for _, exp := range []string{"Part Title", "第一条", "<rt>しゅし</rt>", "この法律の趣旨", "第一号の文"} {
+		if !strings.Contains(result, exp) {
+			t.Errorf("Expected result to contain %q, got %s", exp, result)
+		}
+	}
+	if strings.Contains(result, "id=") || strings.Contains(result, "<h") {
+		t.Errorf("Articles in table cells should have no ids or headings, got %s", result)
 	}
 }
 
@@ -0,0 +1,116 @@
+package jplaw2epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Theme selects the colours of the EPUB text and page
+type Theme string
+
+// Themes
+const (
+	// ThemeDefault is black text on the reader's background. It is the default.
+	ThemeDefault Theme = "default"
+	// ThemeSepia is dark brown text on a warm paper background
+	ThemeSepia Theme = "sepia"
+	// ThemeDark is light text on a dark background
+	ThemeDark Theme = "dark"
+)
+
+// themeCSSPath is the location of the theme stylesheet in the EPUB
+const themeCSSPath = "EPUB/css/theme.css"
+
+// themeCSS are the stylesheets of the themes other than ThemeDefault
+var themeCSS = map[Theme]string{
+	ThemeSepia: `body {
+    color: #4b3a26;
+    background-color: #f6efdc;
+}
+
+a, a.defined-term, a.noteref {
+    color: #7a4b14;
+}
+
+.law-table td, .law-table th, .new-provision, .annotation {
+    border-color: #bfa67a;
+}
+
+.law-table th, .annotation {
+    background-color: #efe3c6;
+}
+`,
+	ThemeDark: `body {
+    color: #e0e0e0;
+    background-color: #1e1e1e;
+}
+
+a, a.defined-term, a.noteref {
+    color: #8ab4f8;
+}
+
+.law-table td, .law-table th, .new-provision, .annotation {
+    border-color: #555;
+}
+
+.law-table th, .annotation {
+    background-color: #2b2b2b;
+}
+
+mark.annotation-highlight {
+    background-color: #5c5020;
+    color: inherit;
+}
+`,
+}
+
+// ParseTheme parses a theme name, returning ThemeDefault for an empty string
+func ParseTheme(s string) (Theme, error) {
+	switch theme := Theme(s); theme {
+	case "":
+		return ThemeDefault, nil
+	case ThemeDefault, ThemeSepia, ThemeDark:
+		return theme, nil
+	default:
+		return "", fmt.Errorf("unknown theme %q (want default, sepia or dark)", s)
+	}
+}
+
+// addThemeStylesheet adds the stylesheet of theme, linked from every content document
+func addThemeStylesheet(archive *epubArchive, theme Theme) error {
+	css, ok := themeCSS[theme]
+	if !ok {
+		return nil
+	}
+	return addLinkedStylesheet(archive, themeCSSPath, "jplaw2epub-theme-css", css)
+}
+
+// addLinkedStylesheet adds a stylesheet to the manifest and links it from every content document
+func addLinkedStylesheet(archive *epubArchive, cssPath, id, css string) error {
+	pkg := archive.file(epubPackagePath)
+	if pkg == nil {
+		return fmt.Errorf("package document %s not found", epubPackagePath)
+	}
+
+	cssHref, err := relativeManifestHref(cssPath)
+	if err != nil {
+		return err
+	}
+	for _, f := range archive.files {
+		if path.Ext(f.name) != ".xhtml" {
+			continue
+		}
+		depth := strings.Count(strings.TrimPrefix(path.Dir(f.name), path.Dir(epubPackagePath)), "/")
+		link := fmt.Sprintf(`<link rel="stylesheet" type="text/css" href="%s%s"/>`, strings.Repeat("../", depth), cssHref)
+		f.data = bytes.Replace(f.data, []byte("</head>"), []byte(link+"\n  </head>"), 1)
+	}
+
+	item := fmt.Sprintf(`  <item id="%s" href="%s" media-type="text/css"></item>
+  </manifest>`, id, cssHref)
+	pkg.data = bytes.Replace(pkg.data, []byte("</manifest>"), []byte(item), 1)
+	archive.files = append(archive.files, &epubArchiveFile{name: cssPath, method: zip.Deflate, data: []byte(css)})
+	return nil
+}
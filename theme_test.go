@@ -0,0 +1,109 @@
+package jplaw2epub
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseTheme(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Theme
+		wantErr bool
+	}{
+		{"", ThemeDefault, false},
+		{"default", ThemeDefault, false},
+		{"sepia", ThemeSepia, false},
+		{"dark", ThemeDark, false},
+		{"Dark", "", true},
+		{"neon", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseTheme(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTheme(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseTheme(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThemeInEPUB(t *testing.T) {
+	tests := []struct {
+		theme     Theme
+		wantSheet bool
+	}{
+		{ThemeDefault, false},
+		{ThemeSepia, true},
+		{ThemeDark, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.theme), func(t *testing.T) {
+			opts := &EPUBOptions{NoCover: true, Theme: tt.theme}
+			book, err := CreateEPUBFromXMLFileWithOptions(bytes.NewReader(readTestdata(t, "json/articles.xml")), opts)
+			if err != nil {
+				t.Fatalf("CreateEPUBFromXMLFileWithOptions() error = %v", err)
+			}
+			var buf bytes.Buffer
+			if err := WriteEPUBToWithOptions(book, &buf, opts); err != nil {
+				t.Fatalf("WriteEPUBToWithOptions() error = %v", err)
+			}
+			archive, err := readEPUBArchive(buf.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := archive.file(themeCSSPath) != nil; got != tt.wantSheet {
+				t.Fatalf("theme stylesheet present = %v, want %v", got, tt.wantSheet)
+			}
+			linked := 0
+			for _, f := range archive.files {
+				if strings.Contains(string(f.data), `href="css/theme.css"`) || strings.Contains(string(f.data), `href="../css/theme.css"`) {
+					linked++
+				}
+			}
+			if tt.wantSheet && linked == 0 {
+				t.Error("theme stylesheet is not linked")
+			}
+			if issues := validateTestArchive(t, archive); len(issues) > 0 {
+				t.Errorf("validation issues: %v", issues)
+			}
+		})
+	}
+}
+
+func TestMetadataOverridesInEPUB(t *testing.T) {
+	opts := &EPUBOptions{
+		NoCover: true,
+		Metadata: MetadataOverrides{
+			Title:     "注釈付き法令",
+			Author:    "編者",
+			Publisher: "出版社 & 編集部",
+		},
+	}
+	book, err := NewBookFromXMLFile(context.Background(), bytes.NewReader(readTestdata(t, "json/articles.xml")), opts)
+	if err != nil {
+		t.Fatalf("NewBookFromXMLFile() error = %v", err)
+	}
+	if book.Title() != "注釈付き法令" || book.Author() != "編者" {
+		t.Errorf("title = %q, author = %q", book.Title(), book.Author())
+	}
+	var buf bytes.Buffer
+	if _, err := book.WriteTo(&buf); err != nil {
+		t.Fatalf("Book.WriteTo() error = %v", err)
+	}
+	archive, err := readEPUBArchive(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg := string(archive.file(epubPackagePath).data); !strings.Contains(pkg, "<dc:publisher>出版社 &amp; 編集部</dc:publisher>") {
+		t.Errorf("package document missing publisher override:\n%s", pkg)
+	}
+}
@@ -5,15 +5,15 @@ import (
 	"html"
 	"strings"
 
-	"github.com/go-shiori/go-epub"
 	"go.ngs.io/jplaw-xml"
 )
 
-// addTitlePage adds a title page as the first page of the EPUB
-func addTitlePage(book *epub.Epub, data *jplaw.Law) error {
+// addTitlePage adds a title page as the first page of the EPUB, showing dates as display selects.
+// A non-empty excerpt records that the book holds only part of the law.
+func addTitlePage(book BookWriter, data *jplaw.Law, display DateDisplay, excerpt string) error {
 	// Build title page content
 	var body strings.Builder
-	body.WriteString(`<div style="text-align: center; margin-top: 20%;">`)
+	body.WriteString(`<section epub:type="titlepage" style="text-align: center; margin-top: 20%;">`)
 
 	// Law title with ruby if available
 	body.WriteString(`<h1 style="font-size: 1.5em; margin-bottom: 1em;">`)
@@ -30,14 +30,20 @@ func addTitlePage(book *epub.Epub, data *jplaw.Law) error {
 	body.WriteString(`</p>`)
 
 	// Promulgation date
-	eraStr := getEraString(data.Era)
 	body.WriteString(`<p style="margin-bottom: 0.5em;">`)
-	body.WriteString(fmt.Sprintf("公布日: %s%d年%d月%d日", eraStr, data.Year, data.PromulgateMonth, data.PromulgateDay))
+	body.WriteString("公布日: " + promulgationDate(data).Format(display))
 	body.WriteString(`</p>`)
 
+	// Range of an excerpt
+	if excerpt != "" {
+		body.WriteString(`<p class="excerpt" style="margin-top: 1em;">`)
+		body.WriteString(html.EscapeString(excerpt))
+		body.WriteString(`</p>`)
+	}
+
 	// Enact statement if present
 	if len(data.LawBody.EnactStatement) > 0 && data.LawBody.EnactStatement[0].Content != "" {
-		body.WriteString(`<div style="margin-top: 3em; text-align: left; padding: 0 10%;">`)
+		body.WriteString(`<section epub:type="preamble" class="enact-statement" style="margin-top: 3em; text-align: left; padding: 0 10%;">`)
 		body.WriteString(`<p style="text-indent: 1em;">`)
 		enactStmt := &data.LawBody.EnactStatement[0]
 		if len(enactStmt.Ruby) > 0 {
@@ -46,10 +52,10 @@ func addTitlePage(book *epub.Epub, data *jplaw.Law) error {
 			body.WriteString(html.EscapeString(enactStmt.Content))
 		}
 		body.WriteString(`</p>`)
-		body.WriteString(`</div>`)
+		body.WriteString(htmlSectionEnd)
 	}
 
-	body.WriteString(`</div>`)
+	body.WriteString(htmlSectionEnd)
 
 	// Add the title page as the first section
 	_, err := book.AddSection(body.String(), "タイトルページ", "title.xhtml", "")
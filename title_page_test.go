@@ -101,7 +101,7 @@ func TestAddTitlePage(t *testing.T) {
 			}
 
 			// Call the function
-			err = addTitlePage(book, tt.data)
+			err = addTitlePage(book, tt.data, DateDisplayJapanese, "")
 
 			// Check error
 			if (err != nil) != tt.wantErr {
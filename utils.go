@@ -101,3 +101,48 @@ func getEraString(era jplaw.Era) string {
 		return ""
 	}
 }
+
+// formatKanjiNumber formats a positive integer as kanji numerals (e.g. 123 -> 百二十三)
+func formatKanjiNumber(n int) string {
+	if n <= 0 {
+		return "〇"
+	}
+
+	digits := []string{"", "一", "二", "三", "四", "五", "六", "七", "八", "九"}
+	smallUnits := []string{"", "十", "百", "千"}
+	largeUnits := []string{"", "万", "億", "兆"}
+
+	var groups []string
+	for group := 0; n > 0 && group < len(largeUnits); group++ {
+		chunk := n % 10000
+		n /= 10000
+		if chunk == 0 {
+			continue
+		}
+
+		var s strings.Builder
+		for pos := 3; pos >= 0; pos-- {
+			d := chunk / pow10(pos) % 10
+			if d == 0 {
+				continue
+			}
+			// 十, 百 and 千 are written without a leading 一
+			if d != 1 || pos == 0 {
+				s.WriteString(digits[d])
+			}
+			s.WriteString(smallUnits[pos])
+		}
+		groups = append([]string{s.String() + largeUnits[group]}, groups...)
+	}
+
+	return strings.Join(groups, "")
+}
+
+// pow10 returns 10 to the power of exp
+func pow10(exp int) int {
+	result := 1
+	for i := 0; i < exp; i++ {
+		result *= 10
+	}
+	return result
+}
@@ -54,3 +54,28 @@ func TestGetListStyleType(t *testing.T) {
 		})
 	}
 }
+
+func TestFormatKanjiNumber(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{1, "一"},
+		{10, "十"},
+		{11, "十一"},
+		{20, "二十"},
+		{100, "百"},
+		{123, "百二十三"},
+		{1000, "千"},
+		{2024, "二千二十四"},
+		{10000, "一万"},
+		{10001, "一万一"},
+		{0, "〇"},
+	}
+
+	for _, tt := range tests {
+		if got := formatKanjiNumber(tt.n); got != tt.want {
+			t.Errorf("formatKanjiNumber(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,406 @@
+package jplaw2epub
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// xhtmlNamespace is the namespace of XHTML elements
+const xhtmlNamespace = "http://www.w3.org/1999/xhtml"
+
+// ValidationIssue is a problem found in an EPUB by ValidateEPUB
+type ValidationIssue struct {
+	// File is the archive entry the problem is in
+	File string
+	// Line is the line in File, or 0 when the problem concerns the whole entry
+	Line    int
+	Message string
+}
+
+// String formats the issue as file:line: message
+func (i ValidationIssue) String() string {
+	if i.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", i.File, i.Line, i.Message)
+	}
+	return fmt.Sprintf("%s: %s", i.File, i.Message)
+}
+
+// ValidationError is returned by the writers, which then write nothing, when
+// EPUBOptions.Validate is set and the EPUB has problems
+type ValidationError struct {
+	Issues []ValidationIssue
+}
+
+// Error summarizes the issues
+func (e *ValidationError) Error() string {
+	if len(e.Issues) == 1 {
+		return "EPUB validation found a problem: " + e.Issues[0].String()
+	}
+	return fmt.Sprintf("EPUB validation found %d problems, the first being %s", len(e.Issues), e.Issues[0])
+}
+
+// validateOutput validates a rendered EPUB, before it is written, when opts ask for it
+func validateOutput(data []byte, opts *EPUBOptions) error {
+	if opts == nil || !opts.Validate {
+		return nil
+	}
+	issues, err := ValidateEPUB(data)
+	if err != nil {
+		return err
+	}
+	if len(issues) > 0 {
+		return &ValidationError{Issues: issues}
+	}
+	return nil
+}
+
+// ValidateEPUBFile validates the EPUB at path with ValidateEPUB
+func ValidateEPUBFile(path string) ([]ValidationIssue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading EPUB: %w", err)
+	}
+	return ValidateEPUB(data)
+}
+
+// ValidateEPUB checks an EPUB for the problems that make readers reject it: a malformed
+// container, manifest entries without files and files missing from the manifest, content
+// documents that are not well-formed XHTML or nest elements invalidly (e.g. <ol> inside <p>),
+// and links or images pointing to missing files or ids. It returns an error only when data
+// is not a ZIP archive.
+func ValidateEPUB(data []byte) ([]ValidationIssue, error) {
+	archive, err := readEPUBArchive(data)
+	if err != nil {
+		return nil, err
+	}
+	v := &epubValidator{archive: archive, ids: make(map[string]map[string]bool)}
+	v.validate()
+	return v.issues, nil
+}
+
+// epubValidator collects the issues of an archive
+type epubValidator struct {
+	archive *epubArchive
+	issues  []ValidationIssue
+	// ids holds the ids of each content document
+	ids map[string]map[string]bool
+	// links are the references found in content documents, resolved once all ids are known
+	links []xhtmlLink
+}
+
+// xhtmlLink is a reference from a content document
+type xhtmlLink struct {
+	file   string
+	line   int
+	target string
+	image  bool
+}
+
+// manifestItem is an item of the package manifest
+type manifestItem struct {
+	ID         string `xml:"id,attr"`
+	Href       string `xml:"href,attr"`
+	MediaType  string `xml:"media-type,attr"`
+	Properties string `xml:"properties,attr"`
+}
+
+// packageDocument is the part of the package document the validator reads
+type packageDocument struct {
+	Manifest struct {
+		Items []manifestItem `xml:"item"`
+	} `xml:"manifest"`
+	Spine struct {
+		ItemRefs []struct {
+			IDRef string `xml:"idref,attr"`
+		} `xml:"itemref"`
+	} `xml:"spine"`
+}
+
+// report records an issue
+func (v *epubValidator) report(file string, line int, format string, args ...any) {
+	v.issues = append(v.issues, ValidationIssue{File: file, Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+// validate runs all checks
+func (v *epubValidator) validate() {
+	if len(v.archive.files) == 0 || v.archive.files[0].name != "mimetype" {
+		v.report("mimetype", 0, "the mimetype file must be the first entry of the archive")
+	} else if string(v.archive.files[0].data) != "application/epub+zip" {
+		v.report("mimetype", 0, "content must be application/epub+zip")
+	}
+
+	pkgPath := v.rootfile()
+	if pkgPath == "" {
+		return
+	}
+	items := v.validatePackage(pkgPath)
+
+	for _, item := range items {
+		if item.MediaType == "application/xhtml+xml" {
+			name := resolveArchivePath(pkgPath, item.Href)
+			if f := v.archive.file(name); f != nil {
+				v.validateXHTML(f)
+			}
+		}
+	}
+	v.validateLinks()
+
+	sort.SliceStable(v.issues, func(i, j int) bool {
+		return v.issues[i].File < v.issues[j].File
+	})
+}
+
+// rootfile returns the package document named by META-INF/container.xml
+func (v *epubValidator) rootfile() string {
+	const containerPath = "META-INF/container.xml"
+	container := v.archive.file(containerPath)
+	if container == nil {
+		v.report(containerPath, 0, "missing container file")
+		return ""
+	}
+	var doc struct {
+		Rootfiles []struct {
+			FullPath string `xml:"full-path,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}
+	if err := xml.Unmarshal(container.data, &doc); err != nil {
+		v.report(containerPath, 0, "not well-formed: %v", err)
+		return ""
+	}
+	if len(doc.Rootfiles) == 0 {
+		v.report(containerPath, 0, "no rootfile")
+		return ""
+	}
+	pkgPath := doc.Rootfiles[0].FullPath
+	if v.archive.file(pkgPath) == nil {
+		v.report(containerPath, 0, "rootfile %s not found", pkgPath)
+		return ""
+	}
+	return pkgPath
+}
+
+// validatePackage checks the manifest and spine, returning the manifest items
+func (v *epubValidator) validatePackage(pkgPath string) []manifestItem {
+	var pkg packageDocument
+	if err := xml.Unmarshal(v.archive.file(pkgPath).data, &pkg); err != nil {
+		v.report(pkgPath, 0, "not well-formed: %v", err)
+		return nil
+	}
+
+	ids := make(map[string]bool)
+	listed := map[string]bool{pkgPath: true}
+	navs := 0
+	for _, item := range pkg.Manifest.Items {
+		if ids[item.ID] {
+			v.report(pkgPath, 0, "duplicate manifest id %q", item.ID)
+		}
+		ids[item.ID] = true
+		if strings.Contains(" "+item.Properties+" ", " nav ") {
+			navs++
+		}
+		name := resolveArchivePath(pkgPath, item.Href)
+		listed[name] = true
+		if v.archive.file(name) == nil {
+			v.report(pkgPath, 0, "manifest item %q refers to missing file %s", item.ID, name)
+		}
+	}
+	if navs != 1 {
+		v.report(pkgPath, 0, "the manifest must have exactly one nav item, found %d", navs)
+	}
+
+	for _, ref := range pkg.Spine.ItemRefs {
+		if !ids[ref.IDRef] {
+			v.report(pkgPath, 0, "spine item %q is not in the manifest", ref.IDRef)
+		}
+	}
+
+	for _, f := range v.archive.files {
+		if f.name == "mimetype" || strings.HasPrefix(f.name, "META-INF/") || strings.HasSuffix(f.name, "/") {
+			continue
+		}
+		if !listed[f.name] {
+			v.report(f.name, 0, "file is not listed in the manifest")
+		}
+	}
+	return pkg.Manifest.Items
+}
+
+// resolveArchivePath resolves a relative reference from the archive entry base
+func resolveArchivePath(base, ref string) string {
+	if unescaped, err := url.PathUnescape(ref); err == nil {
+		ref = unescaped
+	}
+	return path.Join(path.Dir(base), ref)
+}
+
+// htmlElements are the elements of HTML; anything else in the XHTML namespace is an error
+var htmlElements = toSet(`a abbr address area article aside audio b base bdi bdo blockquote body br button
+	canvas caption cite code col colgroup data datalist dd del details dfn dialog div dl dt em embed
+	fieldset figcaption figure footer form h1 h2 h3 h4 h5 h6 head header hgroup hr html i iframe img
+	input ins kbd label legend li link main map mark meta meter nav noscript object ol optgroup option
+	output p param picture pre progress q rb rp rt rtc ruby s samp script section select small source
+	span strong style sub summary sup table tbody td template textarea tfoot th thead time title tr
+	track u ul var video wbr`)
+
+// phrasingParents are the elements whose content may only be phrasing content
+var phrasingParents = toSet(`abbr b bdi bdo button cite code data dfn em h1 h2 h3 h4 h5 h6 i kbd label
+	legend mark p pre q rb rp rt ruby s samp small span strong sub summary sup time u var`)
+
+// blockElements are the flow elements that are not phrasing content
+var blockElements = toSet(`address article aside blockquote dd details dialog div dl dt fieldset
+	figcaption figure footer form h1 h2 h3 h4 h5 h6 header hgroup hr li main nav ol p pre section
+	table tbody td tfoot th thead tr ul`)
+
+// requiredParents restricts the parents of elements that only belong in lists, tables and ruby
+var requiredParents = map[string]map[string]bool{
+	"li":       toSet("ol ul menu"),
+	"dt":       toSet("dl div"),
+	"dd":       toSet("dl div"),
+	"tr":       toSet("table thead tbody tfoot"),
+	"td":       toSet("tr"),
+	"th":       toSet("tr"),
+	"thead":    toSet("table"),
+	"tbody":    toSet("table"),
+	"tfoot":    toSet("table"),
+	"caption":  toSet("table"),
+	"colgroup": toSet("table"),
+	"rt":       toSet("ruby rtc"),
+	"rp":       toSet("ruby rtc"),
+}
+
+// allowedChildren restricts the children of lists and tables
+var allowedChildren = map[string]map[string]bool{
+	"ol":    toSet("li script template"),
+	"ul":    toSet("li script template"),
+	"table": toSet("caption colgroup thead tbody tfoot tr script template"),
+	"tr":    toSet("td th script template"),
+}
+
+// toSet returns the whitespace-separated words of s as a set
+func toSet(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(s) {
+		set[word] = true
+	}
+	return set
+}
+
+// validateXHTML checks that a content document is well-formed XHTML with valid nesting,
+// recording its ids and links
+func (v *epubValidator) validateXHTML(f *epubArchiveFile) {
+	ids := make(map[string]bool)
+	v.ids[f.name] = ids
+
+	decoder := xml.NewDecoder(bytes.NewReader(f.data))
+	var stack []string
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var syntaxErr *xml.SyntaxError
+			line := 0
+			if errors.As(err, &syntaxErr) {
+				line = syntaxErr.Line
+			}
+			v.report(f.name, line, "not well-formed: %v", err)
+			return
+		}
+		line, _ := decoder.InputPos()
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			name := t.Name.Local
+			foreign := t.Name.Space != xhtmlNamespace
+			for _, ancestor := range stack {
+				if ancestor == "" {
+					foreign = true
+				}
+			}
+			if foreign {
+				// SVG and MathML content is not checked
+				stack = append(stack, "")
+				continue
+			}
+
+			parent := ""
+			if len(stack) > 0 {
+				parent = stack[len(stack)-1]
+			}
+			v.checkNesting(f.name, line, name, parent)
+			stack = append(stack, name)
+
+			for _, attr := range t.Attr {
+				switch {
+				case attr.Name.Local == "id" && attr.Name.Space == "":
+					if ids[attr.Value] {
+						v.report(f.name, line, "duplicate id %q", attr.Value)
+					}
+					ids[attr.Value] = true
+				case attr.Name.Local == "href" && (name == "a" || name == "link" || name == "area"):
+					v.links = append(v.links, xhtmlLink{file: f.name, line: line, target: attr.Value})
+				case attr.Name.Local == "src" && (name == "img" || name == "script" || name == "source" || name == "audio" || name == "video"):
+					v.links = append(v.links, xhtmlLink{file: f.name, line: line, target: attr.Value, image: name == "img"})
+				}
+			}
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		}
+	}
+}
+
+// checkNesting reports an element its parent cannot contain
+func (v *epubValidator) checkNesting(file string, line int, name, parent string) {
+	switch {
+	case !htmlElements[name]:
+		v.report(file, line, "unknown element <%s>", name)
+	case parent == "":
+		if name != "html" {
+			v.report(file, line, "root element must be <html>, not <%s>", name)
+		}
+	case phrasingParents[parent] && blockElements[name]:
+		v.report(file, line, "<%s> inside <%s>", name, parent)
+	case requiredParents[name] != nil && !requiredParents[name][parent]:
+		v.report(file, line, "<%s> inside <%s>", name, parent)
+	case allowedChildren[parent] != nil && !allowedChildren[parent][name]:
+		v.report(file, line, "<%s> inside <%s>", name, parent)
+	}
+}
+
+// validateLinks reports links to missing files and ids
+func (v *epubValidator) validateLinks() {
+	for _, link := range v.links {
+		target, fragment, _ := strings.Cut(link.target, "#")
+		if target == "" && fragment == "" {
+			continue
+		}
+		if u, err := url.Parse(target); err != nil || u.Scheme != "" || strings.HasPrefix(target, "//") {
+			continue
+		}
+
+		name := link.file
+		if target != "" {
+			name = resolveArchivePath(link.file, strings.SplitN(target, "?", 2)[0])
+		}
+		if v.archive.file(name) == nil {
+			if link.image {
+				v.report(link.file, link.line, "missing image %s", name)
+			} else {
+				v.report(link.file, link.line, "link to missing file %s", name)
+			}
+			continue
+		}
+		if ids, ok := v.ids[name]; ok && fragment != "" && !ids[fragment] {
+			v.report(link.file, link.line, "link to missing id %s#%s", name, fragment)
+		}
+	}
+}
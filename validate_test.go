@@ -0,0 +1,157 @@
+package jplaw2epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testEPUBArchive converts the chapters fixture and loads the written archive
+func testEPUBArchive(t *testing.T) *epubArchive {
+	t.Helper()
+	book, err := CreateEPUBFromXMLFileWithOptions(bytes.NewReader(readTestdata(t, "json/chapters.xml")), &EPUBOptions{NoCover: true})
+	if err != nil {
+		t.Fatalf("CreateEPUBFromXMLFileWithOptions() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := WriteEPUBTo(book, &buf); err != nil {
+		t.Fatalf("WriteEPUBTo() error = %v", err)
+	}
+	archive, err := readEPUBArchive(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return archive
+}
+
+// validateTestArchive writes archive and validates it
+func validateTestArchive(t *testing.T, archive *epubArchive) []ValidationIssue {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := archive.writeTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	issues, err := ValidateEPUB(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ValidateEPUB() error = %v", err)
+	}
+	return issues
+}
+
+func TestValidateEPUB(t *testing.T) {
+	const articlePath = "EPUB/xhtml/article-0-0.xhtml"
+
+	tests := []struct {
+		name   string
+		modify func(archive *epubArchive)
+		want   []string
+	}{
+		{
+			name:   "generated book",
+			modify: func(*epubArchive) {},
+		},
+		{
+			name: "not well-formed",
+			modify: func(archive *epubArchive) {
+				f := archive.file(articlePath)
+				f.data = bytes.Replace(f.data, []byte("</h3>"), []byte("</h4>"), 1)
+			},
+			want: []string{articlePath + ":"},
+		},
+		{
+			name: "invalid nesting",
+			modify: func(archive *epubArchive) {
+				f := archive.file(articlePath)
+				f.data = bytes.Replace(f.data, []byte("</body>"), []byte("<p><ol><li>一</li></ol></p><ul><p>二</p></ul><Fig src=\"a.jpg\"/></body>"), 1)
+			},
+			want: []string{"<ol> inside <p>", "<p> inside <ul>", "unknown element <Fig>"},
+		},
+		{
+			name: "unresolved links",
+			modify: func(archive *epubArchive) {
+				f := archive.file(articlePath)
+				f.data = bytes.Replace(f.data, []byte("</body>"),
+					[]byte(`<p><a href="missing.xhtml">a</a><a href="article-0-1.xhtml#nowhere">b</a><a href="https://example.com/">c</a><img src="../images/missing.png" alt=""/></p></body>`), 1)
+			},
+			want: []string{"link to missing file EPUB/xhtml/missing.xhtml", "link to missing id EPUB/xhtml/article-0-1.xhtml#nowhere", "missing image EPUB/images/missing.png"},
+		},
+		{
+			name: "manifest inconsistency",
+			modify: func(archive *epubArchive) {
+				pkg := archive.file(epubPackagePath)
+				pkg.data = bytes.Replace(pkg.data, []byte("</manifest>"), []byte(`<item id="ghost" href="xhtml/ghost.xhtml" media-type="application/xhtml+xml"></item></manifest>`), 1)
+				archive.files = append(archive.files, &epubArchiveFile{name: "EPUB/xhtml/extra.xhtml", method: zip.Deflate, data: []byte("<html/>")})
+			},
+			want: []string{`manifest item "ghost" refers to missing file EPUB/xhtml/ghost.xhtml`, "EPUB/xhtml/extra.xhtml: file is not listed in the manifest"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := testEPUBArchive(t)
+			tt.modify(archive)
+			issues := validateTestArchive(t, archive)
+
+			var messages []string
+			for _, issue := range issues {
+				messages = append(messages, issue.String())
+			}
+			got := strings.Join(messages, "\n")
+			if len(tt.want) == 0 && len(issues) > 0 {
+				t.Fatalf("ValidateEPUB() found problems in a valid book:\n%s", got)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("ValidateEPUB() issues missing %q:\n%s", want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateEPUBNotZip(t *testing.T) {
+	if _, err := ValidateEPUB([]byte("not an EPUB")); err == nil {
+		t.Error("ValidateEPUB() should fail for data that is not a ZIP archive")
+	}
+}
+
+func TestWriteEPUBValidate(t *testing.T) {
+	opts := &EPUBOptions{Validate: true, NoCover: true}
+	book, err := CreateEPUBFromXMLFileWithOptions(bytes.NewReader(readTestdata(t, "json/chapters.xml")), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteEPUBToWithOptions(book, new(bytes.Buffer), opts); err != nil {
+		t.Errorf("WriteEPUBToWithOptions() error = %v, want a valid EPUB", err)
+	}
+
+	// EPUBs with problems are not written
+	if _, err := book.AddSection("<p><ol><li>項目</li></ol></p>", "不正", "invalid.xhtml", ""); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	err = WriteEPUBToWithOptions(book, &buf, opts)
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Errorf("WriteEPUBToWithOptions() error = %v, want a *ValidationError", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("WriteEPUBToWithOptions() wrote %d bytes of an invalid EPUB", buf.Len())
+	}
+	destPath := filepath.Join(t.TempDir(), "out", "law.epub")
+	if err := WriteEPUBWithOptions(book, destPath, opts); !errors.As(err, &validationErr) {
+		t.Errorf("WriteEPUBWithOptions() error = %v, want a *ValidationError", err)
+	}
+	if _, err := os.Stat(filepath.Dir(destPath)); !os.IsNotExist(err) {
+		t.Errorf("WriteEPUBWithOptions() should not create %s for an invalid EPUB", filepath.Dir(destPath))
+	}
+
+	err = validateOutput([]byte("not an EPUB"), opts)
+	if err == nil || errors.As(err, &validationErr) {
+		t.Errorf("validateOutput() error = %v, want an archive error", err)
+	}
+}
@@ -0,0 +1,535 @@
+package jplaw2epub
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"regexp"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// WideTableStrategy selects how tables with more columns than EPUBOptions.WideTableColumns are laid out
+type WideTableStrategy string
+
+// Wide table strategies
+const (
+	// WideTableScroll keeps the table in a horizontally scrolling container. It is the default.
+	WideTableScroll WideTableStrategy = "scroll"
+	// WideTableStack lays out each row as a list of header and value pairs for narrow screens
+	WideTableStack WideTableStrategy = "stack"
+	// WideTableImage rasterises the table as an image, with the table text as its alternative text
+	WideTableImage WideTableStrategy = "image"
+)
+
+// defaultWideTableColumns is the number of columns above which a table is wide
+const defaultWideTableColumns = 6
+
+// ParseWideTableStrategy parses a wide table strategy, returning WideTableScroll for an empty string
+func ParseWideTableStrategy(s string) (WideTableStrategy, error) {
+	switch strategy := WideTableStrategy(s); strategy {
+	case "":
+		return WideTableScroll, nil
+	case WideTableScroll, WideTableStack, WideTableImage:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("unknown wide table strategy %q (want scroll, stack or image)", s)
+	}
+}
+
+// Layout of rasterised tables, in pixels
+const (
+	tableImageFontSize   = 16
+	tableImagePadding    = 6
+	tableImageMaxColumn  = 320
+	tableImageLineHeight = tableImageFontSize * 3 / 2
+)
+
+var (
+	tableImageBorder     = color.RGBA{0x33, 0x33, 0x33, 0xff}
+	tableImageHeaderFill = color.RGBA{0xf0, 0xf0, 0xf0, 0xff}
+)
+
+// tableContainerStart starts the tables processTable renders
+const tableContainerStart = `<div class="table-container"><table`
+
+// divTagPattern matches the start and end tags of div elements
+var divTagPattern = regexp.MustCompile(`<(/?)div\b[^>]*>`)
+
+// findTableContainers returns the start and end offsets of the outermost tables processTable
+// renders in body. Tables nested in their cells are part of the outer table.
+func findTableContainers(body string) [][2]int {
+	var matches [][2]int
+	offset := 0
+	for {
+		start := strings.Index(body[offset:], tableContainerStart)
+		if start < 0 {
+			return matches
+		}
+		start += offset
+		end := divEnd(body, start)
+		if end < 0 {
+			return matches
+		}
+		matches = append(matches, [2]int{start, end})
+		offset = end
+	}
+}
+
+// divEnd returns the offset after the end tag of the div starting at start, or -1 when it is not closed
+func divEnd(body string, start int) int {
+	depth := 0
+	for offset := start; ; {
+		loc := divTagPattern.FindStringSubmatchIndex(body[offset:])
+		if loc == nil {
+			return -1
+		}
+		tag := body[offset+loc[0] : offset+loc[1]]
+		switch {
+		case loc[3] > loc[2]:
+			depth--
+		case !strings.HasSuffix(tag, "/>"):
+			depth++
+		}
+		offset += loc[1]
+		if depth == 0 {
+			return offset
+		}
+	}
+}
+
+// htmlTableCell is a cell of a rendered table
+type htmlTableCell struct {
+	header  bool
+	rowspan int
+	colspan int
+	// content is the inner HTML of the cell
+	content string
+}
+
+// htmlTable is a rendered table read back for re-layout
+type htmlTable struct {
+	// caption is the inner HTML of the caption
+	caption string
+	rows    [][]*htmlTableCell
+	// headerRows is the number of leading rows from the thead
+	headerRows int
+}
+
+// parseHTMLTable reads a table rendered by processTable
+func parseHTMLTable(fragment string) (*htmlTable, error) {
+	decoder := xml.NewDecoder(strings.NewReader(fragment))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+
+	table := &htmlTable{}
+	var cell *htmlTableCell
+	inHead := false
+	contentStart := int64(0)
+	// depth counts the open table elements; those of tables nested in cells are cell content
+	depth := 0
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading table: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "table" {
+				depth++
+			}
+			if depth != 1 {
+				continue
+			}
+			switch t.Name.Local {
+			case "thead":
+				inHead = true
+			case "tr":
+				table.rows = append(table.rows, nil)
+				if inHead {
+					table.headerRows++
+				}
+			case "td", "th":
+				if len(table.rows) == 0 {
+					return nil, errors.New("reading table: cell outside a row")
+				}
+				cell = &htmlTableCell{header: t.Name.Local == "th", rowspan: 1, colspan: 1}
+				if span := parseSpan(xmlAttr(&t, "rowspan")); span != nil {
+					cell.rowspan = *span
+				}
+				if span := parseSpan(xmlAttr(&t, "colspan")); span != nil {
+					cell.colspan = *span
+				}
+				contentStart = decoder.InputOffset()
+			case "caption":
+				contentStart = decoder.InputOffset()
+			}
+		case xml.EndElement:
+			if t.Name.Local == "table" {
+				depth--
+			}
+			if depth != 1 {
+				continue
+			}
+			switch t.Name.Local {
+			case "thead":
+				inHead = false
+			case "td", "th":
+				if cell != nil {
+					cell.content = fragment[contentStart:offset]
+					table.rows[len(table.rows)-1] = append(table.rows[len(table.rows)-1], cell)
+					cell = nil
+				}
+			case "caption":
+				table.caption = fragment[contentStart:offset]
+			}
+		}
+	}
+	return table, nil
+}
+
+// grid places the cells on a grid of rows and columns. Cells spanning several rows or
+// columns appear at each position they cover.
+func (t *htmlTable) grid() [][]*htmlTableCell {
+	grid := make([][]*htmlTableCell, len(t.rows))
+	for r, row := range t.rows {
+		c := 0
+		for _, cell := range row {
+			for c < len(grid[r]) && grid[r][c] != nil {
+				c++
+			}
+			for dr := 0; dr < cell.rowspan && r+dr < len(grid); dr++ {
+				for dc := 0; dc < cell.colspan; dc++ {
+					for len(grid[r+dr]) <= c+dc {
+						grid[r+dr] = append(grid[r+dr], nil)
+					}
+					grid[r+dr][c+dc] = cell
+				}
+			}
+			c += cell.colspan
+		}
+	}
+	return grid
+}
+
+// columns returns the number of columns of the table
+func (t *htmlTable) columns() int {
+	columns := 0
+	for _, row := range t.grid() {
+		columns = max(columns, len(row))
+	}
+	return columns
+}
+
+// captionHTML returns the caption as a paragraph for layouts without a table element
+func (t *htmlTable) captionHTML() string {
+	if t.caption == "" {
+		return ""
+	}
+	return fmt.Sprintf(`<p class="table-title">%s</p>`, t.caption)
+}
+
+// stackedHTML lays out each row as a list of header and value pairs. The labels are the last
+// header row, or the first row when the table has no header. It returns an empty string for
+// tables without rows to stack.
+func (t *htmlTable) stackedHTML() string {
+	grid := t.grid()
+	labelRow := t.headerRows - 1
+	if labelRow < 0 {
+		labelRow = 0
+	}
+	if len(grid) <= labelRow+1 {
+		return ""
+	}
+	labels := grid[labelRow]
+
+	var body strings.Builder
+	body.WriteString(`<div class="table-container table-stacked">`)
+	body.WriteString(t.captionHTML())
+	for _, row := range grid[labelRow+1:] {
+		body.WriteString(`<dl class="stacked-row">`)
+		for c, cell := range row {
+			if cell == nil || (c > 0 && row[c-1] == cell) || plainText(cell.content) == "" {
+				continue
+			}
+			label := fmt.Sprintf("第%s列", formatKanjiNumber(c+1))
+			if c < len(labels) && labels[c] != nil && plainText(labels[c].content) != "" {
+				label = labels[c].content
+			}
+			fmt.Fprintf(&body, "<dt>%s</dt><dd>%s</dd>", label, cell.content)
+		}
+		body.WriteString(`</dl>`)
+	}
+	body.WriteString(htmlDivEnd)
+	return body.String()
+}
+
+// scrollHTML keeps the table in a container that scrolls horizontally. The style is inline
+// since readers may not apply the book stylesheet.
+func scrollHTML(fragment string) string {
+	return strings.Replace(fragment, `<div class="table-container">`,
+		`<div class="table-container table-scroll" style="overflow-x: auto; max-width: 100%;">`, 1)
+}
+
+// altText returns the text of the table, row by row, for the alternative text of its image
+func (t *htmlTable) altText() string {
+	var rows []string
+	if caption := plainText(t.caption); caption != "" {
+		rows = append(rows, caption)
+	}
+	for _, row := range t.rows {
+		var cells []string
+		for _, cell := range row {
+			cells = append(cells, plainText(cell.content))
+		}
+		rows = append(rows, strings.Join(cells, "　"))
+	}
+	return strings.Join(rows, " / ")
+}
+
+// renderImage rasterises the table with the font f. Columns are as wide as their text up
+// to tableImageMaxColumn, and longer text wraps.
+func (t *htmlTable) renderImage(f *sfnt.Font) (*image.RGBA, error) {
+	face, err := newCoverFace(f, tableImageFontSize)
+	if err != nil {
+		return nil, err
+	}
+	defer face.Close()
+
+	grid := t.grid()
+	columns := t.columns()
+	if columns == 0 {
+		return nil, errors.New("rasterising table: the table has no cells")
+	}
+
+	// Column widths from the cells spanning a single column
+	widths := make([]int, columns)
+	for i := range widths {
+		widths[i] = tableImageFontSize + 2*tableImagePadding
+	}
+	for _, row := range grid {
+		for c, cell := range row {
+			if cell != nil && cell.colspan == 1 {
+				width := font.MeasureString(face, plainText(cell.content)).Ceil() + 2*tableImagePadding
+				widths[c] = min(max(widths[c], width), tableImageMaxColumn)
+			}
+		}
+	}
+
+	// Wrap the text of each cell, then size the rows to fit it
+	type placedCell struct {
+		row, column int
+		lines       []string
+	}
+	var placed []placedCell
+	for r, row := range grid {
+		for c, cell := range row {
+			if cell == nil || (r > 0 && c < len(grid[r-1]) && grid[r-1][c] == cell) || (c > 0 && row[c-1] == cell) {
+				continue
+			}
+			width := -2 * tableImagePadding
+			for dc := 0; dc < cell.colspan && c+dc < columns; dc++ {
+				width += widths[c+dc]
+			}
+			placed = append(placed, placedCell{row: r, column: c, lines: wrapCoverLine(face, plainText(cell.content), fixed.I(width))})
+		}
+	}
+	heights := make([]int, len(grid))
+	for i := range heights {
+		heights[i] = tableImageLineHeight + 2*tableImagePadding
+	}
+	for _, p := range placed {
+		cell := grid[p.row][p.column]
+		needed := len(p.lines)*tableImageLineHeight + 2*tableImagePadding
+		if cell.rowspan == 1 {
+			heights[p.row] = max(heights[p.row], needed)
+		}
+	}
+	for _, p := range placed {
+		cell := grid[p.row][p.column]
+		last := min(p.row+cell.rowspan, len(grid)) - 1
+		needed := len(p.lines)*tableImageLineHeight + 2*tableImagePadding
+		if have := sumRange(heights, p.row, last+1); have < needed {
+			heights[last] += needed - have
+		}
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, sumRange(widths, 0, columns)+1, sumRange(heights, 0, len(heights))+1))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	drawer := &font.Drawer{Dst: img, Src: image.NewUniform(color.Black), Face: face}
+	ascent := face.Metrics().Ascent.Ceil()
+	for _, p := range placed {
+		cell := grid[p.row][p.column]
+		rect := image.Rect(
+			sumRange(widths, 0, p.column), sumRange(heights, 0, p.row),
+			sumRange(widths, 0, min(p.column+cell.colspan, columns)), sumRange(heights, 0, min(p.row+cell.rowspan, len(grid))),
+		)
+		if cell.header || p.row < t.headerRows {
+			draw.Draw(img, rect, image.NewUniform(tableImageHeaderFill), image.Point{}, draw.Src)
+		}
+		drawRectOutline(img, rect, tableImageBorder)
+		for i, line := range p.lines {
+			drawer.Dot = fixed.P(rect.Min.X+tableImagePadding, rect.Min.Y+tableImagePadding+ascent+i*tableImageLineHeight)
+			drawer.DrawString(line)
+		}
+	}
+	return img, nil
+}
+
+// sumRange returns the sum of values[from:to]
+func sumRange(values []int, from, to int) int {
+	sum := 0
+	for _, v := range values[from:to] {
+		sum += v
+	}
+	return sum
+}
+
+// drawRectOutline draws a one pixel outline around rect, including its right and bottom edges
+func drawRectOutline(img *image.RGBA, rect image.Rectangle, c color.Color) {
+	for x := rect.Min.X; x <= rect.Max.X; x++ {
+		img.Set(x, rect.Min.Y, c)
+		img.Set(x, rect.Max.Y, c)
+	}
+	for y := rect.Min.Y; y <= rect.Max.Y; y++ {
+		img.Set(rect.Min.X, y, c)
+		img.Set(rect.Max.X, y, c)
+	}
+}
+
+// wideTableWriter lays out wide tables in section bodies with the selected strategy
+type wideTableWriter struct {
+	BookWriter
+	strategy WideTableStrategy
+	columns  int
+	// font rasterises tables for WideTableImage
+	font   *sfnt.Font
+	images int
+}
+
+// newWideTableWriter wraps book so that wide tables are laid out as opts select
+func newWideTableWriter(book BookWriter, opts *EPUBOptions) (*wideTableWriter, error) {
+	w := &wideTableWriter{BookWriter: book, strategy: WideTableScroll, columns: defaultWideTableColumns}
+	if opts == nil {
+		return w, nil
+	}
+
+	strategy, err := ParseWideTableStrategy(string(opts.WideTables))
+	if err != nil {
+		return nil, err
+	}
+	w.strategy = strategy
+	if opts.WideTableColumns > 0 {
+		w.columns = opts.WideTableColumns
+	}
+
+	if strategy == WideTableImage {
+		if len(opts.EmbedFont) > 0 {
+			w.font, err = parseFont(opts.EmbedFont)
+		} else {
+			w.font, err = coverFont(opts)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if w.font == nil {
+			return nil, errors.New("rasterising tables needs a font: set EmbedFont or CoverFont, or install a CJK font")
+		}
+	}
+	return w, nil
+}
+
+// AddSection lays out the wide tables of body before adding it
+func (w *wideTableWriter) AddSection(body, sectionTitle, internalFilename, internalCSSPath string) (string, error) {
+	body, err := w.layout(body)
+	if err != nil {
+		return "", err
+	}
+	return w.BookWriter.AddSection(body, sectionTitle, internalFilename, internalCSSPath)
+}
+
+// AddSubSection lays out the wide tables of body before adding it
+func (w *wideTableWriter) AddSubSection(parentFilename, body, sectionTitle, internalFilename, internalCSSPath string) (string, error) {
+	body, err := w.layout(body)
+	if err != nil {
+		return "", err
+	}
+	return w.BookWriter.AddSubSection(parentFilename, body, sectionTitle, internalFilename, internalCSSPath)
+}
+
+// layout replaces each table of body with more than w.columns columns
+func (w *wideTableWriter) layout(body string) (string, error) {
+	matches := findTableContainers(body)
+	if len(matches) == 0 {
+		return body, nil
+	}
+
+	var result strings.Builder
+	last := 0
+	for _, match := range matches {
+		fragment := body[match[0]:match[1]]
+		result.WriteString(body[last:match[0]])
+		last = match[1]
+
+		table, err := parseHTMLTable(fragment)
+		if err != nil || table.columns() <= w.columns {
+			// Lay out the tables nested in its cells
+			inner, err := w.layout(fragment[len(tableContainerStart):])
+			if err != nil {
+				return "", err
+			}
+			result.WriteString(tableContainerStart + inner)
+			continue
+		}
+		replacement, err := w.replacement(table, fragment)
+		if err != nil {
+			return "", err
+		}
+		result.WriteString(replacement)
+	}
+	result.WriteString(body[last:])
+	return result.String(), nil
+}
+
+// replacement returns the layout of a wide table
+func (w *wideTableWriter) replacement(table *htmlTable, fragment string) (string, error) {
+	switch w.strategy {
+	case WideTableStack:
+		if stacked := table.stackedHTML(); stacked != "" {
+			return stacked, nil
+		}
+	case WideTableImage:
+		img, err := table.renderImage(w.font)
+		if err != nil {
+			return "", err
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return "", fmt.Errorf("encoding table image: %w", err)
+		}
+		w.images++
+		dataURL := "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+		src, err := w.AddImage(dataURL, fmt.Sprintf("table-%d.png", w.images))
+		if err != nil {
+			return "", fmt.Errorf("adding table image: %w", err)
+		}
+		return fmt.Sprintf(`<div class="table-container table-image">%s<img src=%q alt="%s" style="max-width: 100%%; height: auto;" /></div>`,
+			table.captionHTML(), src, escapeXMLText(table.altText())), nil
+	}
+	return scrollHTML(fragment), nil
+}
@@ -0,0 +1,157 @@
+package jplaw2epub
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.ngs.io/jplaw-xml"
+)
+
+// wideTestTable renders a table with a header row, a cell spanning two rows and one spanning all columns
+func wideTestTable() string {
+	cell := func(text string) jplaw.TableColumn {
+		return jplaw.TableColumn{Sentence: []jplaw.Sentence{createTestSentence(text)}}
+	}
+	spanned := cell("第一種")
+	spanned.Rowspan = "2"
+	footer := cell("備考")
+	footer.Colspan = "3"
+	return processTable(&jplaw.Table{
+		TableHeaderRow: []jplaw.TableHeaderRow{{TableHeaderColumn: []jplaw.TableHeaderColumn{{Content: "区分"}, {Content: "内容"}, {Content: "税率"}}}},
+		TableRow: []jplaw.TableRow{
+			{TableColumn: []jplaw.TableColumn{spanned, cell("筆記"), cell("一割")}},
+			{TableColumn: []jplaw.TableColumn{cell("口述"), cell("二割")}},
+			{TableColumn: []jplaw.TableColumn{footer}},
+		},
+	}, "税率表", nil)
+}
+
+func TestParseHTMLTable(t *testing.T) {
+	table, err := parseHTMLTable(wideTestTable())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.caption != "税率表" || table.headerRows != 1 || len(table.rows) != 4 {
+		t.Fatalf("parseHTMLTable() = caption %q, %d header rows, %d rows", table.caption, table.headerRows, len(table.rows))
+	}
+	if columns := table.columns(); columns != 3 {
+		t.Errorf("columns() = %d, want 3", columns)
+	}
+
+	grid := table.grid()
+	if grid[2][0] != grid[1][0] || grid[2][1].content != "口述" {
+		t.Errorf("grid() should place the spanned cell in both rows: %+v", grid[2])
+	}
+	if grid[3][0] != grid[3][2] {
+		t.Errorf("grid() should place the column-spanning cell in every column: %+v", grid[3])
+	}
+}
+
+func TestWideTableLayout(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy WideTableStrategy
+		columns  int
+		want     []string
+	}{
+		{name: "narrow table", strategy: WideTableStack, columns: 3, want: []string{`<div class="table-container"><table`}},
+		{name: "scroll", strategy: WideTableScroll, columns: 2, want: []string{`<div class="table-container table-scroll" style="overflow-x: auto; max-width: 100%;"><table`}},
+		{
+			name:     "stack",
+			strategy: WideTableStack,
+			columns:  2,
+			want: []string{
+				`<div class="table-container table-stacked"><p class="table-title">税率表</p>`,
+				`<dl class="stacked-row"><dt>区分</dt><dd>第一種</dd><dt>内容</dt><dd>口述</dd><dt>税率</dt><dd>二割</dd></dl>`,
+				`<dl class="stacked-row"><dt>区分</dt><dd>備考</dd></dl>`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &wideTableWriter{strategy: tt.strategy, columns: tt.columns}
+			got, err := w.layout("<p>前</p>" + wideTestTable() + "<p>後</p>")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(got, "<p>前</p>") || !strings.HasSuffix(got, "<p>後</p>") {
+				t.Errorf("layout() should keep the text around the table:\n%s", got)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("layout() missing %q:\n%s", want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestWideTableLayoutNested(t *testing.T) {
+	cell := func(text string) jplaw.TableColumn {
+		return jplaw.TableColumn{Sentence: []jplaw.Sentence{createTestSentence(text)}}
+	}
+	outer := processTable(&jplaw.Table{
+		TableRow: []jplaw.TableRow{
+			{TableColumn: []jplaw.TableColumn{cell("税率"), cell("内表")}},
+			{TableColumn: []jplaw.TableColumn{cell("備考"), cell("なし")}},
+		},
+	}, "", nil)
+	nested := strings.Replace(outer, "内表", wideTestTable(), 1)
+
+	table, err := parseHTMLTable(nested)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if columns := table.columns(); columns != 2 || len(table.rows) != 2 {
+		t.Fatalf("parseHTMLTable() = %d rows of %d columns, want the 2 by 2 outer table", len(table.rows), columns)
+	}
+	if !strings.Contains(table.rows[0][1].content, "筆記") {
+		t.Errorf("the nested table should be the content of its cell: %q", table.rows[0][1].content)
+	}
+
+	w := &wideTableWriter{strategy: WideTableScroll, columns: 2}
+	got, err := w.layout("<p>前</p>" + nested + "<p>後</p>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(got, "<p>前</p>"+tableContainerStart) || !strings.HasSuffix(got, "</table></div><p>後</p>") {
+		t.Errorf("layout() should keep the narrow outer table and the text around it:\n%s", got)
+	}
+	if strings.Count(got, "table-scroll") != 1 || !strings.Contains(got, `table-scroll" style="overflow-x: auto; max-width: 100%;"><table class="law-table"><caption class="table-title">税率表`) {
+		t.Errorf("layout() should scroll only the wide nested table:\n%s", got)
+	}
+	if strings.Count(got, "<table") != 2 || strings.Count(got, "</table>") != 2 {
+		t.Errorf("layout() should keep both tables whole:\n%s", got)
+	}
+}
+
+func TestParseWideTableStrategy(t *testing.T) {
+	for input, want := range map[string]WideTableStrategy{"": WideTableScroll, "stack": WideTableStack, "image": WideTableImage} {
+		if got, err := ParseWideTableStrategy(input); err != nil || got != want {
+			t.Errorf("ParseWideTableStrategy(%q) = %q, %v, want %q", input, got, err, want)
+		}
+	}
+	if _, err := ParseWideTableStrategy("shrink"); err == nil {
+		t.Error("ParseWideTableStrategy(shrink) should fail")
+	}
+}
+
+func TestWriteEPUBWideTableImage(t *testing.T) {
+	font, err := os.ReadFile(filepath.Join("testdata", "fonts", "CFFTest.otf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := writeTestEPUB(t, &EPUBOptions{WideTables: WideTableImage, WideTableColumns: 1, CoverFont: font, NoCover: true})
+
+	if _, ok := files["EPUB/images/table-1.png"]; !ok {
+		t.Fatal("the wide table should be rasterised as EPUB/images/table-1.png")
+	}
+	article := files["EPUB/xhtml/article-0-1.xhtml"]
+	want := `<img src="../images/table-1.png" alt="試験区分表 / 区分　内容 / 第一種　筆記 / 口述 / 第二種は、別に定める。"`
+	if !strings.Contains(article, want) || strings.Contains(article, "<table") {
+		t.Errorf("article should show the table image with its text as alternative text:\n%s", article)
+	}
+}